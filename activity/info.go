@@ -0,0 +1,49 @@
+package activity
+
+import (
+	"context"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/activity"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+type Info struct {
+	// ActivityID is the ID of the event that scheduled this activity
+	ActivityID string
+
+	// Name is the name the activity was registered with
+	Name string
+
+	// Attempt is the current attempt of an activity scheduled with retry options, starting at 1
+	Attempt int
+
+	// ScheduledAt is the time the workflow scheduled this activity
+	ScheduledAt time.Time
+
+	// Deadline is the deadline of the activity's context, zero if there is none
+	Deadline time.Time
+
+	// WorkflowInstance is the workflow instance this activity is executed for
+	WorkflowInstance *workflow.Instance
+
+	// TaskID is the ID of the activity task assigned by the backend
+	TaskID string
+}
+
+// GetInfo returns information about the currently executing activity
+func GetInfo(ctx context.Context) *Info {
+	as := activity.GetActivityState(ctx)
+
+	deadline, _ := ctx.Deadline()
+
+	return &Info{
+		ActivityID:       as.ActivityID,
+		Name:             as.Name,
+		Attempt:          as.Attempt + 1,
+		ScheduledAt:      as.ScheduledAt,
+		Deadline:         deadline,
+		WorkflowInstance: as.Instance,
+		TaskID:           as.TaskID,
+	}
+}
@@ -0,0 +1,229 @@
+// Package archive provides archivers that store the history of finished workflow instances outside of the backend
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+const fileExtension = ".jsonl.gz"
+
+type fileSystemArchiver struct {
+	dir string
+}
+
+var _ backend.Archiver = (*fileSystemArchiver)(nil)
+
+// NewFileSystemArchiver returns an archiver that stores every archived execution in a gzip compressed JSON lines file
+// at <dir>/<instance id>/<execution id>.jsonl.gz. The first line contains the instance, every following line one
+// history event.
+func NewFileSystemArchiver(dir string) (backend.Archiver, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating archive directory: %w", err)
+	}
+
+	return &fileSystemArchiver{dir}, nil
+}
+
+func (a *fileSystemArchiver) ArchiveWorkflowInstance(ctx context.Context, instance *backend.ArchivedWorkflowInstance) error {
+	dir := filepath.Join(a.dir, escape(instance.Instance.Instance.InstanceID))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so readers never see partially written archives
+	f, err := os.CreateTemp(dir, ".archive-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := write(f, instance); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(dir, escape(instance.Instance.Instance.ExecutionID)+fileExtension))
+}
+
+func write(w io.Writer, instance *backend.ArchivedWorkflowInstance) error {
+	bw := bufio.NewWriter(w)
+	gw := gzip.NewWriter(bw)
+	enc := json.NewEncoder(gw)
+
+	if err := enc.Encode(instance.Instance); err != nil {
+		return fmt.Errorf("encoding instance: %w", err)
+	}
+
+	for _, event := range instance.History {
+		if err := enc.Encode(event); err != nil {
+			return fmt.Errorf("encoding event: %w", err)
+		}
+	}
+
+	if err := gw.Close(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func (a *fileSystemArchiver) GetArchivedWorkflowInstance(ctx context.Context, instanceID, executionID string) (*backend.ArchivedWorkflowInstance, error) {
+	dir := filepath.Join(a.dir, escape(instanceID))
+
+	var path string
+	if executionID != "" {
+		path = filepath.Join(dir, escape(executionID)+fileExtension)
+	} else {
+		var err error
+		if path, err = latest(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	return read(f)
+}
+
+// latest returns the execution in the given instance directory that completed last. Completion times are read from
+// the archives, file modification times change when archives are copied or restored.
+func latest(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", backend.ErrInstanceNotFound
+		}
+
+		return "", err
+	}
+
+	var path string
+	var newest *backend.WorkflowInstanceInfo
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), fileExtension) {
+			continue
+		}
+
+		p := filepath.Join(dir, e.Name())
+
+		info, err := readInfo(p)
+		if err != nil {
+			return "", err
+		}
+
+		if newest == nil || completedAfter(info, newest) {
+			path = p
+			newest = info
+		}
+	}
+
+	if newest == nil {
+		return "", backend.ErrInstanceNotFound
+	}
+
+	return path, nil
+}
+
+// completedAfter reports whether a completed after b, instances that completed at the same time are ordered by their
+// creation time
+func completedAfter(a, b *backend.WorkflowInstanceInfo) bool {
+	var ac, bc time.Time
+	if a.CompletedAt != nil {
+		ac = *a.CompletedAt
+	}
+
+	if b.CompletedAt != nil {
+		bc = *b.CompletedAt
+	}
+
+	if !ac.Equal(bc) {
+		return ac.After(bc)
+	}
+
+	return a.CreatedAt.After(b.CreatedAt)
+}
+
+// readInfo reads only the instance from the first line of the archive at the given path
+func readInfo(path string) (*backend.WorkflowInstanceInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("reading archive: %w", err)
+	}
+	defer gr.Close()
+
+	var info *backend.WorkflowInstanceInfo
+	if err := json.NewDecoder(gr).Decode(&info); err != nil {
+		return nil, fmt.Errorf("decoding instance: %w", err)
+	}
+
+	return info, nil
+}
+
+func read(r io.Reader) (*backend.ArchivedWorkflowInstance, error) {
+	gr, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("reading archive: %w", err)
+	}
+	defer gr.Close()
+
+	dec := json.NewDecoder(gr)
+
+	instance := &backend.ArchivedWorkflowInstance{}
+	if err := dec.Decode(&instance.Instance); err != nil {
+		return nil, fmt.Errorf("decoding instance: %w", err)
+	}
+
+	for {
+		var event *history.Event
+		if err := dec.Decode(&event); err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return nil, fmt.Errorf("decoding event: %w", err)
+		}
+
+		instance.History = append(instance.History, event)
+	}
+
+	return instance, nil
+}
+
+func escape(id string) string {
+	// Escape dots as well, so that ids cannot be interpreted as relative paths
+	return strings.ReplaceAll(url.PathEscape(id), ".", "%2E")
+}
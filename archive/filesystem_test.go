@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FileSystemArchiver(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	a, err := NewFileSystemArchiver(dir)
+	require.NoError(t, err)
+
+	completedAt := time.Now().UTC()
+	instance := &backend.ArchivedWorkflowInstance{
+		Instance: &backend.WorkflowInstanceInfo{
+			Instance:     core.NewWorkflowInstance("../instance/1", "execution-1"),
+			WorkflowName: "wf",
+			State:        core.WorkflowInstanceStateFinished,
+			CreatedAt:    completedAt.Add(-time.Minute),
+			CompletedAt:  &completedAt,
+		},
+		History: []*history.Event{
+			history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"}),
+			history.NewHistoryEvent(2, time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{Result: []byte("42")}),
+		},
+	}
+
+	require.NoError(t, a.ArchiveWorkflowInstance(ctx, instance))
+
+	// Instance ids cannot escape the archive directory
+	_, err = os.Stat(filepath.Join(dir, "%2E%2E%2Finstance%2F1", "execution-1.jsonl.gz"))
+	require.NoError(t, err)
+
+	r, err := a.GetArchivedWorkflowInstance(ctx, "../instance/1", "execution-1")
+	require.NoError(t, err)
+	require.Equal(t, instance.Instance.Instance, r.Instance.Instance)
+	require.Equal(t, "wf", r.Instance.WorkflowName)
+	require.Equal(t, core.WorkflowInstanceStateFinished, r.Instance.State)
+	require.True(t, completedAt.Equal(*r.Instance.CompletedAt))
+	require.Len(t, r.History, 2)
+	require.Equal(t, instance.History[1].ID, r.History[1].ID)
+	require.Equal(t, instance.History[1].Attributes, r.History[1].Attributes)
+
+	// Without an execution id, the execution that completed last is returned, regardless of when it was archived
+	secondCompletedAt := completedAt.Add(time.Minute)
+	second := *instance
+	second.Instance = &backend.WorkflowInstanceInfo{
+		Instance:    core.NewWorkflowInstance("../instance/1", "execution-2"),
+		State:       core.WorkflowInstanceStateFinished,
+		CreatedAt:   completedAt,
+		CompletedAt: &secondCompletedAt,
+	}
+	require.NoError(t, a.ArchiveWorkflowInstance(ctx, &second))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "%2E%2E%2Finstance%2F1", "execution-1.jsonl.gz"), time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+
+	r, err = a.GetArchivedWorkflowInstance(ctx, "../instance/1", "")
+	require.NoError(t, err)
+	require.Equal(t, "execution-2", r.Instance.Instance.ExecutionID)
+
+	_, err = a.GetArchivedWorkflowInstance(ctx, "../instance/1", "execution-3")
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	_, err = a.GetArchivedWorkflowInstance(ctx, "other", "")
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+}
@@ -0,0 +1,26 @@
+package backend
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+// ArchivedWorkflowInstance is a finished workflow instance together with its full history
+type ArchivedWorkflowInstance struct {
+	Instance *WorkflowInstanceInfo
+	History  []*history.Event
+}
+
+// Archiver stores finished workflow instances outside of the backend. Instances are archived before they are removed
+// by the retention cleanup or with client.RemoveWorkflowInstance, and clients and the diagnostics server read removed
+// instances back from the archiver.
+type Archiver interface {
+	// ArchiveWorkflowInstance stores the given finished workflow instance, replacing an earlier archive of the same
+	// execution
+	ArchiveWorkflowInstance(ctx context.Context, instance *ArchivedWorkflowInstance) error
+
+	// GetArchivedWorkflowInstance returns the archived execution of the workflow instance with the given id, or
+	// ErrInstanceNotFound. If executionID is empty, the execution that completed last is returned.
+	GetArchivedWorkflowInstance(ctx context.Context, instanceID, executionID string) (*ArchivedWorkflowInstance, error)
+}
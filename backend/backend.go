@@ -3,6 +3,7 @@ package backend
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/cschleiden/go-workflows/internal/converter"
 	core "github.com/cschleiden/go-workflows/internal/core"
@@ -14,8 +15,10 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
-var ErrInstanceNotFound = errors.New("workflow instance not found")
-var ErrInstanceAlreadyExists = errors.New("workflow instance already exists")
+var ErrInstanceNotFound = core.ErrInstanceNotFound
+var ErrInstanceFinished = core.ErrInstanceFinished
+var ErrInstanceAlreadyExists = core.ErrInstanceAlreadyExists
+var ErrInstanceNotFinished = errors.New("workflow instance is not finished")
 
 const TracerName = "go-workflow"
 
@@ -34,6 +37,19 @@ type Backend interface {
 	// is given, only events after that event are returned. Otherwise the full history is returned.
 	GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64) ([]*history.Event, error)
 
+	// ListWorkflowInstances returns a page of the workflow instances matching the given options, most recently
+	// created instances first
+	ListWorkflowInstances(ctx context.Context, options *ListWorkflowInstancesOptions) (*ListWorkflowInstancesResult, error)
+
+	// DescribeWorkflowInstance returns the status of the given workflow instance and the work it is waiting for,
+	// or ErrInstanceNotFound if the instance does not exist
+	DescribeWorkflowInstance(ctx context.Context, instance *workflow.Instance) (*WorkflowInstanceDescription, error)
+
+	// RemoveWorkflowInstance removes the given finished workflow instance together with its history, pending events,
+	// and search attributes. It returns ErrInstanceNotFound if the instance does not exist and ErrInstanceNotFinished
+	// if it is still running.
+	RemoveWorkflowInstance(ctx context.Context, instance *workflow.Instance) error
+
 	// SignalWorkflow signals a running workflow instance
 	//
 	// If the given instance does not exist, it will return an error
@@ -54,6 +70,12 @@ type Backend interface {
 		ctx context.Context, task *task.Workflow, instance *workflow.Instance, state core.WorkflowInstanceState,
 		executedEvents, activityEvents, timerEvents []*history.Event, workflowEvents []history.WorkflowEvent) error
 
+	// FailWorkflowTask releases a workflow task retrieved using GetWorkflowTask without processing its new events.
+	// failedEvent, if not nil, is added to the workflow instance history, and the task is made available again with
+	// the same new events once retryAfter has elapsed. The attempt of the next task of the instance is increased,
+	// until a task is completed.
+	FailWorkflowTask(ctx context.Context, task *task.Workflow, instance *workflow.Instance, failedEvent *history.Event, retryAfter time.Duration) error
+
 	// GetActivityTask returns a pending activity task or nil if there are no pending activities
 	GetActivityTask(ctx context.Context) (*task.Activity, error)
 
@@ -63,6 +85,9 @@ type Backend interface {
 	// ExtendActivityTask extends the lock of an activity task
 	ExtendActivityTask(ctx context.Context, activityID string) error
 
+	// Options returns the options the backend was created with
+	Options() Options
+
 	// Logger returns the configured logger for the backend
 	Logger() log.Logger
 
@@ -74,4 +99,31 @@ type Backend interface {
 
 	// Converter returns the configured converter for the backend
 	Converter() converter.Converter
+
+	// ContextPropagators returns the configured context propagators for the backend
+	ContextPropagators() []workflow.ContextPropagator
+}
+
+// CompletionSubscriber is an optional interface implemented by backends that can notify waiters when a workflow
+// instance finishes. Clients use it to wait for instances and fall back to polling for backends that do not
+// implement it.
+type CompletionSubscriber interface {
+	// SubscribeWorkflowInstanceCompletion returns a channel that is closed when the given instance finishes and a
+	// function to end the subscription. Only instances finishing after the subscription has been created are
+	// reported, callers need to check the state of the instance after subscribing.
+	//
+	// Notifications are delivered on a best-effort basis, for example only for instances finished within the same
+	// process for some backends, so callers should still check the state of the instance periodically.
+	SubscribeWorkflowInstanceCompletion(ctx context.Context, instance *workflow.Instance) (<-chan struct{}, func(), error)
+}
+
+// HistoryWatcher is an optional interface implemented by backends that can stream the history of a workflow instance
+// as it is committed. Clients fall back to polling the history for backends that do not implement it.
+type HistoryWatcher interface {
+	// WatchWorkflowInstanceHistory returns a channel of the history events of the given instance with a sequence id
+	// greater than fromSequenceID, in order and as they are committed by CompleteWorkflowTask. The channel is
+	// closed once the instance has finished and all its events have been delivered, when ctx is canceled, or when
+	// the instance is removed. Errors reading the history are retried with backoff until then, so that a closed
+	// channel is never mistaken for a finished instance because of a transient error.
+	WatchWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, fromSequenceID int64) (<-chan *history.Event, error)
 }
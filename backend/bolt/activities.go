@@ -0,0 +1,177 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/workflow"
+	bolt "go.etcd.io/bbolt"
+)
+
+// GetActivityTask returns a pending activity task or nil if there are no pending activities. If there is no task, it
+// waits until it is notified about a new task, the lock of an abandoned task expires, or BlockTimeout passes.
+func (b *boltBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
+	// Subscribe before looking for a task, to not miss tasks committed in between
+	wake, cancel := b.taskNotifier.Subscribe(activityTasksKey)
+	defer cancel()
+
+	t, next, err := b.getActivityTask()
+	if err != nil || t != nil {
+		return t, err
+	}
+
+	if !b.waitForTask(ctx, wake, next) {
+		return nil, nil
+	}
+
+	t, _, err = b.getActivityTask()
+	return t, err
+}
+
+func (b *boltBackend) getActivityTask() (*task.Activity, *time.Time, error) {
+	var t *task.Activity
+	var next *time.Time
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		now := time.Now()
+
+		c := tx.Bucket(activitiesBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var a activityState
+			if err := json.Unmarshal(v, &a); err != nil {
+				return fmt.Errorf("unmarshaling activity: %w", err)
+			}
+
+			if a.LockedUntil != nil && a.LockedUntil.After(now) {
+				if next == nil || a.LockedUntil.Before(*next) {
+					next = a.LockedUntil
+				}
+
+				continue
+			}
+
+			s, err := getInstance(tx, a.Instance.InstanceID)
+			if err != nil {
+				return err
+			}
+
+			var metadata *workflow.Metadata
+			if s != nil {
+				metadata = s.Metadata
+			}
+
+			lockedUntil := now.Add(b.options.ActivityLockTimeout)
+			a.LockedUntil = &lockedUntil
+			a.Worker = b.workerName
+			if err := putActivity(tx, append([]byte(nil), k...), &a); err != nil {
+				return fmt.Errorf("locking activity: %w", err)
+			}
+
+			t = &task.Activity{
+				ID:               a.Event.ID,
+				WorkflowInstance: a.Instance,
+				Metadata:         metadata,
+				Event:            a.Event,
+			}
+
+			return nil
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return t, next, nil
+}
+
+// getLockedActivity returns the key and state of the given activity if it is locked by this worker
+func (b *boltBackend) getLockedActivity(tx *bolt.Tx, activityID string) ([]byte, *activityState, error) {
+	key, a, err := getActivity(tx, activityID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if a == nil || a.Worker != b.workerName {
+		return nil, nil, errors.New("could not find activity locked by this worker")
+	}
+
+	return key, a, nil
+}
+
+// CompleteActivityTask completes an activity task retrieved using GetActivityTask
+func (b *boltBackend) CompleteActivityTask(ctx context.Context, instance *workflow.Instance, activityID string, event *history.Event) error {
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		key, a, err := b.getLockedActivity(tx, activityID)
+		if err != nil {
+			return err
+		}
+
+		if a.Instance.InstanceID != instance.InstanceID || a.Instance.ExecutionID != instance.ExecutionID {
+			return errors.New("activity does not belong to workflow instance")
+		}
+
+		// Remove activity
+		if err := tx.Bucket(activitiesBucket).Delete(key); err != nil {
+			return fmt.Errorf("deleting activity: %w", err)
+		}
+
+		if err := tx.Bucket(activityIDsBucket).Delete([]byte(activityID)); err != nil {
+			return fmt.Errorf("deleting activity: %w", err)
+		}
+
+		// Insert new event generated during this workflow execution
+		if err := insertPendingEvents(tx, instance.InstanceID, []*history.Event{event}); err != nil {
+			return fmt.Errorf("inserting new events for completed activity: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	b.taskNotifier.Notify(workflowTasksKey)
+
+	return nil
+}
+
+func (b *boltBackend) ExtendActivityTask(ctx context.Context, activityID string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		key, a, err := b.getLockedActivity(tx, activityID)
+		if err != nil {
+			return fmt.Errorf("extending activity lock: %w", err)
+		}
+
+		lockedUntil := time.Now().Add(b.options.ActivityLockTimeout)
+		a.LockedUntil = &lockedUntil
+
+		return putActivity(tx, key, a)
+	})
+}
+
+// activityWorkers returns the activities of the given instance currently locked by a worker, by schedule event id
+func activityWorkers(tx *bolt.Tx, instance *core.WorkflowInstance) (map[int64]*activityState, error) {
+	r := make(map[int64]*activityState)
+
+	err := tx.Bucket(activitiesBucket).ForEach(func(k, v []byte) error {
+		var a activityState
+		if err := json.Unmarshal(v, &a); err != nil {
+			return fmt.Errorf("unmarshaling activity: %w", err)
+		}
+
+		if a.Instance.InstanceID == instance.InstanceID && a.Instance.ExecutionID == instance.ExecutionID && a.Worker != "" {
+			r[a.Event.ScheduleEventID] = &a
+		}
+
+		return nil
+	})
+
+	return r, err
+}
@@ -0,0 +1,656 @@
+package bolt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/internal/notify"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/search"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Keys of the notifications that wake up pollers waiting for tasks
+const (
+	workflowTasksKey = "workflow-tasks"
+	activityTasksKey = "activity-tasks"
+)
+
+type BoltOptions struct {
+	backend.Options
+
+	// BlockTimeout is the maximum time GetWorkflowTask and GetActivityTask wait for a new task before returning
+	BlockTimeout time.Duration
+
+	// OpenTimeout is the time to wait for the lock on the database file, which is held by the process that has it
+	// open. Zero waits indefinitely.
+	OpenTimeout time.Duration
+}
+
+type BoltBackendOption func(*BoltOptions)
+
+func WithBlockTimeout(timeout time.Duration) BoltBackendOption {
+	return func(o *BoltOptions) {
+		o.BlockTimeout = timeout
+	}
+}
+
+func WithOpenTimeout(timeout time.Duration) BoltBackendOption {
+	return func(o *BoltOptions) {
+		o.OpenTimeout = timeout
+	}
+}
+
+func WithBackendOptions(opts ...backend.BackendOption) BoltBackendOption {
+	return func(o *BoltOptions) {
+		for _, opt := range opts {
+			opt(&o.Options)
+		}
+	}
+}
+
+// NewBoltBackend opens or creates the database file at the given path. The file can only be used by a single process
+// at a time.
+func NewBoltBackend(path string, opts ...BoltBackendOption) (*boltBackend, error) {
+	options := &BoltOptions{
+		Options:      backend.ApplyOptions(),
+		BlockTimeout: time.Second * 2,
+		OpenTimeout:  time.Second * 5,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: options.OpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		// Databases created before workflow tasks were indexed need their index built once
+		backfill := tx.Bucket(workflowTasksBucket) == nil && tx.Bucket(pendingEventsBucket) != nil
+
+		if err := createBuckets(tx); err != nil {
+			return err
+		}
+
+		if backfill {
+			return indexWorkflowTasks(tx)
+		}
+
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing database: %w", err)
+	}
+
+	return &boltBackend{
+		db:                 db,
+		workerName:         fmt.Sprintf("worker-%v", uuid.NewString()),
+		options:            options,
+		taskNotifier:       notify.New(),
+		completionNotifier: notify.New(),
+		historyNotifier:    notify.New(),
+	}, nil
+}
+
+type boltBackend struct {
+	db         *bolt.DB
+	workerName string
+	options    *BoltOptions
+
+	// taskNotifier wakes up pollers waiting for new tasks, completionNotifier and historyNotifier report finished
+	// instances and new history events to clients. The database file is only used by this process, so these see
+	// all changes.
+	taskNotifier       *notify.Notifier
+	completionNotifier *notify.Notifier
+	historyNotifier    *notify.Notifier
+}
+
+func (b *boltBackend) Options() backend.Options {
+	return b.options.Options
+}
+
+func (b *boltBackend) Logger() log.Logger {
+	return b.options.Logger
+}
+
+func (b *boltBackend) Tracer() trace.Tracer {
+	return b.options.TracerProvider.Tracer(backend.TracerName)
+}
+
+func (b *boltBackend) Metrics() metrics.Client {
+	return b.options.Metrics.WithTags(metrics.Tags{metrickeys.Backend: "bolt"})
+}
+
+func (b *boltBackend) Converter() converter.Converter {
+	return b.options.Converter
+}
+
+func (b *boltBackend) ContextPropagators() []workflow.ContextPropagator {
+	return b.options.ContextPropagators
+}
+
+// Close closes the database file
+func (b *boltBackend) Close() error {
+	return b.db.Close()
+}
+
+func (b *boltBackend) CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		a := event.Attributes.(*history.ExecutionStartedAttributes)
+		created, err := createInstance(tx, instance, a.Name, a.Metadata)
+		if err != nil {
+			return fmt.Errorf("creating workflow instance: %w", err)
+		}
+
+		if !created {
+			return backend.ErrInstanceAlreadyExists
+		}
+
+		// Initial history is empty, store only new events
+		if err := insertPendingEvents(tx, instance.InstanceID, []*history.Event{event}); err != nil {
+			return fmt.Errorf("inserting new event: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	b.taskNotifier.Notify(workflowTasksKey)
+
+	return nil
+}
+
+func (b *boltBackend) CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
+	return b.addPendingEvent(instance.InstanceID, event)
+}
+
+func (b *boltBackend) SignalWorkflow(ctx context.Context, instanceID string, event *history.Event) error {
+	return b.addPendingEvent(instanceID, event)
+}
+
+// addPendingEvent adds the given event for an existing instance
+func (b *boltBackend) addPendingEvent(instanceID string, event *history.Event) error {
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		if !instanceExists(tx, instanceID) {
+			return backend.ErrInstanceNotFound
+		}
+
+		return insertPendingEvents(tx, instanceID, []*history.Event{event})
+	}); err != nil {
+		return err
+	}
+
+	b.taskNotifier.Notify(workflowTasksKey)
+
+	return nil
+}
+
+func (b *boltBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64) ([]*history.Event, error) {
+	var h []*history.Event
+
+	if err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		h, err = getHistory(tx, instance.InstanceID, lastSequenceID)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("getting history: %w", err)
+	}
+
+	return h, nil
+}
+
+func (b *boltBackend) GetWorkflowInstanceState(ctx context.Context, instance *workflow.Instance) (core.WorkflowInstanceState, error) {
+	var s *instanceState
+
+	if err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		s, err = getInstance(tx, instance.InstanceID)
+		return err
+	}); err != nil {
+		return core.WorkflowInstanceStateActive, err
+	}
+
+	if s == nil || s.Instance.ExecutionID != instance.ExecutionID {
+		return core.WorkflowInstanceStateActive, backend.ErrInstanceNotFound
+	}
+
+	return s.state(), nil
+}
+
+// GetWorkflowTask returns a pending workflow task or nil if there are no pending workflow executions. If there is no
+// task, it waits until it is notified about a new task, the next timer fires, or BlockTimeout passes.
+func (b *boltBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	// Subscribe before looking for a task, to not miss tasks committed in between
+	wake, cancel := b.taskNotifier.Subscribe(workflowTasksKey)
+	defer cancel()
+
+	t, next, err := b.getWorkflowTask()
+	if err != nil || t != nil {
+		return t, err
+	}
+
+	if !b.waitForTask(ctx, wake, next) {
+		return nil, nil
+	}
+
+	t, _, err = b.getWorkflowTask()
+	return t, err
+}
+
+// getWorkflowTask locks the next workflow task. If there is none, it returns the time at which the next task
+// becomes available without any other changes, if known.
+func (b *boltBackend) getWorkflowTask() (*task.Workflow, *time.Time, error) {
+	for {
+		now := time.Now()
+
+		instanceID, next, err := b.nextWorkflowTask(now)
+		if err != nil || instanceID == "" {
+			return nil, next, err
+		}
+
+		t, err := b.lockWorkflowTask(instanceID, now)
+		if err != nil || t != nil {
+			return t, nil, err
+		}
+
+		// The instance changed since it was found, look again
+	}
+}
+
+// nextWorkflowTask finds an instance with a workflow task ready at now, without locking the database for writes. If
+// there is none, it returns the time at which the next task becomes ready, if known.
+func (b *boltBackend) nextWorkflowTask(now time.Time) (string, *time.Time, error) {
+	var instanceID string
+	var next *time.Time
+
+	wakeAt := func(at time.Time) {
+		if next == nil || at.Before(*next) {
+			next = &at
+		}
+	}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		// Tasks are ordered by the time they become ready
+		c := tx.Bucket(workflowTasksBucket).Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			if at := taskReadyAt(k); at.After(now) {
+				wakeAt(at)
+				return nil
+			}
+
+			id := string(k[8:])
+
+			s, err := getInstance(tx, id)
+			if err != nil {
+				return err
+			}
+
+			if s == nil {
+				continue
+			}
+
+			if s.StickyUntil != nil && s.StickyUntil.After(now) && s.Worker != b.workerName {
+				wakeAt(*s.StickyUntil)
+				continue
+			}
+
+			instanceID = id
+			return nil
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return instanceID, next, nil
+}
+
+// lockWorkflowTask locks the workflow task of the given instance. It returns nil if the task is not ready anymore.
+func (b *boltBackend) lockWorkflowTask(instanceID string, now time.Time) (*task.Workflow, error) {
+	var t *task.Workflow
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		s, err := getInstance(tx, instanceID)
+		if err != nil {
+			return err
+		}
+
+		if s == nil || s.CompletedAt != nil {
+			return nil
+		}
+
+		if s.LockedUntil != nil && s.LockedUntil.After(now) {
+			return nil
+		}
+
+		if s.StickyUntil != nil && s.StickyUntil.After(now) && s.Worker != b.workerName {
+			return nil
+		}
+
+		newEvents, err := getPendingEvents(tx, instanceID, &now)
+		if err != nil {
+			return fmt.Errorf("getting pending events: %w", err)
+		}
+
+		if len(newEvents) == 0 {
+			return nil
+		}
+
+		lockedUntil := now.Add(b.options.WorkflowLockTimeout)
+		s.LockedUntil = &lockedUntil
+		s.Worker = b.workerName
+		if err := putInstance(tx, s); err != nil {
+			return fmt.Errorf("locking workflow instance: %w", err)
+		}
+
+		t = &task.Workflow{
+			ID:                    instanceID,
+			WorkflowInstance:      s.Instance,
+			WorkflowInstanceState: core.WorkflowInstanceStateActive,
+			Metadata:              s.Metadata,
+			Attempt:               s.WorkflowTaskFailures + 1,
+			LastSequenceID:        s.LastSequenceID,
+			NewEvents:             newEvents,
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// waitForTask waits until wake is notified, next is reached, or BlockTimeout passes. It returns false if ctx is done.
+func (b *boltBackend) waitForTask(ctx context.Context, wake <-chan struct{}, next *time.Time) bool {
+	timeout := b.options.BlockTimeout
+	if next != nil {
+		if d := time.Until(*next); d < timeout {
+			timeout = d
+		}
+	}
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-wake:
+	case <-t.C:
+	}
+
+	return true
+}
+
+// getLockedInstance returns the state of the given instance if it is locked by this worker
+func (b *boltBackend) getLockedInstance(tx *bolt.Tx, instance *workflow.Instance) (*instanceState, error) {
+	s, err := getInstance(tx, instance.InstanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == nil || s.Instance.ExecutionID != instance.ExecutionID || s.LockedUntil == nil || s.Worker != b.workerName {
+		return nil, errors.New("could not find workflow instance locked by this worker")
+	}
+
+	return s, nil
+}
+
+// CompleteWorkflowTask checkpoints a workflow task retrieved using GetWorkflowTask
+//
+// This checkpoints the execution. events are new events from the last workflow execution
+// which will be added to the workflow instance history. workflowEvents are new events for the
+// completed or other workflow instances.
+func (b *boltBackend) CompleteWorkflowTask(
+	ctx context.Context,
+	task *task.Workflow,
+	instance *workflow.Instance,
+	state core.WorkflowInstanceState,
+	executedEvents, activityEvents, timerEvents []*history.Event,
+	workflowEvents []history.WorkflowEvent,
+) error {
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		s, err := b.getLockedInstance(tx, instance)
+		if err != nil {
+			return err
+		}
+
+		// Unlock instance, but keep it sticky to the current worker
+		now := time.Now()
+		stickyUntil := now.Add(b.options.StickyTimeout)
+		s.LockedUntil = nil
+		s.WorkflowTaskFailures = 0
+		s.StickyUntil = &stickyUntil
+
+		if state == core.WorkflowInstanceStateFinished {
+			completedAt := now.UTC()
+			s.CompletedAt = &completedAt
+		}
+
+		// Remove handled events from task
+		executed := make(map[string]bool, len(executedEvents))
+		for _, e := range executedEvents {
+			executed[e.ID] = true
+		}
+
+		if err := removePendingEvents(tx, instance.InstanceID, func(event *history.Event) bool {
+			return executed[event.ID]
+		}); err != nil {
+			return fmt.Errorf("deleting handled new events: %w", err)
+		}
+
+		// Add events from last execution to history
+		if err := insertHistoryEvents(tx, s, executedEvents); err != nil {
+			return fmt.Errorf("inserting new history events: %w", err)
+		}
+
+		// Schedule activities
+		for _, event := range activityEvents {
+			if err := scheduleActivity(tx, instance, event); err != nil {
+				return fmt.Errorf("scheduling activity: %w", err)
+			}
+		}
+
+		// Timer events
+		if err := insertPendingEvents(tx, instance.InstanceID, timerEvents); err != nil {
+			return fmt.Errorf("scheduling timers: %w", err)
+		}
+
+		for _, event := range executedEvents {
+			switch event.Type {
+			case history.EventType_TimerCanceled:
+				scheduleEventID := event.ScheduleEventID
+				if err := removePendingEvents(tx, instance.InstanceID, func(event *history.Event) bool {
+					return event.ScheduleEventID == scheduleEventID && event.VisibleAt != nil
+				}); err != nil {
+					return fmt.Errorf("removing future event: %w", err)
+				}
+			}
+		}
+
+		// Update search attributes
+		for name, v := range history.SearchAttributeUpdates(executedEvents) {
+			if v == nil {
+				delete(s.SearchAttributes, name)
+				continue
+			}
+
+			if s.SearchAttributes == nil {
+				s.SearchAttributes = make(map[string]*search.Value)
+			}
+
+			s.SearchAttributes[name] = v
+		}
+
+		if err := putInstance(tx, s); err != nil {
+			return fmt.Errorf("unlocking workflow instance: %w", err)
+		}
+
+		// Insert new workflow events
+		groupedEvents := history.EventsByWorkflowInstanceID(workflowEvents)
+
+		// Errors for target instances that do not exist, have finished, or could not be created
+		targetErrs := make(map[string]error)
+
+		for targetInstanceID, events := range groupedEvents {
+			created := false
+
+			for _, m := range events {
+				if m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted {
+					a := m.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+					// Create new instance. Events for existing instances are not delivered, the sending instance is
+					// notified below
+					ok, err := createInstance(tx, m.WorkflowInstance, a.Name, a.Metadata)
+					if err != nil {
+						return fmt.Errorf("creating workflow instance: %w", err)
+					}
+
+					if !ok {
+						targetErrs[targetInstanceID] = backend.ErrInstanceAlreadyExists
+					}
+
+					created = true
+					break
+				}
+			}
+
+			if targetErrs[targetInstanceID] != nil {
+				continue
+			}
+
+			// Events for instances that do not exist or have finished are not delivered, the sending instance is
+			// notified below
+			if !created {
+				if err := checkTargetInstance(tx, targetInstanceID); err != nil {
+					if !errors.Is(err, backend.ErrInstanceNotFound) && !errors.Is(err, backend.ErrInstanceFinished) {
+						return fmt.Errorf("checking for target workflow instance: %w", err)
+					}
+
+					targetErrs[targetInstanceID] = err
+					continue
+				}
+			}
+
+			historyEvents := []*history.Event{}
+			for _, m := range events {
+				historyEvents = append(historyEvents, m.HistoryEvent)
+			}
+
+			if err := insertPendingEvents(tx, targetInstanceID, historyEvents); err != nil {
+				return fmt.Errorf("inserting messages: %w", err)
+			}
+		}
+
+		// Report the outcome of sub-workflows, signals, and cancellation requests sent to other instances back to
+		// this instance
+		resultEvents := history.ExternalWorkflowResults(now, executedEvents, func(instanceID string) error {
+			return targetErrs[instanceID]
+		})
+		if err := insertPendingEvents(tx, instance.InstanceID, resultEvents); err != nil {
+			return fmt.Errorf("inserting external workflow results: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	b.taskNotifier.Notify(workflowTasksKey)
+
+	if len(activityEvents) > 0 {
+		b.taskNotifier.Notify(activityTasksKey)
+	}
+
+	if len(executedEvents) > 0 {
+		b.historyNotifier.Notify(instance.InstanceID)
+	}
+
+	if state == core.WorkflowInstanceStateFinished {
+		b.completionNotifier.Notify(instance.InstanceID)
+	}
+
+	return nil
+}
+
+func (b *boltBackend) FailWorkflowTask(
+	ctx context.Context,
+	task *task.Workflow,
+	instance *workflow.Instance,
+	failedEvent *history.Event,
+	retryAfter time.Duration,
+) error {
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		s, err := b.getLockedInstance(tx, instance)
+		if err != nil {
+			return err
+		}
+
+		// Keep the instance locked until the task should be retried, pending events are left untouched
+		lockedUntil := time.Now().Add(retryAfter)
+		s.LockedUntil = &lockedUntil
+		s.WorkflowTaskFailures++
+
+		if failedEvent != nil {
+			if err := insertHistoryEvents(tx, s, []*history.Event{failedEvent}); err != nil {
+				return fmt.Errorf("inserting workflow task failed event: %w", err)
+			}
+		}
+
+		return putInstance(tx, s)
+	}); err != nil {
+		return err
+	}
+
+	if failedEvent != nil {
+		b.historyNotifier.Notify(instance.InstanceID)
+	}
+
+	return nil
+}
+
+func (b *boltBackend) ExtendWorkflowTask(ctx context.Context, taskID string, instance *core.WorkflowInstance) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		s, err := b.getLockedInstance(tx, instance)
+		if err != nil {
+			return fmt.Errorf("extending workflow task lock: %w", err)
+		}
+
+		lockedUntil := time.Now().Add(b.options.WorkflowLockTimeout)
+		s.LockedUntil = &lockedUntil
+
+		return putInstance(tx, s)
+	})
+}
+
+var _ backend.CompletionSubscriber = (*boltBackend)(nil)
+
+func (b *boltBackend) SubscribeWorkflowInstanceCompletion(ctx context.Context, instance *workflow.Instance) (<-chan struct{}, func(), error) {
+	c, cancel := b.completionNotifier.Subscribe(instance.InstanceID)
+	return c, cancel, nil
+}
+
+var _ backend.HistoryWatcher = (*boltBackend)(nil)
+
+func (b *boltBackend) WatchWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, fromSequenceID int64) (<-chan *history.Event, error) {
+	return notify.WatchHistory(ctx, b, b.historyNotifier, instance, fromSequenceID)
+}
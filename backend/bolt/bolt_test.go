@@ -0,0 +1,195 @@
+package bolt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/search"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func Test_BoltBackend(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	test.BackendTest(t, newTestBackend, removeTestBackend)
+}
+
+func Test_EndToEndBoltBackend(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	test.EndToEndBackendTest(t, newTestBackend, removeTestBackend)
+}
+
+func newTestBackend() test.TestBackend {
+	dir, err := os.MkdirTemp("", "go-workflows-bolt-")
+	if err != nil {
+		panic(err)
+	}
+
+	// Disable sticky workflow behavior for the test execution
+	b, err := NewBoltBackend(filepath.Join(dir, "test.db"), WithBackendOptions(backend.WithStickyTimeout(0)))
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+func removeTestBackend(tb test.TestBackend) {
+	b := tb.(*boltBackend)
+
+	path := b.db.Path()
+	if err := b.Close(); err != nil {
+		panic(err)
+	}
+
+	if err := os.RemoveAll(filepath.Dir(path)); err != nil {
+		panic(err)
+	}
+}
+
+func Test_MatchQuery(t *testing.T) {
+	attributes := map[string]*search.Value{
+		"customer": {Type: search.TypeString, String: "c1"},
+		"total":    {Type: search.TypeInt, Int: 10},
+	}
+
+	require.True(t, matchQuery(search.Eq("customer", "c1"), attributes))
+	require.False(t, matchQuery(search.Eq("customer", "c2"), attributes))
+	require.True(t, matchQuery(search.Gte("customer", "c"), attributes))
+	require.True(t, matchQuery(search.Between("total", 5, 10), attributes))
+	require.False(t, matchQuery(search.Gt("total", 10), attributes))
+	require.True(t, matchQuery(search.Or(search.Gt("total", 10), search.Eq("customer", "c1")), attributes))
+
+	// Types have to match, missing attributes never match
+	require.False(t, matchQuery(search.Eq("total", "10"), attributes))
+	require.False(t, matchQuery(search.Lt("region", "z"), attributes))
+}
+
+func Test_ListWorkflowInstances_CursorInstanceRemoved(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBackend().(*boltBackend)
+	defer removeTestBackend(b)
+
+	for i := 0; i < 3; i++ {
+		instance := core.NewWorkflowInstance(fmt.Sprintf("instance-%d", i), "execution")
+		require.NoError(t, b.CreateWorkflowInstance(ctx, instance, history.NewHistoryEvent(
+			1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"})))
+	}
+
+	options := &backend.ListWorkflowInstancesOptions{
+		WorkflowName: "wf",
+		PageSize:     1,
+	}
+
+	r, err := b.ListWorkflowInstances(ctx, options)
+	require.NoError(t, err)
+	require.Len(t, r.Instances, 1)
+	require.NotEmpty(t, r.NextCursor)
+
+	// Remove the instance the cursor points to
+	require.NoError(t, b.db.Update(func(tx *bolt.Tx) error {
+		s, err := getInstance(tx, r.Instances[0].Instance.InstanceID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(instancesByCreationBucket).Delete(creationKey(s.CreatedAt, s.Instance.InstanceID)); err != nil {
+			return err
+		}
+
+		return tx.Bucket(instancesBucket).Delete([]byte(s.Instance.InstanceID))
+	}))
+
+	ids := []string{r.Instances[0].Instance.InstanceID}
+	for r.NextCursor != "" {
+		options.Cursor = r.NextCursor
+		r, err = b.ListWorkflowInstances(ctx, options)
+		require.NoError(t, err)
+		require.Len(t, r.Instances, 1)
+
+		ids = append(ids, r.Instances[0].Instance.InstanceID)
+	}
+
+	require.ElementsMatch(t, []string{"instance-0", "instance-1", "instance-2"}, ids)
+}
+
+func Test_WorkflowTaskIndex(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBackend().(*boltBackend)
+
+	instance := core.NewWorkflowInstance("instance", "execution")
+	require.NoError(t, b.CreateWorkflowInstance(ctx, instance, history.NewHistoryEvent(
+		1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"})))
+
+	// Drop the index, like in a database created before it existed
+	require.NoError(t, b.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{workflowTasksBucket, workflowTaskKeysBucket} {
+			if err := tx.DeleteBucket(bucket); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}))
+
+	path := b.db.Path()
+	require.NoError(t, b.Close())
+
+	b, err := NewBoltBackend(path, WithBackendOptions(backend.WithStickyTimeout(0)))
+	require.NoError(t, err)
+	defer removeTestBackend(b)
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	require.Equal(t, instance.InstanceID, task.WorkflowInstance.InstanceID)
+
+	// Locked instances are indexed by the end of their lock
+	next, err := b.db.Begin(false)
+	require.NoError(t, err)
+	defer next.Rollback()
+
+	k, _ := next.Bucket(workflowTasksBucket).Cursor().First()
+	require.NotNil(t, k)
+	require.True(t, taskReadyAt(k).After(time.Now()))
+	require.Equal(t, instance.InstanceID, string(k[8:]))
+}
+
+var _ test.TestBackend = (*boltBackend)(nil)
+
+func (b *boltBackend) GetFutureEvents(ctx context.Context) ([]*history.Event, error) {
+	f := make([]*history.Event, 0)
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(pendingEventsBucket).ForEach(func(k, _ []byte) error {
+			events, err := getPendingEvents(tx, string(k), nil)
+			if err != nil {
+				return err
+			}
+
+			for _, event := range events {
+				if event.VisibleAt != nil {
+					f = append(f, event)
+				}
+			}
+
+			return nil
+		})
+	})
+
+	return f, err
+}
@@ -0,0 +1,71 @@
+package bolt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/workflow"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (b *boltBackend) DescribeWorkflowInstance(ctx context.Context, instance *workflow.Instance) (*backend.WorkflowInstanceDescription, error) {
+	var d *backend.WorkflowInstanceDescription
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		s, err := getInstance(tx, instance.InstanceID)
+		if err != nil {
+			return err
+		}
+
+		if s == nil || s.Instance.ExecutionID != instance.ExecutionID {
+			return backend.ErrInstanceNotFound
+		}
+
+		h, err := getHistory(tx, instance.InstanceID, nil)
+		if err != nil {
+			return fmt.Errorf("getting history: %w", err)
+		}
+
+		// Include future events, they are filtered when building the description
+		pendingEvents, err := getPendingEvents(tx, instance.InstanceID, nil)
+		if err != nil {
+			return fmt.Errorf("getting pending events: %w", err)
+		}
+
+		d = backend.NewWorkflowInstanceDescription(instanceInfo(s), h, pendingEvents, time.Now())
+		d.LastWorkflowTaskWorker = s.Worker
+
+		if len(d.PendingActivities) > 0 {
+			workers, err := activityWorkers(tx, instance)
+			if err != nil {
+				return fmt.Errorf("getting activities: %w", err)
+			}
+
+			for _, pa := range d.PendingActivities {
+				if a, ok := workers[pa.ScheduleEventID]; ok {
+					pa.Worker = a.Worker
+					pa.LockedUntil = a.LockedUntil
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func instanceInfo(s *instanceState) *backend.WorkflowInstanceInfo {
+	return &backend.WorkflowInstanceInfo{
+		Instance:     s.Instance,
+		WorkflowName: s.WorkflowName,
+		State:        s.state(),
+		CreatedAt:    s.CreatedAt,
+		CompletedAt:  s.CompletedAt,
+	}
+}
@@ -0,0 +1,73 @@
+package bolt
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/diag"
+	"github.com/cschleiden/go-workflows/internal/core"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ diag.Backend = (*boltBackend)(nil)
+
+func (b *boltBackend) GetWorkflowInstances(ctx context.Context, afterInstanceID string, count int) ([]*diag.WorkflowInstanceRef, error) {
+	var instances []*diag.WorkflowInstanceRef
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var after []byte
+		if afterInstanceID != "" {
+			s, err := getInstance(tx, afterInstanceID)
+			if err != nil {
+				return err
+			}
+
+			if s == nil {
+				return nil
+			}
+
+			after = creationKey(s.CreatedAt, s.Instance.InstanceID)
+		}
+
+		return iterateInstances(tx, after, func(s *instanceState) (bool, error) {
+			instances = append(instances, instanceRef(s))
+			return len(instances) < count, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+func (b *boltBackend) GetWorkflowInstance(ctx context.Context, instanceID string) (*diag.WorkflowInstanceRef, error) {
+	var s *instanceState
+
+	if err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		s, err = getInstance(tx, instanceID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if s == nil {
+		return nil, nil
+	}
+
+	return instanceRef(s), nil
+}
+
+func (b *boltBackend) GetWorkflowTree(ctx context.Context, instanceID string) (*diag.WorkflowInstanceTree, error) {
+	itb := diag.NewInstanceTreeBuilder(b)
+	return itb.BuildWorkflowInstanceTree(ctx, instanceID)
+}
+
+func instanceRef(s *instanceState) *diag.WorkflowInstanceRef {
+	return &diag.WorkflowInstanceRef{
+		Instance:    core.NewWorkflowInstance(s.Instance.InstanceID, s.Instance.ExecutionID),
+		CreatedAt:   s.CreatedAt,
+		CompletedAt: s.CompletedAt,
+		State:       s.state(),
+	}
+}
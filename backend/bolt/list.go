@@ -0,0 +1,161 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ListWorkflowInstances walks the creation time index from the most recently created instance and filters instances
+// in memory. All instances in the created range are visited to determine the total count.
+func (b *boltBackend) ListWorkflowInstances(ctx context.Context, options *backend.ListWorkflowInstancesOptions) (*backend.ListWorkflowInstancesResult, error) {
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = backend.DefaultListPageSize
+	}
+
+	if options.Query != nil {
+		if err := options.Query.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid query: %w", err)
+		}
+	}
+
+	// The cursor contains the creation time and the id of the last instance of the previous page, so that paging
+	// continues even if that instance has been removed in the meantime
+	var cursor []byte
+	if options.Cursor != "" {
+		createdAt, instanceID, err := parseCursor(options.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		cursor = creationKey(createdAt, instanceID)
+	}
+
+	result := &backend.ListWorkflowInstancesResult{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return iterateInstances(tx, nil, func(s *instanceState) (bool, error) {
+			if !options.CreatedAfter.IsZero() && s.CreatedAt.Before(options.CreatedAfter) {
+				// Instances are ordered by creation time, all remaining instances are older
+				return false, nil
+			}
+
+			if !matchesOptions(s, options) {
+				return true, nil
+			}
+
+			result.TotalCount++
+
+			if cursor != nil && bytes.Compare(creationKey(s.CreatedAt, s.Instance.InstanceID), cursor) >= 0 {
+				return true, nil
+			}
+
+			switch {
+			case len(result.Instances) < pageSize:
+				result.Instances = append(result.Instances, instanceInfo(s))
+			case result.NextCursor == "":
+				last := result.Instances[pageSize-1]
+				result.NextCursor = formatCursor(last.CreatedAt, last.Instance.InstanceID)
+			}
+
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func formatCursor(createdAt time.Time, instanceID string) string {
+	return strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + instanceID
+}
+
+func parseCursor(cursor string) (time.Time, string, error) {
+	createdAt, instanceID, ok := strings.Cut(cursor, ":")
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	n, err := strconv.ParseInt(createdAt, 10, 64)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("invalid cursor %q: %w", cursor, err)
+	}
+
+	return time.Unix(0, n).UTC(), instanceID, nil
+}
+
+func matchesOptions(s *instanceState, options *backend.ListWorkflowInstancesOptions) bool {
+	if options.WorkflowName != "" && s.WorkflowName != options.WorkflowName {
+		return false
+	}
+
+	if options.State != nil && s.state() != *options.State {
+		return false
+	}
+
+	if !options.CreatedBefore.IsZero() && s.CreatedAt.After(options.CreatedBefore) {
+		return false
+	}
+
+	if !options.CompletedAfter.IsZero() && (s.CompletedAt == nil || s.CompletedAt.Before(options.CompletedAfter)) {
+		return false
+	}
+
+	if !options.CompletedBefore.IsZero() && (s.CompletedAt == nil || s.CompletedAt.After(options.CompletedBefore)) {
+		return false
+	}
+
+	if options.ParentInstanceID != "" && s.Instance.ParentInstanceID != options.ParentInstanceID {
+		return false
+	}
+
+	if options.Query != nil && !matchQuery(options.Query, s.SearchAttributes) {
+		return false
+	}
+
+	return true
+}
+
+// iterateInstances calls f for all instances created before the instance with the given creation key, or all
+// instances if before is nil, most recently created first, until f returns false
+func iterateInstances(tx *bolt.Tx, before []byte, f func(s *instanceState) (bool, error)) error {
+	c := tx.Bucket(instancesByCreationBucket).Cursor()
+
+	var k []byte
+	if before != nil {
+		// Seek returns the first key at or after before, the previous key is the first to visit
+		if k, _ = c.Seek(before); k == nil {
+			k, _ = c.Last()
+		} else {
+			k, _ = c.Prev()
+		}
+	} else {
+		k, _ = c.Last()
+	}
+
+	for ; k != nil; k, _ = c.Prev() {
+		s, err := getInstance(tx, string(k[8:]))
+		if err != nil {
+			return err
+		}
+
+		if s == nil {
+			continue
+		}
+
+		if cont, err := f(s); err != nil || !cont {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/workflow"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (b *boltBackend) RemoveWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		s, err := getInstance(tx, instance.InstanceID)
+		if err != nil {
+			return err
+		}
+
+		if s == nil || s.Instance.ExecutionID != instance.ExecutionID {
+			return backend.ErrInstanceNotFound
+		}
+
+		if s.CompletedAt == nil {
+			return backend.ErrInstanceNotFinished
+		}
+
+		for _, bucket := range [][]byte{historyBucket, pendingEventsBucket} {
+			if err := tx.Bucket(bucket).DeleteBucket([]byte(instance.InstanceID)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return fmt.Errorf("removing %s: %w", bucket, err)
+			}
+		}
+
+		if err := removeActivities(tx, s); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(instancesByCreationBucket).Delete(creationKey(s.CreatedAt, instance.InstanceID)); err != nil {
+			return err
+		}
+
+		return tx.Bucket(instancesBucket).Delete([]byte(instance.InstanceID))
+	})
+}
+
+// removeActivities removes the activities of the given instance that have not been completed
+func removeActivities(tx *bolt.Tx, s *instanceState) error {
+	activities := tx.Bucket(activitiesBucket)
+
+	var keys, ids [][]byte
+
+	if err := activities.ForEach(func(k, v []byte) error {
+		var a activityState
+		if err := json.Unmarshal(v, &a); err != nil {
+			return fmt.Errorf("unmarshaling activity: %w", err)
+		}
+
+		if a.Instance.InstanceID == s.Instance.InstanceID {
+			keys = append(keys, append([]byte(nil), k...))
+			ids = append(ids, []byte(a.Event.ID))
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for i := range keys {
+		if err := activities.Delete(keys[i]); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(activityIDsBucket).Delete(ids[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package bolt
+
+import (
+	"strings"
+
+	"github.com/cschleiden/go-workflows/search"
+)
+
+// matchQuery returns true if the given search attributes of an instance match the query
+func matchQuery(query search.Query, attributes map[string]*search.Value) bool {
+	switch q := query.(type) {
+	case *search.Condition:
+		v := attributes[q.Key]
+		if v == nil || v.Type != q.Value.Type {
+			return false
+		}
+
+		return matchOp(q.Op, compareValues(*v, q.Value))
+
+	case *search.AndQuery:
+		for _, sq := range q.Queries {
+			if !matchQuery(sq, attributes) {
+				return false
+			}
+		}
+
+		return true
+
+	case *search.OrQuery:
+		for _, sq := range q.Queries {
+			if matchQuery(sq, attributes) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return false
+}
+
+// compareValues compares two values of the same type, like the SQL backends compare the indexed columns
+func compareValues(a, b search.Value) int {
+	switch {
+	case !a.Numeric():
+		return strings.Compare(a.String, b.String)
+	case a.Number() < b.Number():
+		return -1
+	case a.Number() > b.Number():
+		return 1
+	}
+
+	return 0
+}
+
+func matchOp(op search.Op, c int) bool {
+	switch op {
+	case search.OpEq:
+		return c == 0
+	case search.OpGt:
+		return c > 0
+	case search.OpGte:
+		return c >= 0
+	case search.OpLt:
+		return c < 0
+	case search.OpLte:
+		return c <= 0
+	}
+
+	return false
+}
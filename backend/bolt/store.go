@@ -0,0 +1,495 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/search"
+	bolt "go.etcd.io/bbolt"
+)
+
+// All state is kept in the following top-level buckets. Values are JSON encoded.
+var (
+	// instancesBucket maps instance ids to their instanceState
+	instancesBucket = []byte("instances")
+
+	// instancesByCreationBucket indexes instances by creation time, keys are the creation time in nanoseconds
+	// followed by the instance id, values are empty
+	instancesByCreationBucket = []byte("instances_by_creation")
+
+	// historyBucket contains a bucket per instance, mapping sequence ids to history events
+	historyBucket = []byte("history")
+
+	// pendingEventsBucket contains a bucket per instance with pending events, in the order they were added. The
+	// bucket of an instance is removed once all its events have been processed, so the keys of this bucket are the
+	// instances that might have work to do.
+	pendingEventsBucket = []byte("pending_events")
+
+	// activitiesBucket contains scheduled activities as activityState, in the order they were scheduled
+	activitiesBucket = []byte("activities")
+
+	// activityIDsBucket maps activity ids to their key in activitiesBucket
+	activityIDsBucket = []byte("activity_ids")
+
+	// workflowTasksBucket indexes instances that have pending events by the time their next workflow task becomes
+	// ready, keys are the ready time in nanoseconds followed by the instance id, values are empty
+	workflowTasksBucket = []byte("workflow_tasks")
+
+	// workflowTaskKeysBucket maps instance ids to their key in workflowTasksBucket
+	workflowTaskKeysBucket = []byte("workflow_task_keys")
+)
+
+var buckets = [][]byte{
+	instancesBucket, instancesByCreationBucket, historyBucket, pendingEventsBucket, activitiesBucket, activityIDsBucket,
+	workflowTasksBucket, workflowTaskKeysBucket,
+}
+
+type instanceState struct {
+	Instance     *core.WorkflowInstance `json:"instance"`
+	WorkflowName string                 `json:"workflow_name,omitempty"`
+	Metadata     *core.WorkflowMetadata `json:"metadata,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+	CompletedAt  *time.Time             `json:"completed_at,omitempty"`
+
+	// LockedUntil is set while a worker is processing a workflow task for the instance
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+
+	// StickyUntil is the time until which only Worker picks up workflow tasks for the instance
+	StickyUntil *time.Time `json:"sticky_until,omitempty"`
+	Worker      string     `json:"worker,omitempty"`
+
+	// WorkflowTaskFailures is the number of consecutive failed workflow tasks, reset when a task completes
+	WorkflowTaskFailures int `json:"workflow_task_failures,omitempty"`
+
+	// LastSequenceID is the sequence id of the last event in the history of the instance
+	LastSequenceID int64 `json:"last_sequence_id"`
+
+	SearchAttributes map[string]*search.Value `json:"search_attributes,omitempty"`
+}
+
+func (s *instanceState) state() core.WorkflowInstanceState {
+	if s.CompletedAt != nil {
+		return core.WorkflowInstanceStateFinished
+	}
+
+	return core.WorkflowInstanceStateActive
+}
+
+type activityState struct {
+	Instance    *core.WorkflowInstance `json:"instance"`
+	Event       *history.Event         `json:"event"`
+	LockedUntil *time.Time             `json:"locked_until,omitempty"`
+	Worker      string                 `json:"worker,omitempty"`
+}
+
+func createBuckets(tx *bolt.Tx) error {
+	for _, name := range buckets {
+		if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+			return fmt.Errorf("creating bucket %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// indexWorkflowTasks indexes the workflow tasks of all instances with pending events, for databases created before
+// workflowTasksBucket existed
+func indexWorkflowTasks(tx *bolt.Tx) error {
+	var instanceIDs []string
+	if err := tx.Bucket(pendingEventsBucket).ForEach(func(k, _ []byte) error {
+		instanceIDs = append(instanceIDs, string(k))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, instanceID := range instanceIDs {
+		if err := updateWorkflowTask(tx, instanceID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateWorkflowTask updates the entry of the given instance in workflowTasksBucket after its pending events changed
+func updateWorkflowTask(tx *bolt.Tx, instanceID string) error {
+	s, err := getInstance(tx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	if s == nil {
+		return unindexWorkflowTask(tx, instanceID)
+	}
+
+	return indexWorkflowTask(tx, s)
+}
+
+// indexWorkflowTask adds the given instance to workflowTasksBucket, keyed by the time its next workflow task becomes
+// ready. Instances that have finished or have no pending events are removed from the index.
+func indexWorkflowTask(tx *bolt.Tx, s *instanceState) error {
+	instanceID := s.Instance.InstanceID
+
+	if err := unindexWorkflowTask(tx, instanceID); err != nil {
+		return err
+	}
+
+	if s.CompletedAt != nil {
+		return nil
+	}
+
+	events, err := getPendingEvents(tx, instanceID, nil)
+	if err != nil {
+		return fmt.Errorf("getting pending events: %w", err)
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	// The task is ready once the first event is visible and the instance is not locked anymore
+	var readyAt uint64
+	for i, event := range events {
+		var visibleAt uint64
+		if event.VisibleAt != nil {
+			visibleAt = uint64(event.VisibleAt.UnixNano())
+		}
+
+		if i == 0 || visibleAt < readyAt {
+			readyAt = visibleAt
+		}
+	}
+
+	if s.LockedUntil != nil && uint64(s.LockedUntil.UnixNano()) > readyAt {
+		readyAt = uint64(s.LockedUntil.UnixNano())
+	}
+
+	key := append(itob(readyAt), instanceID...)
+	if err := tx.Bucket(workflowTasksBucket).Put(key, []byte{}); err != nil {
+		return err
+	}
+
+	return tx.Bucket(workflowTaskKeysBucket).Put([]byte(instanceID), key)
+}
+
+func unindexWorkflowTask(tx *bolt.Tx, instanceID string) error {
+	keys := tx.Bucket(workflowTaskKeysBucket)
+
+	key := keys.Get([]byte(instanceID))
+	if key == nil {
+		return nil
+	}
+
+	key = append([]byte(nil), key...)
+
+	if err := tx.Bucket(workflowTasksBucket).Delete(key); err != nil {
+		return err
+	}
+
+	return keys.Delete([]byte(instanceID))
+}
+
+// taskReadyAt returns the time encoded in a key of workflowTasksBucket
+func taskReadyAt(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key[:8])))
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// creationKey returns the key of an instance in instancesByCreationBucket. Keys sort by creation time, and by
+// instance id for instances created at the same time.
+func creationKey(createdAt time.Time, instanceID string) []byte {
+	return append(itob(uint64(createdAt.UnixNano())), instanceID...)
+}
+
+func getInstance(tx *bolt.Tx, instanceID string) (*instanceState, error) {
+	v := tx.Bucket(instancesBucket).Get([]byte(instanceID))
+	if v == nil {
+		return nil, nil
+	}
+
+	var s instanceState
+	if err := json.Unmarshal(v, &s); err != nil {
+		return nil, fmt.Errorf("unmarshaling instance state: %w", err)
+	}
+
+	return &s, nil
+}
+
+func putInstance(tx *bolt.Tx, s *instanceState) error {
+	v, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshaling instance state: %w", err)
+	}
+
+	if err := tx.Bucket(instancesBucket).Put([]byte(s.Instance.InstanceID), v); err != nil {
+		return err
+	}
+
+	// The lock and completion of an instance decide when its next workflow task is ready
+	return indexWorkflowTask(tx, s)
+}
+
+// createInstance creates the given instance. It returns false if an instance with the same id already exists.
+func createInstance(tx *bolt.Tx, wfi *core.WorkflowInstance, workflowName string, metadata *core.WorkflowMetadata) (bool, error) {
+	if tx.Bucket(instancesBucket).Get([]byte(wfi.InstanceID)) != nil {
+		return false, nil
+	}
+
+	s := &instanceState{
+		Instance:     wfi,
+		WorkflowName: workflowName,
+		Metadata:     metadata,
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	if err := putInstance(tx, s); err != nil {
+		return false, err
+	}
+
+	if err := tx.Bucket(instancesByCreationBucket).Put(creationKey(s.CreatedAt, wfi.InstanceID), []byte{}); err != nil {
+		return false, fmt.Errorf("indexing instance: %w", err)
+	}
+
+	return true, nil
+}
+
+func instanceExists(tx *bolt.Tx, instanceID string) bool {
+	return tx.Bucket(instancesBucket).Get([]byte(instanceID)) != nil
+}
+
+// checkTargetInstance returns ErrInstanceNotFound if the given instance does not exist, and ErrInstanceFinished if it
+// has already finished
+func checkTargetInstance(tx *bolt.Tx, instanceID string) error {
+	s, err := getInstance(tx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	if s == nil {
+		return core.ErrInstanceNotFound
+	}
+
+	if s.CompletedAt != nil {
+		return core.ErrInstanceFinished
+	}
+
+	return nil
+}
+
+func insertPendingEvents(tx *bolt.Tx, instanceID string, events []*history.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	b, err := tx.Bucket(pendingEventsBucket).CreateBucketIfNotExists([]byte(instanceID))
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		v, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("marshaling event: %w", err)
+		}
+
+		if err := b.Put(itob(seq), v); err != nil {
+			return err
+		}
+	}
+
+	return updateWorkflowTask(tx, instanceID)
+}
+
+// getPendingEvents returns the pending events of the given instance in the order they were added. If visibleAt is
+// given, only events visible at that time are returned.
+func getPendingEvents(tx *bolt.Tx, instanceID string, visibleAt *time.Time) ([]*history.Event, error) {
+	events := make([]*history.Event, 0)
+
+	b := tx.Bucket(pendingEventsBucket).Bucket([]byte(instanceID))
+	if b == nil {
+		return events, nil
+	}
+
+	err := b.ForEach(func(k, v []byte) error {
+		var event history.Event
+		if err := json.Unmarshal(v, &event); err != nil {
+			return fmt.Errorf("unmarshaling event: %w", err)
+		}
+
+		if visibleAt != nil && event.VisibleAt != nil && event.VisibleAt.After(*visibleAt) {
+			return nil
+		}
+
+		events = append(events, &event)
+		return nil
+	})
+
+	return events, err
+}
+
+// removePendingEvents removes the pending events of the given instance for which remove returns true
+func removePendingEvents(tx *bolt.Tx, instanceID string, remove func(event *history.Event) bool) error {
+	pending := tx.Bucket(pendingEventsBucket)
+
+	b := pending.Bucket([]byte(instanceID))
+	if b == nil {
+		return nil
+	}
+
+	var keys [][]byte
+	remaining := 0
+
+	if err := b.ForEach(func(k, v []byte) error {
+		var event history.Event
+		if err := json.Unmarshal(v, &event); err != nil {
+			return fmt.Errorf("unmarshaling event: %w", err)
+		}
+
+		if remove(&event) {
+			keys = append(keys, append([]byte(nil), k...))
+		} else {
+			remaining++
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if remaining == 0 {
+		if err := pending.DeleteBucket([]byte(instanceID)); err != nil {
+			return err
+		}
+	} else {
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+
+	return updateWorkflowTask(tx, instanceID)
+}
+
+func insertHistoryEvents(tx *bolt.Tx, s *instanceState, events []*history.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	b, err := tx.Bucket(historyBucket).CreateBucketIfNotExists([]byte(s.Instance.InstanceID))
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		v, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("marshaling event: %w", err)
+		}
+
+		if err := b.Put(itob(uint64(event.SequenceID)), v); err != nil {
+			return err
+		}
+
+		if event.SequenceID > s.LastSequenceID {
+			s.LastSequenceID = event.SequenceID
+		}
+	}
+
+	return nil
+}
+
+// getHistory returns the history of the given instance after lastSequenceID, or the full history if it is nil
+func getHistory(tx *bolt.Tx, instanceID string, lastSequenceID *int64) ([]*history.Event, error) {
+	events := make([]*history.Event, 0)
+
+	b := tx.Bucket(historyBucket).Bucket([]byte(instanceID))
+	if b == nil {
+		return events, nil
+	}
+
+	c := b.Cursor()
+
+	var k, v []byte
+	if lastSequenceID != nil {
+		k, v = c.Seek(itob(uint64(*lastSequenceID + 1)))
+	} else {
+		k, v = c.First()
+	}
+
+	for ; k != nil; k, v = c.Next() {
+		var event history.Event
+		if err := json.Unmarshal(v, &event); err != nil {
+			return nil, fmt.Errorf("unmarshaling event: %w", err)
+		}
+
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+func scheduleActivity(tx *bolt.Tx, instance *core.WorkflowInstance, event *history.Event) error {
+	b := tx.Bucket(activitiesBucket)
+
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	// Only the instance and execution id are needed to report the result
+	if err := putActivity(tx, itob(seq), &activityState{
+		Instance: core.NewWorkflowInstance(instance.InstanceID, instance.ExecutionID),
+		Event:    event,
+	}); err != nil {
+		return err
+	}
+
+	return tx.Bucket(activityIDsBucket).Put([]byte(event.ID), itob(seq))
+}
+
+func putActivity(tx *bolt.Tx, key []byte, a *activityState) error {
+	v, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("marshaling activity: %w", err)
+	}
+
+	return tx.Bucket(activitiesBucket).Put(key, v)
+}
+
+// getActivity returns the key and state of the activity with the given id, or nil if it does not exist
+func getActivity(tx *bolt.Tx, activityID string) ([]byte, *activityState, error) {
+	key := tx.Bucket(activityIDsBucket).Get([]byte(activityID))
+	if key == nil {
+		return nil, nil, nil
+	}
+
+	key = append([]byte(nil), key...)
+
+	v := tx.Bucket(activitiesBucket).Get(key)
+	if v == nil {
+		return nil, nil, nil
+	}
+
+	var a activityState
+	if err := json.Unmarshal(v, &a); err != nil {
+		return nil, nil, fmt.Errorf("unmarshaling activity: %w", err)
+	}
+
+	return key, &a, nil
+}
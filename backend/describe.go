@@ -0,0 +1,219 @@
+package backend
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// WorkflowInstanceDescription is a snapshot of the status of a workflow instance and the work it is waiting for
+type WorkflowInstanceDescription struct {
+	Instance     *workflow.Instance    `json:"instance,omitempty"`
+	WorkflowName string                `json:"workflow_name,omitempty"`
+	State        WorkflowInstanceState `json:"state"`
+	CreatedAt    time.Time             `json:"created_at,omitempty"`
+	CompletedAt  *time.Time            `json:"completed_at,omitempty"`
+
+	// PendingActivities are scheduled activities whose result has not been reported yet
+	PendingActivities []*PendingActivity `json:"pending_activities,omitempty"`
+
+	// PendingTimers are scheduled timers that have not fired yet and have not been canceled
+	PendingTimers []*PendingTimer `json:"pending_timers,omitempty"`
+
+	// PendingSubWorkflows are sub-workflows whose result has not been reported yet
+	PendingSubWorkflows []*PendingSubWorkflow `json:"pending_sub_workflows,omitempty"`
+
+	// PendingSignals are signals that have been received but not yet processed by a workflow task
+	PendingSignals []*PendingSignal `json:"pending_signals,omitempty"`
+
+	// LastWorkflowTaskAt is the time the last workflow task was started, if any
+	LastWorkflowTaskAt *time.Time `json:"last_workflow_task_at,omitempty"`
+
+	// LastWorkflowTaskWorker is the worker that processed the last workflow task, if the backend records it
+	LastWorkflowTaskWorker string `json:"last_workflow_task_worker,omitempty"`
+}
+
+type PendingActivity struct {
+	ScheduleEventID int64     `json:"schedule_event_id"`
+	Name            string    `json:"name"`
+	ScheduledAt     time.Time `json:"scheduled_at"`
+
+	// Attempt is the one-based retry attempt of the activity
+	Attempt int `json:"attempt"`
+
+	// LastFailure is the error of the previous attempt of the activity, if any
+	LastFailure string `json:"last_failure,omitempty"`
+
+	// Worker is the worker executing the activity, if it has been picked up and the backend records it
+	Worker string `json:"worker,omitempty"`
+
+	// LockedUntil is the time the lock of the worker executing the activity expires
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+type PendingTimer struct {
+	ScheduleEventID int64     `json:"schedule_event_id"`
+	ScheduledAt     time.Time `json:"scheduled_at"`
+	FireAt          time.Time `json:"fire_at"`
+}
+
+type PendingSubWorkflow struct {
+	ScheduleEventID int64              `json:"schedule_event_id"`
+	Instance        *workflow.Instance `json:"instance,omitempty"`
+	Name            string             `json:"name"`
+	ScheduledAt     time.Time          `json:"scheduled_at"`
+
+	// Started is true once the sub-workflow instance has been created
+	Started bool `json:"started"`
+
+	// CancellationRequested is true if the parent workflow has requested cancellation of the sub-workflow
+	CancellationRequested bool `json:"cancellation_requested"`
+}
+
+type PendingSignal struct {
+	Name       string    `json:"name"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// NewWorkflowInstanceDescription builds the description of a workflow instance from its history and its pending
+// events. Backends can pass all pending events of the instance, events not visible at the given time, like timers
+// that have not fired yet, are ignored.
+func NewWorkflowInstanceDescription(
+	info *WorkflowInstanceInfo, historyEvents, pendingEvents []*history.Event, now time.Time,
+) *WorkflowInstanceDescription {
+	d := &WorkflowInstanceDescription{
+		Instance:     info.Instance,
+		WorkflowName: info.WorkflowName,
+		State:        info.State,
+		CreatedAt:    info.CreatedAt,
+		CompletedAt:  info.CompletedAt,
+	}
+
+	activities := map[int64]*PendingActivity{}
+	timers := map[int64]*PendingTimer{}
+	subWorkflows := map[int64]*PendingSubWorkflow{}
+
+	// Failures of previous attempts, by the schedule event id of the first attempt. Concurrent activities with the
+	// same name are retried independently.
+	lastFailures := map[int64]string{}
+	firstScheduleEventIDs := map[int64]int64{}
+
+	apply := func(event *history.Event) {
+		switch event.Type {
+		case history.EventType_WorkflowTaskStarted:
+			ts := event.Timestamp
+			d.LastWorkflowTaskAt = &ts
+
+		case history.EventType_ActivityScheduled:
+			a := event.Attributes.(*history.ActivityScheduledAttributes)
+
+			pa := &PendingActivity{
+				ScheduleEventID: event.ScheduleEventID,
+				Name:            a.Name,
+				ScheduledAt:     event.Timestamp,
+				Attempt:         a.Attempt + 1,
+			}
+
+			first := event.ScheduleEventID
+			if a.Attempt > 0 {
+				first = a.FirstScheduleEventID
+				pa.LastFailure = lastFailures[first]
+			}
+
+			firstScheduleEventIDs[event.ScheduleEventID] = first
+			activities[event.ScheduleEventID] = pa
+
+		case history.EventType_ActivityCompleted:
+			delete(activities, event.ScheduleEventID)
+
+		case history.EventType_ActivityFailed:
+			if _, ok := activities[event.ScheduleEventID]; ok {
+				lastFailures[firstScheduleEventIDs[event.ScheduleEventID]] = event.Attributes.(*history.ActivityFailedAttributes).Reason
+			}
+
+			delete(activities, event.ScheduleEventID)
+
+		case history.EventType_TimerScheduled:
+			timers[event.ScheduleEventID] = &PendingTimer{
+				ScheduleEventID: event.ScheduleEventID,
+				ScheduledAt:     event.Timestamp,
+				FireAt:          event.Attributes.(*history.TimerScheduledAttributes).At,
+			}
+
+		case history.EventType_TimerFired, history.EventType_TimerCanceled:
+			delete(timers, event.ScheduleEventID)
+
+		case history.EventType_SubWorkflowScheduled:
+			a := event.Attributes.(*history.SubWorkflowScheduledAttributes)
+
+			// Detached sub-workflows do not report back to the parent
+			if a.Detached {
+				return
+			}
+
+			// Sub-workflow instances are created together with recording this event, parents are only notified when
+			// they wait for the sub-workflow to start. Instances that already existed fail the sub-workflow below.
+			subWorkflows[event.ScheduleEventID] = &PendingSubWorkflow{
+				ScheduleEventID: event.ScheduleEventID,
+				Instance:        a.SubWorkflowInstance,
+				Name:            a.Name,
+				ScheduledAt:     event.Timestamp,
+				Started:         true,
+			}
+
+		case history.EventType_SubWorkflowCancellationRequested:
+			if ps, ok := subWorkflows[event.ScheduleEventID]; ok {
+				ps.CancellationRequested = true
+			}
+
+		case history.EventType_SubWorkflowCompleted, history.EventType_SubWorkflowFailed:
+			delete(subWorkflows, event.ScheduleEventID)
+		}
+	}
+
+	for _, event := range historyEvents {
+		apply(event)
+	}
+
+	for _, event := range pendingEvents {
+		if event.VisibleAt != nil && event.VisibleAt.After(now) {
+			continue
+		}
+
+		if event.Type == history.EventType_SignalReceived {
+			d.PendingSignals = append(d.PendingSignals, &PendingSignal{
+				Name:       event.Attributes.(*history.SignalReceivedAttributes).Name,
+				ReceivedAt: event.Timestamp,
+			})
+
+			continue
+		}
+
+		apply(event)
+	}
+
+	for _, pa := range activities {
+		d.PendingActivities = append(d.PendingActivities, pa)
+	}
+	sort.Slice(d.PendingActivities, func(i, j int) bool {
+		return d.PendingActivities[i].ScheduleEventID < d.PendingActivities[j].ScheduleEventID
+	})
+
+	for _, pt := range timers {
+		d.PendingTimers = append(d.PendingTimers, pt)
+	}
+	sort.Slice(d.PendingTimers, func(i, j int) bool {
+		return d.PendingTimers[i].ScheduleEventID < d.PendingTimers[j].ScheduleEventID
+	})
+
+	for _, ps := range subWorkflows {
+		d.PendingSubWorkflows = append(d.PendingSubWorkflows, ps)
+	}
+	sort.Slice(d.PendingSubWorkflows, func(i, j int) bool {
+		return d.PendingSubWorkflows[i].ScheduleEventID < d.PendingSubWorkflows[j].ScheduleEventID
+	})
+
+	return d
+}
@@ -0,0 +1,90 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWorkflowInstanceDescription(t *testing.T) {
+	now := time.Now()
+	fireAt := now.Add(time.Hour)
+	subInstance := core.NewSubWorkflowInstance("sub", "exec", "parent", 5)
+
+	historyEvents := []*history.Event{
+		history.NewHistoryEvent(1, now, history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"}),
+		history.NewHistoryEvent(2, now, history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+		history.NewHistoryEvent(3, now, history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "a"}, history.ScheduleEventID(1)),
+		history.NewHistoryEvent(4, now, history.EventType_TimerScheduled, &history.TimerScheduledAttributes{At: fireAt}, history.ScheduleEventID(2)),
+		history.NewHistoryEvent(5, now, history.EventType_TimerScheduled, &history.TimerScheduledAttributes{At: now}, history.ScheduleEventID(3)),
+		history.NewHistoryEvent(6, now, history.EventType_SubWorkflowScheduled, &history.SubWorkflowScheduledAttributes{SubWorkflowInstance: subInstance, Name: "sub"}, history.ScheduleEventID(4)),
+		history.NewHistoryEvent(7, now, history.EventType_ActivityFailed, &history.ActivityFailedAttributes{Reason: "boom"}, history.ScheduleEventID(1)),
+		history.NewHistoryEvent(8, now, history.EventType_WorkflowTaskStarted, &history.WorkflowTaskStartedAttributes{}),
+		history.NewHistoryEvent(9, now, history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "a", Attempt: 1, FirstScheduleEventID: 1}, history.ScheduleEventID(5)),
+		history.NewHistoryEvent(10, now, history.EventType_SubWorkflowCancellationRequested, &history.SubWorkflowCancellationRequestedAttributes{}, history.ScheduleEventID(4)),
+	}
+
+	pendingEvents := []*history.Event{
+		history.NewPendingEvent(now, history.EventType_TimerFired, &history.TimerFiredAttributes{}, history.ScheduleEventID(2), history.VisibleAt(fireAt)),
+		history.NewPendingEvent(now, history.EventType_TimerFired, &history.TimerFiredAttributes{}, history.ScheduleEventID(3), history.VisibleAt(now)),
+		history.NewPendingEvent(now, history.EventType_SubWorkflowStarted, &history.SubWorkflowStartedAttributes{}, history.ScheduleEventID(4)),
+		history.NewPendingEvent(now, history.EventType_SignalReceived, &history.SignalReceivedAttributes{Name: "signal"}),
+	}
+
+	d := NewWorkflowInstanceDescription(&WorkflowInstanceInfo{
+		Instance:     core.NewWorkflowInstance("parent", "exec"),
+		WorkflowName: "wf",
+		CreatedAt:    now,
+	}, historyEvents, pendingEvents, now)
+
+	require.Equal(t, "wf", d.WorkflowName)
+	require.Equal(t, historyEvents[7].Timestamp, *d.LastWorkflowTaskAt)
+
+	require.Len(t, d.PendingActivities, 1)
+	require.Equal(t, int64(5), d.PendingActivities[0].ScheduleEventID)
+	require.Equal(t, 2, d.PendingActivities[0].Attempt)
+	require.Equal(t, "boom", d.PendingActivities[0].LastFailure)
+
+	// The second timer has fired, the first one is not visible yet
+	require.Len(t, d.PendingTimers, 1)
+	require.Equal(t, int64(2), d.PendingTimers[0].ScheduleEventID)
+	require.Equal(t, fireAt, d.PendingTimers[0].FireAt)
+
+	require.Len(t, d.PendingSubWorkflows, 1)
+	require.Equal(t, subInstance, d.PendingSubWorkflows[0].Instance)
+	require.True(t, d.PendingSubWorkflows[0].Started)
+	require.True(t, d.PendingSubWorkflows[0].CancellationRequested)
+
+	require.Len(t, d.PendingSignals, 1)
+	require.Equal(t, "signal", d.PendingSignals[0].Name)
+}
+
+func TestNewWorkflowInstanceDescription_ConcurrentRetries(t *testing.T) {
+	now := time.Now()
+
+	// Two activities with the same name fail and are retried independently
+	historyEvents := []*history.Event{
+		history.NewHistoryEvent(1, now, history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"}),
+		history.NewHistoryEvent(2, now, history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "a"}, history.ScheduleEventID(1)),
+		history.NewHistoryEvent(3, now, history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "a"}, history.ScheduleEventID(2)),
+		history.NewHistoryEvent(4, now, history.EventType_ActivityFailed, &history.ActivityFailedAttributes{Reason: "first"}, history.ScheduleEventID(1)),
+		history.NewHistoryEvent(5, now, history.EventType_ActivityFailed, &history.ActivityFailedAttributes{Reason: "second"}, history.ScheduleEventID(2)),
+		history.NewHistoryEvent(6, now, history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "a", Attempt: 1, FirstScheduleEventID: 1}, history.ScheduleEventID(3)),
+		history.NewHistoryEvent(7, now, history.EventType_ActivityScheduled, &history.ActivityScheduledAttributes{Name: "a", Attempt: 1, FirstScheduleEventID: 2}, history.ScheduleEventID(4)),
+	}
+
+	d := NewWorkflowInstanceDescription(&WorkflowInstanceInfo{
+		Instance:     core.NewWorkflowInstance("instance", "exec"),
+		WorkflowName: "wf",
+		CreatedAt:    now,
+	}, historyEvents, nil, now)
+
+	require.Len(t, d.PendingActivities, 2)
+	require.Equal(t, int64(3), d.PendingActivities[0].ScheduleEventID)
+	require.Equal(t, "first", d.PendingActivities[0].LastFailure)
+	require.Equal(t, int64(4), d.PendingActivities[1].ScheduleEventID)
+	require.Equal(t, "second", d.PendingActivities[1].LastFailure)
+}
@@ -0,0 +1,68 @@
+package backend
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/search"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+type WorkflowInstanceState = core.WorkflowInstanceState
+
+const (
+	WorkflowInstanceStateActive   = core.WorkflowInstanceStateActive
+	WorkflowInstanceStateFinished = core.WorkflowInstanceStateFinished
+)
+
+// DefaultListPageSize is the number of instances returned by ListWorkflowInstances if no page size is given
+const DefaultListPageSize = 100
+
+// ListWorkflowInstancesOptions filters the instances returned by ListWorkflowInstances. All given filters have
+// to match.
+type ListWorkflowInstancesOptions struct {
+	// WorkflowName only returns instances of the workflow with the given name
+	WorkflowName string
+
+	// State only returns instances in the given state
+	State *WorkflowInstanceState
+
+	// CreatedAfter and CreatedBefore only return instances created in the given, inclusive, range
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// CompletedAfter and CompletedBefore only return finished instances completed in the given, inclusive, range
+	CompletedAfter  time.Time
+	CompletedBefore time.Time
+
+	// ParentInstanceID only returns sub-workflows started by the given instance
+	ParentInstanceID string
+
+	// Query only returns instances whose search attributes match the query
+	Query search.Query
+
+	// PageSize is the maximum number of instances to return, DefaultListPageSize if not set
+	PageSize int
+
+	// Cursor continues a previous listing, pass the NextCursor of the previous result
+	Cursor string
+}
+
+type WorkflowInstanceInfo struct {
+	Instance     *workflow.Instance
+	WorkflowName string
+	State        WorkflowInstanceState
+	CreatedAt    time.Time
+	CompletedAt  *time.Time
+}
+
+type ListWorkflowInstancesResult struct {
+	// Instances are ordered by creation time, most recently created first
+	Instances []*WorkflowInstanceInfo
+
+	// NextCursor is an opaque cursor for the next page, empty if there are no more instances
+	NextCursor string
+
+	// TotalCount is the number of instances matching the filters across all pages
+	TotalCount int64
+}
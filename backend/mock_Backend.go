@@ -5,6 +5,8 @@ package backend
 import (
 	context "context"
 
+	contextpropagation "github.com/cschleiden/go-workflows/internal/contextpropagation"
+
 	converter "github.com/cschleiden/go-workflows/internal/converter"
 	core "github.com/cschleiden/go-workflows/internal/core"
 
@@ -18,6 +20,8 @@ import (
 
 	task "github.com/cschleiden/go-workflows/internal/task"
 
+	time "time"
+
 	trace "go.opentelemetry.io/otel/trace"
 )
 
@@ -68,6 +72,22 @@ func (_m *MockBackend) CompleteWorkflowTask(ctx context.Context, _a1 *task.Workf
 	return r0
 }
 
+// ContextPropagators provides a mock function with given fields:
+func (_m *MockBackend) ContextPropagators() []contextpropagation.ContextPropagator {
+	ret := _m.Called()
+
+	var r0 []contextpropagation.ContextPropagator
+	if rf, ok := ret.Get(0).(func() []contextpropagation.ContextPropagator); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]contextpropagation.ContextPropagator)
+		}
+	}
+
+	return r0
+}
+
 // Converter provides a mock function with given fields:
 func (_m *MockBackend) Converter() converter.Converter {
 	ret := _m.Called()
@@ -98,6 +118,32 @@ func (_m *MockBackend) CreateWorkflowInstance(ctx context.Context, instance *cor
 	return r0
 }
 
+// DescribeWorkflowInstance provides a mock function with given fields: ctx, instance
+func (_m *MockBackend) DescribeWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance) (*WorkflowInstanceDescription, error) {
+	ret := _m.Called(ctx, instance)
+
+	var r0 *WorkflowInstanceDescription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance) (*WorkflowInstanceDescription, error)); ok {
+		return rf(ctx, instance)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance) *WorkflowInstanceDescription); ok {
+		r0 = rf(ctx, instance)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*WorkflowInstanceDescription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *core.WorkflowInstance) error); ok {
+		r1 = rf(ctx, instance)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ExtendActivityTask provides a mock function with given fields: ctx, activityID
 func (_m *MockBackend) ExtendActivityTask(ctx context.Context, activityID string) error {
 	ret := _m.Called(ctx, activityID)
@@ -126,6 +172,20 @@ func (_m *MockBackend) ExtendWorkflowTask(ctx context.Context, taskID string, in
 	return r0
 }
 
+// FailWorkflowTask provides a mock function with given fields: ctx, _a1, instance, failedEvent, retryAfter
+func (_m *MockBackend) FailWorkflowTask(ctx context.Context, _a1 *task.Workflow, instance *core.WorkflowInstance, failedEvent *history.Event, retryAfter time.Duration) error {
+	ret := _m.Called(ctx, _a1, instance, failedEvent, retryAfter)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *task.Workflow, *core.WorkflowInstance, *history.Event, time.Duration) error); ok {
+		r0 = rf(ctx, _a1, instance, failedEvent, retryAfter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetActivityTask provides a mock function with given fields: ctx
 func (_m *MockBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
 	ret := _m.Called(ctx)
@@ -228,6 +288,32 @@ func (_m *MockBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 	return r0, r1
 }
 
+// ListWorkflowInstances provides a mock function with given fields: ctx, options
+func (_m *MockBackend) ListWorkflowInstances(ctx context.Context, options *ListWorkflowInstancesOptions) (*ListWorkflowInstancesResult, error) {
+	ret := _m.Called(ctx, options)
+
+	var r0 *ListWorkflowInstancesResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *ListWorkflowInstancesOptions) (*ListWorkflowInstancesResult, error)); ok {
+		return rf(ctx, options)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *ListWorkflowInstancesOptions) *ListWorkflowInstancesResult); ok {
+		r0 = rf(ctx, options)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ListWorkflowInstancesResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *ListWorkflowInstancesOptions) error); ok {
+		r1 = rf(ctx, options)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Logger provides a mock function with given fields:
 func (_m *MockBackend) Logger() log.Logger {
 	ret := _m.Called()
@@ -260,6 +346,34 @@ func (_m *MockBackend) Metrics() metrics.Client {
 	return r0
 }
 
+// Options provides a mock function with given fields:
+func (_m *MockBackend) Options() Options {
+	ret := _m.Called()
+
+	var r0 Options
+	if rf, ok := ret.Get(0).(func() Options); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(Options)
+	}
+
+	return r0
+}
+
+// RemoveWorkflowInstance provides a mock function with given fields: ctx, instance
+func (_m *MockBackend) RemoveWorkflowInstance(ctx context.Context, instance *core.WorkflowInstance) error {
+	ret := _m.Called(ctx, instance)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *core.WorkflowInstance) error); ok {
+		r0 = rf(ctx, instance)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SignalWorkflow provides a mock function with given fields: ctx, instanceID, event
 func (_m *MockBackend) SignalWorkflow(ctx context.Context, instanceID string, event *history.Event) error {
 	ret := _m.Called(ctx, instanceID, event)
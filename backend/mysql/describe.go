@@ -0,0 +1,166 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+func (b *mysqlBackend) DescribeWorkflowInstance(ctx context.Context, instance *workflow.Instance) (*backend.WorkflowInstanceDescription, error) {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id, executionID string
+	var parentInstanceID, workflowName, worker *string
+	var parentEventID *int64
+	var createdAt time.Time
+	var completedAt *time.Time
+	if err := tx.QueryRowContext(
+		ctx,
+		`SELECT instance_id, execution_id, parent_instance_id, parent_schedule_event_id, workflow_name, created_at, completed_at, worker
+		FROM instances WHERE instance_id = ? AND execution_id = ?`,
+		instance.InstanceID,
+		instance.ExecutionID,
+	).Scan(&id, &executionID, &parentInstanceID, &parentEventID, &workflowName, &createdAt, &completedAt, &worker); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, fmt.Errorf("getting workflow instance: %w", err)
+	}
+
+	info := &backend.WorkflowInstanceInfo{
+		Instance:    core.NewWorkflowInstance(id, executionID),
+		CreatedAt:   createdAt,
+		CompletedAt: completedAt,
+	}
+
+	if parentInstanceID != nil {
+		info.Instance = core.NewSubWorkflowInstance(id, executionID, *parentInstanceID, *parentEventID)
+	}
+
+	if workflowName != nil {
+		info.WorkflowName = *workflowName
+	}
+
+	if completedAt != nil {
+		info.State = core.WorkflowInstanceStateFinished
+	}
+
+	h, err := queryEvents(
+		ctx, tx,
+		"SELECT event_id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM `history` WHERE instance_id = ? ORDER BY sequence_id",
+		instance.InstanceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("getting history: %w", err)
+	}
+
+	// Include future events, they are filtered when building the description
+	pendingEvents, err := queryEvents(
+		ctx, tx,
+		"SELECT event_id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM `pending_events` WHERE instance_id = ? ORDER BY id",
+		instance.InstanceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("getting pending events: %w", err)
+	}
+
+	d := backend.NewWorkflowInstanceDescription(info, h, pendingEvents, time.Now())
+
+	if worker != nil {
+		d.LastWorkflowTaskWorker = *worker
+	}
+
+	if len(d.PendingActivities) > 0 {
+		if err := describeActivityWorkers(ctx, tx, instance, d); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+func queryEvents(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]*history.Event, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]*history.Event, 0)
+
+	for rows.Next() {
+		var instanceID string
+		var attributes []byte
+
+		event := &history.Event{}
+
+		if err := rows.Scan(
+			&event.ID,
+			&event.SequenceID,
+			&instanceID,
+			&event.Type,
+			&event.Timestamp,
+			&event.ScheduleEventID,
+			&attributes,
+			&event.VisibleAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning event: %w", err)
+		}
+
+		a, err := history.DeserializeAttributes(event.Type, attributes)
+		if err != nil {
+			return nil, fmt.Errorf("deserializing attributes: %w", err)
+		}
+
+		event.Attributes = a
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// describeActivityWorkers adds the workers executing activities to the pending activities of the description
+func describeActivityWorkers(ctx context.Context, tx *sql.Tx, instance *workflow.Instance, d *backend.WorkflowInstanceDescription) error {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT schedule_event_id, worker, locked_until FROM `activities` WHERE instance_id = ? AND execution_id = ? AND worker IS NOT NULL",
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+	if err != nil {
+		return fmt.Errorf("getting activities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var scheduleEventID int64
+		var worker string
+		var lockedUntil *time.Time
+		if err := rows.Scan(&scheduleEventID, &worker, &lockedUntil); err != nil {
+			return fmt.Errorf("scanning activity: %w", err)
+		}
+
+		for _, pa := range d.PendingActivities {
+			if pa.ScheduleEventID == scheduleEventID {
+				pa.Worker = worker
+				pa.LockedUntil = lockedUntil
+			}
+		}
+	}
+
+	return rows.Err()
+}
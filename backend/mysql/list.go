@@ -0,0 +1,174 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/sqlsearch"
+)
+
+func (b *mysqlBackend) ListWorkflowInstances(ctx context.Context, options *backend.ListWorkflowInstancesOptions) (*backend.ListWorkflowInstancesResult, error) {
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = backend.DefaultListPageSize
+	}
+
+	conditions := []string{}
+	args := []interface{}{}
+
+	if options.WorkflowName != "" {
+		conditions = append(conditions, "i.workflow_name = ?")
+		args = append(args, options.WorkflowName)
+	}
+
+	if options.State != nil {
+		if *options.State == core.WorkflowInstanceStateFinished {
+			conditions = append(conditions, "i.completed_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "i.completed_at IS NULL")
+		}
+	}
+
+	if !options.CreatedAfter.IsZero() {
+		conditions = append(conditions, "i.created_at >= ?")
+		args = append(args, options.CreatedAfter)
+	}
+
+	if !options.CreatedBefore.IsZero() {
+		conditions = append(conditions, "i.created_at <= ?")
+		args = append(args, options.CreatedBefore)
+	}
+
+	if !options.CompletedAfter.IsZero() {
+		conditions = append(conditions, "i.completed_at >= ?")
+		args = append(args, options.CompletedAfter)
+	}
+
+	if !options.CompletedBefore.IsZero() {
+		conditions = append(conditions, "i.completed_at <= ?")
+		args = append(args, options.CompletedBefore)
+	}
+
+	if options.ParentInstanceID != "" {
+		conditions = append(conditions, "i.parent_instance_id = ?")
+		args = append(args, options.ParentInstanceID)
+	}
+
+	if options.Query != nil {
+		where, queryArgs, err := sqlsearch.Where(options.Query, "i.instance_id")
+		if err != nil {
+			return nil, fmt.Errorf("invalid query: %w", err)
+		}
+
+		conditions = append(conditions, where)
+		args = append(args, queryArgs...)
+	}
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &backend.ListWorkflowInstancesResult{}
+
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM instances i "+whereClause(conditions), args...).Scan(&result.TotalCount); err != nil {
+		return nil, fmt.Errorf("counting workflow instances: %w", err)
+	}
+
+	// The cursor contains the creation time and the instance_id of the last instance of the previous page, so that
+	// paging continues even if that instance has been removed in the meantime
+	if options.Cursor != "" {
+		createdAt, instanceID, err := parseCursor(options.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		conditions = append(conditions, "(i.created_at < ? OR (i.created_at = ? AND i.instance_id < ?))")
+		args = append(args, createdAt, createdAt, instanceID)
+	}
+
+	// Fetch one additional instance to determine whether there is another page
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT i.instance_id, i.execution_id, i.parent_instance_id, i.parent_schedule_event_id, i.workflow_name, i.created_at, i.completed_at
+		FROM instances i `+whereClause(conditions)+`
+		ORDER BY i.created_at DESC, i.instance_id DESC
+		LIMIT ?`,
+		append(args, pageSize+1)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing workflow instances: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, executionID string
+		var parentInstanceID, workflowName *string
+		var parentEventID *int64
+		var createdAt time.Time
+		var completedAt *time.Time
+		if err := rows.Scan(&id, &executionID, &parentInstanceID, &parentEventID, &workflowName, &createdAt, &completedAt); err != nil {
+			return nil, fmt.Errorf("scanning workflow instance: %w", err)
+		}
+
+		if len(result.Instances) == pageSize {
+			last := result.Instances[pageSize-1]
+			result.NextCursor = formatCursor(last.CreatedAt, last.Instance.InstanceID)
+			break
+		}
+
+		info := &backend.WorkflowInstanceInfo{
+			Instance:    core.NewWorkflowInstance(id, executionID),
+			CreatedAt:   createdAt,
+			CompletedAt: completedAt,
+		}
+
+		if parentInstanceID != nil {
+			info.Instance = core.NewSubWorkflowInstance(id, executionID, *parentInstanceID, *parentEventID)
+		}
+
+		if workflowName != nil {
+			info.WorkflowName = *workflowName
+		}
+
+		if completedAt != nil {
+			info.State = core.WorkflowInstanceStateFinished
+		}
+
+		result.Instances = append(result.Instances, info)
+	}
+
+	return result, rows.Err()
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+func formatCursor(createdAt time.Time, instanceID string) string {
+	return strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + instanceID
+}
+
+func parseCursor(cursor string) (time.Time, string, error) {
+	createdAt, instanceID, ok := strings.Cut(cursor, ":")
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	n, err := strconv.ParseInt(createdAt, 10, 64)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("invalid cursor %q: %w", cursor, err)
+	}
+
+	return time.Unix(0, n).UTC(), instanceID, nil
+}
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `search_attributes` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `instance_id` NVARCHAR(128) NOT NULL,
+  `name` NVARCHAR(128) NOT NULL,
+  `type` INT NOT NULL,
+  `string_value` NVARCHAR(512) NULL,
+  `int_value` BIGINT NULL,
+  `float_value` DOUBLE NULL,
+
+  UNIQUE INDEX `idx_search_attributes_instance_id_name` (`instance_id`, `name`),
+  INDEX `idx_search_attributes_string_value` (`name`, `type`, `string_value`),
+  INDEX `idx_search_attributes_int_value` (`name`, `type`, `int_value`),
+  INDEX `idx_search_attributes_float_value` (`name`, `type`, `float_value`)
+);
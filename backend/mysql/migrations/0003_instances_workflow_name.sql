@@ -0,0 +1,2 @@
+-- Instances created before this migration have no workflow name
+ALTER TABLE `instances` ADD COLUMN `workflow_name` NVARCHAR(256) NULL AFTER `metadata`;
@@ -0,0 +1 @@
+CREATE INDEX `idx_instances_created_at` ON `instances` (`created_at`, `instance_id`);
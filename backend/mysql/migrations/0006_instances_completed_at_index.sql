@@ -0,0 +1 @@
+CREATE INDEX `idx_instances_completed_at` ON `instances` (`completed_at`);
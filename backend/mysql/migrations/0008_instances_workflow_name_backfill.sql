@@ -0,0 +1,11 @@
+-- Backfill the workflow name of instances created before 0003 from their WorkflowExecutionStarted event, which is
+-- still pending for instances that have not been started yet
+UPDATE `instances` i
+  JOIN `history` h ON h.`instance_id` = i.`instance_id` AND h.`event_type` = 1
+  SET i.`workflow_name` = JSON_UNQUOTE(JSON_EXTRACT(CONVERT(h.`attributes` USING utf8mb4), '$.name'))
+  WHERE i.`workflow_name` IS NULL;
+
+UPDATE `instances` i
+  JOIN `pending_events` p ON p.`instance_id` = i.`instance_id` AND p.`event_type` = 1
+  SET i.`workflow_name` = JSON_UNQUOTE(JSON_EXTRACT(CONVERT(p.`attributes` USING utf8mb4), '$.name'))
+  WHERE i.`workflow_name` IS NULL;
@@ -3,7 +3,7 @@ package mysql
 import (
 	"context"
 	"database/sql"
-	_ "embed"
+	"embed"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,6 +15,9 @@ import (
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/internal/migrations"
+	"github.com/cschleiden/go-workflows/internal/notify"
+	"github.com/cschleiden/go-workflows/internal/sqlsearch"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/log"
 	"github.com/cschleiden/go-workflows/metrics"
@@ -24,42 +27,62 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
-//go:embed schema.sql
-var schema string
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
 
 func NewMysqlBackend(host string, port int, user, password, database string, opts ...backend.BackendOption) *mysqlBackend {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&interpolateParams=true", user, password, host, port, database)
+	options := backend.ApplyOptions(opts...)
 
-	schemaDsn := dsn + "&multiStatements=true"
-	db, err := sql.Open("mysql", schemaDsn)
+	if !options.DisableAutoMigration {
+		if err := Migrate(context.Background(), host, port, user, password, database); err != nil {
+			panic(fmt.Errorf("migrating database: %w", err))
+		}
+	}
+
+	db, err := sql.Open("mysql", mysqlDSN(host, port, user, password, database))
 	if err != nil {
 		panic(err)
 	}
 
-	if _, err := db.Exec(schema); err != nil {
-		panic(fmt.Errorf("initializing database: %w", err))
+	return &mysqlBackend{
+		db:                 db,
+		workerName:         fmt.Sprintf("worker-%v", uuid.NewString()),
+		options:            options,
+		completionNotifier: notify.New(),
+		historyNotifier:    notify.New(),
 	}
+}
 
-	if err := db.Close(); err != nil {
-		panic(err)
+// Migrate applies all pending schema migrations to the given database
+func Migrate(ctx context.Context, host string, port int, user, password, database string) error {
+	// Migrations can contain multiple statements
+	db, err := sql.Open("mysql", mysqlDSN(host, port, user, password, database)+"&multiStatements=true")
+	if err != nil {
+		return err
 	}
+	defer db.Close()
 
-	db, err = sql.Open("mysql", dsn)
+	ms, err := migrations.Load(migrationsFS, "migrations")
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	return &mysqlBackend{
-		db:         db,
-		workerName: fmt.Sprintf("worker-%v", uuid.NewString()),
-		options:    backend.ApplyOptions(opts...),
-	}
+	return migrations.Apply(ctx, db, migrations.MySQL, ms)
+}
+
+func mysqlDSN(host string, port int, user, password, database string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&interpolateParams=true", user, password, host, port, database)
 }
 
 type mysqlBackend struct {
 	db         *sql.DB
 	workerName string
 	options    backend.Options
+
+	// completionNotifier and historyNotifier report finished instances and new history events committed by this
+	// backend to clients in the same process
+	completionNotifier *notify.Notifier
+	historyNotifier    *notify.Notifier
 }
 
 // CreateWorkflowInstance creates a new workflow instance
@@ -73,7 +96,8 @@ func (b *mysqlBackend) CreateWorkflowInstance(ctx context.Context, instance *wor
 	defer tx.Rollback()
 
 	// Create workflow instance
-	if err := createInstance(ctx, tx, instance, event.Attributes.(*history.ExecutionStartedAttributes).Metadata, false); err != nil {
+	a := event.Attributes.(*history.ExecutionStartedAttributes)
+	if err := createInstance(ctx, tx, instance, a.Name, a.Metadata, false); err != nil {
 		return err
 	}
 
@@ -89,6 +113,10 @@ func (b *mysqlBackend) CreateWorkflowInstance(ctx context.Context, instance *wor
 	return nil
 }
 
+func (b *mysqlBackend) Options() backend.Options {
+	return b.options
+}
+
 func (b *mysqlBackend) Logger() log.Logger {
 	return b.options.Logger
 }
@@ -105,6 +133,10 @@ func (b *mysqlBackend) Converter() converter.Converter {
 	return b.options.Converter
 }
 
+func (b *mysqlBackend) ContextPropagators() []workflow.ContextPropagator {
+	return b.options.ContextPropagators
+}
+
 func (b *mysqlBackend) CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
 	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelReadCommitted,
@@ -216,7 +248,7 @@ func (b *mysqlBackend) GetWorkflowInstanceState(ctx context.Context, instance *w
 	return core.WorkflowInstanceStateActive, nil
 }
 
-func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, metadata *workflow.Metadata, ignoreDuplicate bool) error {
+func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, workflowName string, metadata *workflow.Metadata, ignoreDuplicate bool) error {
 	var parentInstanceID *string
 	var parentEventID *int64
 	if wfi.SubWorkflow() {
@@ -234,12 +266,13 @@ func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, met
 
 	res, err := tx.ExecContext(
 		ctx,
-		"INSERT IGNORE INTO `instances` (instance_id, execution_id, parent_instance_id, parent_schedule_event_id, metadata) VALUES (?, ?, ?, ?, ?)",
+		"INSERT IGNORE INTO `instances` (instance_id, execution_id, parent_instance_id, parent_schedule_event_id, metadata, workflow_name) VALUES (?, ?, ?, ?, ?, ?)",
 		wfi.InstanceID,
 		wfi.ExecutionID,
 		parentInstanceID,
 		parentEventID,
 		string(metadataJson),
+		workflowName,
 	)
 	if err != nil {
 		return fmt.Errorf("inserting workflow instance: %w", err)
@@ -259,6 +292,27 @@ func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, met
 	return nil
 }
 
+// checkTargetInstance returns ErrInstanceNotFound if the given instance does not exist, and ErrInstanceFinished if it
+// has already finished
+func checkTargetInstance(ctx context.Context, tx *sql.Tx, instanceID string) error {
+	row := tx.QueryRowContext(ctx, "SELECT completed_at FROM `instances` WHERE instance_id = ? LIMIT 1", instanceID)
+
+	var completedAt sql.NullTime
+	if err := row.Scan(&completedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return backend.ErrInstanceNotFound
+		}
+
+		return err
+	}
+
+	if completedAt.Valid {
+		return backend.ErrInstanceFinished
+	}
+
+	return nil
+}
+
 // SignalWorkflow signals a running workflow instance
 func (b *mysqlBackend) SignalWorkflow(ctx context.Context, instanceID string, event *history.Event) error {
 	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
@@ -296,7 +350,7 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 	now := time.Now()
 	row := tx.QueryRowContext(
 		ctx,
-		`SELECT i.id, i.instance_id, i.execution_id, i.parent_instance_id, i.parent_schedule_event_id, i.metadata, i.sticky_until
+		`SELECT i.id, i.instance_id, i.execution_id, i.parent_instance_id, i.parent_schedule_event_id, i.metadata, i.sticky_until, i.workflow_task_failures
 			FROM instances i
 			INNER JOIN pending_events pe ON i.instance_id = pe.instance_id
 			WHERE
@@ -318,7 +372,8 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 	var parentEventID *int64
 	var metadataJson sql.NullString
 	var stickyUntil *time.Time
-	if err := row.Scan(&id, &instanceID, &executionID, &parentInstanceID, &parentEventID, &metadataJson, &stickyUntil); err != nil {
+	var failures int
+	if err := row.Scan(&id, &instanceID, &executionID, &parentInstanceID, &parentEventID, &metadataJson, &stickyUntil, &failures); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -365,6 +420,7 @@ func (b *mysqlBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, err
 		WorkflowInstance:      wfi,
 		WorkflowInstanceState: core.WorkflowInstanceStateActive,
 		Metadata:              metadata,
+		Attempt:               failures + 1,
 		NewEvents:             []*history.Event{},
 	}
 
@@ -460,7 +516,7 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 
 	res, err := tx.ExecContext(
 		ctx,
-		`UPDATE instances SET locked_until = NULL, sticky_until = ?, completed_at = ? WHERE instance_id = ? AND execution_id = ? AND worker = ?`,
+		`UPDATE instances SET locked_until = NULL, sticky_until = ?, completed_at = ?, workflow_task_failures = 0 WHERE instance_id = ? AND execution_id = ? AND worker = ?`,
 		time.Now().Add(b.options.StickyTimeout),
 		completedAt,
 		instance.InstanceID,
@@ -521,22 +577,57 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 		}
 	}
 
+	// Update search attribute index
+	if updates := history.SearchAttributeUpdates(executedEvents); len(updates) > 0 {
+		if err := sqlsearch.Upsert(ctx, tx, instance.InstanceID, updates); err != nil {
+			return fmt.Errorf("updating search attributes: %w", err)
+		}
+	}
+
 	// Insert new workflow events
 	groupedEvents := history.EventsByWorkflowInstanceID(workflowEvents)
 
+	// Errors for target instances that do not exist, have finished, or could not be created
+	targetErrs := make(map[string]error)
+
 	for targetInstanceID, events := range groupedEvents {
+		created := false
+
 		for _, m := range events {
 			if m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted {
 				a := m.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
-				// Create new instance
-				if err := createInstance(ctx, tx, m.WorkflowInstance, a.Metadata, true); err != nil {
-					return err
+				// Create new instance. Events for existing instances are not delivered, the sending instance is
+				// notified below
+				if err := createInstance(ctx, tx, m.WorkflowInstance, a.Name, a.Metadata, false); err != nil {
+					if !errors.Is(err, backend.ErrInstanceAlreadyExists) {
+						return err
+					}
+
+					targetErrs[targetInstanceID] = err
 				}
 
+				created = true
 				break
 			}
 		}
 
+		if targetErrs[targetInstanceID] != nil {
+			continue
+		}
+
+		if !created {
+			// Events for instances that do not exist or have finished are not delivered, the sending instance is
+			// notified below
+			if err := checkTargetInstance(ctx, tx, targetInstanceID); err != nil {
+				if !errors.Is(err, backend.ErrInstanceNotFound) && !errors.Is(err, backend.ErrInstanceFinished) {
+					return fmt.Errorf("checking for target workflow instance: %w", err)
+				}
+
+				targetErrs[targetInstanceID] = err
+				continue
+			}
+		}
+
 		historyEvents := []*history.Event{}
 		for _, m := range events {
 			historyEvents = append(historyEvents, m.HistoryEvent)
@@ -547,13 +638,94 @@ func (b *mysqlBackend) CompleteWorkflowTask(
 		}
 	}
 
+	// Report the outcome of sub-workflows, signals, and cancellation requests sent to other instances back to this
+	// instance
+	resultEvents := history.ExternalWorkflowResults(time.Now(), executedEvents, func(instanceID string) error {
+		return targetErrs[instanceID]
+	})
+	if err := insertPendingEvents(ctx, tx, instance.InstanceID, resultEvents); err != nil {
+		return fmt.Errorf("inserting external workflow results: %w", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("committing complete workflow transaction: %w", err)
 	}
 
+	if len(executedEvents) > 0 {
+		b.historyNotifier.Notify(instance.InstanceID)
+	}
+
+	if state == core.WorkflowInstanceStateFinished {
+		b.completionNotifier.Notify(instance.InstanceID)
+	}
+
+	return nil
+}
+
+func (b *mysqlBackend) FailWorkflowTask(
+	ctx context.Context,
+	task *task.Workflow,
+	instance *workflow.Instance,
+	failedEvent *history.Event,
+	retryAfter time.Duration,
+) error {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Keep the instance locked until the task should be retried, pending events are left untouched
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE instances SET locked_until = ?, workflow_task_failures = workflow_task_failures + 1 WHERE instance_id = ? AND execution_id = ? AND worker = ?`,
+		time.Now().Add(retryAfter),
+		instance.InstanceID,
+		instance.ExecutionID,
+		b.workerName,
+	)
+	if err != nil {
+		return fmt.Errorf("delaying workflow task: %w", err)
+	}
+
+	if changedRows, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("checking for delayed workflow instance: %w", err)
+	} else if changedRows != 1 {
+		return errors.New("could not find workflow instance to delay")
+	}
+
+	if failedEvent != nil {
+		if err := insertHistoryEvents(ctx, tx, instance.InstanceID, []*history.Event{failedEvent}); err != nil {
+			return fmt.Errorf("inserting workflow task failed event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing fail workflow task transaction: %w", err)
+	}
+
+	if failedEvent != nil {
+		b.historyNotifier.Notify(instance.InstanceID)
+	}
+
 	return nil
 }
 
+var _ backend.CompletionSubscriber = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) SubscribeWorkflowInstanceCompletion(ctx context.Context, instance *workflow.Instance) (<-chan struct{}, func(), error) {
+	c, cancel := b.completionNotifier.Subscribe(instance.InstanceID)
+	return c, cancel, nil
+}
+
+var _ backend.HistoryWatcher = (*mysqlBackend)(nil)
+
+func (b *mysqlBackend) WatchWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, fromSequenceID int64) (<-chan *history.Event, error) {
+	return notify.WatchHistory(ctx, b, b.historyNotifier, instance, fromSequenceID)
+}
+
 func (b *mysqlBackend) ExtendWorkflowTask(ctx context.Context, taskID string, instance *core.WorkflowInstance) error {
 	tx, err := b.db.BeginTx(ctx, nil)
 	if err != nil {
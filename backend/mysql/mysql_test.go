@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
 )
 
 const testUser = "root"
@@ -58,6 +61,49 @@ func Test_MysqlBackend(t *testing.T) {
 	})
 }
 
+func Test_MysqlMigrate_FromBaselineSchema(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@/?parseTime=true&interpolateParams=true&multiStatements=true", testUser, testPassword))
+	require.NoError(t, err)
+	defer db.Close()
+
+	dbName := "test_" + strings.Replace(uuid.NewString(), "-", "", -1)
+	_, err = db.Exec("CREATE DATABASE " + dbName)
+	require.NoError(t, err)
+	defer db.Exec("DROP DATABASE IF EXISTS " + dbName)
+
+	// Versions before migrations were introduced created the baseline schema on every start
+	baseline, err := migrationsFS.ReadFile("migrations/0001_initial.sql")
+	require.NoError(t, err)
+
+	_, err = db.Exec("USE " + dbName + "; " + string(baseline))
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO " + dbName + ".`instances` (instance_id, execution_id) VALUES ('existing', 'execution')")
+	require.NoError(t, err)
+
+	b := NewMysqlBackend("localhost", 3306, testUser, testPassword, dbName)
+	defer b.db.Close()
+
+	instance := core.NewWorkflowInstance("new", "execution")
+	require.NoError(t, b.CreateWorkflowInstance(ctx, instance, history.NewHistoryEvent(
+		1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"})))
+
+	r, err := b.ListWorkflowInstances(ctx, &backend.ListWorkflowInstancesOptions{})
+	require.NoError(t, err)
+	require.Len(t, r.Instances, 2)
+
+	r, err = b.ListWorkflowInstances(ctx, &backend.ListWorkflowInstancesOptions{WorkflowName: "wf"})
+	require.NoError(t, err)
+	require.Len(t, r.Instances, 1)
+	require.Equal(t, "new", r.Instances[0].Instance.InstanceID)
+}
+
 func TestMySqlBackendE2E(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
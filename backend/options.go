@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"time"
 
 	"github.com/cschleiden/go-workflows/internal/converter"
@@ -8,6 +9,7 @@ import (
 	mi "github.com/cschleiden/go-workflows/internal/metrics"
 	"github.com/cschleiden/go-workflows/log"
 	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/workflow"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -22,6 +24,9 @@ type Options struct {
 	// converter.DefaultConverter is used.
 	Converter converter.Converter
 
+	// ContextPropagators propagate values from the context passed to the client to workflows and activities
+	ContextPropagators []workflow.ContextPropagator
+
 	StickyTimeout time.Duration
 
 	// WorkflowLockTimeout determines how long a workflow task can be locked for. If the workflow task is not completed
@@ -33,6 +38,23 @@ type Options struct {
 	// ActivityLockTimeout determines how long an activity task can be locked for. If the activity task is not completed
 	// by that timeframe, it's considered abandoned and another worker might pick it up
 	ActivityLockTimeout time.Duration
+
+	// RetentionPeriod is how long finished workflow instances are kept. Workers periodically remove instances that
+	// finished longer ago together with their history. Defaults to 0, which keeps instances forever.
+	RetentionPeriod time.Duration
+
+	// Archiver stores finished workflow instances before they are removed. If not set, instances are removed without
+	// archiving them.
+	Archiver Archiver
+
+	// RemovalHooks are called after a workflow instance has been removed by the retention cleanup or with
+	// client.RemoveWorkflowInstance, for example to delete data stored outside of the backend.
+	RemovalHooks []RemovalHook
+
+	// DisableAutoMigration skips applying pending schema migrations when a backend with a database schema is created,
+	// and indexing existing instances when a redis backend is created. Migrations then have to be applied before
+	// starting workers and clients, for example with cmd/migrate.
+	DisableAutoMigration bool
 }
 
 var DefaultOptions Options = Options{
@@ -48,12 +70,43 @@ var DefaultOptions Options = Options{
 
 type BackendOption func(*Options)
 
+// RemovalHook is called after the given workflow instance has been removed
+type RemovalHook func(ctx context.Context, instance *workflow.Instance) error
+
+// WithRetentionPeriod removes finished workflow instances once they have been finished for the given duration
+func WithRetentionPeriod(retention time.Duration) BackendOption {
+	return func(o *Options) {
+		o.RetentionPeriod = retention
+	}
+}
+
+// WithArchiver archives finished workflow instances before they are removed
+func WithArchiver(archiver Archiver) BackendOption {
+	return func(o *Options) {
+		o.Archiver = archiver
+	}
+}
+
+// WithRemovalHook adds a hook that is called after a workflow instance has been removed
+func WithRemovalHook(hook RemovalHook) BackendOption {
+	return func(o *Options) {
+		o.RemovalHooks = append(o.RemovalHooks, hook)
+	}
+}
+
 func WithStickyTimeout(timeout time.Duration) BackendOption {
 	return func(o *Options) {
 		o.StickyTimeout = timeout
 	}
 }
 
+// WithoutAutoMigration disables applying schema migrations when the backend is created
+func WithoutAutoMigration() BackendOption {
+	return func(o *Options) {
+		o.DisableAutoMigration = true
+	}
+}
+
 func WithLogger(logger log.Logger) BackendOption {
 	return func(o *Options) {
 		o.Logger = logger
@@ -78,6 +131,12 @@ func WithConverter(converter converter.Converter) BackendOption {
 	}
 }
 
+func WithContextPropagator(prop workflow.ContextPropagator) BackendOption {
+	return func(o *Options) {
+		o.ContextPropagators = append(o.ContextPropagators, prop)
+	}
+}
+
 func ApplyOptions(opts ...BackendOption) Options {
 	options := DefaultOptions
 
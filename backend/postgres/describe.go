@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+func (b *postgresBackend) DescribeWorkflowInstance(ctx context.Context, instance *workflow.Instance) (*backend.WorkflowInstanceDescription, error) {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id, executionID string
+	var parentInstanceID, workflowName, worker *string
+	var parentEventID *int64
+	var createdAt time.Time
+	var completedAt *time.Time
+	if err := tx.QueryRowContext(
+		ctx,
+		`SELECT instance_id, execution_id, parent_instance_id, parent_schedule_event_id, workflow_name, created_at, completed_at, worker
+		FROM instances WHERE instance_id = $1 AND execution_id = $2`,
+		instance.InstanceID,
+		instance.ExecutionID,
+	).Scan(&id, &executionID, &parentInstanceID, &parentEventID, &workflowName, &createdAt, &completedAt, &worker); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, backend.ErrInstanceNotFound
+		}
+
+		return nil, fmt.Errorf("getting workflow instance: %w", err)
+	}
+
+	info := &backend.WorkflowInstanceInfo{
+		Instance:    core.NewWorkflowInstance(id, executionID),
+		CreatedAt:   createdAt,
+		CompletedAt: completedAt,
+	}
+
+	if parentInstanceID != nil {
+		info.Instance = core.NewSubWorkflowInstance(id, executionID, *parentInstanceID, *parentEventID)
+	}
+
+	if workflowName != nil {
+		info.WorkflowName = *workflowName
+	}
+
+	if completedAt != nil {
+		info.State = core.WorkflowInstanceStateFinished
+	}
+
+	h, err := queryEvents(
+		ctx, tx,
+		"SELECT event_id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM history WHERE instance_id = $1 ORDER BY sequence_id",
+		instance.InstanceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("getting history: %w", err)
+	}
+
+	// Include future events, they are filtered when building the description
+	pendingEvents, err := queryEvents(
+		ctx, tx,
+		"SELECT event_id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM pending_events WHERE instance_id = $1 ORDER BY id",
+		instance.InstanceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("getting pending events: %w", err)
+	}
+
+	d := backend.NewWorkflowInstanceDescription(info, h, pendingEvents, time.Now())
+
+	if worker != nil {
+		d.LastWorkflowTaskWorker = *worker
+	}
+
+	if len(d.PendingActivities) > 0 {
+		if err := describeActivityWorkers(ctx, tx, instance, d); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// describeActivityWorkers adds the workers executing activities to the pending activities of the description
+func describeActivityWorkers(ctx context.Context, tx *sql.Tx, instance *workflow.Instance, d *backend.WorkflowInstanceDescription) error {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT schedule_event_id, worker, locked_until FROM activities WHERE instance_id = $1 AND execution_id = $2 AND worker IS NOT NULL",
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+	if err != nil {
+		return fmt.Errorf("getting activities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var scheduleEventID int64
+		var worker string
+		var lockedUntil *time.Time
+		if err := rows.Scan(&scheduleEventID, &worker, &lockedUntil); err != nil {
+			return fmt.Errorf("scanning activity: %w", err)
+		}
+
+		for _, pa := range d.PendingActivities {
+			if pa.ScheduleEventID == scheduleEventID {
+				pa.Worker = worker
+				pa.LockedUntil = lockedUntil
+			}
+		}
+	}
+
+	return rows.Err()
+}
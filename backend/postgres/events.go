@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+func insertPendingEvents(ctx context.Context, tx *sql.Tx, instanceID string, newEvents []*history.Event) error {
+	return insertEvents(ctx, tx, "pending_events", instanceID, newEvents)
+}
+
+func insertHistoryEvents(ctx context.Context, tx *sql.Tx, instanceID string, historyEvents []*history.Event) error {
+	return insertEvents(ctx, tx, "history", instanceID, historyEvents)
+}
+
+func insertEvents(ctx context.Context, tx *sql.Tx, tableName string, instanceID string, events []*history.Event) error {
+	const batchSize = 20
+	for batchStart := 0; batchStart < len(events); batchStart += batchSize {
+		batchEnd := batchStart + batchSize
+		if batchEnd > len(events) {
+			batchEnd = len(events)
+		}
+		batchEvents := events[batchStart:batchEnd]
+
+		query := "INSERT INTO " + tableName + " (event_id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)" +
+			strings.Repeat(", (?, ?, ?, ?, ?, ?, ?, ?)", len(batchEvents)-1)
+
+		args := make([]interface{}, 0, len(batchEvents)*8)
+
+		for _, newEvent := range batchEvents {
+			a, err := history.SerializeAttributes(newEvent.Attributes)
+			if err != nil {
+				return err
+			}
+
+			args = append(args, newEvent.ID, newEvent.SequenceID, instanceID, newEvent.Type, newEvent.Timestamp, newEvent.ScheduleEventID, a, newEvent.VisibleAt)
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			rebind(query),
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func removeFutureEvent(ctx context.Context, tx *sql.Tx, instanceID string, scheduleEventID int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"DELETE FROM pending_events WHERE instance_id = $1 AND schedule_event_id = $2 AND visible_at IS NOT NULL",
+		instanceID,
+		scheduleEventID,
+	)
+
+	return err
+}
+
+func queryEvents(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]*history.Event, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]*history.Event, 0)
+
+	for rows.Next() {
+		var instanceID string
+		var attributes []byte
+
+		event := &history.Event{}
+
+		if err := rows.Scan(
+			&event.ID,
+			&event.SequenceID,
+			&instanceID,
+			&event.Type,
+			&event.Timestamp,
+			&event.ScheduleEventID,
+			&attributes,
+			&event.VisibleAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning event: %w", err)
+		}
+
+		a, err := history.DeserializeAttributes(event.Type, attributes)
+		if err != nil {
+			return nil, fmt.Errorf("deserializing attributes: %w", err)
+		}
+
+		event.Attributes = a
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// rebind replaces the ? placeholders in the given query with the numbered $n placeholders Postgres expects. Queries
+// built from shared fragments, like the search attribute conditions, use ? placeholders.
+func rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// rebindExecer rebinds the placeholders of queries executed by shared packages like sqlsearch
+type rebindExecer struct {
+	tx *sql.Tx
+}
+
+func (e *rebindExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return e.tx.ExecContext(ctx, rebind(query), args...)
+}
@@ -0,0 +1,87 @@
+CREATE TABLE IF NOT EXISTS instances (
+  id BIGSERIAL PRIMARY KEY,
+  instance_id VARCHAR(128) NOT NULL,
+  execution_id VARCHAR(128) NOT NULL,
+  parent_instance_id VARCHAR(128) NULL,
+  parent_schedule_event_id BIGINT NULL,
+  metadata TEXT NULL,
+  workflow_name VARCHAR(256) NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  completed_at TIMESTAMPTZ NULL,
+  locked_until TIMESTAMPTZ NULL,
+  sticky_until TIMESTAMPTZ NULL,
+  worker VARCHAR(64) NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_instances_instance_id ON instances (instance_id);
+CREATE INDEX IF NOT EXISTS idx_instances_locked_until_completed_at ON instances (completed_at, locked_until, sticky_until, worker);
+CREATE INDEX IF NOT EXISTS idx_instances_parent_instance_id ON instances (parent_instance_id);
+CREATE INDEX IF NOT EXISTS idx_instances_created_at ON instances (created_at, instance_id);
+CREATE INDEX IF NOT EXISTS idx_instances_workflow_name_created_at ON instances (workflow_name, created_at);
+CREATE INDEX IF NOT EXISTS idx_instances_completed_at ON instances (completed_at);
+
+
+CREATE TABLE IF NOT EXISTS pending_events (
+  id BIGSERIAL PRIMARY KEY,
+  event_id VARCHAR(128) NOT NULL,
+  sequence_id BIGINT NOT NULL, -- Not used, but keep for now for query compat
+  instance_id VARCHAR(128) NOT NULL,
+  event_type INT NOT NULL,
+  timestamp TIMESTAMPTZ NOT NULL,
+  schedule_event_id BIGINT NOT NULL,
+  attributes BYTEA NOT NULL,
+  visible_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_events_instance_id ON pending_events (instance_id);
+CREATE INDEX IF NOT EXISTS idx_pending_events_instance_id_visible_at_schedule_event_id ON pending_events (instance_id, visible_at, schedule_event_id);
+
+
+CREATE TABLE IF NOT EXISTS history (
+  id BIGSERIAL PRIMARY KEY,
+  event_id VARCHAR(64) NOT NULL,
+  sequence_id BIGINT NOT NULL,
+  instance_id VARCHAR(128) NOT NULL,
+  event_type INT NOT NULL,
+  timestamp TIMESTAMPTZ NOT NULL,
+  schedule_event_id BIGINT NOT NULL,
+  attributes BYTEA NOT NULL,
+  visible_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_history_instance_id ON history (instance_id);
+CREATE INDEX IF NOT EXISTS idx_history_instance_id_sequence_id ON history (instance_id, sequence_id);
+
+
+CREATE TABLE IF NOT EXISTS activities (
+  id BIGSERIAL PRIMARY KEY,
+  activity_id VARCHAR(64) NOT NULL,
+  instance_id VARCHAR(128) NOT NULL,
+  execution_id VARCHAR(128) NOT NULL,
+  event_type INT NOT NULL,
+  timestamp TIMESTAMPTZ NOT NULL,
+  schedule_event_id BIGINT NOT NULL,
+  attributes BYTEA NOT NULL,
+  visible_at TIMESTAMPTZ NULL,
+  locked_until TIMESTAMPTZ NULL,
+  worker VARCHAR(64) NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_activities_instance_id ON activities (instance_id, activity_id, execution_id, worker);
+CREATE INDEX IF NOT EXISTS idx_activities_locked_until ON activities (locked_until);
+
+
+CREATE TABLE IF NOT EXISTS search_attributes (
+  id BIGSERIAL PRIMARY KEY,
+  instance_id VARCHAR(128) NOT NULL,
+  name VARCHAR(128) NOT NULL,
+  type INT NOT NULL,
+  string_value VARCHAR(512) NULL,
+  int_value BIGINT NULL,
+  float_value DOUBLE PRECISION NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_search_attributes_instance_id_name ON search_attributes (instance_id, name);
+CREATE INDEX IF NOT EXISTS idx_search_attributes_string_value ON search_attributes (name, type, string_value);
+CREATE INDEX IF NOT EXISTS idx_search_attributes_int_value ON search_attributes (name, type, int_value);
+CREATE INDEX IF NOT EXISTS idx_search_attributes_float_value ON search_attributes (name, type, float_value);
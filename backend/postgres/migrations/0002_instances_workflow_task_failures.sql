@@ -0,0 +1,2 @@
+-- Number of consecutive failed workflow tasks of the instance, reset when a task completes
+ALTER TABLE instances ADD COLUMN workflow_task_failures INT NOT NULL DEFAULT 0;
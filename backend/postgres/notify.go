@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channels used with LISTEN/NOTIFY. Task channels wake up pollers, the payload of the completion and history channels
+// is the id of the instance that changed.
+const (
+	workflowTasksChannel = "go_workflows_workflow_tasks"
+	activityTasksChannel = "go_workflows_activity_tasks"
+	completionChannel    = "go_workflows_completion"
+	historyChannel       = "go_workflows_history"
+)
+
+const (
+	minReconnectInterval = time.Millisecond * 100
+	maxReconnectInterval = time.Second * 10
+)
+
+// notifyChannel sends a notification on the given channel. Notifications are delivered when tx commits, and dropped
+// when it is rolled back.
+func notifyChannel(ctx context.Context, tx *sql.Tx, channel, payload string) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, payload); err != nil {
+		return fmt.Errorf("notifying %s: %w", channel, err)
+	}
+
+	return nil
+}
+
+// listen opens a dedicated connection listening on all channels and dispatches notifications to the notifiers of the
+// backend
+func (b *postgresBackend) listen(dsn string) error {
+	b.listener = pq.NewListener(dsn, minReconnectInterval, maxReconnectInterval, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			b.options.Logger.Error("listening for notifications", "event", ev, "error", err)
+		}
+	})
+
+	for _, channel := range []string{workflowTasksChannel, activityTasksChannel, completionChannel, historyChannel} {
+		if err := b.listener.Listen(channel); err != nil {
+			b.listener.Close()
+			return err
+		}
+	}
+
+	go b.dispatchNotifications()
+
+	return nil
+}
+
+func (b *postgresBackend) dispatchNotifications() {
+	for n := range b.listener.Notify {
+		if n == nil {
+			// The connection was re-established, notifications sent in the meantime are lost
+			b.taskNotifier.Notify(workflowTasksChannel)
+			b.taskNotifier.Notify(activityTasksChannel)
+			continue
+		}
+
+		switch n.Channel {
+		case workflowTasksChannel, activityTasksChannel:
+			b.taskNotifier.Notify(n.Channel)
+
+		case completionChannel:
+			b.completionNotifier.Notify(n.Extra)
+
+		case historyChannel:
+			b.historyNotifier.Notify(n.Extra)
+		}
+	}
+}
+
+// waitForTask waits until wake is notified or BlockTimeout passes. It returns false if ctx is done.
+func (b *postgresBackend) waitForTask(ctx context.Context, wake <-chan struct{}) bool {
+	t := time.NewTimer(b.options.BlockTimeout)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-wake:
+	case <-t.C:
+	}
+
+	return true
+}
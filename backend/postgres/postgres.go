@@ -0,0 +1,983 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/internal/migrations"
+	"github.com/cschleiden/go-workflows/internal/notify"
+	"github.com/cschleiden/go-workflows/internal/sqlsearch"
+	"github.com/cschleiden/go-workflows/internal/task"
+	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+type PostgresOptions struct {
+	backend.Options
+
+	// BlockTimeout is the maximum time GetWorkflowTask and GetActivityTask wait for a notification about new tasks
+	// before returning. Tasks that become available without a notification, for example when a timer fires or a lock
+	// expires, are picked up after at most this duration.
+	BlockTimeout time.Duration
+}
+
+type PostgresBackendOption func(*PostgresOptions)
+
+func WithBlockTimeout(timeout time.Duration) PostgresBackendOption {
+	return func(o *PostgresOptions) {
+		o.BlockTimeout = timeout
+	}
+}
+
+func WithBackendOptions(opts ...backend.BackendOption) PostgresBackendOption {
+	return func(o *PostgresOptions) {
+		for _, opt := range opts {
+			opt(&o.Options)
+		}
+	}
+}
+
+func NewPostgresBackend(host string, port int, user, password, database string, opts ...PostgresBackendOption) *postgresBackend {
+	dsn := postgresDSN(host, port, user, password, database)
+
+	options := &PostgresOptions{
+		Options:      backend.ApplyOptions(),
+		BlockTimeout: time.Second * 2,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if !options.DisableAutoMigration {
+		if err := migrate(context.Background(), db); err != nil {
+			panic(fmt.Errorf("migrating database: %w", err))
+		}
+	}
+
+	b := &postgresBackend{
+		db:                 db,
+		workerName:         fmt.Sprintf("worker-%v", uuid.NewString()),
+		options:            options,
+		taskNotifier:       notify.New(),
+		completionNotifier: notify.New(),
+		historyNotifier:    notify.New(),
+	}
+
+	if err := b.listen(dsn); err != nil {
+		panic(fmt.Errorf("listening for notifications: %w", err))
+	}
+
+	return b
+}
+
+// Migrate applies all pending schema migrations to the given database
+func Migrate(ctx context.Context, host string, port int, user, password, database string) error {
+	db, err := sql.Open("postgres", postgresDSN(host, port, user, password, database))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return migrate(ctx, db)
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	ms, err := migrations.Load(migrationsFS, "migrations")
+	if err != nil {
+		return err
+	}
+
+	return migrations.Apply(ctx, db, migrations.Postgres, ms)
+}
+
+func postgresDSN(host string, port int, user, password, database string) string {
+	return (&url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(user, password),
+		Host:     fmt.Sprintf("%s:%d", host, port),
+		Path:     database,
+		RawQuery: "sslmode=disable",
+	}).String()
+}
+
+type postgresBackend struct {
+	db         *sql.DB
+	workerName string
+	options    *PostgresOptions
+
+	listener *pq.Listener
+
+	// taskNotifier wakes up pollers waiting for new tasks, completionNotifier and historyNotifier report finished
+	// instances and new history events to clients. All are notified via LISTEN/NOTIFY, so they also receive changes
+	// committed by other processes.
+	taskNotifier       *notify.Notifier
+	completionNotifier *notify.Notifier
+	historyNotifier    *notify.Notifier
+}
+
+func (b *postgresBackend) Options() backend.Options {
+	return b.options.Options
+}
+
+func (b *postgresBackend) Logger() log.Logger {
+	return b.options.Logger
+}
+
+func (b *postgresBackend) Tracer() trace.Tracer {
+	return b.options.TracerProvider.Tracer(backend.TracerName)
+}
+
+func (b *postgresBackend) Metrics() metrics.Client {
+	return b.options.Metrics.WithTags(metrics.Tags{metrickeys.Backend: "postgres"})
+}
+
+func (b *postgresBackend) Converter() converter.Converter {
+	return b.options.Converter
+}
+
+func (b *postgresBackend) ContextPropagators() []workflow.ContextPropagator {
+	return b.options.ContextPropagators
+}
+
+// Close stops listening for notifications and closes the database connections
+func (b *postgresBackend) Close() error {
+	if err := b.listener.Close(); err != nil {
+		return err
+	}
+
+	return b.db.Close()
+}
+
+// CreateWorkflowInstance creates a new workflow instance
+func (b *postgresBackend) CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Create workflow instance
+	a := event.Attributes.(*history.ExecutionStartedAttributes)
+	if err := createInstance(ctx, tx, instance, a.Name, a.Metadata, false); err != nil {
+		return err
+	}
+
+	// Initial history is empty, store only new events
+	if err := insertPendingEvents(ctx, tx, instance.InstanceID, []*history.Event{event}); err != nil {
+		return fmt.Errorf("inserting new event: %w", err)
+	}
+
+	if err := notifyChannel(ctx, tx, workflowTasksChannel, ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("creating workflow instance: %w", err)
+	}
+
+	return nil
+}
+
+func (b *postgresBackend) CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	instanceID := instance.InstanceID
+
+	exists, err := instanceExists(ctx, tx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return backend.ErrInstanceNotFound
+	}
+
+	if err := insertPendingEvents(ctx, tx, instanceID, []*history.Event{event}); err != nil {
+		return fmt.Errorf("inserting cancellation event: %w", err)
+	}
+
+	if err := notifyChannel(ctx, tx, workflowTasksChannel, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (b *postgresBackend) GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, lastSequenceID *int64) ([]*history.Event, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var h []*history.Event
+	if lastSequenceID != nil {
+		h, err = queryEvents(
+			ctx, tx,
+			"SELECT event_id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM history WHERE instance_id = $1 AND sequence_id > $2 ORDER BY sequence_id",
+			instance.InstanceID,
+			*lastSequenceID,
+		)
+	} else {
+		h, err = queryEvents(
+			ctx, tx,
+			"SELECT event_id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM history WHERE instance_id = $1 ORDER BY sequence_id",
+			instance.InstanceID,
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting history: %w", err)
+	}
+
+	return h, nil
+}
+
+func (b *postgresBackend) GetWorkflowInstanceState(ctx context.Context, instance *workflow.Instance) (core.WorkflowInstanceState, error) {
+	row := b.db.QueryRowContext(
+		ctx,
+		"SELECT completed_at FROM instances WHERE instance_id = $1 AND execution_id = $2",
+		instance.InstanceID,
+		instance.ExecutionID,
+	)
+
+	var completedAt sql.NullTime
+	if err := row.Scan(&completedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return core.WorkflowInstanceStateActive, backend.ErrInstanceNotFound
+		}
+
+		return core.WorkflowInstanceStateActive, err
+	}
+
+	if completedAt.Valid {
+		return core.WorkflowInstanceStateFinished, nil
+	}
+
+	return core.WorkflowInstanceStateActive, nil
+}
+
+func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, workflowName string, metadata *workflow.Metadata, ignoreDuplicate bool) error {
+	var parentInstanceID *string
+	var parentEventID *int64
+	if wfi.SubWorkflow() {
+		i := wfi.ParentInstanceID
+		parentInstanceID = &i
+
+		n := wfi.ParentEventID
+		parentEventID = &n
+	}
+
+	metadataJson, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("marshaling metadata: %w", err)
+	}
+
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO instances (instance_id, execution_id, parent_instance_id, parent_schedule_event_id, metadata, workflow_name)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (instance_id) DO NOTHING`,
+		wfi.InstanceID,
+		wfi.ExecutionID,
+		parentInstanceID,
+		parentEventID,
+		string(metadataJson),
+		workflowName,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting workflow instance: %w", err)
+	}
+
+	if !ignoreDuplicate {
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows != 1 {
+			return backend.ErrInstanceAlreadyExists
+		}
+	}
+
+	return nil
+}
+
+func instanceExists(ctx context.Context, tx *sql.Tx, instanceID string) (bool, error) {
+	row := tx.QueryRowContext(ctx, "SELECT 1 FROM instances WHERE instance_id = $1 LIMIT 1", instanceID)
+	if err := row.Scan(new(int)); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// checkTargetInstance returns ErrInstanceNotFound if the given instance does not exist, and ErrInstanceFinished if it
+// has already finished
+func checkTargetInstance(ctx context.Context, tx *sql.Tx, instanceID string) error {
+	row := tx.QueryRowContext(ctx, "SELECT completed_at FROM instances WHERE instance_id = $1 LIMIT 1", instanceID)
+
+	var completedAt sql.NullTime
+	if err := row.Scan(&completedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return backend.ErrInstanceNotFound
+		}
+
+		return err
+	}
+
+	if completedAt.Valid {
+		return backend.ErrInstanceFinished
+	}
+
+	return nil
+}
+
+// SignalWorkflow signals a running workflow instance
+func (b *postgresBackend) SignalWorkflow(ctx context.Context, instanceID string, event *history.Event) error {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	exists, err := instanceExists(ctx, tx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return backend.ErrInstanceNotFound
+	}
+
+	if err := insertPendingEvents(ctx, tx, instanceID, []*history.Event{event}); err != nil {
+		return fmt.Errorf("inserting signal event: %w", err)
+	}
+
+	if err := notifyChannel(ctx, tx, workflowTasksChannel, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetWorkflowTask returns a pending workflow task or nil if there are no pending worflow executions. If there is no
+// task, it waits for up to BlockTimeout to be notified about a new one.
+func (b *postgresBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	// Subscribe before looking for a task, to not miss notifications about tasks committed in between
+	wake, cancel := b.taskNotifier.Subscribe(workflowTasksChannel)
+	defer cancel()
+
+	t, err := b.getWorkflowTask(ctx)
+	if err != nil || t != nil {
+		return t, err
+	}
+
+	if !b.waitForTask(ctx, wake) {
+		return nil, nil
+	}
+
+	return b.getWorkflowTask(ctx)
+}
+
+func (b *postgresBackend) getWorkflowTask(ctx context.Context) (*task.Workflow, error) {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock next workflow task by finding an unlocked instance with new events to process. Instances locked by
+	// concurrent transactions are skipped instead of waiting for them.
+	now := time.Now()
+	row := tx.QueryRowContext(
+		ctx,
+		`SELECT i.id, i.instance_id, i.execution_id, i.parent_instance_id, i.parent_schedule_event_id, i.metadata, i.workflow_task_failures
+			FROM instances i
+			WHERE
+				i.completed_at IS NULL
+				AND (i.locked_until IS NULL OR i.locked_until < $1)
+				AND (i.sticky_until IS NULL OR i.sticky_until < $1 OR i.worker = $2)
+				AND EXISTS (
+					SELECT 1 FROM pending_events pe
+					WHERE pe.instance_id = i.instance_id AND (pe.visible_at IS NULL OR pe.visible_at <= $1)
+				)
+			LIMIT 1
+			FOR UPDATE OF i SKIP LOCKED`,
+		now,
+		b.workerName,
+	)
+
+	var id int64
+	var instanceID, executionID string
+	var parentInstanceID *string
+	var parentEventID *int64
+	var metadataJson sql.NullString
+	var failures int
+	if err := row.Scan(&id, &instanceID, &executionID, &parentInstanceID, &parentEventID, &metadataJson, &failures); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("scanning workflow instance: %w", err)
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE instances SET locked_until = $1, worker = $2 WHERE id = $3`,
+		now.Add(b.options.WorkflowLockTimeout),
+		b.workerName,
+		id,
+	); err != nil {
+		return nil, fmt.Errorf("locking workflow instance: %w", err)
+	}
+
+	var wfi *workflow.Instance
+	if parentInstanceID != nil {
+		wfi = core.NewSubWorkflowInstance(instanceID, executionID, *parentInstanceID, *parentEventID)
+	} else {
+		wfi = core.NewWorkflowInstance(instanceID, executionID)
+	}
+
+	var metadata *core.WorkflowMetadata
+	if metadataJson.Valid {
+		if err := json.Unmarshal([]byte(metadataJson.String), &metadata); err != nil {
+			return nil, fmt.Errorf("parsing workflow metadata: %w", err)
+		}
+	}
+
+	t := &task.Workflow{
+		ID:                    wfi.InstanceID,
+		WorkflowInstance:      wfi,
+		WorkflowInstanceState: core.WorkflowInstanceStateActive,
+		Metadata:              metadata,
+		Attempt:               failures + 1,
+	}
+
+	// Get new events
+	t.NewEvents, err = queryEvents(
+		ctx, tx,
+		"SELECT event_id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM pending_events WHERE instance_id = $1 AND (visible_at IS NULL OR visible_at <= $2) ORDER BY id",
+		instanceID,
+		now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("getting new events: %w", err)
+	}
+
+	// Return if there aren't any new events
+	if len(t.NewEvents) == 0 {
+		return nil, nil
+	}
+
+	// Get most recent sequence id
+	row = tx.QueryRowContext(ctx, "SELECT sequence_id FROM history WHERE instance_id = $1 ORDER BY id DESC LIMIT 1", instanceID)
+	if err := row.Scan(
+		&t.LastSequenceID,
+	); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("getting most recent sequence id: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// CompleteWorkflowTask completes a workflow task retrieved using GetWorkflowTask
+//
+// This checkpoints the execution. events are new events from the last workflow execution
+// which will be added to the workflow instance history. workflowEvents are new events for the
+// completed or other workflow instances.
+func (b *postgresBackend) CompleteWorkflowTask(
+	ctx context.Context,
+	task *task.Workflow,
+	instance *workflow.Instance,
+	state core.WorkflowInstanceState,
+	executedEvents, activityEvents, timerEvents []*history.Event,
+	workflowEvents []history.WorkflowEvent,
+) error {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Unlock instance, but keep it sticky to the current worker
+	var completedAt *time.Time
+	if state == core.WorkflowInstanceStateFinished {
+		t := time.Now()
+		completedAt = &t
+	}
+
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE instances SET locked_until = NULL, sticky_until = $1, completed_at = $2, workflow_task_failures = 0 WHERE instance_id = $3 AND execution_id = $4 AND worker = $5`,
+		time.Now().Add(b.options.StickyTimeout),
+		completedAt,
+		instance.InstanceID,
+		instance.ExecutionID,
+		b.workerName,
+	)
+	if err != nil {
+		return fmt.Errorf("unlocking instance: %w", err)
+	}
+
+	changedRows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking for unlocked workflow instances: %w", err)
+	} else if changedRows != 1 {
+		return errors.New("could not find workflow instance to unlock")
+	}
+
+	// Remove handled events from task
+	if len(executedEvents) > 0 {
+		eventIDs := make([]string, 0, len(executedEvents))
+		for _, e := range executedEvents {
+			eventIDs = append(eventIDs, e.ID)
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`DELETE FROM pending_events WHERE instance_id = $1 AND event_id = ANY($2)`,
+			instance.InstanceID,
+			pq.Array(eventIDs),
+		); err != nil {
+			return fmt.Errorf("deleting handled new events: %w", err)
+		}
+	}
+
+	// Insert new events generated during this workflow execution to the history
+	if err := insertHistoryEvents(ctx, tx, instance.InstanceID, executedEvents); err != nil {
+		return fmt.Errorf("inserting new history events: %w", err)
+	}
+
+	// Schedule activities
+	for _, e := range activityEvents {
+		if err := scheduleActivity(ctx, tx, instance, e); err != nil {
+			return fmt.Errorf("scheduling activity: %w", err)
+		}
+	}
+
+	// Timer events
+	if err := insertPendingEvents(ctx, tx, instance.InstanceID, timerEvents); err != nil {
+		return fmt.Errorf("scheduling timers: %w", err)
+	}
+
+	for _, event := range executedEvents {
+		switch event.Type {
+		case history.EventType_TimerCanceled:
+			if err := removeFutureEvent(ctx, tx, instance.InstanceID, event.ScheduleEventID); err != nil {
+				return fmt.Errorf("removing future event: %w", err)
+			}
+		}
+	}
+
+	// Update search attribute index
+	if updates := history.SearchAttributeUpdates(executedEvents); len(updates) > 0 {
+		if err := sqlsearch.Upsert(ctx, &rebindExecer{tx}, instance.InstanceID, updates); err != nil {
+			return fmt.Errorf("updating search attributes: %w", err)
+		}
+	}
+
+	// Insert new workflow events
+	groupedEvents := history.EventsByWorkflowInstanceID(workflowEvents)
+
+	// Errors for target instances that do not exist, have finished, or could not be created
+	targetErrs := make(map[string]error)
+
+	for targetInstanceID, events := range groupedEvents {
+		created := false
+
+		for _, m := range events {
+			if m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted {
+				a := m.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+				// Create new instance. Events for existing instances are not delivered, the sending instance is
+				// notified below
+				if err := createInstance(ctx, tx, m.WorkflowInstance, a.Name, a.Metadata, false); err != nil {
+					if !errors.Is(err, backend.ErrInstanceAlreadyExists) {
+						return err
+					}
+
+					targetErrs[targetInstanceID] = err
+				}
+
+				created = true
+				break
+			}
+		}
+
+		if targetErrs[targetInstanceID] != nil {
+			continue
+		}
+
+		if !created {
+			// Events for instances that do not exist or have finished are not delivered, the sending instance is
+			// notified below
+			if err := checkTargetInstance(ctx, tx, targetInstanceID); err != nil {
+				if !errors.Is(err, backend.ErrInstanceNotFound) && !errors.Is(err, backend.ErrInstanceFinished) {
+					return fmt.Errorf("checking for target workflow instance: %w", err)
+				}
+
+				targetErrs[targetInstanceID] = err
+				continue
+			}
+		}
+
+		historyEvents := []*history.Event{}
+		for _, m := range events {
+			historyEvents = append(historyEvents, m.HistoryEvent)
+		}
+
+		if err := insertPendingEvents(ctx, tx, targetInstanceID, historyEvents); err != nil {
+			return fmt.Errorf("inserting messages: %w", err)
+		}
+	}
+
+	// Report the outcome of sub-workflows, signals, and cancellation requests sent to other instances back to this
+	// instance
+	resultEvents := history.ExternalWorkflowResults(time.Now(), executedEvents, func(instanceID string) error {
+		return targetErrs[instanceID]
+	})
+	if err := insertPendingEvents(ctx, tx, instance.InstanceID, resultEvents); err != nil {
+		return fmt.Errorf("inserting external workflow results: %w", err)
+	}
+
+	// Events might have been added to this or other instances, and this instance is unlocked again
+	if err := notifyChannel(ctx, tx, workflowTasksChannel, ""); err != nil {
+		return err
+	}
+
+	if len(activityEvents) > 0 {
+		if err := notifyChannel(ctx, tx, activityTasksChannel, ""); err != nil {
+			return err
+		}
+	}
+
+	if len(executedEvents) > 0 {
+		if err := notifyChannel(ctx, tx, historyChannel, instance.InstanceID); err != nil {
+			return err
+		}
+	}
+
+	if state == core.WorkflowInstanceStateFinished {
+		if err := notifyChannel(ctx, tx, completionChannel, instance.InstanceID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing complete workflow transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (b *postgresBackend) FailWorkflowTask(
+	ctx context.Context,
+	task *task.Workflow,
+	instance *workflow.Instance,
+	failedEvent *history.Event,
+	retryAfter time.Duration,
+) error {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Keep the instance locked until the task should be retried, pending events are left untouched
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE instances SET locked_until = $1, workflow_task_failures = workflow_task_failures + 1 WHERE instance_id = $2 AND execution_id = $3 AND worker = $4`,
+		time.Now().Add(retryAfter),
+		instance.InstanceID,
+		instance.ExecutionID,
+		b.workerName,
+	)
+	if err != nil {
+		return fmt.Errorf("delaying workflow task: %w", err)
+	}
+
+	if changedRows, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("checking for delayed workflow instance: %w", err)
+	} else if changedRows != 1 {
+		return errors.New("could not find workflow instance to delay")
+	}
+
+	if failedEvent != nil {
+		if err := insertHistoryEvents(ctx, tx, instance.InstanceID, []*history.Event{failedEvent}); err != nil {
+			return fmt.Errorf("inserting workflow task failed event: %w", err)
+		}
+
+		if err := notifyChannel(ctx, tx, historyChannel, instance.InstanceID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing fail workflow task transaction: %w", err)
+	}
+
+	return nil
+}
+
+var _ backend.CompletionSubscriber = (*postgresBackend)(nil)
+
+func (b *postgresBackend) SubscribeWorkflowInstanceCompletion(ctx context.Context, instance *workflow.Instance) (<-chan struct{}, func(), error) {
+	c, cancel := b.completionNotifier.Subscribe(instance.InstanceID)
+	return c, cancel, nil
+}
+
+var _ backend.HistoryWatcher = (*postgresBackend)(nil)
+
+func (b *postgresBackend) WatchWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, fromSequenceID int64) (<-chan *history.Event, error) {
+	return notify.WatchHistory(ctx, b, b.historyNotifier, instance, fromSequenceID)
+}
+
+func (b *postgresBackend) ExtendWorkflowTask(ctx context.Context, taskID string, instance *core.WorkflowInstance) error {
+	res, err := b.db.ExecContext(
+		ctx,
+		`UPDATE instances SET locked_until = $1 WHERE instance_id = $2 AND execution_id = $3 AND worker = $4`,
+		time.Now().Add(b.options.WorkflowLockTimeout),
+		instance.InstanceID,
+		instance.ExecutionID,
+		b.workerName,
+	)
+	if err != nil {
+		return fmt.Errorf("extending workflow task lock: %w", err)
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("determining if workflow task was extended: %w", err)
+	} else if rowsAffected == 0 {
+		return errors.New("could not extend workflow task")
+	}
+
+	return nil
+}
+
+// GetActivityTask returns a pending activity task or nil if there are no pending activities. If there is no task, it
+// waits for up to BlockTimeout to be notified about a new one.
+func (b *postgresBackend) GetActivityTask(ctx context.Context) (*task.Activity, error) {
+	// Subscribe before looking for a task, to not miss notifications about tasks committed in between
+	wake, cancel := b.taskNotifier.Subscribe(activityTasksChannel)
+	defer cancel()
+
+	t, err := b.getActivityTask(ctx)
+	if err != nil || t != nil {
+		return t, err
+	}
+
+	if !b.waitForTask(ctx, wake) {
+		return nil, nil
+	}
+
+	return b.getActivityTask(ctx)
+}
+
+func (b *postgresBackend) getActivityTask(ctx context.Context) (*task.Activity, error) {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock next activity
+	now := time.Now()
+	res := tx.QueryRowContext(
+		ctx,
+		`SELECT a.id, a.activity_id, a.instance_id, a.execution_id,
+			i.metadata, a.event_type, a.timestamp, a.schedule_event_id, a.attributes, a.visible_at
+			FROM activities a
+				INNER JOIN instances i ON a.instance_id = i.instance_id
+			WHERE a.locked_until IS NULL OR a.locked_until < $1
+			LIMIT 1
+			FOR UPDATE OF a SKIP LOCKED`,
+		now,
+	)
+
+	var id int64
+	var instanceID, executionID string
+	var attributes []byte
+	var metadataJson sql.NullString
+	event := &history.Event{}
+
+	if err := res.Scan(
+		&id, &event.ID, &instanceID, &executionID, &metadataJson, &event.Type,
+		&event.Timestamp, &event.ScheduleEventID, &attributes, &event.VisibleAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("finding activity task to lock: %w", err)
+	}
+
+	var metadata *workflow.Metadata
+	if err := json.Unmarshal([]byte(metadataJson.String), &metadata); err != nil {
+		return nil, fmt.Errorf("unmarshaling metadata: %w", err)
+	}
+
+	a, err := history.DeserializeAttributes(event.Type, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("deserializing attributes: %w", err)
+	}
+
+	event.Attributes = a
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE activities SET locked_until = $1, worker = $2 WHERE id = $3`,
+		now.Add(b.options.ActivityLockTimeout),
+		b.workerName,
+		id,
+	); err != nil {
+		return nil, fmt.Errorf("locking activity: %w", err)
+	}
+
+	t := &task.Activity{
+		ID:               event.ID,
+		WorkflowInstance: core.NewWorkflowInstance(instanceID, executionID),
+		Metadata:         metadata,
+		Event:            event,
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// CompleteActivityTask completes a activity task retrieved using GetActivityTask
+func (b *postgresBackend) CompleteActivityTask(ctx context.Context, instance *workflow.Instance, id string, event *history.Event) error {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Remove activity
+	res, err := tx.ExecContext(
+		ctx,
+		`DELETE FROM activities WHERE activity_id = $1 AND instance_id = $2 AND execution_id = $3 AND worker = $4`,
+		id,
+		instance.InstanceID,
+		instance.ExecutionID,
+		b.workerName,
+	)
+	if err != nil {
+		return fmt.Errorf("completing activity: %w", err)
+	}
+
+	if affected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("checking for completed activity: %w", err)
+	} else if affected == 0 {
+		return errors.New("could not find locked activity")
+	}
+
+	// Insert new event generated during this workflow execution
+	if err := insertPendingEvents(ctx, tx, instance.InstanceID, []*history.Event{event}); err != nil {
+		return fmt.Errorf("inserting new events for completed activity: %w", err)
+	}
+
+	if err := notifyChannel(ctx, tx, workflowTasksChannel, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (b *postgresBackend) ExtendActivityTask(ctx context.Context, activityID string) error {
+	res, err := b.db.ExecContext(
+		ctx,
+		`UPDATE activities SET locked_until = $1 WHERE activity_id = $2 AND worker = $3`,
+		time.Now().Add(b.options.ActivityLockTimeout),
+		activityID,
+		b.workerName,
+	)
+	if err != nil {
+		return fmt.Errorf("extending activity lock: %w", err)
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("determining if activity was extended: %w", err)
+	} else if rowsAffected == 0 {
+		return errors.New("could not extend activity")
+	}
+
+	return nil
+}
+
+func scheduleActivity(ctx context.Context, tx *sql.Tx, instance *core.WorkflowInstance, event *history.Event) error {
+	a, err := history.SerializeAttributes(event.Attributes)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO activities
+			(activity_id, instance_id, execution_id, event_type, timestamp, schedule_event_id, attributes, visible_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		event.ID,
+		instance.InstanceID,
+		instance.ExecutionID,
+		event.Type,
+		event.Timestamp,
+		event.ScheduleEventID,
+		a,
+		event.VisibleAt,
+	)
+
+	return err
+}
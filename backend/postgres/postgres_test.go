@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const testUser = "postgres"
+const testPassword = "root"
+
+// Every test uses its own database for complete isolation, see the mysql backend tests.
+
+func Test_PostgresBackend(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	skipIfUnreachable(t)
+
+	var dbName string
+
+	test.BackendTest(t, func() test.TestBackend {
+		dbName = createDatabase()
+
+		return NewPostgresBackend("localhost", 5432, testUser, testPassword, dbName, WithBackendOptions(backend.WithStickyTimeout(0)))
+	}, func(b test.TestBackend) {
+		dropDatabase(b, dbName)
+	})
+}
+
+func TestPostgresBackendE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	skipIfUnreachable(t)
+
+	var dbName string
+
+	test.EndToEndBackendTest(t, func() test.TestBackend {
+		dbName = createDatabase()
+
+		return NewPostgresBackend("localhost", 5432, testUser, testPassword, dbName, WithBackendOptions(backend.WithStickyTimeout(0)))
+	}, func(b test.TestBackend) {
+		dropDatabase(b, dbName)
+	})
+}
+
+func Test_Rebind(t *testing.T) {
+	require.Equal(t, "SELECT 1", rebind("SELECT 1"))
+	require.Equal(t,
+		"SELECT * FROM instances WHERE instance_id = $1 AND created_at < $2 LIMIT $3",
+		rebind("SELECT * FROM instances WHERE instance_id = ? AND created_at < ? LIMIT ?"))
+}
+
+func openTestDB() *sql.DB {
+	db, err := sql.Open("postgres", fmt.Sprintf("host=localhost port=5432 user=%s password=%s dbname=postgres sslmode=disable", testUser, testPassword))
+	if err != nil {
+		panic(err)
+	}
+
+	return db
+}
+
+// skipIfUnreachable skips the test if there is no postgres server to run it against
+func skipIfUnreachable(t *testing.T) {
+	t.Helper()
+
+	db := openTestDB()
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Skipf("postgres server not reachable: %v", err)
+	}
+}
+
+func createDatabase() string {
+	db := openTestDB()
+	defer db.Close()
+
+	dbName := "test_" + strings.Replace(uuid.NewString(), "-", "", -1)
+	if _, err := db.Exec("CREATE DATABASE " + dbName); err != nil {
+		panic(fmt.Errorf("creating database: %w", err))
+	}
+
+	return dbName
+}
+
+func dropDatabase(b test.TestBackend, dbName string) {
+	if err := b.(*postgresBackend).Close(); err != nil {
+		panic(err)
+	}
+
+	db := openTestDB()
+	defer db.Close()
+
+	if _, err := db.Exec("DROP DATABASE IF EXISTS " + dbName + " WITH (FORCE)"); err != nil {
+		panic(fmt.Errorf("dropping database: %w", err))
+	}
+}
+
+var _ test.TestBackend = (*postgresBackend)(nil)
+
+func (b *postgresBackend) GetFutureEvents(ctx context.Context) ([]*history.Event, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// There is no index on `visible_at`, but this is okay for test only usage.
+	return queryEvents(
+		ctx, tx,
+		"SELECT event_id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes, visible_at FROM pending_events WHERE visible_at IS NOT NULL",
+	)
+}
@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+var _ backend.CompletionSubscriber = (*redisBackend)(nil)
+
+// SubscribeWorkflowInstanceCompletion uses a single pub/sub subscription per backend, finished instances are published
+// when completing the final workflow task and dispatched to the subscribers in this process.
+func (rb *redisBackend) SubscribeWorkflowInstanceCompletion(ctx context.Context, instance *core.WorkflowInstance) (<-chan struct{}, func(), error) {
+	if err := rb.subscribeFinishedInstances(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	c, cancel := rb.notifier.Subscribe(instance.InstanceID)
+	return c, cancel, nil
+}
+
+func (rb *redisBackend) subscribeFinishedInstances(ctx context.Context) error {
+	rb.pubsubMu.Lock()
+	defer rb.pubsubMu.Unlock()
+
+	if rb.pubsub != nil {
+		return nil
+	}
+
+	ps := rb.rdb.Subscribe(context.Background(), instanceFinishedChannel())
+
+	// Wait for the subscription to be confirmed, otherwise notifications published right after subscribing might
+	// be missed.
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return fmt.Errorf("subscribing to finished instances: %w", err)
+	}
+
+	rb.pubsub = ps
+
+	go func() {
+		for msg := range ps.Channel() {
+			rb.notifier.Notify(msg.Payload)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// DescribeWorkflowInstance builds the description from the history and the pending events stream. Future events
+// are only moved to the pending events stream once they are due, so timers that have not fired yet remain pending.
+// Workers are not recorded by this backend.
+func (rb *redisBackend) DescribeWorkflowInstance(ctx context.Context, instance *workflow.Instance) (*backend.WorkflowInstanceDescription, error) {
+	state, err := readInstance(ctx, rb.rdb, instance.InstanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := rb.GetWorkflowInstanceHistory(ctx, instance, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	msgs, err := rb.rdb.XRange(ctx, pendingEventsKey(instance.InstanceID), "-", "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("reading event stream: %w", err)
+	}
+
+	pendingEvents := make([]*history.Event, 0, len(msgs))
+	for _, msg := range msgs {
+		var event *history.Event
+
+		if err := json.Unmarshal([]byte(msg.Values["event"].(string)), &event); err != nil {
+			return nil, fmt.Errorf("unmarshaling event: %w", err)
+		}
+
+		pendingEvents = append(pendingEvents, event)
+	}
+
+	return backend.NewWorkflowInstanceDescription(&backend.WorkflowInstanceInfo{
+		Instance:     state.Instance,
+		WorkflowName: state.WorkflowName,
+		State:        state.State,
+		CreatedAt:    state.CreatedAt,
+		CompletedAt:  state.CompletedAt,
+	}, h, pendingEvents, time.Now()), nil
+}
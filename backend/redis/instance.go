@@ -25,7 +25,8 @@ func (rb *redisBackend) CreateWorkflowInstance(ctx context.Context, instance *wo
 
 	p := rb.rdb.TxPipeline()
 
-	if err := createInstanceP(ctx, p, instance, event.Attributes.(*history.ExecutionStartedAttributes).Metadata, false); err != nil {
+	a := event.Attributes.(*history.ExecutionStartedAttributes)
+	if err := createInstanceP(ctx, p, instance, a.Name, a.Metadata, false); err != nil {
 		return err
 	}
 
@@ -110,8 +111,9 @@ func (rb *redisBackend) CancelWorkflowInstance(ctx context.Context, instance *co
 }
 
 type instanceState struct {
-	Instance *core.WorkflowInstance     `json:"instance,omitempty"`
-	State    core.WorkflowInstanceState `json:"state,omitempty"`
+	Instance     *core.WorkflowInstance     `json:"instance,omitempty"`
+	WorkflowName string                     `json:"workflow_name,omitempty"`
+	State        core.WorkflowInstanceState `json:"state,omitempty"`
 
 	Metadata *core.WorkflowMetadata `json:"metadata,omitempty"`
 
@@ -119,18 +121,22 @@ type instanceState struct {
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
 
 	LastSequenceID int64 `json:"last_sequence_id,omitempty"`
+
+	// WorkflowTaskFailures is the number of consecutive failed workflow tasks, reset when a task completes
+	WorkflowTaskFailures int `json:"workflow_task_failures,omitempty"`
 }
 
-func createInstanceP(ctx context.Context, p redis.Pipeliner, instance *core.WorkflowInstance, metadata *core.WorkflowMetadata, ignoreDuplicate bool) error {
+func createInstanceP(ctx context.Context, p redis.Pipeliner, instance *core.WorkflowInstance, workflowName string, metadata *core.WorkflowMetadata, ignoreDuplicate bool) error {
 	key := instanceKey(instance.InstanceID)
 
 	createdAt := time.Now()
 
 	b, err := json.Marshal(&instanceState{
-		Instance:  instance,
-		State:     core.WorkflowInstanceStateActive,
-		Metadata:  metadata,
-		CreatedAt: createdAt,
+		Instance:     instance,
+		WorkflowName: workflowName,
+		State:        core.WorkflowInstanceStateActive,
+		Metadata:     metadata,
+		CreatedAt:    createdAt,
 	})
 	if err != nil {
 		return fmt.Errorf("marshaling instance state: %w", err)
@@ -138,10 +144,19 @@ func createInstanceP(ctx context.Context, p redis.Pipeliner, instance *core.Work
 
 	p.SetNX(ctx, key, string(b), 0)
 
-	p.ZAdd(ctx, instancesByCreation(), redis.Z{
+	// Indexes for listing instances are all scored by creation time
+	z := redis.Z{
 		Member: instance.InstanceID,
 		Score:  float64(createdAt.UnixMilli()),
-	})
+	}
+
+	p.ZAdd(ctx, instancesByCreation(), z)
+	p.ZAdd(ctx, instancesByName(workflowName), z)
+	p.ZAdd(ctx, instancesByState(core.WorkflowInstanceStateActive), z)
+
+	if instance.SubWorkflow() {
+		p.ZAdd(ctx, instancesByParent(instance.ParentInstanceID), z)
+	}
 
 	return nil
 }
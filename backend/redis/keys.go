@@ -2,6 +2,9 @@ package redis
 
 import (
 	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/search"
 )
 
 func instanceKey(instanceID string) string {
@@ -12,6 +15,26 @@ func instancesByCreation() string {
 	return "instances-by-creation"
 }
 
+func instancesByName(workflowName string) string {
+	return fmt.Sprintf("instances-by-name:%v", workflowName)
+}
+
+func instancesByState(state core.WorkflowInstanceState) string {
+	return fmt.Sprintf("instances-by-state:%v", state)
+}
+
+func instancesByParent(parentInstanceID string) string {
+	return fmt.Sprintf("instances-by-parent:%v", parentInstanceID)
+}
+
+func instancesByCompletion() string {
+	return "instances-by-completion"
+}
+
+func schemaVersionKey() string {
+	return "schema-version"
+}
+
 func pendingEventsKey(instanceID string) string {
 	return fmt.Sprintf("pending-events:%v", instanceID)
 }
@@ -31,3 +54,20 @@ func futureEventsKey() string {
 func futureEventKey(instanceID string, scheduleEventID int64) string {
 	return fmt.Sprintf("future-event:%v:%v", instanceID, scheduleEventID)
 }
+
+// futureTaskRetryKey is the future event that queues a failed workflow task of the given instance again
+func futureTaskRetryKey(instanceID string) string {
+	return fmt.Sprintf("future-event:%v:retry", instanceID)
+}
+
+func searchAttributesKey(instanceID string) string {
+	return fmt.Sprintf("search-attributes:%v", instanceID)
+}
+
+func searchIndexKey(name string, t search.Type) string {
+	return fmt.Sprintf("search-index:%v:%v", name, t)
+}
+
+func instanceFinishedChannel() string {
+	return "instance-finished"
+}
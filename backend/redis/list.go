@@ -0,0 +1,244 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// tmpKeyExpiration is the time after which temporary keys created while listing instances expire
+const tmpKeyExpiration = time.Minute
+
+// ListWorkflowInstances intersects the sorted set indexes matching the given filters into a temporary sorted set
+// scored by the instance creation time, which is then used for counting and paging.
+func (rb *redisBackend) ListWorkflowInstances(ctx context.Context, options *backend.ListWorkflowInstancesOptions) (*backend.ListWorkflowInstancesResult, error) {
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = backend.DefaultListPageSize
+	}
+
+	// All indexes are scored by creation time. Only the score of the creation index is used for the result, the
+	// scores of the other indexes would be added to it otherwise.
+	keys := []string{instancesByCreation()}
+	weights := []float64{1}
+
+	if options.WorkflowName != "" {
+		keys = append(keys, instancesByName(options.WorkflowName))
+		weights = append(weights, 0)
+	}
+
+	if options.State != nil {
+		keys = append(keys, instancesByState(*options.State))
+		weights = append(weights, 0)
+	}
+
+	if options.ParentInstanceID != "" {
+		keys = append(keys, instancesByParent(options.ParentInstanceID))
+		weights = append(weights, 0)
+	}
+
+	// Filters scored differently only restrict the result as well. Temporary keys are removed once the instances have
+	// been listed, and expire in case that fails.
+	var tmpKeys []string
+	newTmpKey := func() string {
+		k := fmt.Sprintf("tmp:list-instances:%v", uuid.NewString())
+		tmpKeys = append(tmpKeys, k)
+		return k
+	}
+
+	defer func() {
+		if len(tmpKeys) > 0 {
+			rb.rdb.Del(context.Background(), tmpKeys...)
+		}
+	}()
+
+	if !options.CompletedAfter.IsZero() || !options.CompletedBefore.IsZero() {
+		k := newTmpKey()
+		if _, err := rb.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.ZRangeStore(ctx, k, redis.ZRangeArgs{
+				Key:     instancesByCompletion(),
+				Start:   scoreOrInf(options.CompletedAfter, "-inf"),
+				Stop:    scoreOrInf(options.CompletedBefore, "+inf"),
+				ByScore: true,
+			})
+			p.Expire(ctx, k, tmpKeyExpiration)
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("filtering by completion time: %w", err)
+		}
+
+		keys = append(keys, k)
+		weights = append(weights, 0)
+	}
+
+	if options.Query != nil {
+		k, err := rb.evaluateQuery(ctx, options.Query, newTmpKey)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, k)
+		weights = append(weights, 0)
+	}
+
+	key := instancesByCreation()
+	if len(keys) > 1 {
+		key = newTmpKey()
+		if _, err := rb.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.ZInterStore(ctx, key, &redis.ZStore{
+				Keys:    keys,
+				Weights: weights,
+			})
+			p.Expire(ctx, key, tmpKeyExpiration)
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("intersecting instance indexes: %w", err)
+		}
+	}
+
+	min := scoreOrInf(options.CreatedAfter, "-inf")
+	max := scoreOrInf(options.CreatedBefore, "+inf")
+
+	total, err := rb.rdb.ZCount(ctx, key, min, max).Result()
+	if err != nil {
+		return nil, fmt.Errorf("counting instances: %w", err)
+	}
+
+	result := &backend.ListWorkflowInstancesResult{
+		TotalCount: total,
+	}
+
+	// The cursor contains the score and the instance id of the last instance of the previous page, so that paging
+	// continues even if that instance has been removed in the meantime. Instances created in the same millisecond
+	// are ordered by their id, like in the other backends.
+	var cursorScore float64
+	var cursorID string
+	if options.Cursor != "" {
+		var err error
+		cursorScore, cursorID, err = parseCursor(options.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		if s := strconv.FormatFloat(cursorScore, 'f', -1, 64); max == "+inf" || cursorScore < parseScore(max) {
+			max = s
+		}
+	}
+
+	var instanceIDs []string
+	var instanceScores []float64
+
+	for offset := int64(0); len(instanceIDs) <= pageSize; {
+		// Fetch one additional instance to determine whether there is another page
+		count := int64(pageSize + 1 - len(instanceIDs))
+
+		zs, err := rb.rdb.ZRangeArgsWithScores(ctx, redis.ZRangeArgs{
+			Key:     key,
+			Start:   min,
+			Stop:    max,
+			ByScore: true,
+			Rev:     true,
+			Offset:  offset,
+			Count:   count,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("listing instances: %w", err)
+		}
+
+		for _, z := range zs {
+			id := z.Member.(string)
+			if options.Cursor != "" && z.Score == cursorScore && id >= cursorID {
+				continue
+			}
+
+			instanceIDs = append(instanceIDs, id)
+			instanceScores = append(instanceScores, z.Score)
+		}
+
+		if int64(len(zs)) < count {
+			break
+		}
+
+		offset += int64(len(zs))
+	}
+
+	if len(instanceIDs) > pageSize {
+		instanceIDs = instanceIDs[:pageSize]
+		result.NextCursor = formatCursor(instanceScores[pageSize-1], instanceIDs[pageSize-1])
+	}
+
+	if len(instanceIDs) == 0 {
+		return result, nil
+	}
+
+	instanceKeys := make([]string, len(instanceIDs))
+	for i, id := range instanceIDs {
+		instanceKeys[i] = instanceKey(id)
+	}
+
+	states, err := rb.rdb.MGet(ctx, instanceKeys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("getting instances: %w", err)
+	}
+
+	for _, s := range states {
+		str, ok := s.(string)
+		if !ok {
+			// Instance has been removed
+			continue
+		}
+
+		var state instanceState
+		if err := json.Unmarshal([]byte(str), &state); err != nil {
+			return nil, fmt.Errorf("unmarshaling instance state: %w", err)
+		}
+
+		result.Instances = append(result.Instances, &backend.WorkflowInstanceInfo{
+			Instance:     state.Instance,
+			WorkflowName: state.WorkflowName,
+			State:        state.State,
+			CreatedAt:    state.CreatedAt,
+			CompletedAt:  state.CompletedAt,
+		})
+	}
+
+	return result, nil
+}
+
+func scoreOrInf(t time.Time, inf string) string {
+	if t.IsZero() {
+		return inf
+	}
+
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+func formatCursor(score float64, instanceID string) string {
+	return strconv.FormatFloat(score, 'f', -1, 64) + ":" + instanceID
+}
+
+func parseCursor(cursor string) (float64, string, error) {
+	score, instanceID, ok := strings.Cut(cursor, ":")
+	if !ok {
+		return 0, "", fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	f, err := strconv.ParseFloat(score, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid cursor %q: %w", cursor, err)
+	}
+
+	return f, instanceID, nil
+}
+
+func parseScore(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/redis/go-redis/v9"
+)
+
+// schemaVersion is the version of the indexes maintained by the backend. Version 1 added the indexes by workflow
+// name, state, parent, and completion time used for listing instances.
+const schemaVersion = 1
+
+const migrateBatchSize = 100
+
+// Migrate adds instances created by previous versions of the backend to the indexes used for listing instances.
+// Indexing an instance again does not change the indexes, so workers starting concurrently can run it at the same
+// time.
+func Migrate(ctx context.Context, client redis.UniversalClient) error {
+	version, err := client.Get(ctx, schemaVersionKey()).Int()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+
+	if version > schemaVersion {
+		return fmt.Errorf("schema version %d is newer than the supported version %d", version, schemaVersion)
+	}
+
+	if version == schemaVersion {
+		return nil
+	}
+
+	// Every instance has always been added to the creation index
+	for start := int64(0); ; start += migrateBatchSize {
+		instanceIDs, err := client.ZRange(ctx, instancesByCreation(), start, start+migrateBatchSize-1).Result()
+		if err != nil {
+			return fmt.Errorf("listing instances: %w", err)
+		}
+
+		for _, instanceID := range instanceIDs {
+			if err := indexInstance(ctx, client, instanceID); err != nil {
+				return fmt.Errorf("indexing instance %s: %w", instanceID, err)
+			}
+		}
+
+		if len(instanceIDs) < migrateBatchSize {
+			break
+		}
+	}
+
+	if err := client.Set(ctx, schemaVersionKey(), schemaVersion, 0).Err(); err != nil {
+		return fmt.Errorf("storing schema version: %w", err)
+	}
+
+	return nil
+}
+
+func indexInstance(ctx context.Context, client redis.UniversalClient, instanceID string) error {
+	for {
+		err := client.Watch(ctx, func(tx *redis.Tx) error {
+			state, err := readInstancePipelineCmd(tx.Get(ctx, instanceKey(instanceID)))
+			if err != nil {
+				return err
+			}
+
+			// Previous versions did not store the workflow name with the instance
+			storeName := false
+			if state.WorkflowName == "" {
+				if state.WorkflowName, err = workflowNameFromEvents(ctx, tx, instanceID); err != nil {
+					return err
+				}
+
+				storeName = state.WorkflowName != ""
+			}
+
+			_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+				if storeName {
+					if err := updateInstanceP(ctx, p, instanceID, state); err != nil {
+						return err
+					}
+				}
+
+				z := redis.Z{
+					Member: instanceID,
+					Score:  float64(state.CreatedAt.UnixMilli()),
+				}
+
+				p.ZAdd(ctx, instancesByName(state.WorkflowName), z)
+				p.ZAdd(ctx, instancesByState(state.State), z)
+
+				if state.Instance.SubWorkflow() {
+					p.ZAdd(ctx, instancesByParent(state.Instance.ParentInstanceID), z)
+				}
+
+				if state.CompletedAt != nil {
+					p.ZAdd(ctx, instancesByCompletion(), redis.Z{
+						Member: instanceID,
+						Score:  float64(state.CompletedAt.UnixMilli()),
+					})
+				}
+
+				return nil
+			})
+
+			return err
+		}, instanceKey(instanceID))
+
+		switch {
+		case errors.Is(err, redis.TxFailedErr):
+			// The instance was updated concurrently, index the new state
+			continue
+
+		case errors.Is(err, backend.ErrInstanceNotFound):
+			// The instance was removed, nothing to index
+			return nil
+		}
+
+		return err
+	}
+}
+
+// workflowNameFromEvents returns the workflow name from the started event of the instance, which is either part of
+// the history, or still pending if the instance has not been executed yet.
+func workflowNameFromEvents(ctx context.Context, tx *redis.Tx, instanceID string) (string, error) {
+	for _, key := range []string{historyKey(instanceID), pendingEventsKey(instanceID)} {
+		msgs, err := tx.XRangeN(ctx, key, "-", "+", 1).Result()
+		if err != nil {
+			return "", fmt.Errorf("reading started event: %w", err)
+		}
+
+		if len(msgs) == 0 {
+			continue
+		}
+
+		var event *history.Event
+		if err := json.Unmarshal([]byte(msgs[0].Values["event"].(string)), &event); err != nil {
+			return "", fmt.Errorf("unmarshaling event: %w", err)
+		}
+
+		if a, ok := event.Attributes.(*history.ExecutionStartedAttributes); ok {
+			return a.Name, nil
+		}
+	}
+
+	return "", nil
+}
@@ -57,6 +57,7 @@ func newTaskQueue[T any](rdb redis.UniversalClient, tasktype string) (*taskQueue
 	cmds := map[string]*redis.StringCmd{
 		"enqueueCmd":  enqueueCmd.Load(context.Background(), rdb),
 		"completeCmd": completeCmd.Load(context.Background(), rdb),
+		"releaseCmd":  releaseCmd.Load(context.Background(), rdb),
 	}
 
 	for name, cmd := range cmds {
@@ -180,6 +181,26 @@ func (q *taskQueue[T]) Complete(ctx context.Context, p redis.Pipeliner, taskID s
 	return cmd, nil
 }
 
+// Remove the task from the stream, but keep its id in the set. The id cannot be queued again until it is removed
+// from the set or a new task is added to the stream directly.
+// KEYS[1] = stream
+// ARGV[1] = task id
+// ARGV[2] = group
+var releaseCmd = redis.NewScript(
+	`redis.call("XACK", KEYS[1], ARGV[2], ARGV[1])
+	return redis.call("XDEL", KEYS[1], ARGV[1])
+`)
+
+// Release removes the given task from the queue without allowing its caller provided id to be queued again
+func (q *taskQueue[T]) Release(ctx context.Context, p redis.Pipeliner, taskID string) error {
+	cmd := releaseCmd.Run(ctx, p, []string{q.streamKey}, taskID, q.groupName)
+	if err := cmd.Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("releasing task: %w", err)
+	}
+
+	return nil
+}
+
 func (q *taskQueue[T]) Data(ctx context.Context, p redis.Pipeliner, taskID string) (*TaskItem[T], error) {
 	msg, err := p.XRange(ctx, q.streamKey, taskID, taskID).Result()
 	if err != nil && err != redis.Nil {
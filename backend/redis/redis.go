@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
@@ -10,8 +11,10 @@ import (
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/internal/notify"
 	"github.com/cschleiden/go-workflows/log"
 	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/redis/go-redis/v9"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -67,6 +70,8 @@ func NewRedisBackend(client redis.UniversalClient, opts ...RedisBackendOption) (
 
 		workflowQueue: workflowQueue,
 		activityQueue: activityQueue,
+
+		notifier: notify.New(),
 	}
 
 	// Preload scripts here. Usually redis-go attempts to execute them first, and the if redis doesn't know
@@ -88,6 +93,12 @@ func NewRedisBackend(client redis.UniversalClient, opts ...RedisBackendOption) (
 		}
 	}
 
+	if !options.DisableAutoMigration {
+		if err := Migrate(ctx, client); err != nil {
+			return nil, fmt.Errorf("migrating indexes: %w", err)
+		}
+	}
+
 	return rb, nil
 }
 
@@ -97,6 +108,11 @@ type redisBackend struct {
 
 	workflowQueue *taskQueue[any]
 	activityQueue *taskQueue[activityData]
+
+	// notifier reports finished instances received via pub/sub to waiting clients
+	notifier *notify.Notifier
+	pubsubMu sync.Mutex
+	pubsub   *redis.PubSub
 }
 
 type activityData struct {
@@ -105,6 +121,10 @@ type activityData struct {
 	Event    *history.Event         `json:"event,omitempty"`
 }
 
+func (rb *redisBackend) Options() backend.Options {
+	return rb.options.Options
+}
+
 func (rb *redisBackend) Logger() log.Logger {
 	return rb.options.Logger
 }
@@ -121,6 +141,16 @@ func (rb *redisBackend) Converter() converter.Converter {
 	return rb.options.Converter
 }
 
+func (rb *redisBackend) ContextPropagators() []workflow.ContextPropagator {
+	return rb.options.ContextPropagators
+}
+
 func (rb *redisBackend) Close() error {
+	rb.pubsubMu.Lock()
+	if rb.pubsub != nil {
+		rb.pubsub.Close()
+	}
+	rb.pubsubMu.Unlock()
+
 	return rb.rdb.Close()
 }
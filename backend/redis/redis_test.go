@@ -10,9 +10,12 @@ import (
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/log"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -145,3 +148,87 @@ func (rb *redisBackend) GetFutureEvents(ctx context.Context) ([]*history.Event,
 
 	return events, nil
 }
+
+func Test_ListWorkflowInstances_CursorInstanceRemoved(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	client := getClient()
+	b := getCreateBackend(client, true)().(*redisBackend)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, b.CreateWorkflowInstance(ctx, core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()), startedEvent("wf")))
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	active := core.WorkflowInstanceStateActive
+	options := &backend.ListWorkflowInstancesOptions{
+		WorkflowName: "wf",
+		State:        &active,
+		PageSize:     1,
+	}
+
+	r, err := b.ListWorkflowInstances(ctx, options)
+	require.NoError(t, err)
+	require.Len(t, r.Instances, 1)
+	require.Equal(t, int64(3), r.TotalCount)
+	require.NotEmpty(t, r.NextCursor)
+
+	// Remove the instance the cursor points to
+	require.NoError(t, client.Del(ctx, instanceKey(r.Instances[0].Instance.InstanceID)).Err())
+	require.NoError(t, client.ZRem(ctx, instancesByCreation(), r.Instances[0].Instance.InstanceID).Err())
+
+	options.Cursor = r.NextCursor
+	r2, err := b.ListWorkflowInstances(ctx, options)
+	require.NoError(t, err)
+	require.Len(t, r2.Instances, 1)
+	require.NotEqual(t, r.Instances[0].Instance.InstanceID, r2.Instances[0].Instance.InstanceID)
+}
+
+func Test_Migrate_IndexesExistingInstances(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	client := getClient()
+	b := getCreateBackend(client, true)().(*redisBackend)
+
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+	require.NoError(t, b.CreateWorkflowInstance(ctx, instance, startedEvent("wf")))
+
+	// Simulate an instance created by a previous version, which only added it to the creation index and did not
+	// store the workflow name
+	state, err := readInstance(ctx, client, instance.InstanceID)
+	require.NoError(t, err)
+	state.WorkflowName = ""
+	_, err = client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		return updateInstanceP(ctx, p, instance.InstanceID, state)
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.Del(ctx, instancesByName("wf"), instancesByState(core.WorkflowInstanceStateActive), schemaVersionKey()).Err())
+
+	require.NoError(t, Migrate(ctx, client))
+
+	active := core.WorkflowInstanceStateActive
+	r, err := b.ListWorkflowInstances(ctx, &backend.ListWorkflowInstancesOptions{
+		WorkflowName: "wf",
+		State:        &active,
+	})
+	require.NoError(t, err)
+	require.Len(t, r.Instances, 1)
+	require.Equal(t, "wf", r.Instances[0].WorkflowName)
+
+	v, err := client.Get(ctx, schemaVersionKey()).Int()
+	require.NoError(t, err)
+	require.Equal(t, schemaVersion, v)
+}
+
+func startedEvent(name string) *history.Event {
+	return history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+		Name:     name,
+		Metadata: &core.WorkflowMetadata{},
+	})
+}
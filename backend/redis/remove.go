@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/search"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+func (rb *redisBackend) RemoveWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	state, err := readInstance(ctx, rb.rdb, instance.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	if state.Instance.ExecutionID != instance.ExecutionID {
+		return backend.ErrInstanceNotFound
+	}
+
+	if state.State != core.WorkflowInstanceStateFinished {
+		return backend.ErrInstanceNotFinished
+	}
+
+	// Timers that had not fired when the instance finished are still scheduled
+	h, err := rb.GetWorkflowInstanceHistory(ctx, instance, nil)
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+
+	attributes, err := rb.rdb.HGetAll(ctx, searchAttributesKey(instance.InstanceID)).Result()
+	if err != nil {
+		return fmt.Errorf("reading search attributes: %w", err)
+	}
+
+	p := rb.rdb.TxPipeline()
+
+	for _, event := range h {
+		if event.Type == history.EventType_TimerScheduled {
+			removeFutureEventP(ctx, p, instance, event)
+		}
+	}
+
+	removeFutureEventCmd.Run(ctx, p, []string{futureEventsKey(), futureTaskRetryKey(instance.InstanceID)})
+
+	for name, data := range attributes {
+		var v search.Value
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			return fmt.Errorf("unmarshaling search attribute: %w", err)
+		}
+
+		p.ZRem(ctx, searchIndexKey(name, v.Type), searchIndexMember(instance.InstanceID, &v))
+	}
+
+	p.ZRem(ctx, instancesByCreation(), instance.InstanceID)
+	p.ZRem(ctx, instancesByName(state.WorkflowName), instance.InstanceID)
+	p.ZRem(ctx, instancesByState(core.WorkflowInstanceStateFinished), instance.InstanceID)
+	p.ZRem(ctx, instancesByCompletion(), instance.InstanceID)
+
+	if state.Instance.SubWorkflow() {
+		p.ZRem(ctx, instancesByParent(state.Instance.ParentInstanceID), instance.InstanceID)
+	}
+
+	p.Del(
+		ctx,
+		instanceKey(instance.InstanceID),
+		historyKey(instance.InstanceID),
+		pendingEventsKey(instance.InstanceID),
+		searchAttributesKey(instance.InstanceID),
+	)
+
+	if _, err := p.Exec(ctx); err != nil {
+		return fmt.Errorf("removing workflow instance: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/cschleiden/go-workflows/search"
+	"github.com/redis/go-redis/v9"
+)
+
+// Search attributes are indexed in one sorted set per attribute name and type. Numeric values are stored as the
+// score with the instance ID as member. String values are stored as `<value>\x00<instance id>` members with score 0
+// and queried lexicographically. The current values of each instance are kept in a hash, so that index entries can
+// be removed when an attribute changes.
+const searchSeparator = "\x00"
+
+// readSearchAttributes returns the current values of the given search attributes for an instance
+func readSearchAttributes(ctx context.Context, rdb redis.UniversalClient, instanceID string, names []string) (map[string]*search.Value, error) {
+	values, err := rdb.HMGet(ctx, searchAttributesKey(instanceID), names...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("reading search attributes: %w", err)
+	}
+
+	r := make(map[string]*search.Value, len(names))
+
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		var sv search.Value
+		if err := json.Unmarshal([]byte(s), &sv); err != nil {
+			return nil, fmt.Errorf("unmarshaling search attribute: %w", err)
+		}
+
+		r[names[i]] = &sv
+	}
+
+	return r, nil
+}
+
+func updateSearchAttributesP(ctx context.Context, p redis.Pipeliner, instanceID string, current map[string]*search.Value, updates search.Attributes) error {
+	for name, v := range updates {
+		if old := current[name]; old != nil {
+			p.ZRem(ctx, searchIndexKey(name, old.Type), searchIndexMember(instanceID, old))
+		}
+
+		if v == nil {
+			p.HDel(ctx, searchAttributesKey(instanceID), name)
+			continue
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("marshaling search attribute: %w", err)
+		}
+
+		p.HSet(ctx, searchAttributesKey(instanceID), name, string(b))
+
+		score := 0.0
+		if v.Numeric() {
+			score = v.Number()
+		}
+
+		p.ZAdd(ctx, searchIndexKey(name, v.Type), redis.Z{
+			Member: searchIndexMember(instanceID, v),
+			Score:  score,
+		})
+	}
+
+	return nil
+}
+
+func searchIndexMember(instanceID string, v *search.Value) string {
+	if v.Numeric() {
+		return instanceID
+	}
+
+	return v.String + searchSeparator + instanceID
+}
+
+// evaluateQuery stores the IDs of the instances matching the query in a new temporary sorted set and returns its key
+func (rb *redisBackend) evaluateQuery(ctx context.Context, query search.Query, newTmpKey func() string) (string, error) {
+	switch q := query.(type) {
+	case *search.Condition:
+		return rb.evaluateCondition(ctx, q, newTmpKey)
+
+	case *search.AndQuery, *search.OrQuery:
+		var queries []search.Query
+		if aq, ok := q.(*search.AndQuery); ok {
+			queries = aq.Queries
+		} else {
+			queries = q.(*search.OrQuery).Queries
+		}
+
+		keys := make([]string, 0, len(queries))
+		for _, sq := range queries {
+			k, err := rb.evaluateQuery(ctx, sq, newTmpKey)
+			if err != nil {
+				return "", err
+			}
+
+			keys = append(keys, k)
+		}
+
+		key := newTmpKey()
+		if _, err := rb.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			if _, ok := q.(*search.AndQuery); ok {
+				p.ZInterStore(ctx, key, &redis.ZStore{Keys: keys})
+			} else {
+				p.ZUnionStore(ctx, key, &redis.ZStore{Keys: keys})
+			}
+
+			p.Expire(ctx, key, tmpKeyExpiration)
+			return nil
+		}); err != nil {
+			return "", fmt.Errorf("combining search queries: %w", err)
+		}
+
+		return key, nil
+	}
+
+	return "", fmt.Errorf("unsupported query type %T", query)
+}
+
+// storeSearchIndexRange stores the instance IDs of the string search index members in the given lexicographical range
+// in a new sorted set.
+// KEYS[1] - search index key
+// KEYS[2] - destination key
+// ARGV[1] - min
+// ARGV[2] - max
+// ARGV[3] - expiration of the destination key in milliseconds
+var storeSearchIndexRangeCmd = redis.NewScript(`
+	redis.call("DEL", KEYS[2])
+
+	local members = redis.call("ZRANGE", KEYS[1], ARGV[1], ARGV[2], "BYLEX")
+	for _, member in ipairs(members) do
+		local instanceID = string.match(member, "%z([^%z]*)$")
+		redis.call("ZADD", KEYS[2], 0, instanceID)
+	end
+
+	redis.call("PEXPIRE", KEYS[2], ARGV[3])
+	return #members
+`)
+
+func (rb *redisBackend) evaluateCondition(ctx context.Context, c *search.Condition, newTmpKey func() string) (string, error) {
+	key := searchIndexKey(c.Key, c.Value.Type)
+	dest := newTmpKey()
+
+	if c.Value.Numeric() {
+		v := strconv.FormatFloat(c.Value.Number(), 'g', -1, 64)
+
+		min, max := "-inf", "+inf"
+		switch c.Op {
+		case search.OpEq:
+			min, max = v, v
+		case search.OpGt:
+			min = "(" + v
+		case search.OpGte:
+			min = v
+		case search.OpLt:
+			max = "(" + v
+		case search.OpLte:
+			max = v
+		}
+
+		// Members of numeric indexes are the instance IDs
+		if _, err := rb.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.ZRangeStore(ctx, dest, redis.ZRangeArgs{
+				Key:     key,
+				Start:   min,
+				Stop:    max,
+				ByScore: true,
+			})
+			p.Expire(ctx, dest, tmpKeyExpiration)
+			return nil
+		}); err != nil {
+			return "", fmt.Errorf("querying search attribute %v: %w", c.Key, err)
+		}
+
+		return dest, nil
+	}
+
+	// All members for a value are in the range [<value>\x00, <value>\x00\xff]
+	lower, upper := c.Value.String+searchSeparator, c.Value.String+searchSeparator+"\xff"
+
+	min, max := "-", "+"
+	switch c.Op {
+	case search.OpEq:
+		min, max = "["+lower, "["+upper
+	case search.OpGt:
+		min = "(" + upper
+	case search.OpGte:
+		min = "[" + lower
+	case search.OpLt:
+		max = "(" + lower
+	case search.OpLte:
+		max = "[" + upper
+	}
+
+	if err := storeSearchIndexRangeCmd.Run(
+		ctx, rb.rdb, []string{key, dest}, min, max, tmpKeyExpiration.Milliseconds(),
+	).Err(); err != nil {
+		return "", fmt.Errorf("querying search attribute %v: %w", c.Key, err)
+	}
+
+	return dest, nil
+}
@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/notify"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ backend.HistoryWatcher = (*redisBackend)(nil)
+
+// WatchWorkflowInstanceHistory blocks on the history stream of the instance, whose message ids are derived from the
+// sequence ids of the events. Errors reading the stream are retried with backoff.
+func (rb *redisBackend) WatchWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, fromSequenceID int64) (<-chan *history.Event, error) {
+	if _, err := readInstance(ctx, rb.rdb, instance.InstanceID); err != nil {
+		return nil, err
+	}
+
+	c := make(chan *history.Event)
+
+	go func() {
+		defer close(c)
+
+		lastID := historyID(fromSequenceID)
+		bo := notify.NewWatchBackOff()
+
+		for {
+			done, err := func() (bool, error) {
+				// Read the state before the history, all events of a finished instance are committed at that point
+				state, err := readInstance(ctx, rb.rdb, instance.InstanceID)
+				if err != nil {
+					return false, err
+				}
+
+				finished := state.State == core.WorkflowInstanceStateFinished
+
+				block := rb.options.BlockTimeout
+				if finished {
+					// Only read the remaining events
+					block = -1
+				}
+
+				streams, err := rb.rdb.XRead(ctx, &redis.XReadArgs{
+					Streams: []string{historyKey(instance.InstanceID), lastID},
+					Block:   block,
+				}).Result()
+				if err != nil && err != redis.Nil {
+					return false, err
+				}
+
+				read := 0
+				for _, stream := range streams {
+					for _, msg := range stream.Messages {
+						var event *history.Event
+						if err := json.Unmarshal([]byte(msg.Values["event"].(string)), &event); err != nil {
+							return false, fmt.Errorf("unmarshaling event: %w", err)
+						}
+
+						select {
+						case c <- event:
+						case <-ctx.Done():
+							return true, nil
+						}
+
+						lastID = msg.ID
+						read++
+					}
+				}
+
+				return finished && read == 0, nil
+			}()
+			if err != nil {
+				// The instance has been removed while it was watched
+				if errors.Is(err, backend.ErrInstanceNotFound) || ctx.Err() != nil {
+					return
+				}
+
+				retryIn := bo.NextBackOff()
+				rb.Logger().Warn("watching workflow history", "instance_id", instance.InstanceID, "error", err, "retry_in", retryIn)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(retryIn):
+				}
+
+				continue
+			}
+
+			bo.Reset()
+
+			if done {
+				return
+			}
+		}
+	}()
+
+	return c, nil
+}
@@ -3,14 +3,17 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
+	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/internal/tracing"
+	"github.com/cschleiden/go-workflows/search"
 	"github.com/redis/go-redis/v9"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -22,6 +25,8 @@ import (
 // - Try to queue workflow task for workflow instance
 // - Remove event from future event set and delete event data
 //
+// Future events without event data retry a failed workflow task, they only queue the workflow task.
+//
 // KEYS[1] - future event set key
 // KEYS[2] - workflow task queue stream
 // KEYS[3] - workflow task queue set
@@ -34,14 +39,20 @@ var futureEventsCmd = redis.NewScript(`
 	for i = 1, #events do
 		local instanceID = redis.call("HGET", events[i], "instance")
 
-		-- Add event to pending event stream
 		local eventData = redis.call("HGET", events[i], "event")
-		local pending_events_key = "pending-events:" .. instanceID
-		redis.call("XADD", pending_events_key, "*", "event", eventData)
-
-		-- Try to queue workflow task
-		local already_queued = redis.call("SADD", KEYS[3], instanceID)
-		if already_queued ~= 0 then
+		if eventData then
+			-- Add event to pending event stream
+			local pending_events_key = "pending-events:" .. instanceID
+			redis.call("XADD", pending_events_key, "*", "event", eventData)
+
+			-- Try to queue workflow task
+			local already_queued = redis.call("SADD", KEYS[3], instanceID)
+			if already_queued ~= 0 then
+				redis.call("XADD", KEYS[2], "*", "id", instanceID, "data", "")
+			end
+		else
+			-- Retry a failed workflow task. The instance has been kept in the task queue set since the task failed.
+			redis.call("SADD", KEYS[3], instanceID)
 			redis.call("XADD", KEYS[2], "*", "id", instanceID, "data", "")
 		end
 
@@ -105,6 +116,7 @@ func (rb *redisBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, er
 		WorkflowInstance:      instanceState.Instance,
 		WorkflowInstanceState: instanceState.State,
 		Metadata:              instanceState.Metadata,
+		Attempt:               instanceState.WorkflowTaskFailures + 1,
 		LastSequenceID:        instanceState.LastSequenceID,
 		NewEvents:             newEvents,
 		CustomData:            msgs[len(msgs)-1].ID, // Id of last pending message in stream at this point
@@ -145,6 +157,10 @@ var requeueInstanceCmd = redis.NewScript(`
 	return true
 `)
 
+// maxCheckpointRetries is the number of times completing a workflow task is retried when one of the target
+// instances of its events changed concurrently
+const maxCheckpointRetries = 3
+
 func (rb *redisBackend) CompleteWorkflowTask(
 	ctx context.Context,
 	task *task.Workflow,
@@ -158,118 +174,239 @@ func (rb *redisBackend) CompleteWorkflowTask(
 		return err
 	}
 
-	// Check-point the workflow. We guarantee that no other worker is working on this workflow instance at this point via the
-	// task queue, so we don't need to WATCH the keys, we just need to make sure all commands are executed atomically to prevent
-	// a worker crashing in the middle of this execution.
-	p := rb.rdb.TxPipeline()
+	// Events for instances that do not exist or have finished, and for new instances that already exist, are not
+	// delivered. Those instances are watched so that the checkpoint is retried if any of them changes while the task is
+	// being completed.
+	groupedEvents := history.EventsByWorkflowInstanceID(workflowEvents)
+	targetKeys := make(map[string]string)
+	newInstances := make(map[string]bool)
+	for targetInstanceID, events := range groupedEvents {
+		for _, m := range events {
+			if m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted {
+				newInstances[targetInstanceID] = true
+				break
+			}
+		}
+
+		targetKeys[targetInstanceID] = instanceKey(targetInstanceID)
+	}
 
-	// Add executed events to the history
-	if err := addEventsToHistoryStreamP(ctx, p, historyKey(instance.InstanceID), executedEvents); err != nil {
-		return fmt.Errorf("serializing : %w", err)
+	watchKeys := make([]string, 0, len(targetKeys))
+	for _, key := range targetKeys {
+		watchKeys = append(watchKeys, key)
 	}
 
-	for _, event := range executedEvents {
-		switch event.Type {
-		case history.EventType_TimerCanceled:
-			removeFutureEventP(ctx, p, instance, event)
+	// Read the current search attributes, their index entries are replaced below
+	searchAttributeUpdates := history.SearchAttributeUpdates(executedEvents)
+	var currentSearchAttributes map[string]*search.Value
+	if len(searchAttributeUpdates) > 0 {
+		names := make([]string, 0, len(searchAttributeUpdates))
+		for name := range searchAttributeUpdates {
+			names = append(names, name)
 		}
-	}
 
-	// Schedule timers
-	for _, timerEvent := range timerEvents {
-		if err := addFutureEventP(ctx, p, instance, timerEvent); err != nil {
+		currentSearchAttributes, err = readSearchAttributes(ctx, rb.rdb, instance.InstanceID, names)
+		if err != nil {
 			return err
 		}
 	}
 
-	// Send new workflow events to the respective streams
-	groupedEvents := history.EventsByWorkflowInstanceID(workflowEvents)
-	for targetInstanceID, events := range groupedEvents {
-		// Insert pending events for target instance
-		for _, m := range events {
-			m := m
+	checkpoint := func(tx *redis.Tx) error {
+		// Update a copy of the instance state, the checkpoint is retried if a watched key changed
+		is := *instanceState
+		instanceState := &is
+
+		// Errors for target instances that do not exist, have finished, or could not be created
+		targetErrs := make(map[string]error)
+		for targetInstanceID, key := range targetKeys {
+			targetState, err := readInstancePipelineCmd(tx.Get(ctx, key))
+			if newInstances[targetInstanceID] {
+				// New instances are only created if they don't exist yet
+				if err == nil {
+					targetErrs[targetInstanceID] = backend.ErrInstanceAlreadyExists
+				} else if !errors.Is(err, backend.ErrInstanceNotFound) {
+					return fmt.Errorf("checking for new workflow instance: %w", err)
+				}
 
-			if m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted {
-				// Create new instance
-				a := m.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
-				if err := createInstanceP(ctx, p, m.WorkflowInstance, a.Metadata, true); err != nil {
-					return err
+				continue
+			}
+
+			if err != nil {
+				if !errors.Is(err, backend.ErrInstanceNotFound) {
+					return fmt.Errorf("checking for target workflow instance: %w", err)
 				}
+
+				targetErrs[targetInstanceID] = err
+				continue
 			}
 
-			// Add pending event to stream
-			if err := addEventToStreamP(ctx, p, pendingEventsKey(targetInstanceID), m.HistoryEvent); err != nil {
-				return err
+			if targetState.State == core.WorkflowInstanceStateFinished {
+				targetErrs[targetInstanceID] = backend.ErrInstanceFinished
 			}
 		}
 
-		// Try to queue workflow task
-		if targetInstanceID != instance.InstanceID {
-			if err := rb.workflowQueue.Enqueue(ctx, p, targetInstanceID, nil); err != nil {
-				return fmt.Errorf("enqueuing workflow task: %w", err)
+		// Check-point the workflow. We guarantee that no other worker is working on this workflow instance at this point via
+		// the task queue, so only the target instances are watched. All commands are executed atomically to prevent a worker
+		// crashing in the middle of this execution.
+		p := tx.TxPipeline()
+
+		// Add executed events to the history
+		if err := addEventsToHistoryStreamP(ctx, p, historyKey(instance.InstanceID), executedEvents); err != nil {
+			return fmt.Errorf("serializing : %w", err)
+		}
+
+		for _, event := range executedEvents {
+			switch event.Type {
+			case history.EventType_TimerCanceled:
+				removeFutureEventP(ctx, p, instance, event)
 			}
 		}
-	}
 
-	instanceState.State = state
+		// Update search attribute index
+		if err := updateSearchAttributesP(ctx, p, instance.InstanceID, currentSearchAttributes, searchAttributeUpdates); err != nil {
+			return err
+		}
+
+		// Schedule timers
+		for _, timerEvent := range timerEvents {
+			if err := addFutureEventP(ctx, p, instance, timerEvent); err != nil {
+				return err
+			}
+		}
 
-	if state == core.WorkflowInstanceStateFinished {
-		t := time.Now()
-		instanceState.CompletedAt = &t
-	}
+		// Send new workflow events to the respective streams
+		for targetInstanceID, events := range groupedEvents {
+			if targetErrs[targetInstanceID] != nil {
+				continue
+			}
 
-	if len(executedEvents) > 0 {
-		instanceState.LastSequenceID = executedEvents[len(executedEvents)-1].SequenceID
-	}
+			// Insert pending events for target instance
+			for _, m := range events {
+				m := m
 
-	if err := updateInstanceP(ctx, p, instance.InstanceID, instanceState); err != nil {
-		return fmt.Errorf("updating workflow instance: %w", err)
-	}
+				if m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted {
+					// Create new instance
+					a := m.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
+					if err := createInstanceP(ctx, p, m.WorkflowInstance, a.Name, a.Metadata, true); err != nil {
+						return err
+					}
+				}
+
+				// Add pending event to stream
+				if err := addEventToStreamP(ctx, p, pendingEventsKey(targetInstanceID), m.HistoryEvent); err != nil {
+					return err
+				}
+			}
 
-	// Store activity data
-	for _, activityEvent := range activityEvents {
-		if err := rb.activityQueue.Enqueue(ctx, p, activityEvent.ID, &activityData{
-			Instance: instance,
-			ID:       activityEvent.ID,
-			Event:    activityEvent,
-		}); err != nil {
-			return fmt.Errorf("queueing activity task: %w", err)
+			// Try to queue workflow task
+			if targetInstanceID != instance.InstanceID {
+				if err := rb.workflowQueue.Enqueue(ctx, p, targetInstanceID, nil); err != nil {
+					return fmt.Errorf("enqueuing workflow task: %w", err)
+				}
+			}
 		}
-	}
 
-	// Remove executed pending events
-	if task.CustomData != nil {
-		lastPendingEventMessageID := task.CustomData.(string)
-		removePendingEventsCmd.Run(ctx, p, []string{pendingEventsKey(instance.InstanceID)}, lastPendingEventMessageID)
-	}
+		// Report the outcome of sub-workflows, signals, and cancellation requests sent to other instances back to
+		// this instance
+		resultEvents := history.ExternalWorkflowResults(time.Now(), executedEvents, func(instanceID string) error {
+			return targetErrs[instanceID]
+		})
+		for _, resultEvent := range resultEvents {
+			if err := addEventToStreamP(ctx, p, pendingEventsKey(instance.InstanceID), resultEvent); err != nil {
+				return err
+			}
+		}
 
-	// Complete workflow task and unlock instance.
-	completeCmd, err := rb.workflowQueue.Complete(ctx, p, task.ID)
-	if err != nil {
-		return fmt.Errorf("completing workflow task: %w", err)
-	}
+		if state == core.WorkflowInstanceStateFinished && instanceState.State != core.WorkflowInstanceStateFinished {
+			t := time.Now()
+			instanceState.CompletedAt = &t
+
+			// Move instance to the finished index
+			p.ZRem(ctx, instancesByState(core.WorkflowInstanceStateActive), instance.InstanceID)
+			p.ZAdd(ctx, instancesByState(core.WorkflowInstanceStateFinished), redis.Z{
+				Member: instance.InstanceID,
+				Score:  float64(instanceState.CreatedAt.UnixMilli()),
+			})
+			p.ZAdd(ctx, instancesByCompletion(), redis.Z{
+				Member: instance.InstanceID,
+				Score:  float64(t.UnixMilli()),
+			})
+
+			// Notify clients waiting for the instance
+			p.Publish(ctx, instanceFinishedChannel(), instance.InstanceID)
+		}
 
-	// If there are pending events, queue the instance again
-	keyInfo := rb.workflowQueue.Keys()
-	requeueInstanceCmd.Run(ctx, p,
-		[]string{pendingEventsKey(instance.InstanceID), keyInfo.StreamKey, keyInfo.SetKey},
-		instance.InstanceID,
-	)
+		instanceState.State = state
+		instanceState.WorkflowTaskFailures = 0
 
-	// Commit transaction
-	executedCmds, err := p.Exec(ctx)
-	if err != nil {
-		if err := completeCmd.Err(); err != nil && err == redis.Nil {
-			return fmt.Errorf("could not complete workflow task: %w", err)
+		if len(executedEvents) > 0 {
+			instanceState.LastSequenceID = executedEvents[len(executedEvents)-1].SequenceID
+		}
+
+		if err := updateInstanceP(ctx, p, instance.InstanceID, instanceState); err != nil {
+			return fmt.Errorf("updating workflow instance: %w", err)
 		}
 
-		for _, cmd := range executedCmds {
-			if cmdErr := cmd.Err(); cmdErr != nil {
-				rb.Logger().Debug("redis command error", "cmd", cmd.FullName(), "cmdErr", cmdErr.Error())
+		// Store activity data
+		for _, activityEvent := range activityEvents {
+			if err := rb.activityQueue.Enqueue(ctx, p, activityEvent.ID, &activityData{
+				Instance: instance,
+				ID:       activityEvent.ID,
+				Event:    activityEvent,
+			}); err != nil {
+				return fmt.Errorf("queueing activity task: %w", err)
 			}
 		}
 
-		return fmt.Errorf("completing workflow task: %w", err)
+		// Remove executed pending events
+		if task.CustomData != nil {
+			lastPendingEventMessageID := task.CustomData.(string)
+			removePendingEventsCmd.Run(ctx, p, []string{pendingEventsKey(instance.InstanceID)}, lastPendingEventMessageID)
+		}
+
+		// Complete workflow task and unlock instance.
+		completeCmd, err := rb.workflowQueue.Complete(ctx, p, task.ID)
+		if err != nil {
+			return fmt.Errorf("completing workflow task: %w", err)
+		}
+
+		// If there are pending events, queue the instance again
+		keyInfo := rb.workflowQueue.Keys()
+		requeueInstanceCmd.Run(ctx, p,
+			[]string{pendingEventsKey(instance.InstanceID), keyInfo.StreamKey, keyInfo.SetKey},
+			instance.InstanceID,
+		)
+
+		// Commit transaction
+		executedCmds, err := p.Exec(ctx)
+		if err != nil {
+			if err := completeCmd.Err(); err != nil && err == redis.Nil {
+				return fmt.Errorf("could not complete workflow task: %w", err)
+			}
+
+			for _, cmd := range executedCmds {
+				if cmdErr := cmd.Err(); cmdErr != nil {
+					rb.Logger().Debug("redis command error", "cmd", cmd.FullName(), "cmdErr", cmdErr.Error())
+				}
+			}
+
+			return fmt.Errorf("completing workflow task: %w", err)
+		}
+
+		return nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := rb.rdb.Watch(ctx, checkpoint, watchKeys...)
+		if errors.Is(err, redis.TxFailedErr) && attempt < maxCheckpointRetries {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		break
 	}
 
 	if state == core.WorkflowInstanceStateFinished {
@@ -284,6 +421,98 @@ func (rb *redisBackend) CompleteWorkflowTask(
 	return nil
 }
 
+// Fail a workflow task, if it's still locked by the given worker. Another worker might have recovered the task after
+// its lock expired, in that case nothing is changed.
+//
+// KEYS[1] - task queue stream
+// KEYS[2] - future event zset key
+// KEYS[3] - future event key
+// KEYS[4] - instance key
+// KEYS[5] - history stream key
+// ARGV[1] - task id
+// ARGV[2] - consumer group
+// ARGV[3] - worker name
+// ARGV[4] - timestamp to retry the task at
+// ARGV[5] - instance ID
+// ARGV[6] - instance state
+// ARGV[7] - optional history event id
+// ARGV[8] - optional history event
+var failWorkflowTaskCmd = redis.NewScript(`
+	local pending = redis.call("XPENDING", KEYS[1], ARGV[2], ARGV[1], ARGV[1], 1, ARGV[3])
+	if #pending == 0 then
+		return redis.error_reply("workflow task is not locked by this worker")
+	end
+
+	-- Remove the task from the queue, but keep the instance in the task queue set, so that new events don't queue it
+	-- again before the task is retried. Pending events are left untouched.
+	redis.call("XACK", KEYS[1], ARGV[2], ARGV[1])
+	redis.call("XDEL", KEYS[1], ARGV[1])
+
+	redis.call("ZADD", KEYS[2], ARGV[4], KEYS[3])
+	redis.call("HSET", KEYS[3], "instance", ARGV[5])
+
+	redis.call("SET", KEYS[4], ARGV[6])
+
+	if ARGV[7] then
+		redis.call("XADD", KEYS[5], ARGV[7], "event", ARGV[8])
+	end
+
+	return true
+`)
+
+func (rb *redisBackend) FailWorkflowTask(
+	ctx context.Context,
+	task *task.Workflow,
+	instance *core.WorkflowInstance,
+	failedEvent *history.Event,
+	retryAfter time.Duration,
+) error {
+	instanceState, err := readInstance(ctx, rb.rdb, instance.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	instanceState.WorkflowTaskFailures++
+
+	args := []interface{}{
+		task.ID,
+		rb.workflowQueue.groupName,
+		rb.workflowQueue.workerName,
+		strconv.FormatInt(time.Now().Add(retryAfter).UnixMilli(), 10),
+		instance.InstanceID,
+	}
+
+	var eventArgs []interface{}
+	if failedEvent != nil {
+		eventData, err := json.Marshal(failedEvent)
+		if err != nil {
+			return fmt.Errorf("serializing: %w", err)
+		}
+
+		instanceState.LastSequenceID = failedEvent.SequenceID
+		eventArgs = []interface{}{historyID(failedEvent.SequenceID), string(eventData)}
+	}
+
+	state, err := json.Marshal(instanceState)
+	if err != nil {
+		return fmt.Errorf("marshaling instance state: %w", err)
+	}
+
+	args = append(append(args, string(state)), eventArgs...)
+
+	if err := failWorkflowTaskCmd.Run(ctx, rb.rdb, []string{
+		rb.workflowQueue.Keys().StreamKey,
+		futureEventsKey(),
+		futureTaskRetryKey(instance.InstanceID),
+		instanceKey(instance.InstanceID),
+		historyKey(instance.InstanceID),
+	}, args...).Err(); err != nil {
+		return fmt.Errorf("failing workflow task: %w", err)
+	}
+
+	return nil
+}
+
 func (rb *redisBackend) addWorkflowInstanceEventP(ctx context.Context, p redis.Pipeliner, instance *core.WorkflowInstance, event *history.Event) error {
 	// Add event to pending events for instance
 	if err := addEventToStreamP(ctx, p, pendingEventsKey(instance.InstanceID), event); err != nil {
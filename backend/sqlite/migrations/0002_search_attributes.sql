@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `search_attributes` (
+  `instance_id` TEXT NOT NULL,
+  `name` TEXT NOT NULL,
+  `type` INTEGER NOT NULL,
+  `string_value` TEXT NULL,
+  `int_value` INTEGER NULL,
+  `float_value` REAL NULL,
+  PRIMARY KEY(`instance_id`, `name`)
+);
+
+CREATE INDEX IF NOT EXISTS `idx_search_attributes_string_value` ON `search_attributes` (`name`, `type`, `string_value`);
+CREATE INDEX IF NOT EXISTS `idx_search_attributes_int_value` ON `search_attributes` (`name`, `type`, `int_value`);
+CREATE INDEX IF NOT EXISTS `idx_search_attributes_float_value` ON `search_attributes` (`name`, `type`, `float_value`);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS `idx_instances_created_at` ON `instances` (`created_at`, `id`);
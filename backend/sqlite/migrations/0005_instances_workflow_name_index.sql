@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS `idx_instances_workflow_name_created_at` ON `instances` (`workflow_name`, `created_at`);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS `idx_instances_completed_at` ON `instances` (`completed_at`);
@@ -0,0 +1,3 @@
+-- Finished instances are removed with their remaining activities. MySQL and Postgres don't need this migration, their
+-- unique index idx_activities_instance_id starts with instance_id.
+CREATE INDEX IF NOT EXISTS `idx_activities_instance_id` ON `activities` (`instance_id`);
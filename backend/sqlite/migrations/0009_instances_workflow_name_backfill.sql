@@ -0,0 +1,8 @@
+-- Backfill the workflow name of instances created before 0003 from their WorkflowExecutionStarted event, which is
+-- still pending for instances that have not been started yet
+UPDATE `instances` SET `workflow_name` = COALESCE(
+  (SELECT json_extract(CAST(h.`attributes` AS TEXT), '$.name') FROM `history` h
+    WHERE h.`instance_id` = `instances`.`id` AND h.`event_type` = 1 LIMIT 1),
+  (SELECT json_extract(CAST(p.`attributes` AS TEXT), '$.name') FROM `pending_events` p
+    WHERE p.`instance_id` = `instances`.`id` AND p.`event_type` = 1 LIMIT 1)
+) WHERE `workflow_name` IS NULL;
@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+func (sb *sqliteBackend) RemoveWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	tx, err := sb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var completedAt sql.NullTime
+	if err := tx.QueryRowContext(
+		ctx,
+		"SELECT completed_at FROM `instances` WHERE id = ? AND execution_id = ?",
+		instance.InstanceID,
+		instance.ExecutionID,
+	).Scan(&completedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return backend.ErrInstanceNotFound
+		}
+
+		return err
+	}
+
+	if !completedAt.Valid {
+		return backend.ErrInstanceNotFinished
+	}
+
+	for _, table := range []string{"pending_events", "history", "activities", "search_attributes"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM `"+table+"` WHERE instance_id = ?", instance.InstanceID); err != nil {
+			return fmt.Errorf("removing %s: %w", table, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM `instances` WHERE id = ?", instance.InstanceID); err != nil {
+		return fmt.Errorf("removing instance: %w", err)
+	}
+
+	return tx.Commit()
+}
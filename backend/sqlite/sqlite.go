@@ -3,7 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
-	_ "embed"
+	"embed"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,6 +15,9 @@ import (
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/internal/migrations"
+	"github.com/cschleiden/go-workflows/internal/notify"
+	"github.com/cschleiden/go-workflows/internal/sqlsearch"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/log"
 	"github.com/cschleiden/go-workflows/metrics"
@@ -25,36 +28,69 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed schema.sql
-var schema string
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
 
 func NewInMemoryBackend(opts ...backend.BackendOption) *sqliteBackend {
-	b := newSqliteBackend("file::memory:?_mode=memory", opts...)
+	db, err := sql.Open("sqlite3", "file::memory:?_mode=memory")
+	if err != nil {
+		panic(err)
+	}
 
-	b.db.SetMaxOpenConns(1)
+	// Every connection to an in-memory database sees its own database
+	db.SetMaxOpenConns(1)
 
-	return b
+	return newSqliteBackend(db, opts...)
 }
 
 func NewSqliteBackend(path string, opts ...backend.BackendOption) *sqliteBackend {
-	return newSqliteBackend(fmt.Sprintf("file:%v?_mutex=no&_journal=wal", path), opts...)
+	db, err := sql.Open("sqlite3", sqliteDSN(path))
+	if err != nil {
+		panic(err)
+	}
+
+	return newSqliteBackend(db, opts...)
 }
 
-func newSqliteBackend(dsn string, opts ...backend.BackendOption) *sqliteBackend {
-	db, err := sql.Open("sqlite3", dsn)
+// Migrate applies all pending schema migrations to the database at the given path
+func Migrate(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite3", sqliteDSN(path))
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer db.Close()
 
-	// Initialize database
-	if _, err := db.Exec(schema); err != nil {
-		panic(err)
+	return migrate(ctx, db)
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	ms, err := migrations.Load(migrationsFS, "migrations")
+	if err != nil {
+		return err
+	}
+
+	return migrations.Apply(ctx, db, migrations.SQLite, ms)
+}
+
+func sqliteDSN(path string) string {
+	return fmt.Sprintf("file:%v?_mutex=no&_journal=wal", path)
+}
+
+func newSqliteBackend(db *sql.DB, opts ...backend.BackendOption) *sqliteBackend {
+	options := backend.ApplyOptions(opts...)
+
+	if !options.DisableAutoMigration {
+		if err := migrate(context.Background(), db); err != nil {
+			panic(fmt.Errorf("migrating database: %w", err))
+		}
 	}
 
 	return &sqliteBackend{
-		db:         db,
-		workerName: fmt.Sprintf("worker-%v", uuid.NewString()),
-		options:    backend.ApplyOptions(opts...),
+		db:                 db,
+		workerName:         fmt.Sprintf("worker-%v", uuid.NewString()),
+		options:            options,
+		completionNotifier: notify.New(),
+		historyNotifier:    notify.New(),
 	}
 }
 
@@ -62,6 +98,15 @@ type sqliteBackend struct {
 	db         *sql.DB
 	workerName string
 	options    backend.Options
+
+	// completionNotifier and historyNotifier report finished instances and new history events committed by this
+	// backend to clients in the same process
+	completionNotifier *notify.Notifier
+	historyNotifier    *notify.Notifier
+}
+
+func (sb *sqliteBackend) Options() backend.Options {
+	return sb.options
 }
 
 func (sb *sqliteBackend) Logger() log.Logger {
@@ -80,6 +125,10 @@ func (sb *sqliteBackend) Converter() converter.Converter {
 	return sb.options.Converter
 }
 
+func (sb *sqliteBackend) ContextPropagators() []workflow.ContextPropagator {
+	return sb.options.ContextPropagators
+}
+
 func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
 	tx, err := sb.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -88,7 +137,8 @@ func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, instance *w
 	defer tx.Rollback()
 
 	// Create workflow instance
-	if err := createInstance(ctx, tx, instance, event.Attributes.(*history.ExecutionStartedAttributes).Metadata, false); err != nil {
+	a := event.Attributes.(*history.ExecutionStartedAttributes)
+	if err := createInstance(ctx, tx, instance, a.Name, a.Metadata, false); err != nil {
 		return err
 	}
 
@@ -103,7 +153,7 @@ func (sb *sqliteBackend) CreateWorkflowInstance(ctx context.Context, instance *w
 	return nil
 }
 
-func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, metadata *workflow.Metadata, ignoreDuplicate bool) error {
+func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, workflowName string, metadata *workflow.Metadata, ignoreDuplicate bool) error {
 	var parentInstanceID *string
 	var parentEventID *int64
 	if wfi.SubWorkflow() {
@@ -121,12 +171,14 @@ func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, met
 
 	res, err := tx.ExecContext(
 		ctx,
-		"INSERT OR IGNORE INTO `instances` (id, execution_id, parent_instance_id, parent_schedule_event_id, metadata) VALUES (?, ?, ?, ?, ?)",
+		"INSERT OR IGNORE INTO `instances` (id, execution_id, parent_instance_id, parent_schedule_event_id, metadata, workflow_name, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
 		wfi.InstanceID,
 		wfi.ExecutionID,
 		parentInstanceID,
 		parentEventID,
 		string(metadataJson),
+		workflowName,
+		time.Now().UTC(),
 	)
 	if err != nil {
 		return fmt.Errorf("inserting workflow instance: %w", err)
@@ -146,6 +198,27 @@ func createInstance(ctx context.Context, tx *sql.Tx, wfi *workflow.Instance, met
 	return nil
 }
 
+// checkTargetInstance returns ErrInstanceNotFound if the given instance does not exist, and ErrInstanceFinished if it
+// has already finished
+func checkTargetInstance(ctx context.Context, tx *sql.Tx, instanceID string) error {
+	row := tx.QueryRowContext(ctx, "SELECT completed_at FROM `instances` WHERE id = ? LIMIT 1", instanceID)
+
+	var completedAt sql.NullTime
+	if err := row.Scan(&completedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return backend.ErrInstanceNotFound
+		}
+
+		return err
+	}
+
+	if completedAt.Valid {
+		return backend.ErrInstanceFinished
+	}
+
+	return nil
+}
+
 func (sb *sqliteBackend) CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance, event *history.Event) error {
 	tx, err := sb.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -255,7 +328,7 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, e
 								WHERE instance_id = i.id AND execution_id = i.execution_id AND (visible_at IS NULL OR visible_at <= ?)
 						)
 					LIMIT 1
-			) RETURNING id, execution_id, parent_instance_id, parent_schedule_event_id, metadata, sticky_until`,
+			) RETURNING id, execution_id, parent_instance_id, parent_schedule_event_id, metadata, sticky_until, workflow_task_failures`,
 		now.Add(sb.options.WorkflowLockTimeout), // new locked_until
 		sb.workerName,
 		now,           // locked_until
@@ -269,7 +342,8 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, e
 	var parentEventID *int64
 	var metadataJson sql.NullString
 	var stickyUntil *time.Time
-	if err := row.Scan(&instanceID, &executionID, &parentInstanceID, &parentEventID, &metadataJson, &stickyUntil); err != nil {
+	var failures int
+	if err := row.Scan(&instanceID, &executionID, &parentInstanceID, &parentEventID, &metadataJson, &stickyUntil, &failures); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -296,6 +370,7 @@ func (sb *sqliteBackend) GetWorkflowTask(ctx context.Context) (*task.Workflow, e
 		WorkflowInstance:      wfi,
 		WorkflowInstanceState: core.WorkflowInstanceStateActive,
 		Metadata:              metadata,
+		Attempt:               failures + 1,
 		NewEvents:             []*history.Event{},
 	}
 
@@ -346,14 +421,15 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 
 	var completedAt *time.Time
 	if state == core.WorkflowInstanceStateFinished {
-		t := time.Now()
+		// Store timestamps in UTC to keep them comparable when listing instances
+		t := time.Now().UTC()
 		completedAt = &t
 	}
 
 	// Unlock instance, but keep it sticky to the current worker
 	if res, err := tx.ExecContext(
 		ctx,
-		`UPDATE instances SET locked_until = NULL, sticky_until = ?, completed_at = ? WHERE id = ? AND execution_id = ? AND worker = ?`,
+		`UPDATE instances SET locked_until = NULL, sticky_until = ?, completed_at = ?, workflow_task_failures = 0 WHERE id = ? AND execution_id = ? AND worker = ?`,
 		time.Now().Add(sb.options.StickyTimeout),
 		completedAt,
 		instance.InstanceID,
@@ -410,22 +486,57 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 		}
 	}
 
+	// Update search attribute index
+	if updates := history.SearchAttributeUpdates(executedEvents); len(updates) > 0 {
+		if err := sqlsearch.Upsert(ctx, tx, instance.InstanceID, updates); err != nil {
+			return fmt.Errorf("updating search attributes: %w", err)
+		}
+	}
+
 	// Insert new workflow events
 	groupedEvents := history.EventsByWorkflowInstanceID(workflowEvents)
 
+	// Errors for target instances that do not exist, have finished, or could not be created
+	targetErrs := make(map[string]error)
+
 	for targetInstanceID, events := range groupedEvents {
+		created := false
+
 		for _, m := range events {
 			if m.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted {
 				a := m.HistoryEvent.Attributes.(*history.ExecutionStartedAttributes)
-				// Create new instance
-				if err := createInstance(ctx, tx, m.WorkflowInstance, a.Metadata, true); err != nil {
-					return err
+				// Create new instance. Events for existing instances are not delivered, the sending instance is
+				// notified below
+				if err := createInstance(ctx, tx, m.WorkflowInstance, a.Name, a.Metadata, false); err != nil {
+					if !errors.Is(err, backend.ErrInstanceAlreadyExists) {
+						return err
+					}
+
+					targetErrs[targetInstanceID] = err
 				}
 
+				created = true
 				break
 			}
 		}
 
+		if targetErrs[targetInstanceID] != nil {
+			continue
+		}
+
+		if !created {
+			// Events for instances that do not exist or have finished are not delivered, the sending instance is
+			// notified below
+			if err := checkTargetInstance(ctx, tx, targetInstanceID); err != nil {
+				if !errors.Is(err, backend.ErrInstanceNotFound) && !errors.Is(err, backend.ErrInstanceFinished) {
+					return fmt.Errorf("checking for target workflow instance: %w", err)
+				}
+
+				targetErrs[targetInstanceID] = err
+				continue
+			}
+		}
+
 		// Insert pending events for target instance
 		historyEvents := []*history.Event{}
 		for _, m := range events {
@@ -436,7 +547,87 @@ func (sb *sqliteBackend) CompleteWorkflowTask(
 		}
 	}
 
-	return tx.Commit()
+	// Report the outcome of sub-workflows, signals, and cancellation requests sent to other instances back to this
+	// instance
+	resultEvents := history.ExternalWorkflowResults(time.Now(), executedEvents, func(instanceID string) error {
+		return targetErrs[instanceID]
+	})
+	if err := insertPendingEvents(ctx, tx, instance.InstanceID, resultEvents); err != nil {
+		return fmt.Errorf("inserting external workflow results: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if len(executedEvents) > 0 {
+		sb.historyNotifier.Notify(instance.InstanceID)
+	}
+
+	if state == core.WorkflowInstanceStateFinished {
+		sb.completionNotifier.Notify(instance.InstanceID)
+	}
+
+	return nil
+}
+
+func (sb *sqliteBackend) FailWorkflowTask(
+	ctx context.Context,
+	task *task.Workflow,
+	instance *workflow.Instance,
+	failedEvent *history.Event,
+	retryAfter time.Duration,
+) error {
+	tx, err := sb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Keep the instance locked until the task should be retried, pending events are left untouched
+	if res, err := tx.ExecContext(
+		ctx,
+		`UPDATE instances SET locked_until = ?, workflow_task_failures = workflow_task_failures + 1 WHERE id = ? AND execution_id = ? AND worker = ?`,
+		time.Now().Add(retryAfter),
+		instance.InstanceID,
+		instance.ExecutionID,
+		sb.workerName,
+	); err != nil {
+		return fmt.Errorf("delaying workflow task: %w", err)
+	} else if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("checking for delayed workflow instances: %w", err)
+	} else if n != 1 {
+		return errors.New("could not find workflow instance to delay")
+	}
+
+	if failedEvent != nil {
+		if err := insertHistoryEvents(ctx, tx, instance.InstanceID, []*history.Event{failedEvent}); err != nil {
+			return fmt.Errorf("inserting workflow task failed event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if failedEvent != nil {
+		sb.historyNotifier.Notify(instance.InstanceID)
+	}
+
+	return nil
+}
+
+var _ backend.CompletionSubscriber = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) SubscribeWorkflowInstanceCompletion(ctx context.Context, instance *workflow.Instance) (<-chan struct{}, func(), error) {
+	c, cancel := sb.completionNotifier.Subscribe(instance.InstanceID)
+	return c, cancel, nil
+}
+
+var _ backend.HistoryWatcher = (*sqliteBackend)(nil)
+
+func (sb *sqliteBackend) WatchWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, fromSequenceID int64) (<-chan *history.Event, error) {
+	return notify.WatchHistory(ctx, sb, sb.historyNotifier, instance, fromSequenceID)
 }
 
 func (sb *sqliteBackend) ExtendWorkflowTask(ctx context.Context, taskID string, instance *workflow.Instance) error {
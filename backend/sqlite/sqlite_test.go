@@ -2,12 +2,17 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/backend/test"
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_SqliteBackend(t *testing.T) {
@@ -32,6 +37,102 @@ func Test_EndToEndSqliteBackend(t *testing.T) {
 	}, nil)
 }
 
+func Test_Migrate_FromBaselineSchema(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "baseline.sqlite")
+
+	// Versions before migrations were introduced created the baseline schema on every start
+	baseline, err := migrationsFS.ReadFile("migrations/0001_initial.sql")
+	require.NoError(t, err)
+
+	db, err := sql.Open("sqlite3", sqliteDSN(path))
+	require.NoError(t, err)
+
+	_, err = db.Exec(string(baseline))
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO `instances` (id, execution_id) VALUES ('existing', 'execution'), ('pending', 'execution')")
+	require.NoError(t, err)
+
+	// The workflow name of existing instances is backfilled from their started event
+	_, err = db.Exec(
+		"INSERT INTO `history` (id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes) VALUES ('1', 1, 'existing', ?, ?, 0, ?)",
+		history.EventType_WorkflowExecutionStarted, time.Now(), []byte(`{"name":"wf"}`))
+	require.NoError(t, err)
+
+	_, err = db.Exec(
+		"INSERT INTO `pending_events` (id, sequence_id, instance_id, event_type, timestamp, schedule_event_id, attributes) VALUES ('1', 0, 'pending', ?, ?, 0, ?)",
+		history.EventType_WorkflowExecutionStarted, time.Now(), []byte(`{"name":"other-wf"}`))
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	b := NewSqliteBackend(path)
+	defer b.db.Close()
+
+	var version int
+	require.NoError(t, b.db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version))
+
+	ms, err := migrationsFS.ReadDir("migrations")
+	require.NoError(t, err)
+	require.Equal(t, len(ms), version)
+
+	instance := core.NewWorkflowInstance("new", "execution")
+	require.NoError(t, b.CreateWorkflowInstance(ctx, instance, history.NewHistoryEvent(
+		1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"})))
+
+	r, err := b.ListWorkflowInstances(ctx, &backend.ListWorkflowInstancesOptions{})
+	require.NoError(t, err)
+	require.Len(t, r.Instances, 3)
+
+	r, err = b.ListWorkflowInstances(ctx, &backend.ListWorkflowInstancesOptions{WorkflowName: "wf"})
+	require.NoError(t, err)
+	require.Len(t, r.Instances, 2)
+	require.ElementsMatch(t, []string{"new", "existing"}, []string{r.Instances[0].Instance.InstanceID, r.Instances[1].Instance.InstanceID})
+
+	r, err = b.ListWorkflowInstances(ctx, &backend.ListWorkflowInstancesOptions{WorkflowName: "other-wf"})
+	require.NoError(t, err)
+	require.Len(t, r.Instances, 1)
+	require.Equal(t, "pending", r.Instances[0].Instance.InstanceID)
+}
+
+func Test_ListWorkflowInstances_CursorInstanceRemoved(t *testing.T) {
+	ctx := context.Background()
+	b := NewInMemoryBackend()
+	defer b.db.Close()
+
+	for i := 0; i < 3; i++ {
+		instance := core.NewWorkflowInstance(fmt.Sprintf("instance-%d", i), "execution")
+		require.NoError(t, b.CreateWorkflowInstance(ctx, instance, history.NewHistoryEvent(
+			1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{Name: "wf"})))
+	}
+
+	options := &backend.ListWorkflowInstancesOptions{
+		WorkflowName: "wf",
+		PageSize:     1,
+	}
+
+	r, err := b.ListWorkflowInstances(ctx, options)
+	require.NoError(t, err)
+	require.Len(t, r.Instances, 1)
+	require.NotEmpty(t, r.NextCursor)
+
+	// Remove the instance the cursor points to
+	_, err = b.db.Exec("DELETE FROM `instances` WHERE id = ?", r.Instances[0].Instance.InstanceID)
+	require.NoError(t, err)
+
+	ids := []string{r.Instances[0].Instance.InstanceID}
+	for r.NextCursor != "" {
+		options.Cursor = r.NextCursor
+		r, err = b.ListWorkflowInstances(ctx, options)
+		require.NoError(t, err)
+		require.Len(t, r.Instances, 1)
+
+		ids = append(ids, r.Instances[0].Instance.InstanceID)
+	}
+
+	require.ElementsMatch(t, []string{"instance-0", "instance-1", "instance-2"}, ids)
+}
+
 var _ test.TestBackend = (*sqliteBackend)(nil)
 
 func (sb *sqliteBackend) GetFutureEvents(ctx context.Context) ([]*history.Event, error) {
@@ -240,6 +240,68 @@ func BackendTest(t *testing.T, setup func() TestBackend, teardown func(b TestBac
 				require.NotNil(t, s.CompletedAt)
 			},
 		},
+		{
+			name: "FailWorkflowTask_AddsEventAndDelaysTask",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(ctx, wfi, history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}))
+				require.NoError(t, err)
+
+				task, err := b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, task)
+				require.Equal(t, 1, task.Attempt)
+
+				failedEvent := history.NewHistoryEvent(task.LastSequenceID+1, time.Now(), history.EventType_WorkflowTaskFailed, &history.WorkflowTaskFailedAttributes{
+					Error: "task failed",
+				})
+
+				err = b.FailWorkflowTask(ctx, task, wfi, failedEvent, time.Second*2)
+				require.NoError(t, err)
+
+				h, err := b.GetWorkflowInstanceHistory(ctx, wfi, nil)
+				require.NoError(t, err)
+				require.Len(t, h, 1)
+				require.Equal(t, failedEvent.ID, h[0].ID)
+				require.Equal(t, failedEvent.Attributes, h[0].Attributes)
+
+				// Task is not available before the retry interval has passed
+				tctx, cancel := context.WithTimeout(ctx, time.Millisecond*100)
+				defer cancel()
+
+				retried, err := b.GetWorkflowTask(tctx)
+				require.Nil(t, retried)
+				require.True(t, err == nil || errors.Is(err, context.DeadlineExceeded))
+
+				time.Sleep(time.Second * 3)
+
+				retried, err = b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+				require.NotNil(t, retried)
+				require.Equal(t, failedEvent.SequenceID, retried.LastSequenceID)
+				require.Equal(t, 2, retried.Attempt)
+				require.Len(t, retried.NewEvents, len(task.NewEvents))
+				for i, event := range task.NewEvents {
+					require.Equal(t, event.ID, retried.NewEvents[i].ID)
+				}
+
+				// Failing again without an event only counts the attempt
+				err = b.FailWorkflowTask(ctx, retried, wfi, nil, 0)
+				require.NoError(t, err)
+
+				require.Eventually(t, func() bool {
+					retried, err = b.GetWorkflowTask(ctx)
+					require.NoError(t, err)
+					return retried != nil
+				}, time.Second*5, time.Millisecond*10)
+				require.Equal(t, 3, retried.Attempt)
+				require.Equal(t, failedEvent.SequenceID, retried.LastSequenceID)
+
+				h, err = b.GetWorkflowInstanceHistory(ctx, wfi, nil)
+				require.NoError(t, err)
+				require.Len(t, h, 1)
+			},
+		},
 		{
 			name: "SignalWorkflow_ErrorWhenInstanceDoesNotExist",
 			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
@@ -306,6 +368,64 @@ func BackendTest(t *testing.T, setup func() TestBackend, teardown func(b TestBac
 				require.Equal(t, history.EventType_WorkflowExecutionCanceled, task.NewEvents[len(task.NewEvents)-1].Type)
 			},
 		},
+		{
+			name: "RemoveWorkflowInstance_RemovesFinishedInstance",
+			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
+				wfi := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+				err := b.CreateWorkflowInstance(ctx, wfi, history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+					Name:     "some-workflow",
+					Metadata: &core.WorkflowMetadata{},
+				}))
+				require.NoError(t, err)
+
+				err = b.RemoveWorkflowInstance(ctx, wfi)
+				require.ErrorIs(t, err, backend.ErrInstanceNotFinished)
+
+				task, err := b.GetWorkflowTask(ctx)
+				require.NoError(t, err)
+
+				// Finish the instance with a timer that has not fired yet
+				timerAt := time.Now().Add(time.Hour)
+				events := append(task.NewEvents,
+					history.NewPendingEvent(time.Now(), history.EventType_TimerScheduled, &history.TimerScheduledAttributes{At: timerAt}, history.ScheduleEventID(1)),
+					history.NewPendingEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{}),
+				)
+				for i := range events {
+					events[i].SequenceID = int64(i + 1)
+				}
+
+				timerEvents := []*history.Event{
+					history.NewPendingEvent(time.Now(), history.EventType_TimerFired, &history.TimerFiredAttributes{At: timerAt}, history.ScheduleEventID(1), history.VisibleAt(timerAt)),
+				}
+
+				err = b.CompleteWorkflowTask(ctx, task, wfi, core.WorkflowInstanceStateFinished, events, []*history.Event{}, timerEvents, []history.WorkflowEvent{})
+				require.NoError(t, err)
+
+				err = b.RemoveWorkflowInstance(ctx, core.NewWorkflowInstance(wfi.InstanceID, uuid.NewString()))
+				require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+				err = b.RemoveWorkflowInstance(ctx, wfi)
+				require.NoError(t, err)
+
+				_, err = b.GetWorkflowInstanceState(ctx, wfi)
+				require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+				h, err := b.GetWorkflowInstanceHistory(ctx, wfi, nil)
+				require.NoError(t, err)
+				require.Empty(t, h)
+
+				r, err := b.ListWorkflowInstances(ctx, &backend.ListWorkflowInstancesOptions{})
+				require.NoError(t, err)
+				require.Empty(t, r.Instances)
+
+				futureEvents, err := b.(TestBackend).GetFutureEvents(ctx)
+				require.NoError(t, err)
+				require.Empty(t, futureEvents)
+
+				err = b.RemoveWorkflowInstance(ctx, wfi)
+				require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+			},
+		},
 		{
 			name: "GetActivityTask_ReturnsNilWhenTimeout",
 			f: func(t *testing.T, ctx context.Context, b backend.Backend) {
@@ -2,6 +2,8 @@ package test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sync/atomic"
 	"testing"
@@ -10,8 +12,10 @@ import (
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/client"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/history"
 	internalwf "github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/cschleiden/go-workflows/search"
 	"github.com/cschleiden/go-workflows/worker"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
@@ -128,6 +132,48 @@ func EndToEndBackendTest(t *testing.T, setup func() TestBackend, teardown func(b
 				require.ErrorContains(t, err, "mismatched argument count: expected 2, got 1")
 			},
 		},
+		{
+			name: "ActivityPanic_Retried",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
+				var attempts int32
+				a := func(context.Context) (int, error) {
+					if atomic.AddInt32(&attempts, 1) == 1 {
+						panic("activity failed")
+					}
+
+					return 42, nil
+				}
+				wf := func(ctx workflow.Context) (int, error) {
+					return workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{
+						RetryOptions: workflow.RetryOptions{
+							MaxAttempts:        2,
+							FirstRetryInterval: time.Millisecond,
+						},
+					}, a).Get(ctx)
+				}
+				register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+				instance := runWorkflow(t, ctx, c, wf)
+				output, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+
+				require.NoError(t, err)
+				require.Equal(t, 42, output)
+
+				var failed *history.ActivityFailedAttributes
+				historyIterate(ctx, t, b, instance, func(event *history.Event) bool {
+					if event.Type == history.EventType_ActivityFailed {
+						failed = event.Attributes.(*history.ActivityFailedAttributes)
+						return false
+					}
+
+					return true
+				})
+
+				require.NotNil(t, failed)
+				require.Equal(t, "activity panicked: activity failed", failed.Reason)
+				require.Contains(t, failed.StackTrace, "e2e.go")
+			},
+		},
 		{
 			name: "SideEffect_Simple",
 			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
@@ -204,6 +250,50 @@ func EndToEndBackendTest(t *testing.T, setup func() TestBackend, teardown func(b
 				require.Equal(t, 2, r)
 			},
 		},
+		{
+			name: "SubWorkflow_DuplicateInstanceID",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
+				swInstanceID := uuid.NewString()
+
+				swf := func(ctx workflow.Context, i int) (int, error) {
+					return i * 2, nil
+				}
+				wf := func(ctx workflow.Context) (int, error) {
+					options := workflow.SubWorkflowOptions{
+						InstanceID: swInstanceID,
+					}
+
+					h := workflow.StartSubWorkflowInstance[int](ctx, options, swf, 1)
+					if _, err := h.Started().Get(ctx); err != nil {
+						return 0, err
+					}
+
+					r, err := h.Result().Get(ctx)
+					if err != nil {
+						return 0, err
+					}
+
+					// The sub-workflow instance still exists after it has finished
+					dh := workflow.StartSubWorkflowInstance[int](ctx, options, swf, 2)
+					if _, err := dh.Started().Get(ctx); !errors.Is(err, workflow.ErrInstanceAlreadyExists) {
+						return 0, fmt.Errorf("expected started to fail with ErrInstanceAlreadyExists, got %v", err)
+					}
+
+					if _, err := dh.Result().Get(ctx); !errors.Is(err, workflow.ErrInstanceAlreadyExists) {
+						return 0, fmt.Errorf("expected result to fail with ErrInstanceAlreadyExists, got %v", err)
+					}
+
+					return r, nil
+				}
+				register(t, ctx, w, []interface{}{wf, swf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*20)
+				require.NoError(t, err)
+				require.Equal(t, 2, r)
+			},
+		},
 		{
 			name: "SubWorkflow_PropagateCancellation",
 			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
@@ -372,6 +462,90 @@ func EndToEndBackendTest(t *testing.T, setup func() TestBackend, teardown func(b
 				require.ErrorContains(t, err, backend.ErrInstanceNotFound.Error())
 			},
 		},
+		{
+			name: "SignalWorkflow_InstanceNotFound",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
+				wf := func(ctx workflow.Context) (int, error) {
+					id, _ := workflow.SideEffect(ctx, func(ctx workflow.Context) string {
+						return uuid.New().String()
+					}).Get(ctx)
+
+					if _, err := workflow.SignalWorkflow(ctx, id, "signal", "hello").Get(ctx); err != workflow.ErrInstanceNotFound {
+						return 0, err
+					}
+
+					return 42, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*20)
+				require.NoError(t, err)
+				require.Equal(t, 42, r)
+			},
+		},
+		{
+			name: "SignalWorkflow_InstanceFinished",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
+				target := func(ctx workflow.Context) error {
+					return nil
+				}
+				wf := func(ctx workflow.Context, id string) (int, error) {
+					if _, err := workflow.SignalWorkflow(ctx, id, "signal", "hello").Get(ctx); err != workflow.ErrInstanceFinished {
+						return 0, err
+					}
+
+					return 42, nil
+				}
+				register(t, ctx, w, []interface{}{wf, target}, nil)
+
+				targetInstance := runWorkflow(t, ctx, c, target)
+				require.NoError(t, c.WaitForWorkflowInstance(ctx, targetInstance, time.Second*20))
+
+				instance := runWorkflow(t, ctx, c, wf, targetInstance.InstanceID)
+
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*20)
+				require.NoError(t, err)
+				require.Equal(t, 42, r)
+			},
+		},
+		{
+			name: "CancelWorkflow_External",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
+				swf := func(ctx workflow.Context) (string, error) {
+					if _, err := workflow.ScheduleTimer(ctx, time.Hour).Get(ctx); err != workflow.Canceled {
+						return "", err
+					}
+
+					return "canceled", nil
+				}
+				wf := func(ctx workflow.Context) (string, error) {
+					id, _ := workflow.SideEffect(ctx, func(ctx workflow.Context) string {
+						return uuid.New().String()
+					}).Get(ctx)
+
+					f := workflow.CreateSubWorkflowInstance[string](ctx, workflow.SubWorkflowOptions{
+						InstanceID: id,
+					}, swf)
+
+					if _, err := workflow.CancelWorkflow(ctx, id).Get(ctx); err != nil {
+						return "", err
+					}
+
+					return f.Get(ctx)
+				}
+				register(t, ctx, w, []interface{}{wf, swf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				r, err := client.GetWorkflowResult[string](ctx, c, instance, time.Second*20)
+				require.NoError(t, err)
+				require.Equal(t, "canceled", r)
+
+				historyContains(ctx, t, b, instance, history.EventType_RequestCancelExternalWorkflowScheduled, history.EventType_RequestCancelExternalWorkflowCompleted)
+			},
+		},
 		{
 			name: "Timer_CancelWorkflowInstance",
 			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
@@ -523,6 +697,315 @@ func EndToEndBackendTest(t *testing.T, setup func() TestBackend, teardown func(b
 				require.Len(t, futureEvents, 0, "no future events should be scheduled")
 			},
 		},
+		{
+			name: "SearchAttributes_ListWorkflowInstances",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
+				customer := uuid.NewString()
+
+				wf := func(ctx workflow.Context, total int, region string) error {
+					if err := workflow.UpsertSearchAttributes(ctx, map[string]any{
+						"customer": customer,
+						"total":    total,
+						"region":   region,
+						"pending":  true,
+					}); err != nil {
+						return err
+					}
+
+					// Force a second workflow task
+					workflow.Sleep(ctx, time.Millisecond*1)
+
+					return workflow.UpsertSearchAttributes(ctx, map[string]any{
+						"pending": nil,
+					})
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				i1 := runWorkflow(t, ctx, c, wf, 10, "eu")
+				i2 := runWorkflow(t, ctx, c, wf, 20, "us")
+				i3 := runWorkflow(t, ctx, c, wf, 30, "eu")
+
+				for _, i := range []*workflow.Instance{i1, i2, i3} {
+					_, err := client.GetWorkflowResult[any](ctx, c, i, time.Second*10)
+					require.NoError(t, err)
+				}
+
+				instanceIDs := func(query search.Query) []string {
+					r, err := client.ListWorkflowInstances(ctx, c, client.ListWorkflowInstancesOptions{Query: query})
+					require.NoError(t, err)
+
+					ids := make([]string, 0, len(r.Instances))
+					for _, i := range r.Instances {
+						ids = append(ids, i.Instance.InstanceID)
+					}
+
+					return ids
+				}
+
+				require.ElementsMatch(t, []string{i1.InstanceID, i2.InstanceID, i3.InstanceID}, instanceIDs(search.Eq("customer", customer)))
+				require.ElementsMatch(t, []string{i1.InstanceID, i3.InstanceID},
+					instanceIDs(search.And(search.Eq("customer", customer), search.Eq("region", "eu"))))
+				require.ElementsMatch(t, []string{i2.InstanceID, i3.InstanceID},
+					instanceIDs(search.And(search.Eq("customer", customer), search.Gt("total", 10))))
+				require.ElementsMatch(t, []string{i1.InstanceID, i2.InstanceID},
+					instanceIDs(search.And(search.Eq("customer", customer), search.Between("total", 5, 20))))
+				require.ElementsMatch(t, []string{i1.InstanceID, i2.InstanceID},
+					instanceIDs(search.And(search.Eq("customer", customer), search.Or(search.Lt("total", 15), search.Gte("region", "u")))))
+
+				// Removed attributes are no longer indexed, and types have to match
+				require.Empty(t, instanceIDs(search.And(search.Eq("customer", customer), search.Eq("pending", true))))
+				require.Empty(t, instanceIDs(search.And(search.Eq("customer", customer), search.Eq("total", "10"))))
+			},
+		},
+		{
+			name: "ListWorkflowInstances_FiltersAndPagination",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
+				subWorkflow := func(ctx workflow.Context) error {
+					return nil
+				}
+				wf := func(ctx workflow.Context, sub bool) error {
+					if sub {
+						_, err := workflow.CreateSubWorkflowInstance[any](ctx, workflow.DefaultSubWorkflowOptions, subWorkflow).Get(ctx)
+						return err
+					}
+
+					workflow.NewSignalChannel[int](ctx, "finish").Receive(ctx)
+					return nil
+				}
+				register(t, ctx, w, []interface{}{wf, subWorkflow}, nil)
+
+				start := time.Now().Add(-time.Second)
+
+				parent := runWorkflow(t, ctx, c, wf, true)
+				_, err := client.GetWorkflowResult[any](ctx, c, parent, time.Second*10)
+				require.NoError(t, err)
+
+				running := make([]*workflow.Instance, 0)
+				for i := 0; i < 3; i++ {
+					running = append(running, runWorkflow(t, ctx, c, wf, false))
+				}
+
+				// Sub-workflows are found by their parent and name
+				r, err := client.ListWorkflowInstances(ctx, c, client.ListWorkflowInstancesOptions{
+					ParentInstanceID: parent.InstanceID,
+				})
+				require.NoError(t, err)
+				require.Len(t, r.Instances, 1)
+				require.Equal(t, int64(1), r.TotalCount)
+				require.Equal(t, parent.InstanceID, r.Instances[0].Instance.ParentInstanceID)
+				require.Equal(t, fn.Name(subWorkflow), r.Instances[0].WorkflowName)
+
+				// Running instances of wf, paged
+				active := backend.WorkflowInstanceStateActive
+				options := client.ListWorkflowInstancesOptions{
+					WorkflowName: fn.Name(wf),
+					State:        &active,
+					CreatedAfter: start,
+					PageSize:     2,
+				}
+
+				ids := []string{}
+				pages := 0
+				for {
+					r, err := client.ListWorkflowInstances(ctx, c, options)
+					require.NoError(t, err)
+					require.Equal(t, int64(3), r.TotalCount)
+
+					pages++
+					for _, i := range r.Instances {
+						require.Equal(t, backend.WorkflowInstanceStateActive, i.State)
+						ids = append(ids, i.Instance.InstanceID)
+					}
+
+					if r.NextCursor == "" {
+						break
+					}
+
+					options.Cursor = r.NextCursor
+				}
+
+				require.Equal(t, 2, pages)
+				require.ElementsMatch(t, []string{running[0].InstanceID, running[1].InstanceID, running[2].InstanceID}, ids)
+
+				// Finished instances completed after start
+				finished := backend.WorkflowInstanceStateFinished
+				r, err = client.ListWorkflowInstances(ctx, c, client.ListWorkflowInstancesOptions{
+					WorkflowName:   fn.Name(wf),
+					State:          &finished,
+					CompletedAfter: start,
+				})
+				require.NoError(t, err)
+				require.Len(t, r.Instances, 1)
+				require.Equal(t, parent.InstanceID, r.Instances[0].Instance.InstanceID)
+				require.NotNil(t, r.Instances[0].CompletedAt)
+
+				r, err = client.ListWorkflowInstances(ctx, c, client.ListWorkflowInstancesOptions{
+					WorkflowName:  fn.Name(wf),
+					CreatedBefore: start,
+				})
+				require.NoError(t, err)
+				require.Empty(t, r.Instances)
+
+				for _, i := range running {
+					require.NoError(t, c.SignalWorkflow(ctx, i.InstanceID, "finish", 1))
+				}
+			},
+		},
+		{
+			name: "DescribeWorkflowInstance",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
+				release := make(chan struct{})
+				a := func(ctx context.Context) error {
+					<-release
+					return nil
+				}
+				subWorkflow := func(ctx workflow.Context) error {
+					workflow.NewSignalChannel[int](ctx, "finish").Receive(ctx)
+					return nil
+				}
+				wf := func(ctx workflow.Context) error {
+					tctx, cancel := workflow.WithCancel(ctx)
+					workflow.ScheduleTimer(tctx, time.Hour)
+
+					af := workflow.ExecuteActivity[any](ctx, workflow.DefaultActivityOptions, a)
+					options := workflow.DefaultSubWorkflowOptions
+					options.InstanceID = workflow.WorkflowInstance(ctx).InstanceID + "-sub"
+					sf := workflow.CreateSubWorkflowInstance[any](ctx, options, subWorkflow)
+
+					if _, err := af.Get(ctx); err != nil {
+						return err
+					}
+
+					if _, err := sf.Get(ctx); err != nil {
+						return err
+					}
+
+					cancel()
+					return nil
+				}
+				register(t, ctx, w, []interface{}{wf, subWorkflow}, []interface{}{a})
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				var d *client.WorkflowInstanceDescription
+				require.Eventually(t, func() bool {
+					var err error
+					d, err = client.DescribeWorkflowInstance(ctx, c, instance)
+					require.NoError(t, err)
+
+					return len(d.PendingActivities) == 1 && len(d.PendingSubWorkflows) == 1 && d.PendingSubWorkflows[0].Started
+				}, time.Second*10, time.Millisecond*50)
+
+				require.Equal(t, backend.WorkflowInstanceStateActive, d.State)
+				require.Equal(t, fn.Name(wf), d.WorkflowName)
+				require.NotNil(t, d.LastWorkflowTaskAt)
+
+				require.Equal(t, fn.Name(a), d.PendingActivities[0].Name)
+				require.Equal(t, 1, d.PendingActivities[0].Attempt)
+
+				require.Len(t, d.PendingTimers, 1)
+				require.WithinDuration(t, d.PendingTimers[0].ScheduledAt.Add(time.Hour), d.PendingTimers[0].FireAt, time.Second)
+
+				require.Equal(t, instance.InstanceID+"-sub", d.PendingSubWorkflows[0].Instance.InstanceID)
+				require.Equal(t, fn.Name(subWorkflow), d.PendingSubWorkflows[0].Name)
+
+				close(release)
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID+"-sub", "finish", 1))
+
+				_, err := client.GetWorkflowResult[any](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+
+				d, err = client.DescribeWorkflowInstance(ctx, c, instance)
+				require.NoError(t, err)
+				require.Equal(t, backend.WorkflowInstanceStateFinished, d.State)
+				require.Empty(t, d.PendingActivities)
+				require.Empty(t, d.PendingTimers)
+				require.Empty(t, d.PendingSubWorkflows)
+				require.Empty(t, d.PendingSignals)
+
+				_, err = client.DescribeWorkflowInstance(ctx, c, core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()))
+				require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+			},
+		},
+		{
+			name: "WatchWorkflowInstanceHistory",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
+				a := func(ctx context.Context) (int, error) {
+					return 42, nil
+				}
+				wf := func(ctx workflow.Context) error {
+					workflow.NewSignalChannel[int](ctx, "start").Receive(ctx)
+
+					for i := 0; i < 2; i++ {
+						if _, err := workflow.ExecuteActivity[int](ctx, workflow.DefaultActivityOptions, a).Get(ctx); err != nil {
+							return err
+						}
+					}
+
+					return nil
+				}
+				register(t, ctx, w, []interface{}{wf}, []interface{}{a})
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				events, err := client.WatchWorkflowInstanceHistory(ctx, c, instance, 0)
+				require.NoError(t, err)
+
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "start", 1))
+
+				watched := []*client.HistoryEvent{}
+				for event := range events {
+					watched = append(watched, event)
+				}
+
+				h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+				require.NoError(t, err)
+				require.Len(t, watched, len(h))
+				for i, event := range h {
+					require.Equal(t, event.SequenceID, watched[i].SequenceID)
+					require.Equal(t, event.Type, watched[i].Type)
+				}
+				require.Equal(t, history.EventType_WorkflowExecutionFinished, watched[len(watched)-1].Type)
+
+				// Watching a finished instance returns the remaining events
+				events, err = client.WatchWorkflowInstanceHistory(ctx, c, instance, h[len(h)-3].SequenceID)
+				require.NoError(t, err)
+
+				watched = watched[:0]
+				for event := range events {
+					watched = append(watched, event)
+				}
+				require.Len(t, watched, 2)
+				require.Equal(t, h[len(h)-2].SequenceID, watched[0].SequenceID)
+
+				_, err = client.WatchWorkflowInstanceHistory(ctx, c, core.NewWorkflowInstance(uuid.NewString(), uuid.NewString()), 0)
+				require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+			},
+		},
+		{
+			name: "RemoveWorkflowInstance",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
+				wf := func(ctx workflow.Context) error {
+					workflow.NewSignalChannel[int](ctx, "finish").Receive(ctx)
+
+					return nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				err := client.RemoveWorkflowInstance(ctx, c, instance)
+				require.ErrorIs(t, err, backend.ErrInstanceNotFinished)
+
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "finish", 1))
+				require.NoError(t, c.WaitForWorkflowInstance(ctx, instance, time.Second*10))
+
+				require.NoError(t, client.RemoveWorkflowInstance(ctx, c, instance))
+
+				_, err = client.DescribeWorkflowInstance(ctx, c, instance)
+				require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+			},
+		},
 		{
 			name:         "NonDeterminism",
 			withoutCache: true,
@@ -554,6 +1037,200 @@ func EndToEndBackendTest(t *testing.T, setup func() TestBackend, teardown func(b
 				require.Equal(t, 0, r)
 			},
 		},
+		{
+			name:         "NonDeterminism_FailTask",
+			withoutCache: true,
+			f: func(t *testing.T, ctx context.Context, c client.Client, _ worker.Worker, b TestBackend) {
+				var broken atomic.Bool
+				wf := func(ctx workflow.Context) (int, error) {
+					if broken.Load() {
+						workflow.SideEffect(ctx, func(ctx workflow.Context) int {
+							return 1
+						})
+					} else {
+						workflow.Sleep(ctx, time.Millisecond*1)
+					}
+
+					workflow.NewSignalChannel[int](ctx, "continue").Receive(ctx)
+
+					return 42, nil
+				}
+
+				options := worker.DefaultWorkerOptions
+				options.WorkflowExecutorCache = &noopWorkflowExecutorCache{}
+				options.NonDeterminismPolicy = worker.NonDeterminismFailTask
+				options.WorkflowTaskRetryInterval = time.Millisecond * 100
+
+				wctx, cancel := context.WithCancel(ctx)
+				w := worker.New(b, &options)
+				register(t, wctx, w, []interface{}{wf}, nil)
+				t.Cleanup(func() {
+					cancel()
+					w.WaitForCompletion()
+				})
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				waitForEvent := func(eventType history.EventType) *history.Event {
+					var event *history.Event
+					require.Eventually(t, func() bool {
+						historyIterate(ctx, t, b, instance, func(e *history.Event) bool {
+							if e.Type == eventType {
+								event = e
+								return false
+							}
+
+							return true
+						})
+
+						return event != nil
+					}, time.Second*10, time.Millisecond*50)
+
+					return event
+				}
+
+				waitForEvent(history.EventType_TimerFired)
+
+				// Change the workflow, the next task cannot be completed
+				broken.Store(true)
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "continue", 1))
+
+				failedEvent := waitForEvent(history.EventType_WorkflowTaskFailed)
+				a := failedEvent.Attributes.(*history.WorkflowTaskFailedAttributes)
+				require.Contains(t, a.Error, "non-deterministic workflow")
+
+				// Let the task fail a few more times, then restore the workflow. The retried task completes the
+				// workflow.
+				time.Sleep(time.Millisecond * 500)
+				broken.Store(false)
+
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, 42, r)
+
+				historyContains(ctx, t, b, instance,
+					history.EventType_TimerFired,
+					history.EventType_WorkflowTaskFailed,
+					history.EventType_SignalReceived,
+					history.EventType_WorkflowExecutionFinished,
+				)
+
+				// Only the first failure is recorded
+				failures := 0
+				historyIterate(ctx, t, b, instance, func(e *history.Event) bool {
+					if e.Type == history.EventType_WorkflowTaskFailed {
+						failures++
+					}
+
+					return true
+				})
+				require.Equal(t, 1, failures)
+			},
+		},
+		{
+			name: "Deadlock_FailsTask",
+			f: func(t *testing.T, ctx context.Context, c client.Client, _ worker.Worker, b TestBackend) {
+				var deadlocked atomic.Bool
+				deadlocked.Store(true)
+
+				var runs atomic.Int32
+				ch := make(chan struct{})
+				wf := func(ctx workflow.Context) (int, error) {
+					runs.Add(1)
+
+					if deadlocked.Load() {
+						// Block outside of the workflow APIs
+						<-ch
+					}
+
+					return 42, nil
+				}
+
+				options := worker.DefaultWorkerOptions
+				options.WorkflowDeadlockTimeout = time.Millisecond * 100
+				options.WorkflowTaskRetryInterval = time.Millisecond * 100
+
+				wctx, cancel := context.WithCancel(ctx)
+				w := worker.New(b, &options)
+				register(t, wctx, w, []interface{}{wf}, nil)
+				t.Cleanup(func() {
+					cancel()
+					w.WaitForCompletion()
+				})
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				var failed *history.WorkflowTaskFailedAttributes
+				require.Eventually(t, func() bool {
+					historyIterate(ctx, t, b, instance, func(e *history.Event) bool {
+						if e.Type == history.EventType_WorkflowTaskFailed {
+							failed = e.Attributes.(*history.WorkflowTaskFailedAttributes)
+							return false
+						}
+
+						return true
+					})
+
+					return failed != nil
+				}, time.Second*10, time.Millisecond*50)
+
+				require.Contains(t, failed.Error, "potential deadlock detected")
+				require.Contains(t, failed.StackTrace, "coroutine 0:")
+				require.Contains(t, failed.StackTrace, "e2e.go")
+
+				// Retries don't run the workflow again while the deadlocked code is still blocked
+				time.Sleep(time.Millisecond * 500)
+				require.Equal(t, int32(1), runs.Load())
+
+				// Unblock the workflow, the retried task completes it
+				deadlocked.Store(false)
+				close(ch)
+
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, 42, r)
+			},
+		},
+		{
+			name: "StackTrace_Query",
+			f: func(t *testing.T, ctx context.Context, c client.Client, w worker.Worker, b TestBackend) {
+				wf := func(ctx workflow.Context) (int, error) {
+					done := workflow.NewChannel[int]()
+					workflow.Go(ctx, func(ctx workflow.Context) {
+						done.Receive(ctx)
+					})
+
+					workflow.NewSignalChannel[int](ctx, "continue").Receive(ctx)
+					done.Close()
+
+					return 42, nil
+				}
+				register(t, ctx, w, []interface{}{wf}, nil)
+
+				instance := runWorkflow(t, ctx, c, wf)
+
+				var st string
+				require.Eventually(t, func() bool {
+					var err error
+					st, err = w.StackTrace(ctx, instance)
+					return err == nil
+				}, time.Second*10, time.Millisecond*50)
+
+				require.Contains(t, st, "coroutine 0:")
+				require.Contains(t, st, "coroutine 1:")
+				require.Contains(t, st, "Receive")
+				require.Contains(t, st, "e2e.go")
+
+				require.NoError(t, c.SignalWorkflow(ctx, instance.InstanceID, "continue", 1))
+
+				r, err := client.GetWorkflowResult[int](ctx, c, instance, time.Second*10)
+				require.NoError(t, err)
+				require.Equal(t, 42, r)
+
+				_, err = w.StackTrace(ctx, instance)
+				require.ErrorContains(t, err, "has finished")
+			},
+		},
 	}
 
 	run := func(suffix string, workerOptions *worker.Options) {
@@ -610,6 +1287,11 @@ func (*noopWorkflowExecutorCache) Get(ctx context.Context, instance *core.Workfl
 	return nil, false, nil
 }
 
+// Evict implements workflow.ExecutorCache
+func (*noopWorkflowExecutorCache) Evict(ctx context.Context, instance *core.WorkflowInstance) error {
+	return nil
+}
+
 // StartEviction implements workflow.ExecutorCache
 func (*noopWorkflowExecutorCache) StartEviction(ctx context.Context) {
 }
@@ -7,19 +7,24 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/bolt"
 	"github.com/cschleiden/go-workflows/backend/mysql"
+	"github.com/cschleiden/go-workflows/backend/postgres"
 	"github.com/cschleiden/go-workflows/backend/redis"
 	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/blobstore"
 	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/converter"
 	"github.com/cschleiden/go-workflows/worker"
 	redisv8 "github.com/redis/go-redis/v9"
 )
 
-var b = flag.String("backend", "redis", "Backend to use. Supported backends are:\n- redis\n- mysql\n- sqlite\n")
+var b = flag.String("backend", "redis", "Backend to use. Supported backends are:\n- redis\n- bolt\n- mysql\n- postgres\n- sqlite\n")
 var timeout = flag.Duration("timeout", time.Second*30, "Timeout for the benchmark run")
 var scenario = flag.String("scenario", "basic", "Scenario to run. Support scenarios are:\n- basic\n")
 var runs = flag.Int("runs", 1, "Number of root workflows to start")
@@ -30,6 +35,7 @@ var activities = flag.Int("activities", 2, "Number of activities to execute per
 var resultSize = flag.Int("resultsize", 100, "Size of activity result payload in bytes")
 var format = flag.String("format", "text", "Output format. Supported formats are:\n- text\n- csv\n")
 var cacheSize = flag.Int("cachesize", 128, "Size of the workflow executor cache")
+var blobThreshold = flag.Int("blobthreshold", 0, "Store payloads larger than this many bytes in a blob store on the local file system, 0 to disable")
 
 func main() {
 	flag.Parse()
@@ -38,7 +44,18 @@ func main() {
 	defer cancel()
 
 	mm := newMemMetrics()
-	ba := getBackend(*b, backend.WithLogger(&nullLogger{}), backend.WithMetrics(mm))
+	opts := []backend.BackendOption{backend.WithLogger(&nullLogger{}), backend.WithMetrics(mm)}
+	if *blobThreshold > 0 {
+		store, err := blobstore.NewFileSystemStore(filepath.Join(os.TempDir(), "go-workflows-bench-blobs"))
+		if err != nil {
+			panic(err)
+		}
+
+		opts = append(opts, backend.WithConverter(
+			converter.NewCodecConverter(converter.DefaultConverter, blobstore.NewCodec(store, *blobThreshold))))
+	}
+
+	ba := getBackend(*b, opts...)
 
 	wo := worker.DefaultWorkerOptions
 	wo.WorkflowExecutorCacheSize = *cacheSize
@@ -109,6 +126,16 @@ func getBackend(b string, opt ...backend.BackendOption) backend.Backend {
 
 		return sqlite.NewSqliteBackend("bench.sqlite", opt...)
 
+	case "bolt":
+		os.Remove("bench.db")
+
+		b, err := bolt.NewBoltBackend("bench.db", bolt.WithBackendOptions(opt...))
+		if err != nil {
+			panic(err)
+		}
+
+		return b
+
 	case "mysql":
 		db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@/?parseTime=true&interpolateParams=true", "root", "root"))
 		if err != nil {
@@ -129,6 +156,26 @@ func getBackend(b string, opt ...backend.BackendOption) backend.Backend {
 
 		return mysql.NewMysqlBackend("localhost", 3306, "root", "root", "bench", opt...)
 
+	case "postgres":
+		db, err := sql.Open("postgres", "host=localhost port=5432 user=postgres password=root dbname=postgres sslmode=disable")
+		if err != nil {
+			panic(err)
+		}
+
+		if _, err := db.Exec("DROP DATABASE IF EXISTS bench WITH (FORCE)"); err != nil {
+			panic(fmt.Errorf("dropping database: %w", err))
+		}
+
+		if _, err := db.Exec("CREATE DATABASE bench"); err != nil {
+			panic(fmt.Errorf("creating database: %w", err))
+		}
+
+		if err := db.Close(); err != nil {
+			panic(err)
+		}
+
+		return postgres.NewPostgresBackend("localhost", 5432, "postgres", "root", "bench", postgres.WithBackendOptions(opt...))
+
 	case "redis":
 		rclient := redisv8.NewUniversalClient(&redisv8.UniversalOptions{
 			Addrs:        []string{"localhost:6379"},
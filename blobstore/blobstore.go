@@ -0,0 +1,142 @@
+// Package blobstore offloads large payloads to external storage and keeps only a reference to them in the history
+// of workflow instances.
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/converter"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// ErrNotFound is returned by blob stores if the requested blob does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores payloads. Implement it to use object storage like S3, keys are slash separated paths.
+type BlobStore interface {
+	// Put stores data under the given key, overwriting any existing blob
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Get returns the blob stored under the given key, or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Exists reports whether a blob is stored under the given key
+	Exists(ctx context.Context, key string) (bool, error)
+
+	// DeletePrefix deletes all blobs whose keys start with the given prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// referenceMagic marks payloads that have been offloaded. Serialized JSON never starts with a zero byte.
+var referenceMagic = []byte{0x00, 'B', 'L', 'B'}
+
+type codec struct {
+	ctx       context.Context
+	store     BlobStore
+	threshold int
+	prefix    string
+}
+
+var _ converter.InstanceCodec = (*codec)(nil)
+
+// NewCodec returns a payload codec that stores payloads larger than threshold bytes in the given store and replaces
+// them with a reference. Blobs are stored per workflow instance, payloads recorded in the histories of several
+// instances, like the inputs and results of sub-workflows or the arguments of signals, are stored for each of them.
+//
+// Blobs are named after the hash of the serialized payload before any other codecs are applied, so that replaying a
+// workflow does not store payloads again even if they are encrypted with a random nonce. Names reveal whether two
+// payloads of an instance are equal.
+//
+// Pass the codec last to converter.NewCodecConverter, so that payloads are compressed and encrypted before they are
+// stored.
+func NewCodec(store BlobStore, threshold int) converter.PayloadCodec {
+	return &codec{
+		ctx:       context.Background(),
+		store:     store,
+		threshold: threshold,
+	}
+}
+
+func (c *codec) ForInstance(ctx context.Context, instance *workflow.Instance) converter.PayloadCodec {
+	return &codec{
+		ctx:       ctx,
+		store:     c.store,
+		threshold: c.threshold,
+		prefix:    instancePrefix(instance),
+	}
+}
+
+func (c *codec) Encode(p converter.Payload) (converter.Payload, error) {
+	return c.EncodePlaintext(p, p)
+}
+
+// EncodePlaintext stores the given payload if it exceeds the threshold, naming it after the given serialized payload
+// it has been encoded from by earlier codecs
+func (c *codec) EncodePlaintext(plaintext, p converter.Payload) (converter.Payload, error) {
+	if len(p) <= c.threshold {
+		return p, nil
+	}
+
+	hash := sha256.Sum256(plaintext)
+	name := hex.EncodeToString(hash[:])
+	key := c.prefix + name
+
+	exists, err := c.store.Exists(c.ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("checking for stored payload: %w", err)
+	}
+
+	if !exists {
+		if err := c.store.Put(c.ctx, key, p); err != nil {
+			return nil, fmt.Errorf("storing payload: %w", err)
+		}
+	}
+
+	// References are relative to the instance, every instance recording the payload has its own copy
+	return append(append([]byte{}, referenceMagic...), name...), nil
+}
+
+func (c *codec) Decode(p converter.Payload) (converter.Payload, error) {
+	if !bytes.HasPrefix(p, referenceMagic) {
+		return p, nil
+	}
+
+	// References written by earlier versions contain the full key
+	key := string(p[len(referenceMagic):])
+	if !strings.Contains(key, "/") {
+		key = c.prefix + key
+	}
+
+	data, err := c.store.Get(c.ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("loading payload %s: %w", key, err)
+	}
+
+	return data, nil
+}
+
+// DeleteWorkflowInstanceBlobs deletes the blobs stored for the given workflow instance. Call it when removing an
+// instance, or register RemovalHook with the backend to do so automatically.
+func DeleteWorkflowInstanceBlobs(ctx context.Context, store BlobStore, instance *workflow.Instance) error {
+	return store.DeletePrefix(ctx, instancePrefix(instance))
+}
+
+// RemovalHook returns a backend removal hook that deletes the blobs of removed workflow instances
+func RemovalHook(store BlobStore) backend.RemovalHook {
+	return func(ctx context.Context, instance *workflow.Instance) error {
+		return DeleteWorkflowInstanceBlobs(ctx, store, instance)
+	}
+}
+
+func instancePrefix(instance *workflow.Instance) string {
+	// Escape dots as well, so that instance ids cannot be interpreted as relative paths
+	return strings.ReplaceAll(url.PathEscape(instance.InstanceID), ".", "%2E") + "/"
+}
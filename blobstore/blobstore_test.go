@@ -0,0 +1,253 @@
+package blobstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/converter"
+	ic "github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/tester"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Codec(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileSystemStore(dir)
+	require.NoError(t, err)
+
+	instance := core.NewWorkflowInstance("instance/1", "execution")
+	cv := ic.ForInstance(context.Background(), converter.NewCodecConverter(converter.DefaultConverter, NewCodec(store, 16)), instance)
+
+	// Small payloads are kept inline
+	p, err := cv.To("small")
+	require.NoError(t, err)
+	require.Equal(t, `"small"`, string(p))
+
+	large := strings.Repeat("x", 100)
+	p, err = cv.To(large)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(p), "\x00BLB"), string(p))
+
+	entries, err := os.ReadDir(filepath.Join(dir, "instance%2F1"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// Storing the same payload again results in the same reference
+	p2, err := cv.To(large)
+	require.NoError(t, err)
+	require.Equal(t, p, p2)
+
+	var r string
+	require.NoError(t, cv.From(p, &r))
+	require.Equal(t, large, r)
+
+	require.NoError(t, DeleteWorkflowInstanceBlobs(context.Background(), store, instance))
+	require.ErrorIs(t, cv.From(p, &r), ErrNotFound)
+
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func Test_FileSystemStore_InvalidKeys(t *testing.T) {
+	store, err := NewFileSystemStore(t.TempDir())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	for _, key := range []string{"", ".", "..", "../outside", "/absolute"} {
+		require.Error(t, store.Put(ctx, key, []byte("data")), key)
+	}
+
+	// Instance ids are escaped, so they cannot escape the directory
+	require.Equal(t, "%2E%2E/", instancePrefix(core.NewWorkflowInstance("..", "")))
+}
+
+func Test_FileSystemStore_DeletePrefix(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileSystemStore(dir)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "a/1", []byte("1")))
+	require.NoError(t, store.Put(ctx, "ab/2", []byte("2")))
+	require.NoError(t, store.Put(ctx, "b/3", []byte("3")))
+
+	require.NoError(t, store.DeletePrefix(ctx, "a"))
+
+	_, err = store.Get(ctx, "a/1")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = store.Get(ctx, "ab/2")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	data, err := store.Get(ctx, "b/3")
+	require.NoError(t, err)
+	require.Equal(t, "3", string(data))
+
+	_, err = os.Stat(filepath.Join(dir, "b", "3"))
+	require.NoError(t, err)
+}
+
+func Test_Codec_Workflow(t *testing.T) {
+	store, err := NewFileSystemStore(t.TempDir())
+	require.NoError(t, err)
+
+	activity := func(ctx context.Context, s string) (string, error) {
+		return strings.ToUpper(s), nil
+	}
+
+	wf := func(ctx workflow.Context, s string) (int, error) {
+		r, err := workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, activity, s+s).Get(ctx)
+		return len(r), err
+	}
+
+	wft := tester.NewWorkflowTester[int](wf, tester.WithConverter(
+		converter.NewCodecConverter(converter.DefaultConverter, NewCodec(store, 64)),
+	))
+	wft.Registry().RegisterActivity(activity)
+
+	wft.Execute(strings.Repeat("x", 100))
+
+	require.True(t, wft.WorkflowFinished())
+	r, werr := wft.WorkflowResult()
+	require.Empty(t, werr)
+	require.Equal(t, 200, r)
+}
+
+func Test_Codec_SubWorkflow(t *testing.T) {
+	store, err := NewFileSystemStore(t.TempDir())
+	require.NoError(t, err)
+
+	subwf := func(ctx workflow.Context, s string) (string, error) {
+		return s + s, nil
+	}
+
+	wf := func(ctx workflow.Context, s string) (int, error) {
+		r, err := workflow.CreateSubWorkflowInstance[string](ctx, workflow.DefaultSubWorkflowOptions, subwf, s).Get(ctx)
+		return len(r), err
+	}
+
+	wft := tester.NewWorkflowTester[int](wf, tester.WithConverter(
+		converter.NewCodecConverter(converter.DefaultConverter, NewCodec(store, 64)),
+	))
+	wft.Registry().RegisterWorkflow(subwf)
+
+	wft.Execute(strings.Repeat("x", 100))
+
+	require.True(t, wft.WorkflowFinished())
+	r, werr := wft.WorkflowResult()
+	require.Empty(t, werr)
+	require.Equal(t, 200, r)
+}
+
+type countingStore struct {
+	BlobStore
+
+	puts int
+}
+
+func (s *countingStore) Put(ctx context.Context, key string, data []byte) error {
+	s.puts++
+	return s.BlobStore.Put(ctx, key, data)
+}
+
+func Test_Codec_StoresBlobsOnce(t *testing.T) {
+	fs, err := NewFileSystemStore(t.TempDir())
+	require.NoError(t, err)
+
+	store := &countingStore{BlobStore: fs}
+	instance := core.NewWorkflowInstance("instance", "execution")
+	cv := ic.ForInstance(context.Background(), converter.NewCodecConverter(converter.DefaultConverter, NewCodec(store, 16)), instance)
+
+	large := strings.Repeat("x", 100)
+	for i := 0; i < 3; i++ {
+		_, err := cv.To(large)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, 1, store.puts)
+}
+
+func Test_Codec_Encrypted_StoresBlobsOnce(t *testing.T) {
+	fs, err := NewFileSystemStore(t.TempDir())
+	require.NoError(t, err)
+
+	aesCodec, err := converter.NewAESGCMCodec("key1", map[string][]byte{
+		"key1": []byte("0123456789abcdef"),
+	})
+	require.NoError(t, err)
+
+	store := &countingStore{BlobStore: fs}
+	instance := core.NewWorkflowInstance("instance", "execution")
+	cv := ic.ForInstance(context.Background(), converter.NewCodecConverter(converter.DefaultConverter, aesCodec, NewCodec(store, 16)), instance)
+
+	// Encryption uses a random nonce, blobs are still named after the serialized payload
+	large := strings.Repeat("x", 100)
+	p, err := cv.To(large)
+	require.NoError(t, err)
+
+	p2, err := cv.To(large)
+	require.NoError(t, err)
+	require.Equal(t, p, p2)
+	require.Equal(t, 1, store.puts)
+
+	var r string
+	require.NoError(t, cv.From(p, &r))
+	require.Equal(t, large, r)
+}
+
+func Test_Codec_SharedPayloads(t *testing.T) {
+	store, err := NewFileSystemStore(t.TempDir())
+	require.NoError(t, err)
+
+	cv := converter.NewCodecConverter(converter.DefaultConverter, NewCodec(store, 16))
+	parent := core.NewWorkflowInstance("parent", "execution")
+	child := core.NewSubWorkflowInstance("child", "execution", "parent", 1)
+
+	// Sub-workflow inputs are recorded in the histories of the parent and the sub-workflow
+	p, err := ic.SharedWith(ic.NewTaskConverter(cv, parent), child).To(strings.Repeat("x", 100))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, DeleteWorkflowInstanceBlobs(ctx, store, parent))
+
+	var r string
+	require.NoError(t, ic.ForInstance(ctx, cv, child).From(p, &r))
+	require.Equal(t, strings.Repeat("x", 100), r)
+
+	require.ErrorIs(t, ic.ForInstance(ctx, cv, parent).From(p, &r), ErrNotFound)
+}
+
+func Test_Codec_AbsoluteReference(t *testing.T) {
+	store, err := NewFileSystemStore(t.TempDir())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "other/blob", []byte(`"value"`)))
+
+	cv := ic.ForInstance(ctx, converter.NewCodecConverter(converter.DefaultConverter, NewCodec(store, 16)), core.NewWorkflowInstance("instance", ""))
+
+	var r string
+	require.NoError(t, cv.From(append(append([]byte{}, referenceMagic...), "other/blob"...), &r))
+	require.Equal(t, "value", r)
+}
+
+func Test_Codec_Context(t *testing.T) {
+	store, err := NewFileSystemStore(t.TempDir())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	instance := core.NewWorkflowInstance("instance", "execution")
+	cv := ic.ForInstance(ctx, converter.NewCodecConverter(converter.DefaultConverter, NewCodec(store, 16)), instance)
+
+	_, err = cv.To(strings.Repeat("x", 100))
+	require.ErrorIs(t, err, context.Canceled)
+}
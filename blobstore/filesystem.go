@@ -0,0 +1,140 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type fileSystemStore struct {
+	dir string
+}
+
+var _ BlobStore = (*fileSystemStore)(nil)
+
+// NewFileSystemStore returns a blob store that stores blobs as files in the given directory
+func NewFileSystemStore(dir string) (BlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating blob directory: %w", err)
+	}
+
+	return &fileSystemStore{dir}, nil
+}
+
+func (s *fileSystemStore) Put(ctx context.Context, key string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so readers never see partially written blobs
+	f, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func (s *fileSystemStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return data, err
+}
+
+func (s *fileSystemStore) Exists(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *fileSystemStore) DeletePrefix(ctx context.Context, prefix string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Prefixes ending in a slash are directories and can be removed at once
+	if strings.HasSuffix(prefix, "/") {
+		path, err := s.path(strings.TrimSuffix(prefix, "/"))
+		if err != nil {
+			return err
+		}
+
+		return os.RemoveAll(path)
+	}
+
+	return filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(filepath.ToSlash(rel), prefix) {
+			return os.Remove(path)
+		}
+
+		return nil
+	})
+}
+
+func (s *fileSystemStore) path(key string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(rel) || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	return filepath.Join(s.dir, rel), nil
+}
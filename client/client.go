@@ -10,10 +10,14 @@ import (
 	"github.com/cenkalti/backoff/v4"
 	"github.com/cschleiden/go-workflows/backend"
 	a "github.com/cschleiden/go-workflows/internal/args"
+	"github.com/cschleiden/go-workflows/internal/contextpropagation"
+	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/internal/notify"
+	"github.com/cschleiden/go-workflows/internal/retention"
 	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/metrics"
 	"github.com/cschleiden/go-workflows/workflow"
@@ -32,26 +36,183 @@ type WorkflowInstanceOptions struct {
 	// Metadata *core.WorkflowInstanceMetadata
 }
 
+type (
+	ListWorkflowInstancesOptions = backend.ListWorkflowInstancesOptions
+	ListWorkflowInstancesResult  = backend.ListWorkflowInstancesResult
+	WorkflowInstanceInfo         = backend.WorkflowInstanceInfo
+	WorkflowInstanceDescription  = backend.WorkflowInstanceDescription
+	HistoryEvent                 = history.Event
+)
+
 type Client interface {
 	CreateWorkflowInstance(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (*workflow.Instance, error)
 
 	CancelWorkflowInstance(ctx context.Context, instance *workflow.Instance) error
 
+	// WaitForWorkflowInstance waits for the given instance to finish. Backends implementing
+	// backend.CompletionSubscriber notify the client when the instance finishes, for other backends the state of the
+	// instance is polled.
 	WaitForWorkflowInstance(ctx context.Context, instance *workflow.Instance, timeout time.Duration) error
 
+	// GetWorkflowInstanceResult decodes the result of the given finished workflow instance into result, which needs
+	// to be a pointer. If the workflow failed, its error is returned. Use GetWorkflowResult to wait for the instance
+	// and get a typed result.
+	GetWorkflowInstanceResult(ctx context.Context, instance *workflow.Instance, result interface{}) error
+
 	SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}) error
 }
 
+// InstanceManager is an optional extension of Client for inspecting and removing workflow instances. Clients returned
+// by New implement it, use the package level functions like ListWorkflowInstances to call it. Interceptors implement
+// it to intercept these calls, too.
+type InstanceManager interface {
+	// GetWorkflowInstanceHistory returns the full history of the given workflow instance. The history of removed
+	// instances is read from the archiver configured for the backend.
+	GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance) ([]*HistoryEvent, error)
+
+	// ListWorkflowInstances returns a page of the workflow instances matching the given options, most recently
+	// created instances first. Pass the NextCursor of the result in the options to get the next page.
+	ListWorkflowInstances(ctx context.Context, options ListWorkflowInstancesOptions) (*ListWorkflowInstancesResult, error)
+
+	// DescribeWorkflowInstance returns the status of the given workflow instance including pending activities,
+	// timers, sub-workflows, and signals. Removed instances are described from the archiver configured for the
+	// backend.
+	DescribeWorkflowInstance(ctx context.Context, instance *workflow.Instance) (*WorkflowInstanceDescription, error)
+
+	// WatchWorkflowInstanceHistory returns a channel of the history events of the given instance with a sequence id
+	// greater than fromSequenceID, as they are committed. Pass 0 to receive the full history. The channel is closed
+	// once the instance has finished and all its events have been delivered, when ctx is canceled, or when the
+	// instance is removed. Errors reading the history are retried until then.
+	WatchWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, fromSequenceID int64) (<-chan *HistoryEvent, error)
+
+	// RemoveWorkflowInstance removes the given finished workflow instance together with its history and calls the
+	// removal hooks configured for the backend. It returns backend.ErrInstanceNotFinished if the instance is still
+	// running.
+	RemoveWorkflowInstance(ctx context.Context, instance *workflow.Instance) error
+}
+
+// ErrInstanceManagementNotSupported is returned by the instance management functions for clients that don't implement
+// InstanceManager
+var ErrInstanceManagementNotSupported = errors.New("client does not support managing workflow instances")
+
 type client struct {
 	backend backend.Backend
 	clock   clock.Clock
 }
 
-func New(backend backend.Backend) Client {
-	return &client{
+var _ InstanceManager = (*client)(nil)
+
+// Interceptor wraps a client to add behavior around its methods, like auditing or authorization. Embed next in a
+// struct to only override some of the methods.
+type Interceptor func(next Client) Client
+
+type options struct {
+	interceptors []Interceptor
+}
+
+type Option func(*options)
+
+// WithInterceptors wraps the client with the given interceptors. Interceptors are called in the order they are
+// passed, the first interceptor is the outermost one.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+func New(backend backend.Backend, opts ...Option) Client {
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	base := &client{
 		backend: backend,
 		clock:   clock.New(),
 	}
+
+	var c Client = base
+	for i := len(options.interceptors) - 1; i >= 0; i-- {
+		c = options.interceptors[i](c)
+	}
+
+	// Keep instance management available if the outermost interceptor doesn't intercept it
+	if _, ok := c.(InstanceManager); !ok {
+		c = &managedClient{Client: c, InstanceManager: base}
+	}
+
+	return c
+}
+
+type managedClient struct {
+	Client
+	InstanceManager
+}
+
+func instanceManager(c Client) (InstanceManager, error) {
+	m, ok := c.(InstanceManager)
+	if !ok {
+		return nil, ErrInstanceManagementNotSupported
+	}
+
+	return m, nil
+}
+
+// GetWorkflowInstanceHistory returns the full history of the given workflow instance. The history of removed instances
+// is read from the archiver configured for the backend.
+func GetWorkflowInstanceHistory(ctx context.Context, c Client, instance *workflow.Instance) ([]*HistoryEvent, error) {
+	m, err := instanceManager(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.GetWorkflowInstanceHistory(ctx, instance)
+}
+
+// ListWorkflowInstances returns a page of the workflow instances matching the given options, most recently created
+// instances first. Pass the NextCursor of the result in the options to get the next page.
+func ListWorkflowInstances(ctx context.Context, c Client, options ListWorkflowInstancesOptions) (*ListWorkflowInstancesResult, error) {
+	m, err := instanceManager(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.ListWorkflowInstances(ctx, options)
+}
+
+// DescribeWorkflowInstance returns the status of the given workflow instance including pending activities, timers,
+// sub-workflows, and signals. Removed instances are described from the archiver configured for the backend.
+func DescribeWorkflowInstance(ctx context.Context, c Client, instance *workflow.Instance) (*WorkflowInstanceDescription, error) {
+	m, err := instanceManager(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.DescribeWorkflowInstance(ctx, instance)
+}
+
+// WatchWorkflowInstanceHistory returns a channel of the history events of the given instance with a sequence id
+// greater than fromSequenceID, as they are committed. Pass 0 to receive the full history. The channel is closed once
+// the instance has finished and all its events have been delivered, when ctx is canceled, or when the instance is
+// removed.
+func WatchWorkflowInstanceHistory(ctx context.Context, c Client, instance *workflow.Instance, fromSequenceID int64) (<-chan *HistoryEvent, error) {
+	m, err := instanceManager(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.WatchWorkflowInstanceHistory(ctx, instance, fromSequenceID)
+}
+
+// RemoveWorkflowInstance removes the given finished workflow instance together with its history and calls the removal
+// hooks configured for the backend. It returns backend.ErrInstanceNotFinished if the instance is still running.
+func RemoveWorkflowInstance(ctx context.Context, c Client, instance *workflow.Instance) error {
+	m, err := instanceManager(c)
+	if err != nil {
+		return err
+	}
+
+	return m.RemoveWorkflowInstance(ctx, instance)
 }
 
 func (c *client) CreateWorkflowInstance(ctx context.Context, options WorkflowInstanceOptions, wf workflow.Workflow, args ...interface{}) (*workflow.Instance, error) {
@@ -60,12 +221,12 @@ func (c *client) CreateWorkflowInstance(ctx context.Context, options WorkflowIns
 		return nil, err
 	}
 
-	inputs, err := a.ArgsToInputs(c.backend.Converter(), args...)
+	wfi := core.NewWorkflowInstance(options.InstanceID, uuid.NewString())
+
+	inputs, err := a.ArgsToInputs(converter.ForInstance(ctx, c.backend.Converter(), wfi), args...)
 	if err != nil {
 		return nil, fmt.Errorf("converting arguments: %w", err)
 	}
-
-	wfi := core.NewWorkflowInstance(options.InstanceID, uuid.NewString())
 	metadata := &workflow.Metadata{}
 
 	workflowName := fn.Name(wf)
@@ -79,6 +240,10 @@ func (c *client) CreateWorkflowInstance(ctx context.Context, options WorkflowIns
 
 	tracing.MarshalSpan(sctx, metadata)
 
+	if err := contextpropagation.Inject(ctx, c.backend.ContextPropagators(), metadata); err != nil {
+		return nil, err
+	}
+
 	startedEvent := history.NewPendingEvent(
 		c.clock.Now(),
 		history.EventType_WorkflowExecutionStarted,
@@ -105,7 +270,8 @@ func (c *client) CancelWorkflowInstance(ctx context.Context, instance *workflow.
 }
 
 func (c *client) SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}) error {
-	input, err := c.backend.Converter().To(arg)
+	cv := converter.ForInstance(ctx, c.backend.Converter(), core.NewWorkflowInstance(instanceID, ""))
+	input, err := cv.To(arg)
 	if err != nil {
 		return fmt.Errorf("converting arguments: %w", err)
 	}
@@ -129,6 +295,77 @@ func (c *client) SignalWorkflow(ctx context.Context, instanceID string, name str
 	return nil
 }
 
+func (c *client) ListWorkflowInstances(ctx context.Context, options ListWorkflowInstancesOptions) (*ListWorkflowInstancesResult, error) {
+	if options.Query != nil {
+		if err := options.Query.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid query: %w", err)
+		}
+	}
+
+	if options.PageSize < 0 {
+		return nil, errors.New("page size cannot be negative")
+	}
+
+	return c.backend.ListWorkflowInstances(ctx, &options)
+}
+
+func (c *client) DescribeWorkflowInstance(ctx context.Context, instance *workflow.Instance) (*WorkflowInstanceDescription, error) {
+	d, err := c.backend.DescribeWorkflowInstance(ctx, instance)
+	if err != nil {
+		if errors.Is(err, backend.ErrInstanceNotFound) {
+			a, aerr := c.getArchivedWorkflowInstance(ctx, instance)
+			if aerr != nil {
+				return nil, aerr
+			}
+
+			if a == nil {
+				return nil, err
+			}
+
+			return backend.NewWorkflowInstanceDescription(a.Instance, a.History, nil, c.clock.Now()), nil
+		}
+
+		return nil, fmt.Errorf("describing workflow instance: %w", err)
+	}
+
+	return d, nil
+}
+
+func (c *client) WatchWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance, fromSequenceID int64) (<-chan *HistoryEvent, error) {
+	var events <-chan *history.Event
+	var err error
+
+	if hw, ok := c.backend.(backend.HistoryWatcher); ok {
+		events, err = hw.WatchWorkflowInstanceHistory(ctx, instance, fromSequenceID)
+	} else {
+		events, err = notify.WatchHistory(ctx, c.backend, nil, instance, fromSequenceID)
+	}
+
+	if err != nil {
+		if errors.Is(err, backend.ErrInstanceNotFound) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("watching workflow history: %w", err)
+	}
+
+	return events, nil
+}
+
+func (c *client) RemoveWorkflowInstance(ctx context.Context, instance *workflow.Instance) error {
+	if err := retention.RemoveWorkflowInstance(ctx, c.backend, instance); err != nil {
+		if errors.Is(err, backend.ErrInstanceNotFound) || errors.Is(err, backend.ErrInstanceNotFinished) {
+			return err
+		}
+
+		return fmt.Errorf("removing workflow instance: %w", err)
+	}
+
+	c.backend.Logger().Debug("Removed workflow instance", "instance_id", instance.InstanceID, "execution_id", instance.ExecutionID)
+
+	return nil
+}
+
 func (c *client) WaitForWorkflowInstance(ctx context.Context, instance *workflow.Instance, timeout time.Duration) error {
 	if timeout == 0 {
 		timeout = time.Second * 20
@@ -143,38 +380,58 @@ func (c *client) WaitForWorkflowInstance(ctx context.Context, instance *workflow
 		Stop:                backoff.Stop,
 		Clock:               c.clock,
 	}
+
+	// Subscribe before checking the state for the first time, to not miss the instance finishing in between. The
+	// channel stays nil and never fires for backends without support for notifications.
+	var finished <-chan struct{}
+	if cs, ok := c.backend.(backend.CompletionSubscriber); ok {
+		f, cancel, err := cs.SubscribeWorkflowInstanceCompletion(ctx, instance)
+		if err != nil {
+			return fmt.Errorf("subscribing to workflow completion: %w", err)
+		}
+		defer cancel()
+
+		finished = f
+
+		// Notifications are best-effort, keep polling at a lower rate
+		b.InitialInterval = time.Second
+		b.MaxInterval = time.Second * 5
+	}
+
 	b.Reset()
 
 	ticker := backoff.NewTicker(&b)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		s, err := c.backend.GetWorkflowInstanceState(ctx, instance)
-		if err != nil {
-			return fmt.Errorf("getting workflow state: %w", err)
-		}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
 
-		if s == core.WorkflowInstanceStateFinished {
+		case <-finished:
 			return nil
-		}
-	}
 
-	return errors.New("workflow did not finish in specified timeout")
-}
+		case _, ok := <-ticker.C:
+			if !ok {
+				return errors.New("workflow did not finish in specified timeout")
+			}
 
-// GetWorkflowResult gets the workflow result for the given workflow result. It first waits for the workflow to finish or until
-// the given timeout has expired.
-func GetWorkflowResult[T any](ctx context.Context, c Client, instance *workflow.Instance, timeout time.Duration) (T, error) {
-	if err := c.WaitForWorkflowInstance(ctx, instance, timeout); err != nil {
-		return *new(T), fmt.Errorf("workflow did not finish in time: %w", err)
-	}
+			s, err := c.backend.GetWorkflowInstanceState(ctx, instance)
+			if err != nil {
+				return fmt.Errorf("getting workflow state: %w", err)
+			}
 
-	ic := c.(*client)
-	b := ic.backend
+			if s == core.WorkflowInstanceStateFinished {
+				return nil
+			}
+		}
+	}
+}
 
-	h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+func (c *client) GetWorkflowInstanceResult(ctx context.Context, instance *workflow.Instance, result interface{}) error {
+	h, err := c.GetWorkflowInstanceHistory(ctx, instance)
 	if err != nil {
-		return *new(T), fmt.Errorf("getting workflow history: %w", err)
+		return err
 	}
 
 	// Iterate over history backwards
@@ -184,23 +441,79 @@ func GetWorkflowResult[T any](ctx context.Context, c Client, instance *workflow.
 		case history.EventType_WorkflowExecutionFinished:
 			a := event.Attributes.(*history.ExecutionCompletedAttributes)
 			if a.Error != "" {
-				return *new(T), errors.New(a.Error)
+				return errors.New(a.Error)
 			}
 
-			var r T
-			if err := b.Converter().From(a.Result, &r); err != nil {
-				return *new(T), fmt.Errorf("converting result: %w", err)
+			if err := converter.ForInstance(ctx, c.backend.Converter(), instance).From(a.Result, result); err != nil {
+				return fmt.Errorf("converting result: %w", err)
 			}
 
-			return r, nil
+			return nil
 
 		case history.EventType_WorkflowExecutionCanceled:
-			return *new(T), ErrWorkflowCanceled
+			return ErrWorkflowCanceled
 
 		case history.EventType_WorkflowExecutionTerminated:
-			return *new(T), ErrWorkflowTerminated
+			return ErrWorkflowTerminated
 		}
 	}
 
-	return *new(T), errors.New("workflow finished, but could not find result event")
+	return errors.New("workflow finished, but could not find result event")
+}
+
+// GetWorkflowResult gets the workflow result for the given workflow result. It first waits for the workflow to finish or until
+// the given timeout has expired.
+func GetWorkflowResult[T any](ctx context.Context, c Client, instance *workflow.Instance, timeout time.Duration) (T, error) {
+	if err := c.WaitForWorkflowInstance(ctx, instance, timeout); err != nil {
+		return *new(T), fmt.Errorf("workflow did not finish in time: %w", err)
+	}
+
+	var r T
+	if err := c.GetWorkflowInstanceResult(ctx, instance, &r); err != nil {
+		return *new(T), err
+	}
+
+	return r, nil
+}
+
+func (c *client) GetWorkflowInstanceHistory(ctx context.Context, instance *workflow.Instance) ([]*HistoryEvent, error) {
+	h, err := c.backend.GetWorkflowInstanceHistory(ctx, instance, nil)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow history: %w", err)
+	}
+
+	if len(h) > 0 {
+		return h, nil
+	}
+
+	a, err := c.getArchivedWorkflowInstance(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	if a != nil {
+		return a.History, nil
+	}
+
+	return h, nil
+}
+
+// getArchivedWorkflowInstance returns the archived execution of the given instance, or nil if there is no archiver
+// or the instance has not been archived
+func (c *client) getArchivedWorkflowInstance(ctx context.Context, instance *workflow.Instance) (*backend.ArchivedWorkflowInstance, error) {
+	archiver := c.backend.Options().Archiver
+	if archiver == nil {
+		return nil, nil
+	}
+
+	a, err := archiver.GetArchivedWorkflowInstance(ctx, instance.InstanceID, instance.ExecutionID)
+	if err != nil {
+		if errors.Is(err, backend.ErrInstanceNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("reading archived workflow instance: %w", err)
+	}
+
+	return a, nil
 }
@@ -12,10 +12,12 @@ import (
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/logger"
+	"github.com/cschleiden/go-workflows/internal/metrics"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func Test_Client_CreateWorkflowInstance_ParamMismatch(t *testing.T) {
@@ -39,6 +41,57 @@ func Test_Client_CreateWorkflowInstance_ParamMismatch(t *testing.T) {
 	b.AssertExpectations(t)
 }
 
+type requestIDKey struct{}
+
+type requestIDPropagator struct{}
+
+func (*requestIDPropagator) Inject(ctx context.Context, metadata *workflow.Metadata) error {
+	metadata.Set("request_id", ctx.Value(requestIDKey{}).(string))
+	return nil
+}
+
+func (*requestIDPropagator) Extract(ctx context.Context, metadata *workflow.Metadata) (context.Context, error) {
+	return context.WithValue(ctx, requestIDKey{}, metadata.Get("request_id")), nil
+}
+
+func (*requestIDPropagator) InjectFromWorkflow(ctx workflow.Context, metadata *workflow.Metadata) error {
+	return nil
+}
+
+func (*requestIDPropagator) ExtractToWorkflow(ctx workflow.Context, metadata *workflow.Metadata) (workflow.Context, error) {
+	return ctx, nil
+}
+
+func Test_Client_CreateWorkflowInstance_ContextPropagation(t *testing.T) {
+	wf := func(workflow.Context) error {
+		return nil
+	}
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "request1")
+
+	b := &backend.MockBackend{}
+	b.On("Logger").Return(logger.NewDefaultLogger())
+	b.On("Metrics").Return(metrics.NewNoopMetricsClient())
+	b.On("Converter").Return(converter.DefaultConverter)
+	b.On("Tracer").Return(trace.NewNoopTracerProvider().Tracer("test"))
+	b.On("ContextPropagators").Return([]workflow.ContextPropagator{&requestIDPropagator{}})
+	b.On("CreateWorkflowInstance", mock.Anything, mock.Anything, mock.MatchedBy(func(event *history.Event) bool {
+		a := event.Attributes.(*history.ExecutionStartedAttributes)
+		return a.Metadata.Get("request_id") == "request1"
+	})).Return(nil)
+
+	c := &client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	_, err := c.CreateWorkflowInstance(ctx, WorkflowInstanceOptions{
+		InstanceID: "id",
+	}, wf)
+	require.NoError(t, err)
+	b.AssertExpectations(t)
+}
+
 func Test_Client_GetWorkflowResultTimeout(t *testing.T) {
 	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
 
@@ -93,6 +146,137 @@ func Test_Client_GetWorkflowResultSuccess(t *testing.T) {
 	b.AssertExpectations(t)
 }
 
+type testArchiver struct {
+	archived map[string]*backend.ArchivedWorkflowInstance
+}
+
+func (a *testArchiver) ArchiveWorkflowInstance(ctx context.Context, instance *backend.ArchivedWorkflowInstance) error {
+	a.archived[instance.Instance.Instance.InstanceID] = instance
+	return nil
+}
+
+func (a *testArchiver) GetArchivedWorkflowInstance(ctx context.Context, instanceID, executionID string) (*backend.ArchivedWorkflowInstance, error) {
+	if instance, ok := a.archived[instanceID]; ok {
+		return instance, nil
+	}
+
+	return nil, backend.ErrInstanceNotFound
+}
+
+func Test_Client_GetWorkflowInstanceResult_Archived(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	ctx := context.Background()
+
+	r, _ := converter.DefaultConverter.To(42)
+
+	archiver := &testArchiver{archived: map[string]*backend.ArchivedWorkflowInstance{
+		instance.InstanceID: {
+			Instance: &backend.WorkflowInstanceInfo{
+				Instance: instance,
+				State:    core.WorkflowInstanceStateFinished,
+			},
+			History: []*history.Event{
+				history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+				history.NewHistoryEvent(2, time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{
+					Result: r,
+				}),
+			},
+		},
+	}}
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceHistory", mock.Anything, mock.Anything, (*int64)(nil)).Return([]*history.Event{}, nil)
+	b.On("DescribeWorkflowInstance", mock.Anything, mock.Anything).Return(nil, backend.ErrInstanceNotFound)
+	b.On("Options").Return(backend.Options{Archiver: archiver})
+	b.On("Converter").Return(converter.DefaultConverter)
+
+	c := &client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	var result int
+	require.NoError(t, c.GetWorkflowInstanceResult(ctx, instance, &result))
+	require.Equal(t, 42, result)
+
+	d, err := DescribeWorkflowInstance(ctx, c, instance)
+	require.NoError(t, err)
+	require.Equal(t, core.WorkflowInstanceStateFinished, d.State)
+
+	// Instances that are neither in the backend nor archived are not found
+	_, err = DescribeWorkflowInstance(ctx, c, core.NewWorkflowInstance(uuid.NewString(), "test"))
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	h, err := GetWorkflowInstanceHistory(ctx, c, core.NewWorkflowInstance(uuid.NewString(), "test"))
+	require.NoError(t, err)
+	require.Empty(t, h)
+}
+
+type subscriberBackend struct {
+	*backend.MockBackend
+
+	finished chan struct{}
+}
+
+func (b *subscriberBackend) SubscribeWorkflowInstanceCompletion(ctx context.Context, instance *workflow.Instance) (<-chan struct{}, func(), error) {
+	return b.finished, func() {}, nil
+}
+
+func Test_Client_WaitForWorkflowInstance_Subscriber(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	ctx := context.Background()
+
+	b := &subscriberBackend{
+		MockBackend: &backend.MockBackend{},
+		finished:    make(chan struct{}),
+	}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateActive, nil).Once().Run(func(args mock.Arguments) {
+		// Instance finishes after the first check, the client is notified without polling again
+		close(b.finished)
+	})
+
+	c := &client{
+		backend: b,
+		clock:   clock.New(),
+	}
+
+	require.NoError(t, c.WaitForWorkflowInstance(ctx, instance, time.Minute))
+	b.AssertExpectations(t)
+}
+
+type wrappedClient struct {
+	Client
+}
+
+func Test_Client_GetWorkflowResult_Wrapped(t *testing.T) {
+	instance := core.NewWorkflowInstance(uuid.NewString(), "test")
+
+	ctx := context.Background()
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateFinished, nil)
+	b.On("GetWorkflowInstanceHistory", mock.Anything, instance, (*int64)(nil)).Return([]*history.Event{
+		history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{}),
+		history.NewHistoryEvent(2, time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{
+			Error: "workflow failed",
+		}),
+	}, nil)
+
+	c := &wrappedClient{
+		Client: &client{
+			backend: b,
+			clock:   clock.New(),
+		},
+	}
+
+	result, err := GetWorkflowResult[int](ctx, c, instance, 0)
+	require.Zero(t, result)
+	require.EqualError(t, err, "workflow failed")
+	b.AssertExpectations(t)
+}
+
 func Test_Client_SignalWorkflow(t *testing.T) {
 	instanceID := uuid.NewString()
 
@@ -145,3 +329,65 @@ func Test_Client_SignalWorkflow_WithArgs(t *testing.T) {
 	require.Nil(t, err)
 	b.AssertExpectations(t)
 }
+
+type recordingClient struct {
+	Client
+
+	name  string
+	calls *[]string
+}
+
+func (rc *recordingClient) SignalWorkflow(ctx context.Context, instanceID string, name string, arg interface{}) error {
+	*rc.calls = append(*rc.calls, rc.name)
+
+	return rc.Client.SignalWorkflow(ctx, instanceID, name, arg)
+}
+
+func Test_Client_Interceptors(t *testing.T) {
+	instanceID := uuid.NewString()
+
+	ctx := context.Background()
+
+	b := &backend.MockBackend{}
+	b.On("Logger").Return(logger.NewDefaultLogger())
+	b.On("Converter").Return(converter.DefaultConverter)
+	b.On("SignalWorkflow", ctx, instanceID, mock.Anything).Return(nil)
+
+	calls := []string{}
+	interceptor := func(name string) Interceptor {
+		return func(next Client) Client {
+			return &recordingClient{Client: next, name: name, calls: &calls}
+		}
+	}
+
+	c := New(b, WithInterceptors(interceptor("first"), interceptor("second")))
+
+	err := c.SignalWorkflow(ctx, instanceID, "test", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, calls)
+	b.AssertExpectations(t)
+}
+
+func Test_Client_InstanceManager(t *testing.T) {
+	ctx := context.Background()
+
+	b := &backend.MockBackend{}
+	b.On("ListWorkflowInstances", ctx, &backend.ListWorkflowInstancesOptions{PageSize: 1}).
+		Return(&backend.ListWorkflowInstancesResult{}, nil)
+
+	interceptor := func(next Client) Client {
+		return &wrappedClient{Client: next}
+	}
+
+	// Instance management is available through interceptors that don't intercept it
+	c := New(b, WithInterceptors(interceptor))
+
+	r, err := ListWorkflowInstances(ctx, c, ListWorkflowInstancesOptions{PageSize: 1})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	b.AssertExpectations(t)
+
+	// Other clients don't have to support it
+	_, err = ListWorkflowInstances(ctx, &wrappedClient{Client: c}, ListWorkflowInstancesOptions{})
+	require.ErrorIs(t, err, ErrInstanceManagementNotSupported)
+}
@@ -0,0 +1,84 @@
+// Command migrate applies pending schema migrations for the SQL backends, and indexes existing instances for the
+// redis backend. Use it together with backend.WithoutAutoMigration to upgrade the schema as a deployment step instead
+// of when workers start.
+//
+//	go run github.com/cschleiden/go-workflows/cmd/migrate -backend sqlite -path ./workflows.db
+//	go run github.com/cschleiden/go-workflows/cmd/migrate -backend mysql -host localhost -user root -password root -database go-workflows
+//	go run github.com/cschleiden/go-workflows/cmd/migrate -backend redis -host localhost -user "" -password RedisPassw0rd
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cschleiden/go-workflows/backend/mysql"
+	"github.com/cschleiden/go-workflows/backend/postgres"
+	"github.com/cschleiden/go-workflows/backend/redis"
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	redisv9 "github.com/redis/go-redis/v9"
+)
+
+func main() {
+	b := flag.String("backend", "", "backend to migrate: sqlite, mysql, postgres, or redis")
+	path := flag.String("path", "", "path of the sqlite database")
+	host := flag.String("host", "localhost", "database host")
+	port := flag.Int("port", 0, "database port, defaults to the default port of the backend")
+	user := flag.String("user", "root", "database user")
+	password := flag.String("password", "", "database password")
+	database := flag.String("database", "", "database name")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	var err error
+
+	switch *b {
+	case "sqlite":
+		if *path == "" {
+			log.Fatal("-path is required for sqlite")
+		}
+
+		err = sqlite.Migrate(ctx, *path)
+
+	case "mysql":
+		if *port == 0 {
+			*port = 3306
+		}
+
+		err = mysql.Migrate(ctx, *host, *port, *user, *password, *database)
+
+	case "postgres":
+		if *port == 0 {
+			*port = 5432
+		}
+
+		err = postgres.Migrate(ctx, *host, *port, *user, *password, *database)
+
+	case "redis":
+		if *port == 0 {
+			*port = 6379
+		}
+
+		client := redisv9.NewUniversalClient(&redisv9.UniversalOptions{
+			Addrs:    []string{fmt.Sprintf("%s:%d", *host, *port)},
+			Username: *user,
+			Password: *password,
+		})
+		defer client.Close()
+
+		err = redis.Migrate(ctx, client)
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("migrating %s database: %v", *b, err)
+	}
+
+	fmt.Println("Database schema is up to date")
+}
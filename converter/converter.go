@@ -0,0 +1,84 @@
+// Package converter provides the converters used to serialize inputs and results of workflows and activities, and
+// codecs to compress or encrypt the serialized payloads before they are persisted. Configure a converter using
+// backend.WithConverter, it is used by both the client and the worker.
+package converter
+
+import (
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+type (
+	Converter        = converter.Converter
+	PayloadConverter = converter.PayloadConverter
+	PayloadCodec     = converter.PayloadCodec
+	InstanceCodec    = converter.InstanceCodec
+	AESGCMOption     = converter.AESGCMOption
+	Payload          = payload.Payload
+)
+
+const (
+	EncodingJSON     = converter.EncodingJSON
+	EncodingProtobuf = converter.EncodingProtobuf
+)
+
+// DefaultConverter serializes values as JSON. Its payloads do not carry an encoding header, so they can be read by
+// any converter.
+var DefaultConverter = converter.DefaultConverter
+
+// NewCompositeConverter returns a converter that serializes each value with the first of the given converters that
+// can handle it, and records the encoding in the payload. Payloads are deserialized with the converter matching
+// their encoding, payloads without an encoding header, like those written by DefaultConverter, are treated as JSON.
+func NewCompositeConverter(converters ...PayloadConverter) Converter {
+	return converter.NewCompositeConverter(converters...)
+}
+
+// NewJSONPayloadConverter returns a payload converter serializing any value as JSON
+func NewJSONPayloadConverter() PayloadConverter {
+	return converter.NewJSONPayloadConverter()
+}
+
+// NewProtoPayloadConverter returns a payload converter serializing protocol buffer messages in the binary wire
+// format, and recording their message type
+func NewProtoPayloadConverter() PayloadConverter {
+	return converter.NewProtoPayloadConverter()
+}
+
+// NewProtoConverter returns a converter serializing protocol buffer messages in the binary wire format, and all
+// other values as JSON
+func NewProtoConverter() Converter {
+	return converter.NewProtoConverter()
+}
+
+// NewCodecConverter returns a converter that serializes values with the given converter and then encodes the
+// payloads with the given codecs. Codecs are applied in the order they are passed when encoding, and in reverse
+// order when decoding.
+//
+//	converter.NewCodecConverter(converter.DefaultConverter, gzipCodec, aesCodec)
+func NewCodecConverter(c Converter, codecs ...PayloadCodec) Converter {
+	return converter.NewCodecConverter(c, codecs...)
+}
+
+// NewChainCodec returns a codec that applies the given codecs in order when encoding, and in reverse order when
+// decoding
+func NewChainCodec(codecs ...PayloadCodec) PayloadCodec {
+	return converter.NewChainCodec(codecs...)
+}
+
+// NewGzipCodec returns a codec compressing payloads with gzip at the given compress/gzip level
+func NewGzipCodec(level int) (PayloadCodec, error) {
+	return converter.NewGzipCodec(level)
+}
+
+// NewAESGCMCodec returns a codec encrypting payloads using AES-GCM with the key identified by keyID. To rotate keys,
+// add a new key and make it the active key while keeping the previous keys to decrypt existing payloads. Payloads
+// that are not encrypted fail to decode, unless WithPlaintextPassthrough is passed.
+func NewAESGCMCodec(keyID string, keys map[string][]byte, opts ...AESGCMOption) (PayloadCodec, error) {
+	return converter.NewAESGCMCodec(keyID, keys, opts...)
+}
+
+// WithPlaintextPassthrough makes the AES-GCM codec decode payloads that are not encrypted unchanged. Use it while
+// migrating instances whose payloads have been written before encryption was configured.
+func WithPlaintextPassthrough() AESGCMOption {
+	return converter.WithPlaintextPassthrough()
+}
@@ -113,14 +113,24 @@ function eventColor(event: string): [Color, string] {
       return ["light", "primary"];
 
     case "SignalReceived":
+    case "SignalExternalWorkflowScheduled":
+    case "SignalExternalWorkflowCompleted":
+    case "SignalExternalWorkflowFailed":
+    case "RequestCancelExternalWorkflowScheduled":
+    case "RequestCancelExternalWorkflowCompleted":
+    case "RequestCancelExternalWorkflowFailed":
       return ["light", "dark"];
 
     case "SideEffectResult":
+    case "SearchAttributesUpserted":
       return ["dark", "secondary"];
 
     case "WorkflowTaskStarted":
       return ["dark", "light"];
 
+    case "WorkflowTaskFailed":
+      return ["light", "danger"];
+
     default:
       return ["dark", "info"];
   }
@@ -1,10 +1,11 @@
-import { Accordion, Alert, Card } from "react-bootstrap";
+import { Accordion, Alert, Badge, Card } from "react-bootstrap";
 import { Link, useParams } from "react-router-dom";
 import {
   ExecutionCompletedAttributes,
   ExecutionStartedAttributes,
   HistoryEvent,
   WorkflowInstanceInfo,
+  WorkflowTaskFailedAttributes,
 } from "./client";
 import {
   decodePayload,
@@ -62,8 +63,23 @@ function Instance() {
     wfError = finishedEvent.attributes.error;
   }
 
+  // Failed workflow tasks are retried, the instance does not make progress until a task succeeds. Only the first
+  // failure is recorded.
+  const lastEvent = instance.history[instance.history.length - 1];
+  const taskFailure =
+    lastEvent?.type === "WorkflowTaskFailed"
+      ? (lastEvent as HistoryEvent<WorkflowTaskFailedAttributes>)
+      : undefined;
+
   return (
     <div>
+      {taskFailure && (
+        <Alert variant="danger">
+          Workflow task failed and is being retried:{" "}
+          <code>{taskFailure.attributes.error}</code>
+        </Alert>
+      )}
+
       <div className="d-flex align-items-center">
         <h2>
           Workflow: <code>{workflowName}</code>
@@ -143,6 +159,12 @@ function Instance() {
                   )}
                 </div>
                 {event.type !== "WorkflowExecutionStarted" && <div className="flex-grow-1"><code>{event.attributes?.name}</code></div>}
+                {event.type === "ActivityFailed" &&
+                  event.attributes?.stack_trace && (
+                    <Badge bg="danger" className="me-3">
+                      panic
+                    </Badge>
+                  )}
                 <div>{event.timestamp}</div>
               </h5>
             </Accordion.Header>
@@ -176,6 +198,14 @@ function Instance() {
                     ]}
                   />
                 </dd>
+                {event.attributes?.stack_trace && (
+                  <>
+                    <dt>Stack Trace</dt>
+                    <dd>
+                      <Payload payloads={[event.attributes.stack_trace]} />
+                    </dd>
+                  </>
+                )}
               </dl>
             </Accordion.Body>
           </Accordion.Item>
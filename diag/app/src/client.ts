@@ -37,7 +37,53 @@ export interface ExecutionCompletedAttributes {
   error: string;
 }
 
+export interface WorkflowTaskFailedAttributes {
+  error: string;
+  stack_trace?: string;
+}
+
 export type WorkflowInstanceTree = WorkflowInstanceRef & {
   workflow_name: string;
   children: WorkflowInstanceTree[];
 };
+
+export interface WorkflowInstanceDescription {
+  instance: WorkflowInstance;
+  workflow_name?: string;
+  state: number;
+  created_at: string;
+  completed_at?: string;
+
+  pending_activities?: {
+    schedule_event_id: number;
+    name: string;
+    scheduled_at: string;
+    attempt: number;
+    last_failure?: string;
+    worker?: string;
+    locked_until?: string;
+  }[];
+
+  pending_timers?: {
+    schedule_event_id: number;
+    scheduled_at: string;
+    fire_at: string;
+  }[];
+
+  pending_sub_workflows?: {
+    schedule_event_id: number;
+    instance: WorkflowInstance;
+    name: string;
+    scheduled_at: string;
+    started: boolean;
+    cancellation_requested: boolean;
+  }[];
+
+  pending_signals?: {
+    name: string;
+    received_at: string;
+  }[];
+
+  last_workflow_task_at?: string;
+  last_workflow_task_worker?: string;
+}
@@ -0,0 +1,58 @@
+package diag
+
+import (
+	"context"
+	"errors"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/backend"
+)
+
+// getArchivedWorkflowInstance returns the archived execution of the instance with the given id that completed last, or
+// nil if the backend has no archiver or the instance has not been archived
+func getArchivedWorkflowInstance(ctx context.Context, b Backend, instanceID string) (*backend.ArchivedWorkflowInstance, error) {
+	archiver := b.Options().Archiver
+	if archiver == nil {
+		return nil, nil
+	}
+
+	a, err := archiver.GetArchivedWorkflowInstance(ctx, instanceID, "")
+	if err != nil {
+		if errors.Is(err, backend.ErrInstanceNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// describeWorkflowInstance describes the instance with the given id, or its archived execution that completed last if
+// it has been removed. It returns nil if the instance does not exist.
+func describeWorkflowInstance(ctx context.Context, b Backend, clock clock.Clock, instanceID string) (*backend.WorkflowInstanceDescription, error) {
+	instance, err := b.GetWorkflowInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance != nil {
+		return b.DescribeWorkflowInstance(ctx, instance.Instance)
+	}
+
+	archived, err := getArchivedWorkflowInstance(ctx, b, instanceID)
+	if err != nil || archived == nil {
+		return nil, err
+	}
+
+	return backend.NewWorkflowInstanceDescription(archived.Instance, archived.History, nil, clock.Now()), nil
+}
+
+func archivedInstanceRef(a *backend.ArchivedWorkflowInstance) *WorkflowInstanceRef {
+	return &WorkflowInstanceRef{
+		Instance:    a.Instance.Instance,
+		CreatedAt:   a.Instance.CreatedAt,
+		CompletedAt: a.Instance.CompletedAt,
+		State:       a.Instance.State,
+	}
+}
@@ -0,0 +1,135 @@
+package diag
+
+import (
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/converter"
+	ic "github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+type options struct {
+	codecs []converter.PayloadCodec
+	clock  clock.Clock
+}
+
+type Option func(*options)
+
+// WithPayloadCodecs decodes payloads in the returned history with the given codecs, so they can be displayed by the
+// web app. Pass the codecs in the same order as to converter.NewCodecConverter.
+func WithPayloadCodecs(codecs ...converter.PayloadCodec) Option {
+	return func(o *options) {
+		o.codecs = append(o.codecs, codecs...)
+	}
+}
+
+// newPayloadCodec returns a codec that undoes the given codecs and then decodes payloads for display. A chain decodes
+// payloads in reverse order, so the display codec comes first.
+func newPayloadCodec(codecs []converter.PayloadCodec) converter.PayloadCodec {
+	return converter.NewChainCodec(append([]converter.PayloadCodec{&displayCodec{}}, codecs...)...)
+}
+
+// displayCodec decodes payloads with an encoding header into JSON that can be displayed by the web app. Protocol
+// buffer messages are converted to JSON if their type is linked into the binary, otherwise they are left unchanged.
+type displayCodec struct{}
+
+func (*displayCodec) Encode(p payload.Payload) (payload.Payload, error) {
+	return p, nil
+}
+
+func (*displayCodec) Decode(p payload.Payload) (payload.Payload, error) {
+	info, data, err := ic.OpenEnvelope(p)
+	if err != nil {
+		return nil, err
+	}
+
+	switch info.Encoding {
+	case ic.EncodingJSON:
+		return data, nil
+
+	case ic.EncodingProtobuf:
+		mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(info.MessageType))
+		if err != nil {
+			return p, nil
+		}
+
+		m := mt.New().Interface()
+		if err := proto.Unmarshal(data, m); err != nil {
+			return p, nil
+		}
+
+		return protojson.Marshal(m)
+	}
+
+	return p, nil
+}
+
+// decodeAttributes returns a copy of the given event attributes with all payloads decoded
+func decodeAttributes(codec converter.PayloadCodec, attributes interface{}) (interface{}, error) {
+	var err error
+
+	switch a := attributes.(type) {
+	case *history.ExecutionStartedAttributes:
+		c := *a
+		c.Inputs, err = decodePayloads(codec, a.Inputs)
+		return &c, err
+
+	case *history.ExecutionCompletedAttributes:
+		c := *a
+		c.Result, err = codec.Decode(a.Result)
+		return &c, err
+
+	case *history.ActivityScheduledAttributes:
+		c := *a
+		c.Inputs, err = decodePayloads(codec, a.Inputs)
+		return &c, err
+
+	case *history.ActivityCompletedAttributes:
+		c := *a
+		c.Result, err = codec.Decode(a.Result)
+		return &c, err
+
+	case *history.SubWorkflowScheduledAttributes:
+		c := *a
+		c.Inputs, err = decodePayloads(codec, a.Inputs)
+		return &c, err
+
+	case *history.SubWorkflowCompletedAttributes:
+		c := *a
+		c.Result, err = codec.Decode(a.Result)
+		return &c, err
+
+	case *history.SideEffectResultAttributes:
+		c := *a
+		c.Result, err = codec.Decode(a.Result)
+		return &c, err
+
+	case *history.SignalReceivedAttributes:
+		c := *a
+		c.Arg, err = codec.Decode(a.Arg)
+		return &c, err
+
+	case *history.SignalExternalWorkflowScheduledAttributes:
+		c := *a
+		c.Arg, err = codec.Decode(a.Arg)
+		return &c, err
+	}
+
+	return attributes, nil
+}
+
+func decodePayloads(codec converter.PayloadCodec, payloads []payload.Payload) ([]payload.Payload, error) {
+	decoded := make([]payload.Payload, len(payloads))
+	for i, p := range payloads {
+		var err error
+		if decoded[i], err = codec.Decode(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return decoded, nil
+}
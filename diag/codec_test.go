@@ -0,0 +1,30 @@
+package diag
+
+import (
+	"testing"
+
+	"github.com/cschleiden/go-workflows/converter"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DecodeAttributes_Codecs(t *testing.T) {
+	gzip, err := converter.NewGzipCodec(-1)
+	require.NoError(t, err)
+
+	aes, err := converter.NewAESGCMCodec("key", map[string][]byte{"key": make([]byte, 32)})
+	require.NoError(t, err)
+
+	// Spare capacity, to ensure the codecs passed by the caller are not appended to
+	codecs := make([]converter.PayloadCodec, 0, 3)
+	codecs = append(codecs, gzip, aes)
+	c := converter.NewCodecConverter(converter.NewCompositeConverter(converter.NewProtoPayloadConverter(), converter.NewJSONPayloadConverter()), codecs...)
+
+	result, err := c.To(map[string]int{"a": 42})
+	require.NoError(t, err)
+
+	decoded, err := decodeAttributes(newPayloadCodec(codecs), &history.ActivityCompletedAttributes{Result: result})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"a":42}`, string(decoded.(*history.ActivityCompletedAttributes).Result))
+	require.Nil(t, codecs[:3][2])
+}
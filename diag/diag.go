@@ -7,6 +7,9 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/internal/history"
 )
 
 //go:embed app/build
@@ -14,7 +17,17 @@ var embeddedFiles embed.FS
 
 // NewServeMux returns an *http.ServeMux that serves the diagnostics web app at / and the diagnostics API at /api which is
 // used by the web app.
-func NewServeMux(backend Backend) *http.ServeMux {
+func NewServeMux(backend Backend, opts ...Option) *http.ServeMux {
+	options := &options{
+		clock: clock.New(),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// Decode payloads so the web app can display them
+	codec := newPayloadCodec(options.codecs)
+
 	mux := http.NewServeMux()
 
 	// API
@@ -70,26 +83,46 @@ func NewServeMux(backend Backend) *http.ServeMux {
 				return
 			}
 
-			if instance == nil {
-				w.WriteHeader(http.StatusNotFound)
-				return
-			}
+			var events []*history.Event
 
-			history, err := backend.GetWorkflowInstanceHistory(r.Context(), instance.Instance, nil)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				return
+			if instance != nil {
+				events, err = backend.GetWorkflowInstanceHistory(r.Context(), instance.Instance, nil)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			} else {
+				// Removed instances might have been archived
+				archived, err := getArchivedWorkflowInstance(r.Context(), backend, instanceID)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				if archived == nil {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				instance = archivedInstanceRef(archived)
+				events = archived.History
 			}
 
 			newHistory := make([]*Event, 0)
-			for _, event := range history {
+			for _, event := range events {
+				attributes, err := decodeAttributes(codec, event.Attributes)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
 				newHistory = append(newHistory, &Event{
 					ID:              event.ID,
 					SequenceID:      event.SequenceID,
 					Type:            event.Type.String(),
 					Timestamp:       event.Timestamp,
 					ScheduleEventID: event.ScheduleEventID,
-					Attributes:      event.Attributes,
+					Attributes:      attributes,
 					VisibleAt:       event.VisibleAt,
 				})
 			}
@@ -109,33 +142,58 @@ func NewServeMux(backend Backend) *http.ServeMux {
 		}
 
 		// /api/{instanceID}/tree
-		if len(segments) == 2 {
+		if len(segments) == 2 && segments[1] == "tree" {
 			instanceID := segments[0]
-			op := segments[1]
-			if op != "tree" {
-				w.WriteHeader(http.StatusNotFound)
+
+			tree, err := backend.GetWorkflowTree(r.Context(), instanceID)
+			if err != nil || tree == nil {
+				// Archived instances are shown without their sub-workflows
+				archived, aerr := getArchivedWorkflowInstance(r.Context(), backend, instanceID)
+				if aerr != nil || archived == nil {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				tree = &WorkflowInstanceTree{
+					WorkflowInstanceRef: archivedInstanceRef(archived),
+					WorkflowName:        archived.Instance.WorkflowName,
+				}
+			}
+
+			w.Header().Add("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(tree); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 
-			tree, err := backend.GetWorkflowTree(r.Context(), instanceID)
+			return
+		}
+
+		// /api/{instanceID}/describe
+		if len(segments) == 2 && segments[1] == "describe" {
+			instanceID := segments[0]
+
+			description, err := describeWorkflowInstance(r.Context(), backend, options.clock, instanceID)
 			if err != nil {
-				w.WriteHeader(http.StatusNotFound)
+				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 
-			if tree == nil {
+			if description == nil {
 				w.WriteHeader(http.StatusNotFound)
 				return
 			}
 
 			w.Header().Add("Content-Type", "application/json")
-			if err := json.NewEncoder(w).Encode(tree); err != nil {
+			if err := json.NewEncoder(w).Encode(description); err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 
 			return
 		}
+
+		w.WriteHeader(http.StatusNotFound)
 	})
 
 	// App
@@ -8,13 +8,16 @@ require (
 	github.com/google/uuid v1.3.0
 	github.com/jellydator/ttlcache/v3 v3.0.0
 	github.com/jstemmer/go-junit-report/v2 v2.0.0-beta1
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.12
 	github.com/redis/go-redis/v9 v9.0.2
 	github.com/stretchr/testify v1.8.1
+	go.etcd.io/bbolt v1.3.7
 	go.opentelemetry.io/otel v1.7.0
 	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.7.0
 	go.opentelemetry.io/otel/trace v1.7.0
 	golang.org/x/tools v0.1.12
+	google.golang.org/protobuf v1.28.0
 )
 
 require (
@@ -181,11 +184,10 @@ require (
 	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4 // indirect
 	golang.org/x/net v0.0.0-20220722155237-a158d28d115b // indirect
 	golang.org/x/sync v0.0.0-20220819030929-7fc1605a5dde // indirect
-	golang.org/x/sys v0.3.0 // indirect
+	golang.org/x/sys v0.4.0 // indirect
 	golang.org/x/text v0.3.7 // indirect
 	google.golang.org/genproto v0.0.0-20220519153652-3a47de7e79bd // indirect
 	google.golang.org/grpc v1.46.2 // indirect
-	google.golang.org/protobuf v1.28.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
@@ -0,0 +1,58 @@
+// Package interceptor defines interceptors that wrap workflow and activity executions on a worker. Configure them
+// using the worker options. Interceptors are called in the order they are configured, the first interceptor is the
+// outermost one.
+package interceptor
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// Payload is a serialized value, like the arguments or the result of a workflow or activity. Use the converter
+// configured for the backend to decode or encode values.
+type Payload = payload.Payload
+
+// ActivityExecution describes an activity execution. Use activity.GetInfo to get additional information about the
+// activity from the context.
+type ActivityExecution struct {
+	// Name is the name the activity was registered with
+	Name string
+
+	// Inputs are the serialized arguments of the activity
+	Inputs []Payload
+}
+
+// ExecuteActivityFunc executes an activity and returns its serialized result
+type ExecuteActivityFunc func(ctx context.Context, execution *ActivityExecution) (Payload, error)
+
+type ActivityInterceptor interface {
+	// ExecuteActivity is called for every activity execution. Call next to continue with the next interceptor and
+	// eventually execute the activity.
+	ExecuteActivity(ctx context.Context, execution *ActivityExecution, next ExecuteActivityFunc) (Payload, error)
+}
+
+// WorkflowExecution describes a workflow execution. Use workflow.GetInfo to get additional information about the
+// workflow from the context.
+type WorkflowExecution struct {
+	// Name is the name the workflow was registered with
+	Name string
+
+	// Inputs are the serialized arguments of the workflow
+	Inputs []Payload
+}
+
+// ExecuteWorkflowFunc executes a workflow and returns its serialized result
+type ExecuteWorkflowFunc func(ctx workflow.Context, execution *WorkflowExecution) (Payload, error)
+
+type WorkflowInterceptor interface {
+	// ExecuteWorkflow is called when the execution of a workflow starts. Call next to continue with the next
+	// interceptor and eventually execute the workflow.
+	//
+	// Workflows are replayed from the start of their history when they are not cached by the worker, so this is
+	// called every time a workflow is replayed. Interceptors run as part of the workflow and have to be
+	// deterministic like the workflow itself. Use workflow.Replaying to skip side effects, like writing audit logs
+	// or recording metrics, while the workflow is being replayed.
+	ExecuteWorkflow(ctx workflow.Context, execution *WorkflowExecution, next ExecuteWorkflowFunc) (Payload, error)
+}
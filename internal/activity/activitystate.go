@@ -2,22 +2,31 @@ package activity
 
 import (
 	"context"
+	"time"
 
 	"github.com/cschleiden/go-workflows/log"
 	"github.com/cschleiden/go-workflows/workflow"
 )
 
 type ActivityState struct {
-	ActivityID string
-	Instance   *workflow.Instance
-	Logger     log.Logger
+	ActivityID  string
+	TaskID      string
+	Name        string
+	Attempt     int
+	ScheduledAt time.Time
+	Instance    *workflow.Instance
+	Logger      log.Logger
 }
 
-func NewActivityState(activityID string, instance *workflow.Instance, logger log.Logger) *ActivityState {
+func NewActivityState(activityID, taskID, name string, attempt int, scheduledAt time.Time, instance *workflow.Instance, logger log.Logger) *ActivityState {
 	return &ActivityState{
-		activityID,
-		instance,
-		logger.With(
+		ActivityID:  activityID,
+		TaskID:      taskID,
+		Name:        name,
+		Attempt:     attempt,
+		ScheduledAt: scheduledAt,
+		Instance:    instance,
+		Logger: logger.With(
 			"activity_id", activityID,
 			"instance_id", instance.InstanceID,
 			"execution_id", instance.ExecutionID,
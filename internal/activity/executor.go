@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"runtime/debug"
 
+	"github.com/cschleiden/go-workflows/interceptor"
 	"github.com/cschleiden/go-workflows/internal/args"
+	"github.com/cschleiden/go-workflows/internal/contextpropagation"
 	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/payload"
@@ -18,19 +21,39 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// PanicError is returned when an activity panics
+type PanicError struct {
+	// Value is the value the activity panicked with
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine executing the activity at the time of the panic
+	Stack string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("activity panicked: %v", e.Value)
+}
+
 type Executor struct {
-	logger    log.Logger
-	tracer    trace.Tracer
-	converter converter.Converter
-	r         *workflow.Registry
+	logger       log.Logger
+	tracer       trace.Tracer
+	converter    converter.Converter
+	propagators  []contextpropagation.ContextPropagator
+	interceptors []interceptor.ActivityInterceptor
+	r            *workflow.Registry
 }
 
-func NewExecutor(logger log.Logger, tracer trace.Tracer, converter converter.Converter, r *workflow.Registry) Executor {
+func NewExecutor(
+	logger log.Logger, tracer trace.Tracer, converter converter.Converter, propagators []contextpropagation.ContextPropagator,
+	interceptors []interceptor.ActivityInterceptor, r *workflow.Registry,
+) Executor {
 	return Executor{
-		logger:    logger,
-		tracer:    tracer,
-		converter: converter,
-		r:         r,
+		logger:       logger,
+		tracer:       tracer,
+		converter:    converter,
+		propagators:  propagators,
+		interceptors: interceptors,
+		r:            r,
 	}
 }
 
@@ -47,14 +70,13 @@ func (e *Executor) ExecuteActivity(ctx context.Context, task *task.Activity) (pa
 		return nil, errors.New("activity not a function")
 	}
 
-	args, addContext, err := args.InputsToArgs(e.converter, activityFn, a.Inputs)
-	if err != nil {
-		return nil, fmt.Errorf("converting activity inputs: %w", err)
-	}
-
 	// Add activity state to context
 	as := NewActivityState(
 		task.Event.ID,
+		task.ID,
+		a.Name,
+		a.Attempt,
+		task.Event.Timestamp,
 		task.WorkflowInstance,
 		e.logger)
 	activityCtx := WithActivityState(ctx, as)
@@ -67,9 +89,47 @@ func (e *Executor) ExecuteActivity(ctx context.Context, task *task.Activity) (pa
 	))
 	defer span.End()
 
+	activityCtx, err = contextpropagation.Extract(activityCtx, e.propagators, &a.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrap the activity execution with the configured interceptors, the first interceptor is the outermost one
+	execute := func(ctx context.Context, execution *interceptor.ActivityExecution) (payload.Payload, error) {
+		return e.callActivity(ctx, converter.ForInstance(ctx, e.converter, task.WorkflowInstance), activityFn, execution.Inputs)
+	}
+
+	for i := len(e.interceptors) - 1; i >= 0; i-- {
+		ic, next := e.interceptors[i], execute
+		execute = func(ctx context.Context, execution *interceptor.ActivityExecution) (payload.Payload, error) {
+			return ic.ExecuteActivity(ctx, execution, next)
+		}
+	}
+
+	return execute(activityCtx, &interceptor.ActivityExecution{
+		Name:   a.Name,
+		Inputs: a.Inputs,
+	})
+}
+
+func (e *Executor) callActivity(
+	ctx context.Context, cv converter.Converter, activityFn reflect.Value, inputs []payload.Payload,
+) (result payload.Payload, err error) {
+	// Report panics like any other activity error, so they are subject to the retry policy of the activity
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &PanicError{Value: r, Stack: string(debug.Stack())}
+		}
+	}()
+
+	args, addContext, err := args.InputsToArgs(cv, activityFn, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("converting activity inputs: %w", err)
+	}
+
 	// Execute activity
 	if addContext {
-		args[0] = reflect.ValueOf(activityCtx)
+		args[0] = reflect.ValueOf(ctx)
 	}
 	r := activityFn.Call(args)
 
@@ -77,11 +137,8 @@ func (e *Executor) ExecuteActivity(ctx context.Context, task *task.Activity) (pa
 		return nil, errors.New("activity has to return either (error) or (<result>, error)")
 	}
 
-	var result payload.Payload
-
 	if len(r) > 1 {
-		var err error
-		result, err = e.converter.To(r[0].Interface())
+		result, err = cv.To(r[0].Interface())
 		if err != nil {
 			return nil, fmt.Errorf("converting activity result: %w", err)
 		}
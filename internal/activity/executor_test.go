@@ -2,9 +2,12 @@ package activity
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/cschleiden/go-workflows/interceptor"
+	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/history"
@@ -14,6 +17,7 @@ import (
 	"github.com/cschleiden/go-workflows/internal/workflow"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestExecutor_ExecuteActivity(t *testing.T) {
@@ -51,22 +55,93 @@ func TestExecutor_ExecuteActivity(t *testing.T) {
 				require.EqualError(t, err, "converting activity inputs: mismatched argument count: expected 2, got 0")
 			},
 		},
+		{
+			name: "activity panics",
+			setup: func(t *testing.T, r *workflow.Registry) *history.ActivityScheduledAttributes {
+				a := func(context.Context) (int, error) { panic("activity failed") }
+				require.NoError(t, r.RegisterActivity(a))
+
+				return &history.ActivityScheduledAttributes{
+					Name: fn.Name(a),
+				}
+			},
+			result: func(t *testing.T, result payload.Payload, err error) {
+				require.Nil(t, result)
+				require.EqualError(t, err, "activity panicked: activity failed")
+
+				var perr *PanicError
+				require.True(t, errors.As(err, &perr))
+				require.Equal(t, "activity failed", perr.Value)
+				require.Contains(t, perr.Stack, "executor_test.go")
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := workflow.NewRegistry()
 			attr := tt.setup(t, r)
 
-			e := &Executor{
-				logger: logger.NewDefaultLogger(),
-				r:      r,
-			}
-			got, err := e.ExecuteActivity(context.Background(), &task.Activity{
-				ID:               uuid.NewString(),
-				WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
-				Event:            history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, attr),
-			})
+			e := newExecutor(r, nil)
+			got, err := e.ExecuteActivity(context.Background(), newActivityTask(attr))
 			tt.result(t, got, err)
 		})
 	}
 }
+
+type recordingInterceptor struct {
+	name  string
+	calls *[]string
+}
+
+func (ri *recordingInterceptor) ExecuteActivity(
+	ctx context.Context, execution *interceptor.ActivityExecution, next interceptor.ExecuteActivityFunc,
+) (payload.Payload, error) {
+	*ri.calls = append(*ri.calls, ri.name+":"+execution.Name)
+
+	return next(ctx, execution)
+}
+
+func TestExecutor_ExecuteActivity_Interceptors(t *testing.T) {
+	r := workflow.NewRegistry()
+
+	calls := []string{}
+	a := func(ctx context.Context, s string) (string, error) {
+		calls = append(calls, "activity")
+
+		return s + "!", nil
+	}
+	require.NoError(t, r.RegisterActivity(a))
+
+	input, err := converter.DefaultConverter.To("hello")
+	require.NoError(t, err)
+
+	e := newExecutor(r, []interceptor.ActivityInterceptor{
+		&recordingInterceptor{"first", &calls},
+		&recordingInterceptor{"second", &calls},
+	})
+
+	got, err := e.ExecuteActivity(context.Background(), newActivityTask(&history.ActivityScheduledAttributes{
+		Name:   fn.Name(a),
+		Inputs: []payload.Payload{input},
+	}))
+	require.NoError(t, err)
+
+	var result string
+	require.NoError(t, converter.DefaultConverter.From(got, &result))
+	require.Equal(t, "hello!", result)
+	require.Equal(t, []string{"first:" + fn.Name(a), "second:" + fn.Name(a), "activity"}, calls)
+}
+
+func newExecutor(r *workflow.Registry, interceptors []interceptor.ActivityInterceptor) Executor {
+	return NewExecutor(
+		logger.NewDefaultLogger(), trace.NewNoopTracerProvider().Tracer("test"), converter.DefaultConverter, nil, interceptors, r)
+}
+
+func newActivityTask(attr *history.ActivityScheduledAttributes) *task.Activity {
+	return &task.Activity{
+		ID:               uuid.NewString(),
+		WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+		Metadata:         &core.WorkflowMetadata{},
+		Event:            history.NewHistoryEvent(1, time.Now(), history.EventType_ActivityScheduled, attr),
+	}
+}
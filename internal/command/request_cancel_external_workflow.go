@@ -0,0 +1,58 @@
+package command
+
+import (
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+type RequestCancelExternalWorkflowCommand struct {
+	command
+
+	InstanceID string
+}
+
+var _ Command = (*RequestCancelExternalWorkflowCommand)(nil)
+
+func NewRequestCancelExternalWorkflowCommand(id int64, instanceID string) *RequestCancelExternalWorkflowCommand {
+	return &RequestCancelExternalWorkflowCommand{
+		command: command{
+			id:    id,
+			name:  "RequestCancelExternalWorkflow",
+			state: CommandState_Pending,
+		},
+
+		InstanceID: instanceID,
+	}
+}
+
+func (c *RequestCancelExternalWorkflowCommand) Execute(clock clock.Clock) *CommandResult {
+	switch c.state {
+	case CommandState_Pending:
+		c.state = CommandState_Committed
+
+		return &CommandResult{
+			// Record that cancellation was requested
+			Events: []*history.Event{
+				history.NewPendingEvent(
+					clock.Now(),
+					history.EventType_RequestCancelExternalWorkflowScheduled,
+					&history.RequestCancelExternalWorkflowScheduledAttributes{
+						InstanceID: c.InstanceID,
+					},
+					history.ScheduleEventID(c.id),
+				),
+			},
+
+			// Send cancellation event to the target instance
+			WorkflowEvents: []history.WorkflowEvent{
+				{
+					WorkflowInstance: core.NewWorkflowInstance(c.InstanceID, ""),
+					HistoryEvent:     history.NewWorkflowCancellationEvent(clock.Now()),
+				},
+			},
+		}
+	}
+
+	return nil
+}
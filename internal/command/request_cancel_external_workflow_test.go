@@ -0,0 +1,42 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestCancelExternalWorkflowCommand_StateTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, c *RequestCancelExternalWorkflowCommand)
+	}{
+		{"Execute requests cancellation", func(t *testing.T, c *RequestCancelExternalWorkflowCommand) {
+			r := assertExecuteWithEvent(t, c, CommandState_Committed, history.EventType_RequestCancelExternalWorkflowScheduled)
+
+			require.Len(t, r.WorkflowEvents, 1)
+			require.Equal(t, "target", r.WorkflowEvents[0].WorkflowInstance.InstanceID)
+			require.Equal(t, history.EventType_WorkflowExecutionCanceled, r.WorkflowEvents[0].HistoryEvent.Type)
+		}},
+		{"Commit", func(t *testing.T, c *RequestCancelExternalWorkflowCommand) {
+			c.Commit()
+			require.Equal(t, CommandState_Committed, c.State())
+
+			assertExecuteNoEvent(t, c, CommandState_Committed)
+		}},
+		{"Done", func(t *testing.T, c *RequestCancelExternalWorkflowCommand) {
+			assertExecuteWithEvent(t, c, CommandState_Committed, history.EventType_RequestCancelExternalWorkflowScheduled)
+
+			c.Done()
+			require.Equal(t, CommandState_Done, c.State())
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRequestCancelExternalWorkflowCommand(1, "target")
+
+			tt.f(t, cmd)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package command
 
 import (
 	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/payload"
 )
@@ -9,21 +10,30 @@ import (
 type ScheduleActivityCommand struct {
 	command
 
-	Name   string
-	Inputs []payload.Payload
+	Name     string
+	Inputs   []payload.Payload
+	Metadata *core.WorkflowMetadata
+	Attempt  int
+
+	// FirstScheduleEventID links retries to the first attempt of the activity
+	FirstScheduleEventID int64
 }
 
 var _ Command = (*ScheduleActivityCommand)(nil)
 
-func NewScheduleActivityCommand(id int64, name string, inputs []payload.Payload) *ScheduleActivityCommand {
+func NewScheduleActivityCommand(id int64, name string, inputs []payload.Payload, metadata *core.WorkflowMetadata, attempt int, firstScheduleEventID int64) *ScheduleActivityCommand {
 	return &ScheduleActivityCommand{
 		command: command{
 			id:    id,
 			name:  "ScheduleActivity",
 			state: CommandState_Pending,
 		},
-		Name:   name,
-		Inputs: inputs,
+		Name:     name,
+		Inputs:   inputs,
+		Metadata: metadata,
+		Attempt:  attempt,
+
+		FirstScheduleEventID: firstScheduleEventID,
 	}
 }
 
@@ -32,13 +42,21 @@ func (c *ScheduleActivityCommand) Execute(clock clock.Clock) *CommandResult {
 	case CommandState_Pending:
 		c.state = CommandState_Committed
 
+		attrs := &history.ActivityScheduledAttributes{
+			Name:                 c.Name,
+			Inputs:               c.Inputs,
+			Attempt:              c.Attempt,
+			FirstScheduleEventID: c.FirstScheduleEventID,
+		}
+
+		if c.Metadata != nil {
+			attrs.Metadata = *c.Metadata
+		}
+
 		event := history.NewPendingEvent(
 			clock.Now(),
 			history.EventType_ActivityScheduled,
-			&history.ActivityScheduledAttributes{
-				Name:   c.Name,
-				Inputs: c.Inputs,
-			},
+			attrs,
 			history.ScheduleEventID(c.id))
 
 		return &CommandResult{
@@ -36,7 +36,7 @@ func TestScheduleActivityCommand_StateTransitions(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			clock := clock.NewMock()
-			cmd := NewScheduleActivityCommand(1, "activity", []payload.Payload{})
+			cmd := NewScheduleActivityCommand(1, "activity", []payload.Payload{}, nil, 0, 0)
 
 			tt.f(t, cmd, clock)
 		})
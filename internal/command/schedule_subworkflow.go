@@ -14,19 +14,33 @@ type ScheduleSubWorkflowCommand struct {
 	Instance *core.WorkflowInstance
 	Metadata *core.WorkflowMetadata
 
-	Name   string
-	Inputs []payload.Payload
+	Name    string
+	Inputs  []payload.Payload
+	Attempt int
+
+	// Detached sub-workflows are not linked to the parent instance
+	Detached bool
+
+	// NotifyStarted requests to be notified once the sub-workflow instance has been created
+	NotifyStarted bool
+
+	whenStarted func(instance *core.WorkflowInstance, err error)
 }
 
 var _ CancelableCommand = (*ScheduleSubWorkflowCommand)(nil)
 
 func NewScheduleSubWorkflowCommand(
-	id int64, parentInstance *core.WorkflowInstance, subWorkflowInstanceID, name string, inputs []payload.Payload, metadata *core.WorkflowMetadata,
+	id int64, parentInstance *core.WorkflowInstance, subWorkflowInstanceID, name string, inputs []payload.Payload, metadata *core.WorkflowMetadata, attempt int, detached bool,
 ) *ScheduleSubWorkflowCommand {
 	if subWorkflowInstanceID == "" {
 		subWorkflowInstanceID = uuid.New().String()
 	}
 
+	instance := core.NewSubWorkflowInstance(subWorkflowInstanceID, uuid.NewString(), parentInstance.InstanceID, id)
+	if detached {
+		instance = core.NewWorkflowInstance(subWorkflowInstanceID, uuid.NewString())
+	}
+
 	return &ScheduleSubWorkflowCommand{
 		cancelableCommand: cancelableCommand{
 			command: command{
@@ -36,11 +50,34 @@ func NewScheduleSubWorkflowCommand(
 			},
 		},
 
-		Instance: core.NewSubWorkflowInstance(subWorkflowInstanceID, uuid.NewString(), parentInstance.InstanceID, id),
+		Instance: instance,
 		Metadata: metadata,
 
-		Name:   name,
-		Inputs: inputs,
+		Name:    name,
+		Inputs:  inputs,
+		Attempt: attempt,
+
+		Detached: detached,
+	}
+}
+
+// WhenStarted registers a callback that is invoked once the sub-workflow instance has been started, or with an error
+// if the sub-workflow failed.
+func (c *ScheduleSubWorkflowCommand) WhenStarted(f func(instance *core.WorkflowInstance, err error)) {
+	c.whenStarted = f
+}
+
+// HandleStarted handles the started event for the sub-workflow instance.
+func (c *ScheduleSubWorkflowCommand) HandleStarted(instance *core.WorkflowInstance) {
+	if c.whenStarted != nil {
+		c.whenStarted(instance, nil)
+	}
+}
+
+// HandleFailed handles the failed event for the sub-workflow instance.
+func (c *ScheduleSubWorkflowCommand) HandleFailed(err error) {
+	if c.whenStarted != nil {
+		c.whenStarted(nil, err)
 	}
 }
 
@@ -59,6 +96,9 @@ func (c *ScheduleSubWorkflowCommand) Execute(clock clock.Clock) *CommandResult {
 						Metadata:            c.Metadata,
 						Name:                c.Name,
 						Inputs:              c.Inputs,
+						Attempt:             c.Attempt,
+						Detached:            c.Detached,
+						NotifyStarted:       c.NotifyStarted,
 					},
 					history.ScheduleEventID(c.id),
 				),
@@ -74,6 +114,7 @@ func (c *ScheduleSubWorkflowCommand) Execute(clock clock.Clock) *CommandResult {
 							Name:     c.Name,
 							Inputs:   c.Inputs,
 							Metadata: c.Metadata,
+							Attempt:  c.Attempt,
 						},
 						history.ScheduleEventID(0),
 					),
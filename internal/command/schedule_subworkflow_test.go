@@ -96,9 +96,41 @@ func TestScheduleSubWorkflowCommand_StateTransitions(t *testing.T) {
 
 			parentInstance := core.NewWorkflowInstance(uuid.NewString(), "")
 
-			cmd := NewScheduleSubWorkflowCommand(1, parentInstance, uuid.NewString(), "SubWorkflow", []payload.Payload{}, &core.WorkflowMetadata{})
+			cmd := NewScheduleSubWorkflowCommand(1, parentInstance, uuid.NewString(), "SubWorkflow", []payload.Payload{}, &core.WorkflowMetadata{}, 0, false)
 
 			tt.f(t, cmd, clock)
 		})
 	}
 }
+
+func TestScheduleSubWorkflowCommand_Detached(t *testing.T) {
+	parentInstance := core.NewWorkflowInstance(uuid.NewString(), "")
+
+	cmd := NewScheduleSubWorkflowCommand(1, parentInstance, "sub", "SubWorkflow", []payload.Payload{}, &core.WorkflowMetadata{}, 0, true)
+	require.False(t, cmd.Instance.SubWorkflow())
+
+	r := assertExecuteWithEvent(t, cmd, CommandState_Committed, history.EventType_SubWorkflowScheduled)
+	require.True(t, r.Events[0].Attributes.(*history.SubWorkflowScheduledAttributes).Detached)
+	require.Equal(t, "sub", r.WorkflowEvents[0].WorkflowInstance.InstanceID)
+}
+
+func TestScheduleSubWorkflowCommand_HandleStarted(t *testing.T) {
+	parentInstance := core.NewWorkflowInstance(uuid.NewString(), "")
+
+	cmd := NewScheduleSubWorkflowCommand(1, parentInstance, "sub", "SubWorkflow", []payload.Payload{}, &core.WorkflowMetadata{}, 0, false)
+
+	var started *core.WorkflowInstance
+	var startErr error
+	cmd.WhenStarted(func(instance *core.WorkflowInstance, err error) {
+		started = instance
+		startErr = err
+	})
+
+	cmd.HandleStarted(cmd.Instance)
+	require.Equal(t, cmd.Instance, started)
+	require.NoError(t, startErr)
+
+	cmd.HandleFailed(core.ErrInstanceAlreadyExists)
+	require.Nil(t, started)
+	require.ErrorIs(t, startErr, core.ErrInstanceAlreadyExists)
+}
@@ -10,12 +10,13 @@ import (
 type ScheduleTimerCommand struct {
 	cancelableCommand
 
-	at time.Time
+	at       time.Time
+	duration time.Duration
 }
 
 var _ CancelableCommand = (*ScheduleTimerCommand)(nil)
 
-func NewScheduleTimerCommand(id int64, at time.Time) *ScheduleTimerCommand {
+func NewScheduleTimerCommand(id int64, at time.Time, duration time.Duration) *ScheduleTimerCommand {
 	return &ScheduleTimerCommand{
 		cancelableCommand: cancelableCommand{
 			command: command{
@@ -24,10 +25,16 @@ func NewScheduleTimerCommand(id int64, at time.Time) *ScheduleTimerCommand {
 				state: CommandState_Pending,
 			},
 		},
-		at: at,
+		at:       at,
+		duration: duration,
 	}
 }
 
+// Duration returns the delay the timer was scheduled with
+func (c *ScheduleTimerCommand) Duration() time.Duration {
+	return c.duration
+}
+
 func (c *ScheduleTimerCommand) Execute(clock clock.Clock) *CommandResult {
 	switch c.state {
 	case CommandState_Pending:
@@ -39,7 +46,8 @@ func (c *ScheduleTimerCommand) Execute(clock clock.Clock) *CommandResult {
 					clock.Now(),
 					history.EventType_TimerScheduled,
 					&history.TimerScheduledAttributes{
-						At: c.at,
+						At:       c.at,
+						Duration: c.duration,
 					},
 					history.ScheduleEventID(c.id),
 				),
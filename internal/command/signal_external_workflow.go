@@ -0,0 +1,72 @@
+package command
+
+import (
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+type SignalExternalWorkflowCommand struct {
+	command
+
+	InstanceID string
+	Name       string
+	Arg        payload.Payload
+}
+
+var _ Command = (*SignalExternalWorkflowCommand)(nil)
+
+func NewSignalExternalWorkflowCommand(id int64, instanceID, name string, arg payload.Payload) *SignalExternalWorkflowCommand {
+	return &SignalExternalWorkflowCommand{
+		command: command{
+			id:    id,
+			name:  "SignalExternalWorkflow",
+			state: CommandState_Pending,
+		},
+
+		InstanceID: instanceID,
+		Name:       name,
+		Arg:        arg,
+	}
+}
+
+func (c *SignalExternalWorkflowCommand) Execute(clock clock.Clock) *CommandResult {
+	switch c.state {
+	case CommandState_Pending:
+		c.state = CommandState_Committed
+
+		return &CommandResult{
+			// Record that the signal was sent
+			Events: []*history.Event{
+				history.NewPendingEvent(
+					clock.Now(),
+					history.EventType_SignalExternalWorkflowScheduled,
+					&history.SignalExternalWorkflowScheduledAttributes{
+						InstanceID: c.InstanceID,
+						Name:       c.Name,
+						Arg:        c.Arg,
+					},
+					history.ScheduleEventID(c.id),
+				),
+			},
+
+			// Deliver signal to the target instance
+			WorkflowEvents: []history.WorkflowEvent{
+				{
+					WorkflowInstance: core.NewWorkflowInstance(c.InstanceID, ""),
+					HistoryEvent: history.NewPendingEvent(
+						clock.Now(),
+						history.EventType_SignalReceived,
+						&history.SignalReceivedAttributes{
+							Name: c.Name,
+							Arg:  c.Arg,
+						},
+					),
+				},
+			},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignalExternalWorkflowCommand_StateTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, c *SignalExternalWorkflowCommand)
+	}{
+		{"Execute sends signal", func(t *testing.T, c *SignalExternalWorkflowCommand) {
+			r := assertExecuteWithEvent(t, c, CommandState_Committed, history.EventType_SignalExternalWorkflowScheduled)
+
+			require.Len(t, r.WorkflowEvents, 1)
+			require.Equal(t, "target", r.WorkflowEvents[0].WorkflowInstance.InstanceID)
+			require.Equal(t, history.EventType_SignalReceived, r.WorkflowEvents[0].HistoryEvent.Type)
+
+			a := r.WorkflowEvents[0].HistoryEvent.Attributes.(*history.SignalReceivedAttributes)
+			require.Equal(t, "signal", a.Name)
+		}},
+		{"Commit", func(t *testing.T, c *SignalExternalWorkflowCommand) {
+			c.Commit()
+			require.Equal(t, CommandState_Committed, c.State())
+
+			assertExecuteNoEvent(t, c, CommandState_Committed)
+		}},
+		{"Done", func(t *testing.T, c *SignalExternalWorkflowCommand) {
+			assertExecuteWithEvent(t, c, CommandState_Committed, history.EventType_SignalExternalWorkflowScheduled)
+
+			c.Done()
+			require.Equal(t, CommandState_Done, c.State())
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewSignalExternalWorkflowCommand(1, "target", "signal", []byte("42"))
+
+			tt.f(t, cmd)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/search"
+)
+
+type UpsertSearchAttributesCommand struct {
+	command
+
+	SearchAttributes search.Attributes
+}
+
+var _ Command = (*UpsertSearchAttributesCommand)(nil)
+
+func NewUpsertSearchAttributesCommand(id int64, attributes search.Attributes) *UpsertSearchAttributesCommand {
+	return &UpsertSearchAttributesCommand{
+		command: command{
+			id:    id,
+			name:  "UpsertSearchAttributes",
+			state: CommandState_Pending,
+		},
+		SearchAttributes: attributes,
+	}
+}
+
+func (c *UpsertSearchAttributesCommand) Commit() {
+	switch c.state {
+	case CommandState_Pending:
+		c.state = CommandState_Done
+
+	default:
+		c.invalidStateTransition(CommandState_Done)
+	}
+}
+
+func (c *UpsertSearchAttributesCommand) Execute(clock clock.Clock) *CommandResult {
+	switch c.state {
+	case CommandState_Pending:
+		// Search attributes are only added to the history, the backend updates its index from there
+		c.state = CommandState_Done
+
+		return &CommandResult{
+			Events: []*history.Event{
+				history.NewPendingEvent(
+					clock.Now(),
+					history.EventType_SearchAttributesUpserted,
+					&history.SearchAttributesUpsertedAttributes{
+						SearchAttributes: c.SearchAttributes,
+					},
+					history.ScheduleEventID(c.id),
+				),
+			},
+		}
+	}
+
+	return nil
+}
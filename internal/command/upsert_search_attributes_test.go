@@ -0,0 +1,42 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/search"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertSearchAttributesCommand_StateTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, c *UpsertSearchAttributesCommand, clock clock.Clock)
+	}{
+		{"Execute records search attributes", func(t *testing.T, c *UpsertSearchAttributesCommand, clock clock.Clock) {
+			r := assertExecuteWithEvent(t, c, CommandState_Done, history.EventType_SearchAttributesUpserted)
+
+			a := r.Events[0].Attributes.(*history.SearchAttributesUpsertedAttributes)
+			require.Equal(t, "c1", a.SearchAttributes["customer"].String)
+		}},
+		{"Commit", func(t *testing.T, c *UpsertSearchAttributesCommand, _ clock.Clock) {
+			require.Equal(t, CommandState_Pending, c.State())
+
+			c.Commit()
+			require.Equal(t, CommandState_Done, c.State())
+
+			assertExecuteNoEvent(t, c, CommandState_Done)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := clock.NewMock()
+			cmd := NewUpsertSearchAttributesCommand(1, search.Attributes{
+				"customer": &search.Value{Type: search.TypeString, String: "c1"},
+			})
+
+			tt.f(t, cmd, clock)
+		})
+	}
+}
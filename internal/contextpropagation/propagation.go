@@ -0,0 +1,86 @@
+package contextpropagation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/sync"
+)
+
+// ContextPropagator propagates values from a context to workflows and activities. Values are serialized into the
+// metadata of a workflow instance when it is created and of activities and sub-workflows when they are scheduled,
+// and restored into the context of the workflow or activity that is executed.
+type ContextPropagator interface {
+	// Inject serializes values from the given context into the metadata. It is called when a workflow instance is
+	// created using the client.
+	Inject(ctx context.Context, metadata *core.WorkflowMetadata) error
+
+	// Extract restores values from the metadata into the context. It is called before an activity is executed.
+	Extract(ctx context.Context, metadata *core.WorkflowMetadata) (context.Context, error)
+
+	// InjectFromWorkflow serializes values from the given workflow context into the metadata. It is called when a
+	// workflow schedules an activity or a sub-workflow. Workflows are replayed, so this has to be deterministic.
+	InjectFromWorkflow(ctx sync.Context, metadata *core.WorkflowMetadata) error
+
+	// ExtractToWorkflow restores values from the metadata into the workflow context. It is called every time the
+	// execution of a workflow starts, including replays.
+	ExtractToWorkflow(ctx sync.Context, metadata *core.WorkflowMetadata) (sync.Context, error)
+}
+
+type propagatorsKey struct{}
+
+func WithPropagators(ctx sync.Context, propagators []ContextPropagator) sync.Context {
+	return sync.WithValue(ctx, propagatorsKey{}, propagators)
+}
+
+func Propagators(ctx sync.Context) []ContextPropagator {
+	propagators, _ := ctx.Value(propagatorsKey{}).([]ContextPropagator)
+	return propagators
+}
+
+// Inject calls all given propagators to serialize values from ctx into metadata
+func Inject(ctx context.Context, propagators []ContextPropagator, metadata *core.WorkflowMetadata) error {
+	for _, p := range propagators {
+		if err := p.Inject(ctx, metadata); err != nil {
+			return fmt.Errorf("injecting context: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Extract calls all given propagators to restore values from metadata into ctx
+func Extract(ctx context.Context, propagators []ContextPropagator, metadata *core.WorkflowMetadata) (context.Context, error) {
+	for _, p := range propagators {
+		var err error
+		if ctx, err = p.Extract(ctx, metadata); err != nil {
+			return nil, fmt.Errorf("extracting context: %w", err)
+		}
+	}
+
+	return ctx, nil
+}
+
+// InjectFromWorkflow calls the propagators configured for the workflow to serialize values from ctx into metadata
+func InjectFromWorkflow(ctx sync.Context, metadata *core.WorkflowMetadata) error {
+	for _, p := range Propagators(ctx) {
+		if err := p.InjectFromWorkflow(ctx, metadata); err != nil {
+			return fmt.Errorf("injecting workflow context: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ExtractToWorkflow calls the propagators configured for the workflow to restore values from metadata into ctx
+func ExtractToWorkflow(ctx sync.Context, metadata *core.WorkflowMetadata) (sync.Context, error) {
+	for _, p := range Propagators(ctx) {
+		var err error
+		if ctx, err = p.ExtractToWorkflow(ctx, metadata); err != nil {
+			return nil, fmt.Errorf("extracting workflow context: %w", err)
+		}
+	}
+
+	return ctx, nil
+}
@@ -0,0 +1,139 @@
+package converter
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// aesGCMMagic marks encrypted payloads. Serialized JSON never starts with a zero byte.
+var aesGCMMagic = []byte{0x00, 'A', 'E', 'S'}
+
+type aesGCMCodec struct {
+	keyID string
+	aeads map[string]cipher.AEAD
+
+	plaintextPassthrough bool
+}
+
+// AESGCMOption configures the AES-GCM codec
+type AESGCMOption func(*aesGCMCodec)
+
+// WithPlaintextPassthrough decodes payloads that are not encrypted unchanged instead of failing. Use it to migrate
+// existing instances whose payloads have been written before the codec was configured. Without it, anyone who can
+// write to the backend could inject unencrypted payloads.
+func WithPlaintextPassthrough() AESGCMOption {
+	return func(c *aesGCMCodec) {
+		c.plaintextPassthrough = true
+	}
+}
+
+// NewAESGCMCodec returns a codec encrypting payloads using AES-GCM with the key identified by keyID. keys maps key
+// ids to 16, 24, or 32 byte AES keys. The id of the key is stored with every payload, so to rotate keys, add a new key
+// and pass its id as keyID while keeping the previous keys to decrypt existing payloads.
+//
+// Payloads that are not encrypted fail to decode, unless WithPlaintextPassthrough is passed.
+func NewAESGCMCodec(keyID string, keys map[string][]byte, opts ...AESGCMOption) (PayloadCodec, error) {
+	if len(keyID) > 255 {
+		return nil, errors.New("key id must not be longer than 255 bytes")
+	}
+
+	if _, ok := keys[keyID]; !ok {
+		return nil, fmt.Errorf("key %q not found", keyID)
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("creating cipher for key %q: %w", id, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("creating cipher for key %q: %w", id, err)
+		}
+
+		aeads[id] = aead
+	}
+
+	c := &aesGCMCodec{
+		keyID: keyID,
+		aeads: aeads,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Encode encrypts the payload. Encrypted payloads are laid out as magic, key id length, key id, nonce, ciphertext.
+func (c *aesGCMCodec) Encode(p payload.Payload) (payload.Payload, error) {
+	if len(p) == 0 {
+		return p, nil
+	}
+
+	aead := c.aeads[c.keyID]
+
+	header := make([]byte, 0, len(aesGCMMagic)+1+len(c.keyID))
+	header = append(header, aesGCMMagic...)
+	header = append(header, byte(len(c.keyID)))
+	header = append(header, c.keyID...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(header)+len(nonce)+len(p)+aead.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+
+	// Authenticate the header, so the key id cannot be changed
+	return aead.Seal(out, nonce, p, header), nil
+}
+
+func (c *aesGCMCodec) Decode(p payload.Payload) (payload.Payload, error) {
+	if !bytes.HasPrefix(p, aesGCMMagic) {
+		// Empty payloads are not encrypted
+		if len(p) == 0 || c.plaintextPassthrough {
+			return p, nil
+		}
+
+		return nil, errors.New("payload is not encrypted")
+	}
+
+	rest := p[len(aesGCMMagic):]
+	if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+		return nil, errors.New("invalid encrypted payload")
+	}
+
+	keyID := string(rest[1 : 1+int(rest[0])])
+	header := p[:len(aesGCMMagic)+1+len(keyID)]
+	rest = rest[1+len(keyID):]
+
+	aead, ok := c.aeads[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found", keyID)
+	}
+
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("invalid encrypted payload")
+	}
+
+	nonce, ciphertext := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting payload with key %q: %w", keyID, err)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,139 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// PayloadCodec transforms serialized payloads before they are persisted and restores them after they are loaded,
+// for example to compress or encrypt them.
+type PayloadCodec interface {
+	Encode(p payload.Payload) (payload.Payload, error)
+	Decode(p payload.Payload) (payload.Payload, error)
+}
+
+// plaintextCodec is implemented by codecs that need the serialized payload before any codecs were applied to it, for
+// example to name stored payloads deterministically even if an earlier codec is not deterministic
+type plaintextCodec interface {
+	PayloadCodec
+
+	EncodePlaintext(plaintext, p payload.Payload) (payload.Payload, error)
+}
+
+func encodePlaintext(codec PayloadCodec, plaintext, p payload.Payload) (payload.Payload, error) {
+	if pc, ok := codec.(plaintextCodec); ok {
+		return pc.EncodePlaintext(plaintext, p)
+	}
+
+	return codec.Encode(p)
+}
+
+type chainCodec struct {
+	codecs []PayloadCodec
+}
+
+// NewChainCodec returns a codec that encodes payloads with the given codecs in order, and decodes them in reverse
+// order. Pass a compression codec before an encryption codec, encrypted payloads cannot be compressed.
+func NewChainCodec(codecs ...PayloadCodec) PayloadCodec {
+	return &chainCodec{codecs}
+}
+
+func (c *chainCodec) Encode(p payload.Payload) (payload.Payload, error) {
+	return c.EncodePlaintext(p, p)
+}
+
+func (c *chainCodec) EncodePlaintext(plaintext, p payload.Payload) (payload.Payload, error) {
+	for _, codec := range c.codecs {
+		var err error
+		if p, err = encodePlaintext(codec, plaintext, p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func (c *chainCodec) ForInstance(ctx context.Context, instance *core.WorkflowInstance) PayloadCodec {
+	return c.forInstances(ctx, []*core.WorkflowInstance{instance})
+}
+
+func (c *chainCodec) forInstances(ctx context.Context, instances []*core.WorkflowInstance) PayloadCodec {
+	codecs := make([]PayloadCodec, len(c.codecs))
+	for i, codec := range c.codecs {
+		codecs[i] = codecForInstances(ctx, codec, instances)
+	}
+
+	return &chainCodec{codecs}
+}
+
+func (c *chainCodec) Decode(p payload.Payload) (payload.Payload, error) {
+	for i := len(c.codecs) - 1; i >= 0; i-- {
+		var err error
+		if p, err = c.codecs[i].Decode(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+type codecConverter struct {
+	converter Converter
+	codec     PayloadCodec
+}
+
+// NewCodecConverter returns a converter that serializes values with the given converter and then encodes the
+// payloads with the given codecs, see NewChainCodec.
+func NewCodecConverter(converter Converter, codecs ...PayloadCodec) Converter {
+	return &codecConverter{
+		converter: converter,
+		codec:     NewChainCodec(codecs...),
+	}
+}
+
+// DecodePayload returns the given payload with the codecs of the given converter undone. Codecs are not necessarily
+// deterministic, e.g. encryption uses a random nonce, so payloads have to be decoded before they can be compared.
+func DecodePayload(c Converter, p payload.Payload) (payload.Payload, error) {
+	if tc, ok := c.(*TaskConverter); ok {
+		c = tc.current
+	}
+
+	if cc, ok := c.(*codecConverter); ok {
+		return cc.codec.Decode(p)
+	}
+
+	return p, nil
+}
+
+func (cc *codecConverter) forInstances(ctx context.Context, instances []*core.WorkflowInstance) Converter {
+	return &codecConverter{
+		converter: ForInstances(ctx, cc.converter, instances...),
+		codec:     codecForInstances(ctx, cc.codec, instances),
+	}
+}
+
+func (cc *codecConverter) To(v interface{}) (payload.Payload, error) {
+	p, err := cc.converter.To(v)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = cc.codec.Encode(p)
+	if err != nil {
+		return nil, fmt.Errorf("encoding payload: %w", err)
+	}
+
+	return p, nil
+}
+
+func (cc *codecConverter) From(data payload.Payload, v interface{}) error {
+	data, err := cc.codec.Decode(data)
+	if err != nil {
+		return fmt.Errorf("decoding payload: %w", err)
+	}
+
+	return cc.converter.From(data, v)
+}
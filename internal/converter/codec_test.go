@@ -0,0 +1,116 @@
+package converter
+
+import (
+	"compress/gzip"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CodecConverter_RoundTrip(t *testing.T) {
+	gzipCodec, err := NewGzipCodec(gzip.DefaultCompression)
+	require.NoError(t, err)
+
+	aesCodec, err := NewAESGCMCodec("key1", map[string][]byte{
+		"key1": []byte("0123456789abcdef"),
+	})
+	require.NoError(t, err)
+
+	c := NewCodecConverter(DefaultConverter, gzipCodec, aesCodec)
+
+	p, err := c.To("secret value")
+	require.NoError(t, err)
+	require.NotContains(t, string(p), "secret value")
+
+	var r string
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, "secret value", r)
+}
+
+func Test_CodecConverter_DecodesPlainPayloads(t *testing.T) {
+	gzipCodec, err := NewGzipCodec(gzip.BestSpeed)
+	require.NoError(t, err)
+
+	aesCodec, err := NewAESGCMCodec("key1", map[string][]byte{
+		"key1": []byte("0123456789abcdef"),
+	}, WithPlaintextPassthrough())
+	require.NoError(t, err)
+
+	c := NewCodecConverter(DefaultConverter, gzipCodec, aesCodec)
+
+	// Payloads written before the codecs were configured can still be read
+	var r int
+	require.NoError(t, c.From(payload.Payload("42"), &r))
+	require.Equal(t, 42, r)
+}
+
+func Test_AESGCMCodec_RejectsPlainPayloads(t *testing.T) {
+	c, err := NewAESGCMCodec("key1", map[string][]byte{
+		"key1": []byte("0123456789abcdef"),
+	})
+	require.NoError(t, err)
+
+	_, err = c.Decode(payload.Payload("42"))
+	require.Error(t, err)
+
+	// Empty payloads are never encrypted
+	p, err := c.Decode(payload.Payload{})
+	require.NoError(t, err)
+	require.Empty(t, p)
+}
+
+func Test_AESGCMCodec_KeyRotation(t *testing.T) {
+	keys := map[string][]byte{
+		"key1": []byte("0123456789abcdef"),
+	}
+
+	old, err := NewAESGCMCodec("key1", keys)
+	require.NoError(t, err)
+
+	p, err := old.Encode(payload.Payload(`"value"`))
+	require.NoError(t, err)
+
+	keys["key2"] = []byte("fedcba9876543210fedcba9876543210")
+	rotated, err := NewAESGCMCodec("key2", keys)
+	require.NoError(t, err)
+
+	// Existing payloads are decrypted with the previous key
+	d, err := rotated.Decode(p)
+	require.NoError(t, err)
+	require.Equal(t, payload.Payload(`"value"`), d)
+
+	// New payloads use the new key and cannot be decrypted without it
+	p2, err := rotated.Encode(payload.Payload(`"value"`))
+	require.NoError(t, err)
+
+	_, err = old.Decode(p2)
+	require.EqualError(t, err, `key "key2" not found`)
+}
+
+func Test_AESGCMCodec_Tampered(t *testing.T) {
+	c, err := NewAESGCMCodec("key1", map[string][]byte{
+		"key1": []byte("0123456789abcdef"),
+	})
+	require.NoError(t, err)
+
+	p, err := c.Encode(payload.Payload(`"value"`))
+	require.NoError(t, err)
+
+	p[len(p)-1] ^= 0xff
+
+	_, err = c.Decode(p)
+	require.Error(t, err)
+}
+
+func Test_NewAESGCMCodec_InvalidKeys(t *testing.T) {
+	_, err := NewAESGCMCodec("key2", map[string][]byte{
+		"key1": []byte("0123456789abcdef"),
+	})
+	require.EqualError(t, err, `key "key2" not found`)
+
+	_, err = NewAESGCMCodec("key1", map[string][]byte{
+		"key1": []byte("short"),
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,91 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// PayloadConverter serializes values using a single encoding. It is used by the composite converter, which records
+// the encoding in the payload.
+type PayloadConverter interface {
+	// Encoding returns the encoding of the payloads produced by this converter
+	Encoding() string
+
+	// ToData serializes the given value. If the converter cannot serialize values of this type, ok is false and the
+	// composite converter tries the next converter.
+	ToData(v interface{}) (data []byte, messageType string, ok bool, err error)
+
+	// FromData deserializes data into the value vptr points to
+	FromData(data []byte, messageType string, vptr interface{}) error
+}
+
+type compositeConverter struct {
+	converters []PayloadConverter
+	byEncoding map[string]PayloadConverter
+}
+
+// NewCompositeConverter returns a converter that serializes each value with the first of the given converters that
+// can handle it, and prefixes the payload with its encoding. Payloads are deserialized with the converter matching
+// their encoding, payloads without an encoding header are treated as JSON.
+func NewCompositeConverter(converters ...PayloadConverter) Converter {
+	byEncoding := make(map[string]PayloadConverter, len(converters))
+	for _, c := range converters {
+		byEncoding[c.Encoding()] = c
+	}
+
+	return &compositeConverter{
+		converters: converters,
+		byEncoding: byEncoding,
+	}
+}
+
+func (cc *compositeConverter) To(v interface{}) (payload.Payload, error) {
+	for _, c := range cc.converters {
+		data, messageType, ok, err := c.ToData(v)
+		if err != nil {
+			return nil, fmt.Errorf("converting value with encoding %s: %w", c.Encoding(), err)
+		}
+
+		if ok {
+			return Envelope(EncodingInfo{Encoding: c.Encoding(), MessageType: messageType}, data), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no converter for value of type %T", v)
+}
+
+func (cc *compositeConverter) From(p payload.Payload, vptr interface{}) error {
+	info, data, err := OpenEnvelope(p)
+	if err != nil {
+		return err
+	}
+
+	c, ok := cc.byEncoding[info.Encoding]
+	if !ok {
+		return fmt.Errorf("no converter for encoding %s", info.Encoding)
+	}
+
+	return c.FromData(data, info.MessageType, vptr)
+}
+
+type jsonPayloadConverter struct{}
+
+// NewJSONPayloadConverter returns a payload converter serializing any value as JSON
+func NewJSONPayloadConverter() PayloadConverter {
+	return &jsonPayloadConverter{}
+}
+
+func (*jsonPayloadConverter) Encoding() string {
+	return EncodingJSON
+}
+
+func (*jsonPayloadConverter) ToData(v interface{}) ([]byte, string, bool, error) {
+	data, err := json.Marshal(v)
+	return data, "", err == nil, err
+}
+
+func (*jsonPayloadConverter) FromData(data []byte, _ string, vptr interface{}) error {
+	return json.Unmarshal(data, vptr)
+}
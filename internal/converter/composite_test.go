@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func Test_ProtoConverter_Message(t *testing.T) {
+	c := NewProtoConverter()
+
+	p, err := c.To(wrapperspb.String("hello"))
+	require.NoError(t, err)
+
+	info, _, err := OpenEnvelope(p)
+	require.NoError(t, err)
+	require.Equal(t, EncodingInfo{Encoding: EncodingProtobuf, MessageType: "google.protobuf.StringValue"}, info)
+
+	// Decode into a message pointer, like workflow and activity arguments
+	var r *wrapperspb.StringValue
+	require.NoError(t, c.From(p, &r))
+	require.True(t, proto.Equal(wrapperspb.String("hello"), r))
+
+	// Decode into a message
+	var m wrapperspb.StringValue
+	require.NoError(t, c.From(p, &m))
+	require.Equal(t, "hello", m.Value)
+}
+
+func Test_ProtoConverter_MismatchedMessageType(t *testing.T) {
+	c := NewProtoConverter()
+
+	p, err := c.To(wrapperspb.String("hello"))
+	require.NoError(t, err)
+
+	var r *wrapperspb.Int64Value
+	require.EqualError(t, c.From(p, &r), "cannot decode protobuf message google.protobuf.StringValue into google.protobuf.Int64Value")
+}
+
+func Test_ProtoConverter_JSON(t *testing.T) {
+	c := NewProtoConverter()
+
+	p, err := c.To(map[string]int{"a": 1})
+	require.NoError(t, err)
+
+	info, data, err := OpenEnvelope(p)
+	require.NoError(t, err)
+	require.Equal(t, EncodingJSON, info.Encoding)
+	require.Equal(t, `{"a":1}`, string(data))
+
+	var r map[string]int
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, map[string]int{"a": 1}, r)
+}
+
+func Test_CompositeConverter_ReadsPlainJSON(t *testing.T) {
+	c := NewProtoConverter()
+
+	// Payloads written by the default converter do not have an encoding header
+	p, err := DefaultConverter.To(42)
+	require.NoError(t, err)
+
+	var r int
+	require.NoError(t, c.From(p, &r))
+	require.Equal(t, 42, r)
+}
+
+func Test_CompositeConverter_UnknownEncoding(t *testing.T) {
+	c := NewCompositeConverter(NewJSONPayloadConverter())
+
+	p := Envelope(EncodingInfo{Encoding: EncodingProtobuf}, []byte{})
+
+	var r int
+	require.EqualError(t, c.From(p, &r), "no converter for encoding binary/protobuf")
+}
+
+func Test_OpenEnvelope_Invalid(t *testing.T) {
+	_, _, err := OpenEnvelope(payload.Payload(append(envelopeMagic, 0x05, 'a')))
+	require.EqualError(t, err, "invalid payload encoding header")
+}
@@ -0,0 +1,75 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+const (
+	// EncodingJSON is the encoding of values serialized as JSON. Payloads without an encoding header are assumed to
+	// be JSON, which is how payloads have been written before encodings were introduced.
+	EncodingJSON = "json/plain"
+
+	// EncodingProtobuf is the encoding of protocol buffer messages serialized in the binary wire format
+	EncodingProtobuf = "binary/protobuf"
+)
+
+// envelopeMagic marks payloads with an encoding header. Serialized JSON never starts with this byte.
+var envelopeMagic = []byte{0x01, 'W', 'F', 'P'}
+
+// EncodingInfo describes how a payload has been serialized
+type EncodingInfo struct {
+	Encoding string
+
+	// MessageType is the type of the serialized value, if the encoding records it, like the full name of a
+	// protocol buffer message
+	MessageType string
+}
+
+// Envelope prefixes the serialized data with a header describing its encoding. The header is laid out as magic,
+// length of the encoding, encoding, length of the message type, message type.
+func Envelope(info EncodingInfo, data []byte) payload.Payload {
+	p := make([]byte, 0, len(envelopeMagic)+2*binary.MaxVarintLen64+len(info.Encoding)+len(info.MessageType)+len(data))
+	p = append(p, envelopeMagic...)
+	p = binary.AppendUvarint(p, uint64(len(info.Encoding)))
+	p = append(p, info.Encoding...)
+	p = binary.AppendUvarint(p, uint64(len(info.MessageType)))
+	p = append(p, info.MessageType...)
+	p = append(p, data...)
+
+	return p
+}
+
+// OpenEnvelope returns the encoding and the serialized data of the given payload. Payloads without an encoding
+// header are returned unchanged as JSON.
+func OpenEnvelope(p payload.Payload) (EncodingInfo, []byte, error) {
+	if !bytes.HasPrefix(p, envelopeMagic) {
+		return EncodingInfo{Encoding: EncodingJSON}, p, nil
+	}
+
+	rest := p[len(envelopeMagic):]
+
+	encoding, rest, err := readString(rest)
+	if err != nil {
+		return EncodingInfo{}, nil, err
+	}
+
+	messageType, rest, err := readString(rest)
+	if err != nil {
+		return EncodingInfo{}, nil, err
+	}
+
+	return EncodingInfo{Encoding: encoding, MessageType: messageType}, rest, nil
+}
+
+func readString(b []byte) (string, []byte, error) {
+	l, n := binary.Uvarint(b)
+	if n <= 0 || uint64(len(b)-n) < l {
+		return "", nil, errors.New("invalid payload encoding header")
+	}
+
+	return string(b[n : n+int(l)]), b[n+int(l):], nil
+}
@@ -0,0 +1,63 @@
+package converter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+type gzipCodec struct {
+	level int
+}
+
+// NewGzipCodec returns a codec compressing payloads with gzip. Payloads that are not compressed, for example
+// because they have been written before the codec was configured, are decoded unchanged.
+func NewGzipCodec(level int) (PayloadCodec, error) {
+	// Validate level
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		return nil, err
+	}
+
+	return &gzipCodec{level}, nil
+}
+
+func (c *gzipCodec) Encode(p payload.Payload) (payload.Payload, error) {
+	if len(p) == 0 {
+		return p, nil
+	}
+
+	var buf bytes.Buffer
+
+	w, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(p); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c *gzipCodec) Decode(p payload.Payload) (payload.Payload, error) {
+	if !bytes.HasPrefix(p, gzipMagic) {
+		return p, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(p))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,140 @@
+package converter
+
+import (
+	"context"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// InstanceCodec is implemented by codecs that need to know the workflow instance payloads belong to, for example to
+// store them per instance
+type InstanceCodec interface {
+	PayloadCodec
+
+	// ForInstance returns a codec for payloads of the given workflow instance. Any I/O the returned codec does uses
+	// the given context.
+	//
+	// Payloads recorded in the histories of several instances, like the inputs of sub-workflows, are encoded with the
+	// codec of each of them and decoded by each of them, so the codecs of all instances have to encode a payload
+	// the same way.
+	ForInstance(ctx context.Context, instance *core.WorkflowInstance) PayloadCodec
+}
+
+type instanceConverter interface {
+	forInstances(ctx context.Context, instances []*core.WorkflowInstance) Converter
+}
+
+// ForInstance returns a converter for payloads of the given workflow instance. Converters without codecs that depend
+// on the instance are returned unchanged.
+func ForInstance(ctx context.Context, c Converter, instance *core.WorkflowInstance) Converter {
+	return ForInstances(ctx, c, instance)
+}
+
+// ForInstances returns a converter for payloads that are recorded in the histories of all of the given workflow
+// instances. Payloads are decoded for the first instance.
+func ForInstances(ctx context.Context, c Converter, instances ...*core.WorkflowInstance) Converter {
+	if ic, ok := c.(instanceConverter); ok {
+		return ic.forInstances(ctx, instances)
+	}
+
+	return c
+}
+
+// SharedWith returns a converter for payloads that are recorded in the histories of the given workflow instances as
+// well as in the history of the instance the converter is bound to, for example the inputs of sub-workflows.
+// Converters that are not bound to a workflow instance are returned unchanged.
+func SharedWith(c Converter, instances ...*core.WorkflowInstance) Converter {
+	tc, ok := c.(*TaskConverter)
+	if !ok || len(instances) == 0 {
+		return c
+	}
+
+	return ForInstances(tc.ctx, tc.converter, append([]*core.WorkflowInstance{tc.instance}, instances...)...)
+}
+
+func codecForInstances(ctx context.Context, codec PayloadCodec, instances []*core.WorkflowInstance) PayloadCodec {
+	switch c := codec.(type) {
+	case *chainCodec:
+		return c.forInstances(ctx, instances)
+
+	case InstanceCodec:
+		if len(instances) == 1 {
+			return c.ForInstance(ctx, instances[0])
+		}
+
+		codecs := make([]PayloadCodec, len(instances))
+		for i, instance := range instances {
+			codecs[i] = c.ForInstance(ctx, instance)
+		}
+
+		return &sharedCodec{codecs}
+	}
+
+	return codec
+}
+
+// sharedCodec encodes payloads recorded in the histories of several workflow instances with the codec of each of
+// them, and decodes them with the codec of the first instance
+type sharedCodec struct {
+	codecs []PayloadCodec
+}
+
+func (c *sharedCodec) Encode(p payload.Payload) (payload.Payload, error) {
+	return c.EncodePlaintext(p, p)
+}
+
+func (c *sharedCodec) EncodePlaintext(plaintext, p payload.Payload) (payload.Payload, error) {
+	var r payload.Payload
+
+	for i, codec := range c.codecs {
+		e, err := encodePlaintext(codec, plaintext, p)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			r = e
+		}
+	}
+
+	return r, nil
+}
+
+func (c *sharedCodec) Decode(p payload.Payload) (payload.Payload, error) {
+	return c.codecs[0].Decode(p)
+}
+
+// TaskConverter is the converter of a workflow instance. Workflows keep their converter for their whole lifetime,
+// SetContext binds it to the context of the workflow task currently being executed.
+type TaskConverter struct {
+	converter Converter
+	instance  *core.WorkflowInstance
+	ctx       context.Context
+	current   Converter
+}
+
+func NewTaskConverter(c Converter, instance *core.WorkflowInstance) *TaskConverter {
+	tc := &TaskConverter{
+		converter: c,
+		instance:  instance,
+	}
+
+	tc.SetContext(context.Background())
+
+	return tc
+}
+
+// SetContext binds the converter to the context of the current workflow task
+func (tc *TaskConverter) SetContext(ctx context.Context) {
+	tc.ctx = ctx
+	tc.current = ForInstance(ctx, tc.converter, tc.instance)
+}
+
+func (tc *TaskConverter) To(v interface{}) (payload.Payload, error) {
+	return tc.current.To(v)
+}
+
+func (tc *TaskConverter) From(data payload.Payload, v interface{}) error {
+	return tc.current.From(data, v)
+}
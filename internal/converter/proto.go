@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type protoPayloadConverter struct{}
+
+// NewProtoPayloadConverter returns a payload converter serializing protocol buffer messages in the binary wire
+// format. Other values are left to the next converter.
+func NewProtoPayloadConverter() PayloadConverter {
+	return &protoPayloadConverter{}
+}
+
+// NewProtoConverter returns a converter serializing protocol buffer messages in the binary wire format, and all
+// other values as JSON
+func NewProtoConverter() Converter {
+	return NewCompositeConverter(NewProtoPayloadConverter(), NewJSONPayloadConverter())
+}
+
+func (*protoPayloadConverter) Encoding() string {
+	return EncodingProtobuf
+}
+
+func (*protoPayloadConverter) ToData(v interface{}) ([]byte, string, bool, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, "", false, nil
+	}
+
+	// Payloads are compared when replaying workflows, so they have to be serialized deterministically
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	return data, string(m.ProtoReflect().Descriptor().FullName()), true, nil
+}
+
+func (*protoPayloadConverter) FromData(data []byte, messageType string, vptr interface{}) error {
+	var m proto.Message
+
+	switch v := vptr.(type) {
+	case proto.Message:
+		// Pointer to a message struct
+		m = v
+
+	default:
+		// Pointer to a message pointer, like a *pb.Message argument or result
+		rv := reflect.ValueOf(vptr)
+		if rv.Kind() != reflect.Pointer || rv.IsNil() {
+			return fmt.Errorf("cannot decode protobuf message into %T", vptr)
+		}
+
+		elem := rv.Elem()
+		if elem.Kind() != reflect.Pointer {
+			return fmt.Errorf("cannot decode protobuf message into %T", vptr)
+		}
+
+		if elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+
+		em, ok := elem.Interface().(proto.Message)
+		if !ok {
+			return fmt.Errorf("cannot decode protobuf message into %T", vptr)
+		}
+
+		m = em
+	}
+
+	if messageType != "" {
+		if name := m.ProtoReflect().Descriptor().FullName(); name != protoreflect.FullName(messageType) {
+			return fmt.Errorf("cannot decode protobuf message %s into %s", messageType, name)
+		}
+	}
+
+	return proto.Unmarshal(data, m)
+}
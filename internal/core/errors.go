@@ -0,0 +1,13 @@
+package core
+
+import "errors"
+
+// ErrInstanceNotFound is returned when a workflow instance that is targeted by an operation does not exist.
+var ErrInstanceNotFound = errors.New("workflow instance not found")
+
+// ErrInstanceFinished is returned when a workflow instance that is targeted by an operation has already finished.
+var ErrInstanceFinished = errors.New("workflow instance has finished")
+
+// ErrInstanceAlreadyExists is returned when a workflow instance cannot be created because an instance with the same
+// ID already exists.
+var ErrInstanceAlreadyExists = errors.New("workflow instance already exists")
@@ -2,4 +2,7 @@ package history
 
 type ActivityFailedAttributes struct {
 	Reason string `json:"reason,omitempty"`
+
+	// StackTrace is the stack of the activity at the time it panicked, if it failed because of a panic
+	StackTrace string `json:"stack_trace,omitempty"`
 }
@@ -11,4 +11,10 @@ type ActivityScheduledAttributes struct {
 	Inputs []payload.Payload `json:"inputs,omitempty"`
 
 	Metadata core.WorkflowMetadata `json:"metadata,omitempty"`
+
+	// Attempt is the zero-based retry attempt this activity was scheduled for
+	Attempt int `json:"attempt,omitempty"`
+
+	// FirstScheduleEventID is the schedule event id of the first attempt, if this is a retry
+	FirstScheduleEventID int64 `json:"first_schedule_event_id,omitempty"`
 }
@@ -0,0 +1,98 @@
+package history
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+// ExternalWorkflowResults returns the events reporting the outcome of requests to other workflow instances recorded in
+// the given executed events: created sub-workflows, and delivered signals and cancellation requests. Backends add the
+// returned events to the pending events of the instance that sent the requests.
+//
+// targetErr returns nil if the target instance of a request is running, core.ErrInstanceNotFound if it does not
+// exist, and core.ErrInstanceFinished if it has already finished. For scheduled sub-workflows it returns
+// core.ErrInstanceAlreadyExists if the instance was not created because it already existed. Signals to finished
+// instances fail, cancellation requests for them succeed, since there is nothing left to cancel.
+//
+// Created sub-workflows are only reported if the parent waits for them to start, every result event costs the parent
+// another workflow task.
+func ExternalWorkflowResults(timestamp time.Time, executedEvents []*Event, targetErr func(instanceID string) error) []*Event {
+	var results []*Event
+
+	for _, event := range executedEvents {
+		switch event.Type {
+		case EventType_SubWorkflowScheduled:
+			a := event.Attributes.(*SubWorkflowScheduledAttributes)
+
+			if err := targetErr(a.SubWorkflowInstance.InstanceID); errors.Is(err, core.ErrInstanceAlreadyExists) {
+				results = append(results, NewPendingEvent(
+					timestamp,
+					EventType_SubWorkflowFailed,
+					&SubWorkflowFailedAttributes{
+						Error:                 err.Error(),
+						InstanceAlreadyExists: true,
+					},
+					ScheduleEventID(event.ScheduleEventID),
+				))
+			} else if a.NotifyStarted || a.Detached {
+				results = append(results, NewPendingEvent(
+					timestamp,
+					EventType_SubWorkflowStarted,
+					&SubWorkflowStartedAttributes{
+						SubWorkflowInstance: a.SubWorkflowInstance,
+					},
+					ScheduleEventID(event.ScheduleEventID),
+				))
+			}
+
+		case EventType_SignalExternalWorkflowScheduled:
+			a := event.Attributes.(*SignalExternalWorkflowScheduledAttributes)
+
+			if err := targetErr(a.InstanceID); err == nil {
+				results = append(results, NewPendingEvent(
+					timestamp,
+					EventType_SignalExternalWorkflowCompleted,
+					&SignalExternalWorkflowCompletedAttributes{},
+					ScheduleEventID(event.ScheduleEventID),
+				))
+			} else {
+				results = append(results, NewPendingEvent(
+					timestamp,
+					EventType_SignalExternalWorkflowFailed,
+					&SignalExternalWorkflowFailedAttributes{
+						Error:            err.Error(),
+						InstanceNotFound: errors.Is(err, core.ErrInstanceNotFound),
+						InstanceFinished: errors.Is(err, core.ErrInstanceFinished),
+					},
+					ScheduleEventID(event.ScheduleEventID),
+				))
+			}
+
+		case EventType_RequestCancelExternalWorkflowScheduled:
+			a := event.Attributes.(*RequestCancelExternalWorkflowScheduledAttributes)
+
+			if err := targetErr(a.InstanceID); err == nil || errors.Is(err, core.ErrInstanceFinished) {
+				results = append(results, NewPendingEvent(
+					timestamp,
+					EventType_RequestCancelExternalWorkflowCompleted,
+					&RequestCancelExternalWorkflowCompletedAttributes{},
+					ScheduleEventID(event.ScheduleEventID),
+				))
+			} else {
+				results = append(results, NewPendingEvent(
+					timestamp,
+					EventType_RequestCancelExternalWorkflowFailed,
+					&RequestCancelExternalWorkflowFailedAttributes{
+						Error:            core.ErrInstanceNotFound.Error(),
+						InstanceNotFound: true,
+					},
+					ScheduleEventID(event.ScheduleEventID),
+				))
+			}
+		}
+	}
+
+	return results
+}
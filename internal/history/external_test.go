@@ -0,0 +1,97 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalWorkflowResults(t *testing.T) {
+	now := time.Now()
+
+	executedEvents := []*Event{
+		NewPendingEvent(now, EventType_TimerScheduled, &TimerScheduledAttributes{}, ScheduleEventID(1)),
+		NewPendingEvent(now, EventType_SubWorkflowScheduled, &SubWorkflowScheduledAttributes{
+			SubWorkflowInstance: core.NewSubWorkflowInstance("sub", "exid", "parent", 6),
+			NotifyStarted:       true,
+		}, ScheduleEventID(6)),
+		NewPendingEvent(now, EventType_SubWorkflowScheduled, &SubWorkflowScheduledAttributes{
+			SubWorkflowInstance: core.NewSubWorkflowInstance("sub-silent", "exid", "parent", 9),
+		}, ScheduleEventID(9)),
+		NewPendingEvent(now, EventType_SubWorkflowScheduled, &SubWorkflowScheduledAttributes{
+			SubWorkflowInstance: core.NewSubWorkflowInstance("duplicate", "exid", "parent", 10),
+			NotifyStarted:       true,
+		}, ScheduleEventID(10)),
+		NewPendingEvent(now, EventType_SignalExternalWorkflowScheduled, &SignalExternalWorkflowScheduledAttributes{
+			InstanceID: "exists",
+			Name:       "signal",
+		}, ScheduleEventID(2)),
+		NewPendingEvent(now, EventType_SignalExternalWorkflowScheduled, &SignalExternalWorkflowScheduledAttributes{
+			InstanceID: "missing",
+			Name:       "signal",
+		}, ScheduleEventID(3)),
+		NewPendingEvent(now, EventType_SignalExternalWorkflowScheduled, &SignalExternalWorkflowScheduledAttributes{
+			InstanceID: "finished",
+			Name:       "signal",
+		}, ScheduleEventID(7)),
+		NewPendingEvent(now, EventType_RequestCancelExternalWorkflowScheduled, &RequestCancelExternalWorkflowScheduledAttributes{
+			InstanceID: "exists",
+		}, ScheduleEventID(4)),
+		NewPendingEvent(now, EventType_RequestCancelExternalWorkflowScheduled, &RequestCancelExternalWorkflowScheduledAttributes{
+			InstanceID: "missing",
+		}, ScheduleEventID(5)),
+		NewPendingEvent(now, EventType_RequestCancelExternalWorkflowScheduled, &RequestCancelExternalWorkflowScheduledAttributes{
+			InstanceID: "finished",
+		}, ScheduleEventID(8)),
+	}
+
+	r := ExternalWorkflowResults(now, executedEvents, func(instanceID string) error {
+		switch instanceID {
+		case "missing":
+			return core.ErrInstanceNotFound
+		case "finished":
+			return core.ErrInstanceFinished
+		case "duplicate":
+			return core.ErrInstanceAlreadyExists
+		}
+
+		return nil
+	})
+
+	require.Len(t, r, 8)
+
+	require.Equal(t, EventType_SubWorkflowStarted, r[0].Type)
+	require.Equal(t, int64(6), r[0].ScheduleEventID)
+	require.Equal(t, "sub", r[0].Attributes.(*SubWorkflowStartedAttributes).SubWorkflowInstance.InstanceID)
+
+	// Sub-workflows nobody waits to start for are not reported, duplicates fail
+	require.Equal(t, EventType_SubWorkflowFailed, r[1].Type)
+	require.Equal(t, int64(10), r[1].ScheduleEventID)
+	require.True(t, r[1].Attributes.(*SubWorkflowFailedAttributes).InstanceAlreadyExists)
+	r = r[2:]
+
+	require.Equal(t, EventType_SignalExternalWorkflowCompleted, r[0].Type)
+	require.Equal(t, int64(2), r[0].ScheduleEventID)
+
+	require.Equal(t, EventType_SignalExternalWorkflowFailed, r[1].Type)
+	require.Equal(t, int64(3), r[1].ScheduleEventID)
+	require.True(t, r[1].Attributes.(*SignalExternalWorkflowFailedAttributes).InstanceNotFound)
+
+	// Signals to finished instances fail
+	require.Equal(t, EventType_SignalExternalWorkflowFailed, r[2].Type)
+	require.Equal(t, int64(7), r[2].ScheduleEventID)
+	require.True(t, r[2].Attributes.(*SignalExternalWorkflowFailedAttributes).InstanceFinished)
+
+	require.Equal(t, EventType_RequestCancelExternalWorkflowCompleted, r[3].Type)
+	require.Equal(t, int64(4), r[3].ScheduleEventID)
+
+	require.Equal(t, EventType_RequestCancelExternalWorkflowFailed, r[4].Type)
+	require.Equal(t, int64(5), r[4].ScheduleEventID)
+	require.True(t, r[4].Attributes.(*RequestCancelExternalWorkflowFailedAttributes).InstanceNotFound)
+
+	// Finished instances don't need to be canceled anymore
+	require.Equal(t, EventType_RequestCancelExternalWorkflowCompleted, r[5].Type)
+	require.Equal(t, int64(8), r[5].ScheduleEventID)
+}
@@ -53,6 +53,30 @@ const (
 
 	// Recorded result of a side-efect
 	EventType_SideEffectResult
+
+	// Signal to another workflow instance has been scheduled
+	EventType_SignalExternalWorkflowScheduled
+	// Signal has been delivered to the other workflow instance
+	EventType_SignalExternalWorkflowCompleted
+	// Signal could not be delivered to the other workflow instance
+	EventType_SignalExternalWorkflowFailed
+
+	// Cancellation of another workflow instance has been requested
+	EventType_RequestCancelExternalWorkflowScheduled
+	// Cancellation request has been delivered to the other workflow instance
+	EventType_RequestCancelExternalWorkflowCompleted
+	// Cancellation request could not be delivered to the other workflow instance
+	EventType_RequestCancelExternalWorkflowFailed
+
+	// SubWorkflow has been started
+	EventType_SubWorkflowStarted
+
+	// Search attributes of the workflow instance have been updated
+	EventType_SearchAttributesUpserted
+
+	// Workflow task could not be completed and will be retried. This event is added to the history instead of the
+	// events of the failed task, it is ignored when replaying the history.
+	EventType_WorkflowTaskFailed
 )
 
 func (et EventType) String() string {
@@ -98,11 +122,45 @@ func (et EventType) String() string {
 	case EventType_SideEffectResult:
 		return "SideEffectResult"
 
+	case EventType_SignalExternalWorkflowScheduled:
+		return "SignalExternalWorkflowScheduled"
+	case EventType_SignalExternalWorkflowCompleted:
+		return "SignalExternalWorkflowCompleted"
+	case EventType_SignalExternalWorkflowFailed:
+		return "SignalExternalWorkflowFailed"
+
+	case EventType_RequestCancelExternalWorkflowScheduled:
+		return "RequestCancelExternalWorkflowScheduled"
+	case EventType_RequestCancelExternalWorkflowCompleted:
+		return "RequestCancelExternalWorkflowCompleted"
+	case EventType_RequestCancelExternalWorkflowFailed:
+		return "RequestCancelExternalWorkflowFailed"
+
+	case EventType_SubWorkflowStarted:
+		return "SubWorkflowStarted"
+
+	case EventType_SearchAttributesUpserted:
+		return "SearchAttributesUpserted"
+
+	case EventType_WorkflowTaskFailed:
+		return "WorkflowTaskFailed"
+
 	default:
 		return "Unknown"
 	}
 }
 
+// ParseEventType returns the event type with the given name as returned by String
+func ParseEventType(name string) (EventType, bool) {
+	for et := EventType_WorkflowExecutionStarted; et.String() != "Unknown"; et++ {
+		if et.String() == name {
+			return et, true
+		}
+	}
+
+	return 0, false
+}
+
 type Event struct {
 	// ID is a unique identifier for this event
 	ID string `json:"id,omitempty"`
@@ -0,0 +1,8 @@
+package history
+
+type RequestCancelExternalWorkflowFailedAttributes struct {
+	Error string `json:"error,omitempty"`
+
+	// InstanceNotFound indicates that the target workflow instance does not exist
+	InstanceNotFound bool `json:"instance_not_found,omitempty"`
+}
@@ -0,0 +1,5 @@
+package history
+
+type RequestCancelExternalWorkflowScheduledAttributes struct {
+	InstanceID string `json:"instance_id,omitempty"`
+}
@@ -0,0 +1,30 @@
+package history
+
+import "github.com/cschleiden/go-workflows/search"
+
+type SearchAttributesUpsertedAttributes struct {
+	SearchAttributes search.Attributes `json:"search_attributes,omitempty"`
+}
+
+// SearchAttributeUpdates merges all search attribute updates recorded in the given events, later updates
+// take precedence. A nil value means the attribute was removed. Backends apply the result to their index.
+func SearchAttributeUpdates(events []*Event) search.Attributes {
+	var updates search.Attributes
+
+	for _, event := range events {
+		if event.Type != EventType_SearchAttributesUpserted {
+			continue
+		}
+
+		if updates == nil {
+			updates = make(search.Attributes)
+		}
+
+		a := event.Attributes.(*SearchAttributesUpsertedAttributes)
+		for k, v := range a.SearchAttributes {
+			updates[k] = v
+		}
+	}
+
+	return updates
+}
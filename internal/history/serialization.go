@@ -43,6 +43,8 @@ func DeserializeAttributes(eventType EventType, attributes []byte) (attr interfa
 
 	case EventType_WorkflowTaskStarted:
 		attr = &WorkflowTaskStartedAttributes{}
+	case EventType_WorkflowTaskFailed:
+		attr = &WorkflowTaskFailedAttributes{}
 
 	case EventType_ActivityScheduled:
 		attr = &ActivityScheduledAttributes{}
@@ -66,6 +68,8 @@ func DeserializeAttributes(eventType EventType, attributes []byte) (attr interfa
 
 	case EventType_SubWorkflowScheduled:
 		attr = &SubWorkflowScheduledAttributes{}
+	case EventType_SubWorkflowStarted:
+		attr = &SubWorkflowStartedAttributes{}
 	case EventType_SubWorkflowCancellationRequested:
 		attr = &SubWorkflowCancellationRequestedAttributes{}
 	case EventType_SubWorkflowCompleted:
@@ -73,6 +77,23 @@ func DeserializeAttributes(eventType EventType, attributes []byte) (attr interfa
 	case EventType_SubWorkflowFailed:
 		attr = &SubWorkflowFailedAttributes{}
 
+	case EventType_SignalExternalWorkflowScheduled:
+		attr = &SignalExternalWorkflowScheduledAttributes{}
+	case EventType_SignalExternalWorkflowCompleted:
+		attr = &SignalExternalWorkflowCompletedAttributes{}
+	case EventType_SignalExternalWorkflowFailed:
+		attr = &SignalExternalWorkflowFailedAttributes{}
+
+	case EventType_RequestCancelExternalWorkflowScheduled:
+		attr = &RequestCancelExternalWorkflowScheduledAttributes{}
+	case EventType_RequestCancelExternalWorkflowCompleted:
+		attr = &RequestCancelExternalWorkflowCompletedAttributes{}
+	case EventType_RequestCancelExternalWorkflowFailed:
+		attr = &RequestCancelExternalWorkflowFailedAttributes{}
+
+	case EventType_SearchAttributesUpserted:
+		attr = &SearchAttributesUpsertedAttributes{}
+
 	default:
 		return nil, errors.New("unknown event type when deserializing attributes")
 	}
@@ -0,0 +1,11 @@
+package history
+
+type SignalExternalWorkflowFailedAttributes struct {
+	Error string `json:"error,omitempty"`
+
+	// InstanceNotFound indicates that the target workflow instance does not exist
+	InstanceNotFound bool `json:"instance_not_found,omitempty"`
+
+	// InstanceFinished indicates that the target workflow instance has already finished
+	InstanceFinished bool `json:"instance_finished,omitempty"`
+}
@@ -2,4 +2,8 @@ package history
 
 type SubWorkflowFailedAttributes struct {
 	Error string `json:"error,omitempty"`
+
+	// InstanceAlreadyExists is set if the sub-workflow could not be created because an instance with the same ID
+	// already exists
+	InstanceAlreadyExists bool `json:"instance_already_exists,omitempty"`
 }
@@ -13,4 +13,13 @@ type SubWorkflowScheduledAttributes struct {
 	Inputs []payload.Payload `json:"inputs,omitempty"`
 
 	Metadata *core.WorkflowMetadata `json:"metadata,omitempty"`
+
+	// Attempt is the zero-based retry attempt this sub-workflow was scheduled for
+	Attempt int `json:"attempt,omitempty"`
+
+	// Detached sub-workflows are not linked to their parent and do not report their result back
+	Detached bool `json:"detached,omitempty"`
+
+	// NotifyStarted requests a SubWorkflowStarted event once the sub-workflow instance has been created
+	NotifyStarted bool `json:"notify_started,omitempty"`
 }
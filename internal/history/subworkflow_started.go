@@ -0,0 +1,9 @@
+package history
+
+import (
+	"github.com/cschleiden/go-workflows/internal/core"
+)
+
+type SubWorkflowStartedAttributes struct {
+	SubWorkflowInstance *core.WorkflowInstance `json:"sub_workflow_instance,omitempty"`
+}
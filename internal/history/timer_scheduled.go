@@ -4,4 +4,7 @@ import "time"
 
 type TimerScheduledAttributes struct {
 	At time.Time `json:"at,omitempty"`
+
+	// Duration is the delay the timer was scheduled with. It's not set for timers scheduled by older versions.
+	Duration time.Duration `json:"duration,omitempty"`
 }
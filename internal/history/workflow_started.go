@@ -11,4 +11,7 @@ type ExecutionStartedAttributes struct {
 	Metadata *core.WorkflowMetadata `json:"metadata,omitempty"`
 
 	Inputs []payload.Payload `json:"inputs,omitempty"`
+
+	// Attempt is the zero-based retry attempt for sub-workflows started by a parent workflow
+	Attempt int `json:"attempt,omitempty"`
 }
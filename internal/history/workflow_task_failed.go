@@ -0,0 +1,8 @@
+package history
+
+type WorkflowTaskFailedAttributes struct {
+	Error string `json:"error,omitempty"`
+
+	// StackTrace is the stack of the goroutine that panicked, if the task failed because of a panic
+	StackTrace string `json:"stack_trace,omitempty"`
+}
@@ -6,10 +6,12 @@ const (
 	// Workflows
 	WorkflowInstanceCreated  = Prefix + "workflow.created"
 	WorkflowInstanceFinished = Prefix + "workflow.finished"
+	WorkflowInstanceRemoved  = Prefix + "workflow.removed"
 
 	WorkflowTaskScheduled = Prefix + "workflow.task.scheduled"
 	WorkflowTaskProcessed = Prefix + "workflow.task.processed"
 	WorkflowTaskDelay     = Prefix + "workflow.task.time_in_queue"
+	WorkflowTaskFailed    = Prefix + "workflow.task.failed"
 
 	WorkflowInstanceCacheSize     = Prefix + "workflow.cache.size"
 	WorkflowInstanceCacheEviction = Prefix + "workflow.cache.eviction"
@@ -18,6 +20,7 @@ const (
 	ActivityTaskScheduled = Prefix + "activity.task.scheduled"
 	ActivityTaskProcessed = Prefix + "activity.task.processed"
 	ActivityTaskDelay     = Prefix + "activity.task.time_in_queue"
+	ActivityTaskPanicked  = Prefix + "activity.task.panicked"
 )
 
 // Tag names
@@ -0,0 +1,84 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// lockName identifies the migration lock in MySQL
+const lockName = "go_workflows_migrations"
+
+// postgresLockID is the key of the advisory lock held while applying migrations in Postgres
+const postgresLockID = 0x676f2d77666c
+
+// Dialect contains the database specific parts of applying migrations
+type Dialect struct {
+	// lock acquires an exclusive migration lock on conn. The returned function releases it, ok reports whether all
+	// migrations were applied successfully.
+	lock func(ctx context.Context, conn *sql.Conn) (release func(ok bool) error, err error)
+
+	// transactional is true if each migration is applied in a transaction together with its version record
+	transactional bool
+
+	// insert records an applied migration with its version, name, minimum compatible version, and time
+	insert string
+}
+
+// SQLite applies all pending migrations in a single immediate transaction. Taking the write lock up front blocks
+// other connections until the migrations are committed.
+var SQLite = &Dialect{
+	lock: func(ctx context.Context, conn *sql.Conn) (func(bool) error, error) {
+		if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+			return nil, err
+		}
+
+		return func(ok bool) error {
+			stmt := "COMMIT"
+			if !ok {
+				stmt = "ROLLBACK"
+			}
+
+			_, err := conn.ExecContext(context.Background(), stmt)
+			return err
+		}, nil
+	},
+	insert: "INSERT INTO schema_migrations (version, name, min_compatible_version, applied_at) VALUES (?, ?, ?, ?)",
+}
+
+// MySQL serializes migrations with a named lock. DDL statements commit implicitly, so migrations are not
+// transactional. The connection has to allow multiple statements per query.
+var MySQL = &Dialect{
+	lock: func(ctx context.Context, conn *sql.Conn) (func(bool) error, error) {
+		var acquired sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, 60).Scan(&acquired); err != nil {
+			return nil, err
+		}
+
+		if acquired.Int64 != 1 {
+			return nil, errors.New("timed out waiting for lock")
+		}
+
+		return func(bool) error {
+			_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+			return err
+		}, nil
+	},
+	insert: "INSERT INTO schema_migrations (version, name, min_compatible_version, applied_at) VALUES (?, ?, ?, ?)",
+}
+
+// Postgres serializes migrations with a session level advisory lock and applies each migration in a transaction
+var Postgres = &Dialect{
+	lock: func(ctx context.Context, conn *sql.Conn) (func(bool) error, error) {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", postgresLockID); err != nil {
+			return nil, err
+		}
+
+		return func(bool) error {
+			_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", postgresLockID)
+			return err
+		}, nil
+	},
+	transactional: true,
+	insert:        "INSERT INTO schema_migrations (version, name, min_compatible_version, applied_at) VALUES ($1, $2, $3, $4)",
+}
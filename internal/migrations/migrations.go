@@ -0,0 +1,215 @@
+// Package migrations applies versioned schema migrations for the SQL backends. Migrations are SQL files named
+// <version>_<description>.sql with versions starting at 1. Applied versions are recorded in the schema_migrations
+// table, so every migration is applied exactly once.
+//
+// Migrations are expected to be backward compatible, so that workers that only know older migrations keep running
+// against the migrated database during a rolling deployment. A migration that breaks older workers declares the
+// oldest schema version that still works with it in a comment line:
+//
+//	-- min-compatible-version: 7
+//
+// Workers that only know migrations before that version refuse to start against a database it was applied to.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER NOT NULL PRIMARY KEY,
+	name VARCHAR(256) NOT NULL,
+	min_compatible_version INTEGER NOT NULL DEFAULT 0,
+	applied_at TIMESTAMP NOT NULL
+)`
+
+// minCompatibleVersionPrefix starts the comment line declaring the minimum compatible version of a migration
+const minCompatibleVersionPrefix = "-- min-compatible-version:"
+
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+
+	// MinCompatibleVersion is the oldest schema version whose code still works after this migration was applied, 0
+	// if the migration is compatible with all older versions
+	MinCompatibleVersion int
+}
+
+// Load reads all migrations from dir in fsys, ordered by version
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+
+		v, name, ok := strings.Cut(strings.TrimSuffix(e.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(v)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %s, expected <version>_<description>.sql", e.Name())
+		}
+
+		sql, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %s: %w", e.Name(), err)
+		}
+
+		minVersion, err := minCompatibleVersion(string(sql))
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration %s: %w", e.Name(), err)
+		}
+
+		if minVersion > version {
+			return nil, fmt.Errorf("invalid migration %s: minimum compatible version %d is newer than the migration", e.Name(), minVersion)
+		}
+
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(sql), MinCompatibleVersion: minVersion})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	// Versions have to be contiguous, this also catches duplicate versions
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("expected migration version %d, found %d_%s", i+1, m.Version, m.Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// minCompatibleVersion returns the minimum compatible version declared in the given migration, or 0
+func minCompatibleVersion(sql string) (int, error) {
+	for _, line := range strings.Split(sql, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, minCompatibleVersionPrefix) {
+			continue
+		}
+
+		v := strings.TrimPrefix(line, minCompatibleVersionPrefix)
+		version, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || version <= 0 {
+			return 0, fmt.Errorf("invalid minimum compatible version %q", strings.TrimSpace(v))
+		}
+
+		return version, nil
+	}
+
+	return 0, nil
+}
+
+// Apply applies all migrations that have not been applied to db yet. It holds the dialect's migration lock while
+// doing so, so that concurrently starting workers don't race each other.
+func Apply(ctx context.Context, db *sql.DB, d *Dialect, migrations []Migration) (err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("getting connection: %w", err)
+	}
+	defer conn.Close()
+
+	release, err := d.lock(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer func() {
+		if rerr := release(err == nil); rerr != nil && err == nil {
+			err = fmt.Errorf("releasing migration lock: %w", rerr)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("creating schema_migrations table: %w", err)
+	}
+
+	current, err := version(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	// Newer workers may have migrated the database already, keep running unless one of their migrations breaks us
+	if current > len(migrations) {
+		required, err := requiredVersion(ctx, conn, len(migrations))
+		if err != nil {
+			return err
+		}
+
+		if required > len(migrations) {
+			return fmt.Errorf("database schema version %d requires at least version %d, the latest known version is %d",
+				current, required, len(migrations))
+		}
+
+		return nil
+	}
+
+	for _, m := range migrations[current:] {
+		if err := apply(ctx, conn, d, m); err != nil {
+			return fmt.Errorf("applying migration %d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func version(ctx context.Context, conn *sql.Conn) (int, error) {
+	var v int
+	if err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&v); err != nil {
+		return 0, fmt.Errorf("getting schema version: %w", err)
+	}
+
+	return v, nil
+}
+
+// requiredVersion returns the highest minimum compatible version of the migrations applied after the given version
+func requiredVersion(ctx context.Context, conn *sql.Conn, after int) (int, error) {
+	var v int
+	if err := conn.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT COALESCE(MAX(min_compatible_version), 0) FROM schema_migrations WHERE version > %d", after),
+	).Scan(&v); err != nil {
+		return 0, fmt.Errorf("getting minimum compatible schema version: %w", err)
+	}
+
+	return v, nil
+}
+
+func apply(ctx context.Context, conn *sql.Conn, d *Dialect, m Migration) error {
+	if !d.transactional {
+		if _, err := conn.ExecContext(ctx, m.SQL); err != nil {
+			return err
+		}
+
+		_, err := conn.ExecContext(ctx, d.insert, m.Version, m.Name, m.MinCompatibleVersion, time.Now().UTC())
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, d.insert, m.Version, m.Name, m.MinCompatibleVersion, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
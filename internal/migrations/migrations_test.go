@@ -0,0 +1,106 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Load(t *testing.T) {
+	ms, err := Load(fstest.MapFS{
+		"migrations/0002_add_index.sql":   {Data: []byte("CREATE INDEX i ON t (c);")},
+		"migrations/0001_initial.sql":     {Data: []byte("CREATE TABLE t (c INTEGER);")},
+		"migrations/0003_drop_column.sql": {Data: []byte("-- min-compatible-version: 3\nALTER TABLE t DROP COLUMN c;")},
+		"migrations/README.md":            {Data: []byte("ignored")},
+	}, "migrations")
+	require.NoError(t, err)
+	require.Equal(t, []Migration{
+		{Version: 1, Name: "initial", SQL: "CREATE TABLE t (c INTEGER);"},
+		{Version: 2, Name: "add_index", SQL: "CREATE INDEX i ON t (c);"},
+		{Version: 3, Name: "drop_column", SQL: "-- min-compatible-version: 3\nALTER TABLE t DROP COLUMN c;", MinCompatibleVersion: 3},
+	}, ms)
+}
+
+func Test_Load_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"invalid name", fstest.MapFS{"m/initial.sql": {}}},
+		{"invalid version", fstest.MapFS{"m/0_initial.sql": {}}},
+		{"gap", fstest.MapFS{"m/0001_initial.sql": {}, "m/0003_next.sql": {}}},
+		{"duplicate", fstest.MapFS{"m/0001_initial.sql": {}, "m/0001_other.sql": {}}},
+		{"invalid min compatible version", fstest.MapFS{"m/0001_initial.sql": {Data: []byte("-- min-compatible-version: x")}}},
+		{"newer min compatible version", fstest.MapFS{"m/0001_initial.sql": {Data: []byte("-- min-compatible-version: 2")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.files, "m")
+			require.Error(t, err)
+		})
+	}
+}
+
+func Test_Apply(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite3", "file::memory:?_mode=memory")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ms := []Migration{
+		{Version: 1, Name: "initial", SQL: "CREATE TABLE t (c INTEGER);"},
+	}
+
+	require.NoError(t, Apply(ctx, db, SQLite, ms))
+
+	// Applying again is a no-op
+	require.NoError(t, Apply(ctx, db, SQLite, ms))
+
+	ms = append(ms, Migration{Version: 2, Name: "add_column", SQL: "ALTER TABLE t ADD COLUMN d INTEGER;"})
+	require.NoError(t, Apply(ctx, db, SQLite, ms))
+
+	_, err = db.Exec("INSERT INTO t (c, d) VALUES (1, 2)")
+	require.NoError(t, err)
+
+	var v int
+	require.NoError(t, db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&v))
+	require.Equal(t, 2, v)
+
+	// Older code keeps running against a newer, compatible schema
+	require.NoError(t, Apply(ctx, db, SQLite, ms[:1]))
+
+	ms = append(ms, Migration{Version: 3, Name: "drop_column", SQL: "ALTER TABLE t DROP COLUMN c;", MinCompatibleVersion: 3})
+	require.NoError(t, Apply(ctx, db, SQLite, ms))
+
+	// but not if a newer migration is incompatible with it
+	require.Error(t, Apply(ctx, db, SQLite, ms[:1]))
+	require.Error(t, Apply(ctx, db, SQLite, ms[:2]))
+	require.NoError(t, Apply(ctx, db, SQLite, ms))
+}
+
+func Test_Apply_Failure(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite3", "file::memory:?_mode=memory")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	err = Apply(ctx, db, SQLite, []Migration{
+		{Version: 1, Name: "initial", SQL: "CREATE TABLE t (c INTEGER);"},
+		{Version: 2, Name: "broken", SQL: "ALTER TABLE missing ADD COLUMN d INTEGER;"},
+	})
+	require.Error(t, err)
+
+	// All migrations are rolled back together
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name IN ('t', 'schema_migrations')").Scan(&n))
+	require.Equal(t, 0, n)
+}
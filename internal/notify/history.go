@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+)
+
+// HistoryPollInterval is the interval in which WatchHistory reads the history of an instance if it has not been
+// notified about new events
+const HistoryPollInterval = time.Second
+
+// NewWatchBackOff returns the backoff for retrying to read the history of a watched instance after an error. It does
+// not give up, watches only end once they are canceled or the instance has been removed.
+func NewWatchBackOff() *backoff.ExponentialBackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = 100 * time.Millisecond
+	b.MaxInterval = 10 * time.Second
+	b.MaxElapsedTime = 0
+
+	return b
+}
+
+// WatchHistory streams the history of the given instance by reading it from the backend whenever n is notified
+// about new events for the instance, and at least every HistoryPollInterval to pick up events committed by other
+// processes. n can be nil, then the history is only polled. Errors reading the history are retried with backoff.
+func WatchHistory(ctx context.Context, b backend.Backend, n *Notifier, instance *core.WorkflowInstance, fromSequenceID int64) (<-chan *history.Event, error) {
+	if _, err := b.GetWorkflowInstanceState(ctx, instance); err != nil {
+		return nil, err
+	}
+
+	c := make(chan *history.Event)
+
+	go func() {
+		defer close(c)
+
+		lastSequenceID := fromSequenceID
+		bo := NewWatchBackOff()
+
+		for {
+			var wake <-chan struct{}
+			cancel := func() {}
+			if n != nil {
+				// Subscribe before reading, to not miss events committed in between
+				wake, cancel = n.Subscribe(instance.InstanceID)
+			}
+
+			done, err := func() (bool, error) {
+				defer cancel()
+
+				// Read the state before the history, all events of a finished instance are committed at that point
+				state, err := b.GetWorkflowInstanceState(ctx, instance)
+				if err != nil {
+					return false, err
+				}
+
+				events, err := b.GetWorkflowInstanceHistory(ctx, instance, &lastSequenceID)
+				if err != nil {
+					return false, err
+				}
+
+				for _, event := range events {
+					select {
+					case c <- event:
+						lastSequenceID = event.SequenceID
+					case <-ctx.Done():
+						return true, nil
+					}
+				}
+
+				if state == core.WorkflowInstanceStateFinished {
+					return true, nil
+				}
+
+				select {
+				case <-ctx.Done():
+					return true, nil
+				case <-wake:
+				case <-time.After(HistoryPollInterval):
+				}
+
+				return false, nil
+			}()
+			if err != nil {
+				// The instance has been removed while it was watched
+				if errors.Is(err, backend.ErrInstanceNotFound) || ctx.Err() != nil {
+					return
+				}
+
+				retryIn := bo.NextBackOff()
+				b.Logger().Warn("watching workflow history", "instance_id", instance.InstanceID, "error", err, "retry_in", retryIn)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(retryIn):
+				}
+
+				continue
+			}
+
+			bo.Reset()
+
+			if done {
+				return
+			}
+		}
+	}()
+
+	return c, nil
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/logger"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchHistory(t *testing.T) {
+	instance := core.NewWorkflowInstance("instance", "execution")
+	e1 := history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{})
+	e2 := history.NewHistoryEvent(2, time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{})
+
+	n := New()
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateActive, nil).Twice()
+	b.On("GetWorkflowInstanceHistory", mock.Anything, instance, mock.Anything).Return([]*history.Event{e1}, nil).Once().Run(func(args mock.Arguments) {
+		require.Equal(t, int64(0), *args.Get(2).(*int64))
+
+		// New events are committed after the first read
+		go n.Notify(instance.InstanceID)
+	})
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateFinished, nil).Once()
+	b.On("GetWorkflowInstanceHistory", mock.Anything, instance, mock.Anything).Return([]*history.Event{e2}, nil).Once().Run(func(args mock.Arguments) {
+		require.Equal(t, int64(1), *args.Get(2).(*int64))
+	})
+
+	c, err := WatchHistory(context.Background(), b, n, instance, 0)
+	require.NoError(t, err)
+
+	events := []*history.Event{}
+	for event := range c {
+		events = append(events, event)
+	}
+
+	require.Equal(t, []*history.Event{e1, e2}, events)
+	b.AssertExpectations(t)
+}
+
+func TestWatchHistory_RetriesErrors(t *testing.T) {
+	instance := core.NewWorkflowInstance("instance", "execution")
+	e1 := history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{})
+
+	b := &backend.MockBackend{}
+	b.On("Logger").Return(logger.NewDefaultLogger())
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateActive, nil).Once()
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceState(0), errors.New("connection refused")).Once()
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateFinished, nil).Once()
+	b.On("GetWorkflowInstanceHistory", mock.Anything, instance, mock.Anything).Return([]*history.Event{e1}, nil).Once()
+
+	c, err := WatchHistory(context.Background(), b, nil, instance, 0)
+	require.NoError(t, err)
+
+	events := []*history.Event{}
+	for event := range c {
+		events = append(events, event)
+	}
+
+	require.Equal(t, []*history.Event{e1}, events)
+	b.AssertExpectations(t)
+}
+
+func TestWatchHistory_InstanceRemoved(t *testing.T) {
+	instance := core.NewWorkflowInstance("instance", "execution")
+
+	b := &backend.MockBackend{}
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceStateActive, nil).Once()
+	b.On("GetWorkflowInstanceState", mock.Anything, instance).Return(core.WorkflowInstanceState(0), backend.ErrInstanceNotFound).Once()
+
+	c, err := WatchHistory(context.Background(), b, nil, instance, 0)
+	require.NoError(t, err)
+
+	_, ok := <-c
+	require.False(t, ok)
+	b.AssertExpectations(t)
+}
@@ -0,0 +1,52 @@
+// Package notify implements in-process notifications about workflow instances, used by backends to implement
+// backend.CompletionSubscriber and backend.HistoryWatcher.
+package notify
+
+import "sync"
+
+type Notifier struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+}
+
+func New() *Notifier {
+	return &Notifier{
+		subscribers: make(map[string]map[chan struct{}]struct{}),
+	}
+}
+
+// Subscribe returns a channel that is closed the next time the given instance is notified, and a function to remove
+// the subscription.
+func (n *Notifier) Subscribe(instanceID string) (<-chan struct{}, func()) {
+	c := make(chan struct{})
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.subscribers[instanceID] == nil {
+		n.subscribers[instanceID] = make(map[chan struct{}]struct{})
+	}
+	n.subscribers[instanceID][c] = struct{}{}
+
+	return c, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		delete(n.subscribers[instanceID], c)
+		if len(n.subscribers[instanceID]) == 0 {
+			delete(n.subscribers, instanceID)
+		}
+	}
+}
+
+// Notify notifies all current subscribers of the given instance. Subscriptions are removed after being notified.
+func (n *Notifier) Notify(instanceID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for c := range n.subscribers[instanceID] {
+		close(c)
+	}
+
+	delete(n.subscribers, instanceID)
+}
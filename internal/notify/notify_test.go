@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier(t *testing.T) {
+	n := New()
+
+	c1, cancel1 := n.Subscribe("a")
+	defer cancel1()
+	c2, cancel2 := n.Subscribe("a")
+	c3, cancel3 := n.Subscribe("b")
+	defer cancel3()
+
+	cancel2()
+	n.Notify("a")
+
+	require.True(t, isClosed(c1))
+	require.False(t, isClosed(c2))
+	require.False(t, isClosed(c3))
+	require.Empty(t, n.subscribers["a"])
+
+	// Notifying again or canceling after notification is a no-op
+	n.Notify("a")
+	cancel1()
+}
+
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
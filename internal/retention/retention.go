@@ -0,0 +1,189 @@
+// Package retention removes finished workflow instances from backends
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	"github.com/cschleiden/go-workflows/metrics"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// RemoveWorkflowInstance archives the given finished instance if an archiver is configured, removes it from the
+// backend, and calls the configured removal hooks
+func RemoveWorkflowInstance(ctx context.Context, b backend.Backend, instance *workflow.Instance) error {
+	if archiver := b.Options().Archiver; archiver != nil {
+		if err := archiveWorkflowInstance(ctx, b, archiver, instance); err != nil {
+			return err
+		}
+	}
+
+	if err := b.RemoveWorkflowInstance(ctx, instance); err != nil {
+		return err
+	}
+
+	for _, hook := range b.Options().RemovalHooks {
+		if err := hook(ctx, instance); err != nil {
+			return fmt.Errorf("running removal hook: %w", err)
+		}
+	}
+
+	b.Metrics().Counter(metrickeys.WorkflowInstanceRemoved, metrics.Tags{}, 1)
+
+	return nil
+}
+
+func archiveWorkflowInstance(ctx context.Context, b backend.Backend, archiver backend.Archiver, instance *workflow.Instance) error {
+	d, err := b.DescribeWorkflowInstance(ctx, instance)
+	if err != nil {
+		return err
+	}
+
+	if d.State != core.WorkflowInstanceStateFinished {
+		return backend.ErrInstanceNotFinished
+	}
+
+	h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+	if err != nil {
+		return fmt.Errorf("getting history: %w", err)
+	}
+
+	if err := archiver.ArchiveWorkflowInstance(ctx, &backend.ArchivedWorkflowInstance{
+		Instance: &backend.WorkflowInstanceInfo{
+			Instance:     d.Instance,
+			WorkflowName: d.WorkflowName,
+			State:        d.State,
+			CreatedAt:    d.CreatedAt,
+			CompletedAt:  d.CompletedAt,
+		},
+		History: h,
+	}); err != nil {
+		return fmt.Errorf("archiving workflow instance: %w", err)
+	}
+
+	return nil
+}
+
+// Cleaner periodically removes workflow instances that finished longer than the retention period of the backend ago
+type Cleaner struct {
+	backend   backend.Backend
+	interval  time.Duration
+	batchSize int
+	clock     clock.Clock
+
+	wg sync.WaitGroup
+}
+
+func NewCleaner(b backend.Backend, clock clock.Clock, interval time.Duration, batchSize int) *Cleaner {
+	return &Cleaner{
+		backend:   b,
+		interval:  interval,
+		batchSize: batchSize,
+		clock:     clock,
+	}
+}
+
+// Start starts removing expired instances until ctx is canceled. Nothing is removed if the backend has no retention
+// period configured.
+func (c *Cleaner) Start(ctx context.Context) {
+	if c.backend.Options().RetentionPeriod <= 0 {
+		return
+	}
+
+	c.wg.Add(1)
+
+	go func() {
+		defer c.wg.Done()
+
+		t := c.clock.Ticker(c.interval)
+		defer t.Stop()
+
+		for {
+			if _, err := c.Cleanup(ctx); err != nil && ctx.Err() == nil {
+				c.backend.Logger().Error("removing expired workflow instances", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+// WaitForCompletion waits for the cleaner to stop after the context passed to Start has been canceled
+func (c *Cleaner) WaitForCompletion() {
+	c.wg.Wait()
+}
+
+// Cleanup removes all instances that finished before the retention period, in batches, and returns the number of
+// removed instances. Instances that cannot be removed are logged and skipped, so that they don't block the removal of
+// other instances. They are tried again in the next cleanup.
+func (c *Cleaner) Cleanup(ctx context.Context) (int, error) {
+	cutoff := c.clock.Now().Add(-c.backend.Options().RetentionPeriod)
+	state := core.WorkflowInstanceStateFinished
+
+	removed := 0
+	failed := make(map[string]bool)
+	cursor := ""
+
+	for {
+		r, err := c.backend.ListWorkflowInstances(ctx, &backend.ListWorkflowInstancesOptions{
+			State:           &state,
+			CompletedBefore: cutoff,
+			PageSize:        c.batchSize,
+			Cursor:          cursor,
+		})
+		if err != nil {
+			return removed, fmt.Errorf("listing expired workflow instances: %w", err)
+		}
+
+		batchRemoved := 0
+
+		for _, i := range r.Instances {
+			if failed[i.Instance.InstanceID] {
+				continue
+			}
+
+			if err := RemoveWorkflowInstance(ctx, c.backend, i.Instance); err != nil {
+				// Other workers might be removing the same instances
+				if errors.Is(err, backend.ErrInstanceNotFound) {
+					continue
+				}
+
+				if ctx.Err() != nil {
+					return removed, ctx.Err()
+				}
+
+				c.backend.Logger().Error("removing expired workflow instance",
+					"instance_id", i.Instance.InstanceID, "error", err)
+
+				failed[i.Instance.InstanceID] = true
+				continue
+			}
+
+			batchRemoved++
+		}
+
+		removed += batchRemoved
+
+		if r.NextCursor == "" {
+			return removed, nil
+		}
+
+		// Removed instances are not listed again, so the next batch starts from the beginning. If nothing was removed,
+		// the batch only contained instances that failed, continue after them.
+		cursor = ""
+		if batchRemoved == 0 {
+			cursor = r.NextCursor
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/archive"
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Cleaner_Cleanup(t *testing.T) {
+	ctx := context.Background()
+
+	var removed []string
+	b := sqlite.NewInMemoryBackend(
+		backend.WithStickyTimeout(0),
+		backend.WithRetentionPeriod(time.Hour),
+		backend.WithRemovalHook(func(ctx context.Context, instance *workflow.Instance) error {
+			removed = append(removed, instance.InstanceID)
+			return nil
+		}),
+	)
+
+	finished1 := createInstance(t, ctx, b, core.WorkflowInstanceStateFinished)
+	finished2 := createInstance(t, ctx, b, core.WorkflowInstanceStateFinished)
+	active := createInstance(t, ctx, b, core.WorkflowInstanceStateActive)
+
+	c := clock.NewMock()
+	c.Set(time.Now())
+
+	// Batch size 1 removes the instances one batch at a time
+	cleaner := NewCleaner(b, c, time.Minute, 1)
+
+	n, err := cleaner.Cleanup(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	c.Add(2 * time.Hour)
+
+	n, err = cleaner.Cleanup(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.ElementsMatch(t, []string{finished1.InstanceID, finished2.InstanceID}, removed)
+
+	_, err = b.GetWorkflowInstanceState(ctx, finished1)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	s, err := b.GetWorkflowInstanceState(ctx, active)
+	require.NoError(t, err)
+	require.Equal(t, core.WorkflowInstanceStateActive, s)
+}
+
+type failingArchiver struct {
+	backend.Archiver
+
+	instanceID string
+}
+
+func (a *failingArchiver) ArchiveWorkflowInstance(ctx context.Context, instance *backend.ArchivedWorkflowInstance) error {
+	if instance.Instance.Instance.InstanceID == a.instanceID {
+		return errors.New("archive not available")
+	}
+
+	return a.Archiver.ArchiveWorkflowInstance(ctx, instance)
+}
+
+func Test_Cleaner_Cleanup_SkipsFailingInstances(t *testing.T) {
+	// Fail each of the instances, so that the failing instance is at every position of the listing once
+	for failing := 0; failing < 3; failing++ {
+		ctx := context.Background()
+
+		fs, err := archive.NewFileSystemArchiver(t.TempDir())
+		require.NoError(t, err)
+
+		archiver := &failingArchiver{Archiver: fs}
+		b := sqlite.NewInMemoryBackend(
+			backend.WithStickyTimeout(0),
+			backend.WithRetentionPeriod(time.Hour),
+			backend.WithArchiver(archiver),
+		)
+
+		instances := []*workflow.Instance{
+			createInstance(t, ctx, b, core.WorkflowInstanceStateFinished),
+			createInstance(t, ctx, b, core.WorkflowInstanceStateFinished),
+			createInstance(t, ctx, b, core.WorkflowInstanceStateFinished),
+		}
+
+		archiver.instanceID = instances[failing].InstanceID
+
+		c := clock.NewMock()
+		c.Set(time.Now().Add(2 * time.Hour))
+
+		cleaner := NewCleaner(b, c, time.Minute, 1)
+
+		n, err := cleaner.Cleanup(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+
+		for i, instance := range instances {
+			_, err = b.GetWorkflowInstanceState(ctx, instance)
+			if i == failing {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+			}
+		}
+
+		// The instance is removed once archiving works again
+		archiver.instanceID = ""
+
+		n, err = cleaner.Cleanup(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	}
+}
+
+func Test_RemoveWorkflowInstance_NotFinished(t *testing.T) {
+	ctx := context.Background()
+
+	hookCalled := false
+	b := sqlite.NewInMemoryBackend(backend.WithRemovalHook(func(ctx context.Context, instance *workflow.Instance) error {
+		hookCalled = true
+		return nil
+	}))
+
+	instance := createInstance(t, ctx, b, core.WorkflowInstanceStateActive)
+
+	err := RemoveWorkflowInstance(ctx, b, instance)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFinished)
+	require.False(t, hookCalled)
+}
+
+func Test_RemoveWorkflowInstance_Archives(t *testing.T) {
+	ctx := context.Background()
+
+	archiver, err := archive.NewFileSystemArchiver(t.TempDir())
+	require.NoError(t, err)
+
+	b := sqlite.NewInMemoryBackend(backend.WithArchiver(archiver))
+
+	instance := createInstance(t, ctx, b, core.WorkflowInstanceStateFinished)
+
+	require.NoError(t, RemoveWorkflowInstance(ctx, b, instance))
+
+	_, err = b.GetWorkflowInstanceState(ctx, instance)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+
+	a, err := archiver.GetArchivedWorkflowInstance(ctx, instance.InstanceID, instance.ExecutionID)
+	require.NoError(t, err)
+	require.Equal(t, instance, a.Instance.Instance)
+	require.Equal(t, core.WorkflowInstanceStateFinished, a.Instance.State)
+	require.Len(t, a.History, 2)
+	require.Equal(t, history.EventType_WorkflowExecutionFinished, a.History[1].Type)
+}
+
+func Test_RemoveWorkflowInstance_NotFinished_NotArchived(t *testing.T) {
+	ctx := context.Background()
+
+	archiver, err := archive.NewFileSystemArchiver(t.TempDir())
+	require.NoError(t, err)
+
+	b := sqlite.NewInMemoryBackend(backend.WithArchiver(archiver))
+
+	instance := createInstance(t, ctx, b, core.WorkflowInstanceStateActive)
+
+	err = RemoveWorkflowInstance(ctx, b, instance)
+	require.ErrorIs(t, err, backend.ErrInstanceNotFinished)
+
+	_, err = archiver.GetArchivedWorkflowInstance(ctx, instance.InstanceID, "")
+	require.ErrorIs(t, err, backend.ErrInstanceNotFound)
+}
+
+func createInstance(t *testing.T, ctx context.Context, b backend.Backend, state core.WorkflowInstanceState) *workflow.Instance {
+	instance := core.NewWorkflowInstance(uuid.NewString(), uuid.NewString())
+
+	require.NoError(t, b.CreateWorkflowInstance(ctx, instance, history.NewHistoryEvent(1, time.Now(), history.EventType_WorkflowExecutionStarted, &history.ExecutionStartedAttributes{
+		Metadata: &core.WorkflowMetadata{},
+	})))
+
+	task, err := b.GetWorkflowTask(ctx)
+	require.NoError(t, err)
+
+	events := task.NewEvents
+	if state == core.WorkflowInstanceStateFinished {
+		events = append(events, history.NewPendingEvent(time.Now(), history.EventType_WorkflowExecutionFinished, &history.ExecutionCompletedAttributes{}))
+	}
+
+	for i := range events {
+		events[i].SequenceID = int64(i + 1)
+	}
+
+	require.NoError(t, b.CompleteWorkflowTask(ctx, task, instance, state, events, nil, nil, nil))
+
+	return instance
+}
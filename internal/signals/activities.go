@@ -12,6 +12,9 @@ type Activities struct {
 	Signaler Signaler
 }
 
+// DeliverWorkflowSignal delivers a signal to another workflow instance. Workflows now send signals via the
+// SignalExternalWorkflow command. The activity remains registered so that activities scheduled by existing histories
+// still execute, and the executor matches those histories against the command when replaying them.
 func (a *Activities) DeliverWorkflowSignal(ctx context.Context, instanceID, signalName string, arg interface{}) error {
 	return a.Signaler.SignalWorkflow(ctx, instanceID, signalName, arg)
 }
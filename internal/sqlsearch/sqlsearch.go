@@ -0,0 +1,144 @@
+// Package sqlsearch implements the search attribute index shared by the SQL backends. Attributes are stored in a
+// `search_attributes` table with one row per instance and attribute name. Queries use ? placeholders, backends with
+// a different placeholder syntax rewrite them.
+package sqlsearch
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/search"
+)
+
+// Execer executes statements, usually a *sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Upsert applies the given search attribute updates for the given instance. Attributes with a nil value are removed.
+func Upsert(ctx context.Context, tx Execer, instanceID string, attributes search.Attributes) error {
+	// Apply updates in a stable order to avoid lock order inversions between concurrent transactions
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM search_attributes WHERE instance_id = ? AND name = ?",
+			instanceID,
+			name,
+		); err != nil {
+			return fmt.Errorf("removing search attribute: %w", err)
+		}
+
+		v := attributes[name]
+		if v == nil {
+			continue
+		}
+
+		var stringValue *string
+		var intValue *int64
+		var floatValue *float64
+
+		switch v.Type {
+		case search.TypeString:
+			stringValue = &v.String
+		case search.TypeFloat:
+			floatValue = &v.Float
+		default:
+			intValue = &v.Int
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO search_attributes (instance_id, name, type, string_value, int_value, float_value) VALUES (?, ?, ?, ?, ?, ?)",
+			instanceID,
+			name,
+			v.Type,
+			stringValue,
+			intValue,
+			floatValue,
+		); err != nil {
+			return fmt.Errorf("inserting search attribute: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Where translates the given query into a SQL condition on instanceIDColumn and its arguments
+func Where(query search.Query, instanceIDColumn string) (string, []interface{}, error) {
+	if err := query.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	b := &builder{column: instanceIDColumn}
+	if err := b.build(query); err != nil {
+		return "", nil, err
+	}
+
+	return b.sb.String(), b.args, nil
+}
+
+type builder struct {
+	column string
+	sb     strings.Builder
+	args   []interface{}
+}
+
+func (b *builder) build(query search.Query) error {
+	switch q := query.(type) {
+	case *search.Condition:
+		var valueColumn string
+		var value interface{}
+
+		switch q.Value.Type {
+		case search.TypeString:
+			valueColumn, value = "string_value", q.Value.String
+		case search.TypeFloat:
+			valueColumn, value = "float_value", q.Value.Float
+		default:
+			valueColumn, value = "int_value", q.Value.Int
+		}
+
+		fmt.Fprintf(&b.sb,
+			"%s IN (SELECT instance_id FROM search_attributes WHERE name = ? AND type = ? AND %s %s ?)",
+			b.column, valueColumn, q.Op)
+		b.args = append(b.args, q.Key, q.Value.Type, value)
+
+	case *search.AndQuery:
+		return b.compound(" AND ", q.Queries)
+
+	case *search.OrQuery:
+		return b.compound(" OR ", q.Queries)
+
+	default:
+		return fmt.Errorf("unsupported query type %T", query)
+	}
+
+	return nil
+}
+
+func (b *builder) compound(op string, queries []search.Query) error {
+	b.sb.WriteString("(")
+
+	for i, q := range queries {
+		if i > 0 {
+			b.sb.WriteString(op)
+		}
+
+		if err := b.build(q); err != nil {
+			return err
+		}
+	}
+
+	b.sb.WriteString(")")
+
+	return nil
+}
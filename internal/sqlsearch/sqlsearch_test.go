@@ -0,0 +1,47 @@
+package sqlsearch
+
+import (
+	"testing"
+
+	"github.com/cschleiden/go-workflows/search"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhere(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    search.Query
+		want     string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "Eq string",
+			query:    search.Eq("customer", "c1"),
+			want:     "i.id IN (SELECT instance_id FROM search_attributes WHERE name = ? AND type = ? AND string_value = ?)",
+			wantArgs: []interface{}{"customer", search.TypeString, "c1"},
+		},
+		{
+			name:  "And/Or",
+			query: search.And(search.Gt("total", 12), search.Or(search.Lte("weight", 1.5), search.Eq("express", true))),
+			want: "(i.id IN (SELECT instance_id FROM search_attributes WHERE name = ? AND type = ? AND int_value > ?)" +
+				" AND (i.id IN (SELECT instance_id FROM search_attributes WHERE name = ? AND type = ? AND float_value <= ?)" +
+				" OR i.id IN (SELECT instance_id FROM search_attributes WHERE name = ? AND type = ? AND int_value = ?)))",
+			wantArgs: []interface{}{
+				"total", search.TypeInt, int64(12),
+				"weight", search.TypeFloat, 1.5,
+				"express", search.TypeBool, int64(1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := Where(tt.query, "i.id")
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantArgs, args)
+		})
+	}
+
+	_, _, err := Where(search.Eq("customer", struct{}{}), "i.id")
+	require.Error(t, err)
+}
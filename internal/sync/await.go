@@ -0,0 +1,23 @@
+package sync
+
+// AwaitCondition blocks the calling coroutine until the given condition returns true. The condition is re-evaluated
+// every time the coroutine is continued, i.e., whenever any coroutine made progress or new events were processed.
+//
+// If the context is canceled before the condition is met, the context's error is returned.
+func AwaitCondition(ctx Context, condition func() bool) error {
+	cs := getCoState(ctx)
+
+	for {
+		if condition() {
+			cs.MadeProgress()
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			cs.MadeProgress()
+			return err
+		}
+
+		cs.Yield()
+	}
+}
@@ -0,0 +1,84 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Await_BlocksUntilConditionIsMet(t *testing.T) {
+	s := NewScheduler()
+	ctx := Background()
+
+	c := NewChannel[int]()
+	received := 0
+	reachedEnd := false
+
+	s.NewCoroutine(ctx, func(ctx Context) error {
+		Go(ctx, func(ctx Context) {
+			for i := 0; i < 3; i++ {
+				c.Receive(ctx)
+				received++
+			}
+		})
+
+		err := AwaitCondition(ctx, func() bool {
+			return received == 3
+		})
+		require.NoError(t, err)
+
+		reachedEnd = true
+
+		return nil
+	})
+
+	require.NoError(t, s.Execute())
+	require.False(t, reachedEnd)
+
+	for i := 0; i < 3; i++ {
+		require.True(t, c.SendNonblocking(i))
+		require.NoError(t, s.Execute())
+	}
+
+	require.True(t, reachedEnd)
+	require.Equal(t, 0, s.RunningCoroutines())
+}
+
+func Test_Await_ConditionAlreadyMet(t *testing.T) {
+	ctx := Background()
+
+	reachedEnd := false
+
+	cr := NewCoroutine(ctx, func(ctx Context) error {
+		require.NoError(t, AwaitCondition(ctx, func() bool { return true }))
+
+		reachedEnd = true
+
+		return nil
+	})
+
+	cr.Execute()
+	require.True(t, reachedEnd)
+	require.True(t, cr.Finished())
+}
+
+func Test_Await_Canceled(t *testing.T) {
+	ctx, cancel := WithCancel(Background())
+
+	var awaitErr error
+
+	cr := NewCoroutine(ctx, func(ctx Context) error {
+		awaitErr = AwaitCondition(ctx, func() bool { return false })
+
+		return nil
+	})
+
+	cr.Execute()
+	require.False(t, cr.Finished())
+
+	cancel()
+
+	cr.Execute()
+	require.True(t, cr.Finished())
+	require.ErrorIs(t, awaitErr, Canceled)
+}
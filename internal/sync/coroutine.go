@@ -40,12 +40,16 @@ type logger interface {
 }
 
 type coState struct {
-	blocking   chan bool    // coroutine is going to be blocked
-	unblock    chan bool    // channel to unblock block coroutine
-	blocked    atomic.Value // coroutine is currently blocked
-	finished   atomic.Value // coroutine finished executing
-	shouldExit atomic.Value // coroutine should exit
-	progress   atomic.Value // did the coroutine make progress since last yield?
+	blocking   chan bool     // coroutine is going to be blocked
+	unblock    chan bool     // channel to unblock block coroutine
+	blocked    atomic.Value  // coroutine is currently blocked
+	finished   atomic.Value  // coroutine finished executing
+	shouldExit atomic.Value  // coroutine should exit
+	progress   atomic.Value  // did the coroutine make progress since last yield?
+	deadlocked chan struct{} // closed when the coroutine did not yield within the deadlock detection timeout
+	done       chan struct{} // closed when the goroutine of the coroutine has exited
+
+	goroutineID atomic.Int64 // id of the goroutine running the coroutine, used to find its stack trace
 
 	err error
 
@@ -57,10 +61,18 @@ type coState struct {
 }
 
 func NewCoroutine(ctx Context, fn func(ctx Context) error) Coroutine {
+	return newCoroutine(ctx, fn, DeadlockDetection)
+}
+
+func newCoroutine(ctx Context, fn func(ctx Context) error, deadlockDetection time.Duration) *coState {
 	s := newState()
+	s.deadlockDetection = deadlockDetection
 	ctx = withCoState(ctx, s)
 
 	go func() {
+		s.goroutineID.Store(currentGoroutineID())
+
+		defer close(s.done)
 		defer s.finish() // Ensure we always mark the coroutine as finished
 		defer func() {
 			if r := recover(); r != nil {
@@ -83,8 +95,10 @@ func newState() *coState {
 	// i++
 
 	return &coState{
-		blocking: make(chan bool, 1),
-		unblock:  make(chan bool),
+		blocking:   make(chan bool, 1),
+		unblock:    make(chan bool),
+		deadlocked: make(chan struct{}),
+		done:       make(chan struct{}),
 		// Only used while debugging issues, default to discarding log messages
 		logger: log.New(io.Discard, "[co]", log.LstdFlags),
 		// logger:            log.New(os.Stderr, fmt.Sprintf("[co %v]", i), log.Lmsgprefix|log.Ltime),
@@ -94,7 +108,16 @@ func newState() *coState {
 
 func (s *coState) finish() {
 	s.finished.Store(true)
-	s.blocking <- true
+
+	// Nobody waits for a deadlocked coroutine anymore, and it might have marked itself as blocking already
+	if s.Deadlocked() {
+		select {
+		case s.blocking <- true:
+		default:
+		}
+	} else {
+		s.blocking <- true
+	}
 
 	s.logger.Println("finish")
 }
@@ -121,6 +144,15 @@ func (s *coState) ResetProgress() {
 	s.progress.Store(false)
 }
 
+func (s *coState) Deadlocked() bool {
+	select {
+	case <-s.deadlocked:
+		return true
+	default:
+		return false
+	}
+}
+
 func (s *coState) Progress() bool {
 	x := s.progress.Load()
 	v, ok := x.(bool)
@@ -142,7 +174,14 @@ func (s *coState) yield(markBlocking bool) {
 
 	s.logger.Println("yielded")
 
-	<-s.unblock
+	select {
+	case <-s.unblock:
+	case <-s.deadlocked:
+		// Nobody continues a deadlocked coroutine, stop it once it yields again instead of leaking it forever
+		s.logger.Println("deadlocked, goexit")
+		runtime.Goexit()
+	}
+
 	if s.shouldExit.Load() != nil {
 		s.logger.Println("shouldExit")
 		s.blocking <- true
@@ -177,10 +216,26 @@ func (s *coState) Execute() {
 	case <-s.blocking:
 		s.logger.Println("execute: blocked")
 	case <-t.C:
-		panic("coroutine timed out")
+		close(s.deadlocked)
+
+		panic(&DeadlockError{
+			Timeout: s.deadlockDetection,
+			Stacks:  s.stackTrace(),
+			done:    s.done,
+		})
 	}
 }
 
+// stackTrace returns the stack traces of all coroutines of the scheduler, or only of this coroutine if it's not
+// tracked by a scheduler
+func (s *coState) stackTrace() string {
+	if s.scheduler != nil {
+		return s.scheduler.StackTrace()
+	}
+
+	return goroutineStacks([]int64{s.goroutineID.Load()})[0]
+}
+
 func (s *coState) Exit() {
 	s.logger.Println("exit")
 
@@ -188,6 +243,12 @@ func (s *coState) Exit() {
 		return
 	}
 
+	if s.Deadlocked() {
+		// The coroutine cannot be stopped while it's blocked. Its goroutine exits once it yields again or returns,
+		// until then it's leaked, but the caller is not blocked forever.
+		return
+	}
+
 	s.shouldExit.Store(true)
 	s.Execute()
 }
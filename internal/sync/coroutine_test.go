@@ -149,11 +149,119 @@ func Test_Coroutine_PanicsWhenDeadlocked(t *testing.T) {
 
 	c.Execute()
 
-	require.Panics(t, func() {
+	require.PanicsWithError(t, "potential deadlock detected: workflow did not yield within 1ms, it might be blocked on a native channel or mutex, or loop forever", func() {
 		c.Execute()
 	})
 }
 
+func Test_Coroutine_DeadlockErrorContainsStack(t *testing.T) {
+	c := NewCoroutine(Background(), func(ctx Context) error {
+		s := getCoState(ctx)
+		s.deadlockDetection = time.Millisecond
+		s.Yield()
+
+		blockForever()
+
+		return nil
+	})
+
+	c.Execute()
+
+	defer func() {
+		r := recover()
+		require.IsType(t, &DeadlockError{}, r)
+		require.Contains(t, r.(*DeadlockError).StackTrace(), "blockForever")
+
+		// Exiting a deadlocked coroutine must not block
+		c.Exit()
+	}()
+
+	c.Execute()
+}
+
+func Test_Coroutine_DeadlockedExitsWhenYielding(t *testing.T) {
+	unblock := make(chan struct{})
+	continued := false
+
+	c := NewCoroutine(Background(), func(ctx Context) error {
+		s := getCoState(ctx)
+		s.deadlockDetection = time.Millisecond
+		s.Yield()
+
+		<-unblock
+		s.Yield()
+
+		continued = true
+
+		return nil
+	})
+
+	c.Execute()
+
+	var derr *DeadlockError
+	func() {
+		defer func() {
+			derr = recover().(*DeadlockError)
+		}()
+
+		c.Execute()
+	}()
+
+	select {
+	case <-derr.Done():
+		require.Fail(t, "deadlocked coroutine should still be running")
+	default:
+	}
+
+	// Once the coroutine yields again, its goroutine exits without continuing the workflow code
+	close(unblock)
+
+	select {
+	case <-derr.Done():
+	case <-time.After(time.Second):
+		require.Fail(t, "deadlocked coroutine did not exit")
+	}
+
+	require.False(t, continued)
+}
+
+func Test_Coroutine_DeadlockedExitsWhenReturning(t *testing.T) {
+	unblock := make(chan struct{})
+
+	c := NewCoroutine(Background(), func(ctx Context) error {
+		s := getCoState(ctx)
+		s.deadlockDetection = time.Millisecond
+		s.Yield()
+
+		<-unblock
+
+		return nil
+	})
+
+	c.Execute()
+
+	var derr *DeadlockError
+	func() {
+		defer func() {
+			derr = recover().(*DeadlockError)
+		}()
+
+		c.Execute()
+	}()
+
+	close(unblock)
+
+	select {
+	case <-derr.Done():
+	case <-time.After(time.Second):
+		require.Fail(t, "deadlocked coroutine did not exit")
+	}
+}
+
+func blockForever() {
+	select {}
+}
+
 func Test_Coroutine_Error(t *testing.T) {
 	c := NewCoroutine(Background(), func(ctx Context) error {
 		return errors.New("custom error")
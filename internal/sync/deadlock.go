@@ -0,0 +1,84 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// DeadlockError is raised when a coroutine does not yield back to its scheduler within the deadlock timeout. This
+// happens when workflow code blocks outside of the workflow APIs, for example on a native channel or mutex, or when
+// it loops forever.
+type DeadlockError struct {
+	Timeout time.Duration
+
+	// Stacks are the stack traces of all coroutines of the scheduler at the time the deadlock was detected
+	Stacks string
+
+	done <-chan struct{}
+}
+
+// Done returns a channel that is closed once the goroutine of the deadlocked coroutine has exited. It cannot be
+// stopped while it's blocked, but exits as soon as it yields again or returns.
+func (e *DeadlockError) Done() <-chan struct{} {
+	return e.done
+}
+
+func (e *DeadlockError) Error() string {
+	return fmt.Sprintf(
+		"potential deadlock detected: workflow did not yield within %v, it might be blocked on a native channel or mutex, or loop forever",
+		e.Timeout)
+}
+
+func (e *DeadlockError) StackTrace() string {
+	return e.Stacks
+}
+
+// currentGoroutineID returns the id of the calling goroutine, as reported in stack traces
+func currentGoroutineID() int64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	return parseGoroutineID(buf[:n])
+}
+
+// parseGoroutineID parses the goroutine id from the header of a stack trace, e.g. "goroutine 42 [running]:"
+func parseGoroutineID(stack []byte) int64 {
+	stack = bytes.TrimPrefix(stack, []byte("goroutine "))
+	if i := bytes.IndexByte(stack, ' '); i >= 0 {
+		stack = stack[:i]
+	}
+
+	id, _ := strconv.ParseInt(string(stack), 10, 64)
+	return id
+}
+
+// goroutineStacks returns the stack traces of the goroutines with the given ids, in the same order. The trace of a
+// goroutine that does not exist anymore is empty.
+func goroutineStacks(ids []int64) []string {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := make(map[int64]string)
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if id := parseGoroutineID(stack); id != 0 {
+			stacks[id] = string(bytes.TrimSpace(stack))
+		}
+	}
+
+	r := make([]string, len(ids))
+	for i, id := range ids {
+		r[i] = stacks[id]
+	}
+
+	return r
+}
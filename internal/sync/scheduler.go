@@ -1,5 +1,11 @@
 package sync
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 type Scheduler interface {
 	// Starts a new co-routine and tracks it in this scheduler
 	NewCoroutine(ctx Context, fn func(Context) error)
@@ -9,21 +15,42 @@ type Scheduler interface {
 
 	RunningCoroutines() int
 
+	// StackTrace returns the stack traces of all running coroutines
+	StackTrace() string
+
 	Exit()
 }
 
 type scheduler struct {
 	coroutines []Coroutine
+
+	deadlockDetection time.Duration
+}
+
+type SchedulerOption func(s *scheduler)
+
+// WithDeadlockDetection sets the duration after which a coroutine that does not yield is considered deadlocked
+func WithDeadlockDetection(d time.Duration) SchedulerOption {
+	return func(s *scheduler) {
+		s.deadlockDetection = d
+	}
 }
 
-func NewScheduler() Scheduler {
-	return &scheduler{
-		coroutines: make([]Coroutine, 0),
+func NewScheduler(opts ...SchedulerOption) Scheduler {
+	s := &scheduler{
+		coroutines:        make([]Coroutine, 0),
+		deadlockDetection: DeadlockDetection,
 	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
 }
 
 func (s *scheduler) NewCoroutine(ctx Context, fn func(Context) error) {
-	c := NewCoroutine(ctx, fn)
+	c := newCoroutine(ctx, fn, s.deadlockDetection)
 	s.coroutines = append(s.coroutines, c)
 	c.SetScheduler(s)
 }
@@ -64,6 +91,26 @@ func (s *scheduler) RunningCoroutines() int {
 	return len(s.coroutines)
 }
 
+func (s *scheduler) StackTrace() string {
+	ids := make([]int64, 0, len(s.coroutines))
+	for _, c := range s.coroutines {
+		if cs, ok := c.(*coState); ok {
+			ids = append(ids, cs.goroutineID.Load())
+		}
+	}
+
+	var b strings.Builder
+	for i, stack := range goroutineStacks(ids) {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+
+		fmt.Fprintf(&b, "coroutine %d:\n%s", i, stack)
+	}
+
+	return b.String()
+}
+
 func (s *scheduler) Exit() {
 	for _, c := range s.coroutines {
 		c.Exit()
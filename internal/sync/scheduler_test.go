@@ -171,3 +171,67 @@ func Test_Scheduler_Panic(t *testing.T) {
 	require.Equal(t, "panic: something went wrong", err.Error())
 	require.Equal(t, 0, s.RunningCoroutines())
 }
+
+func Test_Scheduler_StackTrace(t *testing.T) {
+	s := NewScheduler()
+
+	ctx := Background()
+	s.NewCoroutine(ctx, func(ctx Context) error {
+		getCoState(ctx).Yield()
+
+		return nil
+	})
+
+	s.NewCoroutine(ctx, func(ctx Context) error {
+		getCoState(ctx).Yield()
+
+		return nil
+	})
+
+	require.NoError(t, s.Execute())
+
+	st := s.StackTrace()
+	require.Contains(t, st, "coroutine 0:")
+	require.Contains(t, st, "coroutine 1:")
+	require.Contains(t, st, "Test_Scheduler_StackTrace.func1")
+	require.Contains(t, st, "Test_Scheduler_StackTrace.func2")
+
+	s.Exit()
+}
+
+func Test_Scheduler_Deadlock(t *testing.T) {
+	s := NewScheduler(WithDeadlockDetection(10 * time.Millisecond))
+
+	ch := make(chan struct{})
+	defer close(ch)
+
+	ctx := Background()
+	s.NewCoroutine(ctx, func(ctx Context) error {
+		getCoState(ctx).Yield()
+
+		return nil
+	})
+
+	s.NewCoroutine(ctx, func(ctx Context) error {
+		// Block on a native channel
+		<-ch
+
+		return nil
+	})
+
+	defer func() {
+		r := recover()
+		require.IsType(t, &DeadlockError{}, r)
+
+		derr := r.(*DeadlockError)
+		require.Equal(t, 10*time.Millisecond, derr.Timeout)
+		require.Contains(t, derr.StackTrace(), "coroutine 0:")
+		require.Contains(t, derr.StackTrace(), "coroutine 1:")
+		require.Contains(t, derr.StackTrace(), "Test_Scheduler_Deadlock.func2")
+
+		// Exiting must not block on the deadlocked coroutine
+		s.Exit()
+	}()
+
+	s.Execute()
+}
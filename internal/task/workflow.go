@@ -16,6 +16,10 @@ type Workflow struct {
 
 	Metadata *core.WorkflowMetadata
 
+	// Attempt is the number of times the workflow task of this instance has been attempted, starting at 1. Failed
+	// tasks increase it, it's reset once a task of the instance completes.
+	Attempt int
+
 	// LastSequenceID is the sequence ID of the newest event in the workflow instances's history
 	LastSequenceID int64
 
@@ -38,7 +38,7 @@ func NewActivityWorker(backend backend.Backend, registry *workflow.Registry, clo
 		options: options,
 
 		activityTaskQueue:    make(chan *task.Activity),
-		activityTaskExecutor: activity.NewExecutor(backend.Logger(), backend.Tracer(), backend.Converter(), registry),
+		activityTaskExecutor: activity.NewExecutor(backend.Logger(), backend.Tracer(), backend.Converter(), backend.ContextPropagators(), options.ActivityInterceptors, registry),
 
 		clock: clock,
 	}
@@ -155,12 +155,22 @@ func (aw *ActivityWorker) handleTask(ctx context.Context, task *task.Activity) {
 	var event *history.Event
 
 	if err != nil {
+		attributes := &history.ActivityFailedAttributes{
+			Reason: err.Error(),
+		}
+
+		var perr *activity.PanicError
+		if errors.As(err, &perr) {
+			aw.backend.Logger().Error("activity panicked", "activity", a.Name, "instance_id", task.WorkflowInstance.InstanceID, "panic", perr.Value)
+			ametrics.Counter(metrickeys.ActivityTaskPanicked, metrics.Tags{}, 1)
+
+			attributes.StackTrace = perr.Stack
+		}
+
 		event = history.NewPendingEvent(
 			aw.clock.Now(),
 			history.EventType_ActivityFailed,
-			&history.ActivityFailedAttributes{
-				Reason: err.Error(),
-			},
+			attributes,
 			history.ScheduleEventID(task.Event.ScheduleEventID),
 		)
 	} else {
@@ -3,9 +3,24 @@ package worker
 import (
 	"time"
 
+	"github.com/cschleiden/go-workflows/interceptor"
+	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/workflow"
 )
 
+// NonDeterminismPolicy determines how workers handle workflow code that does not match the recorded history of a
+// workflow instance
+type NonDeterminismPolicy int
+
+const (
+	// NonDeterminismFailInstance fails the workflow instance with the non-determinism error
+	NonDeterminismFailInstance NonDeterminismPolicy = iota
+
+	// NonDeterminismFailTask fails the workflow task without processing its events. The task is retried with a
+	// backoff, for example until workers with the previous workflow code have been restored.
+	NonDeterminismFailTask
+)
+
 type Options struct {
 	// WorkflowsPollers is the number of pollers to start. Defaults to 2.
 	WorkflowPollers int
@@ -42,6 +57,46 @@ type Options struct {
 	// WorkflowExecutorCache is the cache to use for workflow executors. If nil, a default cache implementation
 	// will be used.
 	WorkflowExecutorCache workflow.ExecutorCache
+
+	// WorkflowInterceptors wrap every workflow execution, the first interceptor is the outermost one. Workflow
+	// interceptors are called again when a workflow is replayed.
+	WorkflowInterceptors []interceptor.WorkflowInterceptor
+
+	// ActivityInterceptors wrap every activity execution, the first interceptor is the outermost one.
+	ActivityInterceptors []interceptor.ActivityInterceptor
+
+	// NonDeterminismPolicy determines what happens when workflow code does not match the recorded history of a
+	// workflow instance. Defaults to NonDeterminismFailInstance.
+	NonDeterminismPolicy NonDeterminismPolicy
+
+	// MatchReplayedPayloads makes replays also compare the inputs of activities and sub-workflows and the arguments
+	// of signals issued by the workflow code with the recorded history. Values are compared after codecs have been
+	// undone, JSON values regardless of their formatting. Changes to how values are serialized, for example new fields
+	// or a different converter, are reported as non-determinism as well. Defaults to false.
+	MatchReplayedPayloads bool
+
+	// WorkflowTaskRetryInterval is the delay before a failed workflow task is retried. The delay doubles with every
+	// consecutive failure of the same workflow instance. Defaults to 1 second.
+	WorkflowTaskRetryInterval time.Duration
+
+	// WorkflowTaskMaxRetryInterval is the maximum delay before a failed workflow task is retried. Defaults to 5 minutes.
+	WorkflowTaskMaxRetryInterval time.Duration
+
+	// WorkflowDeadlockTimeout is the maximum time workflow code may run without yielding back to the worker, for
+	// example while it waits on a native channel or mutex instead of the workflow APIs. When it's exceeded, the
+	// workflow task fails with the stack traces of all coroutines of the workflow. Defaults to 40 seconds.
+	//
+	// The goroutine of the blocked coroutine cannot be stopped, it keeps running until it yields to the workflow APIs
+	// again or returns, and then exits without continuing the workflow. Until then the worker fails all tasks of the
+	// instance without running the workflow again, so that every retry does not leak another goroutine.
+	WorkflowDeadlockTimeout time.Duration
+
+	// RetentionCleanupInterval is the interval at which the worker removes workflow instances that finished longer
+	// than the RetentionPeriod of the backend ago. Defaults to 1 minute.
+	RetentionCleanupInterval time.Duration
+
+	// RetentionBatchSize is the number of expired workflow instances removed at a time. Defaults to 100.
+	RetentionBatchSize int
 }
 
 var DefaultOptions = Options{
@@ -55,4 +110,12 @@ var DefaultOptions = Options{
 	WorkflowExecutorCacheSize: 128,
 	WorkflowExecutorCacheTTL:  time.Second * 10,
 	WorkflowExecutorCache:     nil,
+
+	WorkflowTaskRetryInterval:    time.Second,
+	WorkflowTaskMaxRetryInterval: 5 * time.Minute,
+
+	WorkflowDeadlockTimeout: sync.DeadlockDetection,
+
+	RetentionCleanupInterval: time.Minute,
+	RetentionBatchSize:       100,
 }
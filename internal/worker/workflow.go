@@ -4,13 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"runtime/debug"
 	"sync"
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/metrickeys"
+	internalsync "github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/internal/workflow"
 	"github.com/cschleiden/go-workflows/internal/workflow/cache"
@@ -18,6 +23,32 @@ import (
 	"github.com/cschleiden/go-workflows/metrics"
 )
 
+// backendRetries is the number of times backend operations of a workflow task are retried before giving up
+const backendRetries = 5
+
+// panicError is returned when processing a workflow task panics
+type panicError struct {
+	value interface{}
+	stack string
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+func (e *panicError) StackTrace() string {
+	return e.stack
+}
+
+// errDeadlockedWorkflowRunning is returned for workflow tasks of instances whose code is still blocked after an
+// earlier task deadlocked
+var errDeadlockedWorkflowRunning = errors.New("workflow code of an earlier deadlocked task is still running, not executing the workflow again until it has stopped")
+
+// stackTracer is implemented by errors that carry the stack traces of where they occurred
+type stackTracer interface {
+	StackTrace() string
+}
+
 type WorkflowWorker struct {
 	backend backend.Backend
 
@@ -31,11 +62,17 @@ type WorkflowWorker struct {
 
 	logger log.Logger
 
+	clock clock.Clock
+
+	// deadlocked tracks the coroutines of deadlocked workflow tasks that are still running, by instance id
+	deadlocked   map[string]<-chan struct{}
+	deadlockedMu sync.Mutex
+
 	pollersWg sync.WaitGroup
 	wg        sync.WaitGroup
 }
 
-func NewWorkflowWorker(backend backend.Backend, registry *workflow.Registry, options *Options) *WorkflowWorker {
+func NewWorkflowWorker(backend backend.Backend, registry *workflow.Registry, clock clock.Clock, options *Options) *WorkflowWorker {
 	var c workflow.ExecutorCache
 	if options.WorkflowExecutorCache != nil {
 		c = options.WorkflowExecutorCache
@@ -54,6 +91,10 @@ func NewWorkflowWorker(backend backend.Backend, registry *workflow.Registry, opt
 		cache: c,
 
 		logger: backend.Logger(),
+
+		clock: clock,
+
+		deadlocked: make(map[string]<-chan struct{}),
 	}
 }
 
@@ -134,14 +175,33 @@ func (ww *WorkflowWorker) runDispatcher() {
 func (ww *WorkflowWorker) handle(ctx context.Context, t *task.Workflow) {
 	// Record how long this task was in the queue
 	scheduledAt := t.NewEvents[0].Timestamp // Use the timestamp of the first event as the schedule time
-	timeInQueue := time.Since(scheduledAt)
+	timeInQueue := ww.clock.Since(scheduledAt)
 	ww.backend.Metrics().Distribution(metrickeys.WorkflowTaskDelay, metrics.Tags{}, float64(timeInQueue/time.Millisecond))
 
 	timer := metrics.Timer(ww.backend.Metrics(), metrickeys.WorkflowTaskProcessed, metrics.Tags{})
 
+	// Running the workflow again while the code of a deadlocked task is still blocked would leak another goroutine
+	// every time the task is retried
+	if ww.deadlockedRunning(t.WorkflowInstance) {
+		ww.failTask(ctx, t, errDeadlockedWorkflowRunning)
+		return
+	}
+
 	result, err := ww.handleTask(ctx, t)
 	if err != nil {
-		ww.logger.Panic("could not handle workflow task", "error", err)
+		var derr *internalsync.DeadlockError
+		if errors.As(err, &derr) {
+			ww.trackDeadlocked(t.WorkflowInstance, derr)
+		}
+
+		ww.failTask(ctx, t, err)
+		return
+	}
+
+	var nderr *workflow.NonDeterminismError
+	if ww.options.NonDeterminismPolicy == NonDeterminismFailTask && result.ReplayError != nil && errors.As(result.ReplayError, &nderr) {
+		ww.failTask(ctx, t, result.ReplayError)
+		return
 	}
 
 	// Only record the time spent in the workflow code
@@ -161,17 +221,133 @@ func (ww *WorkflowWorker) handle(ctx context.Context, t *task.Workflow) {
 
 	ww.backend.Metrics().Counter(metrickeys.ActivityTaskScheduled, metrics.Tags{}, int64(len(result.ActivityEvents)))
 
-	if err := ww.backend.CompleteWorkflowTask(
-		ctx, t, t.WorkflowInstance, state, result.Executed, result.ActivityEvents, result.TimerEvents, result.WorkflowEvents); err != nil {
-		ww.logger.Panic("could not complete workflow task", "error", err)
+	if err := ww.retryBackend(ctx, func() error {
+		return ww.backend.CompleteWorkflowTask(
+			ctx, t, t.WorkflowInstance, state, result.Executed, result.ActivityEvents, result.TimerEvents, result.WorkflowEvents)
+	}); err != nil {
+		// The task will be picked up again once its lock expires. The executor has already processed the new events
+		// of the task, don't reuse it.
+		ww.logger.Error("could not complete workflow task", "instance_id", t.WorkflowInstance.InstanceID, "error", err)
+
+		ww.evictExecutor(ctx, t)
+	}
+}
+
+// failTask releases the given workflow task without processing its new events. The task is retried with an
+// exponential backoff. Only the first failure in a row is recorded in the history of the workflow instance, the
+// backend keeps track of the number of attempts.
+func (ww *WorkflowWorker) failTask(ctx context.Context, t *task.Workflow, taskErr error) {
+	// The state of the executor does not match the history anymore, don't reuse it
+	ww.evictExecutor(ctx, t)
+
+	// Backends that don't count attempts leave it unset
+	attempt := t.Attempt
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	retryAfter := ww.taskRetryInterval(attempt)
+
+	ww.logger.Error("workflow task failed",
+		"instance_id", t.WorkflowInstance.InstanceID,
+		"attempt", attempt,
+		"retry_after", retryAfter,
+		"error", taskErr,
+	)
+
+	ww.backend.Metrics().Counter(metrickeys.WorkflowTaskFailed, metrics.Tags{}, 1)
+
+	var failedEvent *history.Event
+	if attempt == 1 {
+		a := &history.WorkflowTaskFailedAttributes{
+			Error: taskErr.Error(),
+		}
+
+		var st stackTracer
+		if errors.As(taskErr, &st) {
+			a.StackTrace = st.StackTrace()
+		}
+
+		failedEvent = history.NewHistoryEvent(t.LastSequenceID+1, ww.clock.Now(), history.EventType_WorkflowTaskFailed, a)
+	}
+
+	if err := ww.retryBackend(ctx, func() error {
+		return ww.backend.FailWorkflowTask(ctx, t, t.WorkflowInstance, failedEvent, retryAfter)
+	}); err != nil {
+		// The task will be picked up again once its lock expires
+		ww.logger.Error("could not fail workflow task", "instance_id", t.WorkflowInstance.InstanceID, "error", err)
+	}
+}
+
+// trackDeadlocked remembers the deadlocked coroutine of the given instance until it has stopped
+func (ww *WorkflowWorker) trackDeadlocked(instance *core.WorkflowInstance, derr *internalsync.DeadlockError) {
+	ww.deadlockedMu.Lock()
+	defer ww.deadlockedMu.Unlock()
+
+	ww.deadlocked[instance.InstanceID] = derr.Done()
+}
+
+// deadlockedRunning reports whether the code of an earlier deadlocked task of the given instance is still running
+func (ww *WorkflowWorker) deadlockedRunning(instance *core.WorkflowInstance) bool {
+	ww.deadlockedMu.Lock()
+	defer ww.deadlockedMu.Unlock()
+
+	done, ok := ww.deadlocked[instance.InstanceID]
+	if !ok {
+		return false
+	}
+
+	select {
+	case <-done:
+		delete(ww.deadlocked, instance.InstanceID)
+		return false
+	default:
+		return true
+	}
+}
+
+func (ww *WorkflowWorker) taskRetryInterval(attempt int) time.Duration {
+	d := float64(ww.options.WorkflowTaskRetryInterval) * math.Pow(2, float64(attempt-1))
+	if max := float64(ww.options.WorkflowTaskMaxRetryInterval); max > 0 {
+		d = math.Min(d, max)
+	}
+
+	return time.Duration(d)
+}
+
+// retryBackend retries the given backend operation with a short exponential backoff, to ride out transient errors
+func (ww *WorkflowWorker) retryBackend(ctx context.Context, op func() error) error {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = 100 * time.Millisecond
+
+	return backoff.RetryNotify(
+		op,
+		backoff.WithContext(backoff.WithMaxRetries(b, backendRetries), ctx),
+		func(err error, d time.Duration) {
+			ww.logger.Warn("backend operation failed, retrying", "error", err, "retry_after", d)
+		},
+	)
+}
+
+func (ww *WorkflowWorker) evictExecutor(ctx context.Context, t *task.Workflow) {
+	if err := ww.cache.Evict(ctx, t.WorkflowInstance); err != nil {
+		ww.logger.Error("could not evict workflow task executor", "error", err)
 	}
 }
 
 func (ww *WorkflowWorker) handleTask(
 	ctx context.Context,
 	t *task.Workflow,
-) (*workflow.ExecutionResult, error) {
-	executor, err := ww.getExecutor(ctx, t)
+) (result *workflow.ExecutionResult, err error) {
+	// Workflow code runs in its own goroutines and fails the workflow when it panics. Panics here are caused by the
+	// executor, for example for histories it cannot process, and only fail the task.
+	defer func() {
+		if r := recover(); r != nil {
+			err = &panicError{value: r, stack: string(debug.Stack())}
+		}
+	}()
+
+	executor, cached, err := ww.getExecutor(ctx, t)
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +359,23 @@ func (ww *WorkflowWorker) handleTask(
 		go ww.heartbeatTask(heartbeatCtx, t)
 	}
 
-	result, err := executor.ExecuteTask(ctx, t)
+	result, err = executor.ExecuteTask(ctx, t)
+
+	var merr *workflow.HistoryMismatchError
+	if errors.As(err, &merr) && cached {
+		// The cached executor is outdated, replay the whole history with a new executor
+		ww.logger.Debug("cached workflow executor does not match task, replaying history",
+			"instance_id", t.WorkflowInstance.InstanceID, "error", err)
+
+		ww.evictExecutor(ctx, t)
+
+		if executor, _, err = ww.getExecutor(ctx, t); err != nil {
+			return nil, err
+		}
+
+		result, err = executor.ExecuteTask(ctx, t)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("executing workflow task: %w", err)
 	}
@@ -191,7 +383,9 @@ func (ww *WorkflowWorker) handleTask(
 	return result, nil
 }
 
-func (ww *WorkflowWorker) getExecutor(ctx context.Context, t *task.Workflow) (workflow.WorkflowExecutor, error) {
+// getExecutor returns the cached executor for the instance of the given task, or a new one. cached reports whether
+// the executor was taken from the cache.
+func (ww *WorkflowWorker) getExecutor(ctx context.Context, t *task.Workflow) (workflow.WorkflowExecutor, bool, error) {
 	// Try to get a cached executor
 	executor, ok, err := ww.cache.Get(ctx, t.WorkflowInstance)
 	if err != nil {
@@ -200,7 +394,10 @@ func (ww *WorkflowWorker) getExecutor(ctx context.Context, t *task.Workflow) (wo
 
 	if !ok {
 		executor = workflow.NewExecutor(
-			ww.backend.Logger(), ww.backend.Tracer(), ww.registry, ww.backend.Converter(), ww.backend, t.WorkflowInstance, clock.New())
+			ww.backend.Logger(), ww.backend.Tracer(), ww.registry, ww.backend.Converter(), ww.backend.ContextPropagators(),
+			ww.options.WorkflowInterceptors, ww.backend, t.WorkflowInstance, ww.clock,
+			workflow.WithDeadlockTimeout(ww.options.WorkflowDeadlockTimeout),
+			workflow.WithPayloadMatching(ww.options.MatchReplayedPayloads))
 	}
 
 	// Cache executor instance for future continuation tasks, or refresh last access time
@@ -208,7 +405,59 @@ func (ww *WorkflowWorker) getExecutor(ctx context.Context, t *task.Workflow) (wo
 		ww.logger.Error("error while caching workflow task executor:", "error", err)
 	}
 
-	return executor, nil
+	return executor, ok, nil
+}
+
+// StackTrace replays the history of the given workflow instance and returns the stack traces of its coroutines,
+// showing where the workflow is currently blocked. Only the recorded history is replayed, nothing is executed live.
+func (ww *WorkflowWorker) StackTrace(ctx context.Context, instance *core.WorkflowInstance) (string, error) {
+	h, err := ww.backend.GetWorkflowInstanceHistory(ctx, instance, nil)
+	if err != nil {
+		return "", fmt.Errorf("getting workflow history: %w", err)
+	}
+
+	if len(h) == 0 {
+		return "", fmt.Errorf("workflow instance %s has not started yet", instance.InstanceID)
+	}
+
+	last := h[len(h)-1]
+	if last.Type == history.EventType_WorkflowExecutionFinished {
+		return "", fmt.Errorf("workflow instance %s has finished", instance.InstanceID)
+	}
+
+	if ww.deadlockedRunning(instance) {
+		return "", errDeadlockedWorkflowRunning
+	}
+
+	executor := workflow.NewExecutor(
+		ww.backend.Logger(), ww.backend.Tracer(), ww.registry, ww.backend.Converter(), ww.backend.ContextPropagators(),
+		ww.options.WorkflowInterceptors, ww.backend, instance, ww.clock,
+		workflow.WithDeadlockTimeout(ww.options.WorkflowDeadlockTimeout),
+		workflow.WithPayloadMatching(ww.options.MatchReplayedPayloads),
+		workflow.WithReplayOnly())
+	defer executor.Close()
+
+	result, err := executor.ExecuteTask(ctx, &task.Workflow{
+		ID:                    "stacktrace",
+		WorkflowInstance:      instance,
+		WorkflowInstanceState: core.WorkflowInstanceStateActive,
+		Metadata:              &core.WorkflowMetadata{},
+		LastSequenceID:        last.SequenceID,
+	})
+	if err != nil {
+		var derr *internalsync.DeadlockError
+		if errors.As(err, &derr) {
+			ww.trackDeadlocked(instance, derr)
+		}
+
+		return "", fmt.Errorf("replaying workflow instance: %w", err)
+	}
+
+	if result.ReplayError != nil {
+		return "", fmt.Errorf("replaying workflow instance: %w", result.ReplayError)
+	}
+
+	return executor.StackTrace(), nil
 }
 
 func (ww *WorkflowWorker) heartbeatTask(ctx context.Context, task *task.Workflow) {
@@ -221,7 +470,7 @@ func (ww *WorkflowWorker) heartbeatTask(ctx context.Context, task *task.Workflow
 			return
 		case <-t.C:
 			if err := ww.backend.ExtendWorkflowTask(ctx, task.ID, task.WorkflowInstance); err != nil {
-				ww.logger.Panic("could not heartbeat workflow task", "error", err)
+				ww.logger.Error("could not heartbeat workflow task", "error", err)
 			}
 		}
 	}
@@ -9,5 +9,6 @@ import (
 type ExecutorCache interface {
 	Store(ctx context.Context, instance *core.WorkflowInstance, workflow WorkflowExecutor) error
 	Get(ctx context.Context, instance *core.WorkflowInstance) (WorkflowExecutor, bool, error)
+	Evict(ctx context.Context, instance *core.WorkflowInstance) error
 	StartEviction(ctx context.Context)
 }
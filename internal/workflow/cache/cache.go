@@ -32,6 +32,8 @@ func NewWorkflowExecutorLRUCache(mc metrics.Client, size int, expiration time.Du
 			reason = "expired"
 		case ttlcache.EvictionReasonCapacityReached:
 			reason = "capacity"
+		case ttlcache.EvictionReasonDeleted:
+			reason = "deleted"
 		}
 
 		mc.Counter(metrickeys.WorkflowInstanceCacheEviction, metrics.Tags{metrickeys.EvictionReason: reason}, 1)
@@ -60,6 +62,14 @@ func (lc *LruCache) Store(ctx context.Context, instance *core.WorkflowInstance,
 	return nil
 }
 
+func (lc *LruCache) Evict(ctx context.Context, instance *core.WorkflowInstance) error {
+	lc.c.Delete(getKey(instance))
+
+	lc.mc.Gauge(metrickeys.WorkflowInstanceCacheSize, metrics.Tags{}, int64(lc.c.Len()))
+
+	return nil
+}
+
 func (lc *LruCache) StartEviction(ctx context.Context) {
 	go lc.c.Start()
 
@@ -5,13 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/interceptor"
 	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/contextpropagation"
 	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/history"
 	"github.com/cschleiden/go-workflows/internal/payload"
+	"github.com/cschleiden/go-workflows/internal/signals"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/internal/tracing"
@@ -28,6 +33,45 @@ type ExecutionResult struct {
 	ActivityEvents []*history.Event
 	TimerEvents    []*history.Event
 	WorkflowEvents []history.WorkflowEvent
+
+	// ReplayError is set if the history of the workflow instance could not be replayed. The workflow instance has
+	// been failed with this error.
+	ReplayError *ReplayError
+}
+
+// ReplayError describes the history event at which replaying the history of a workflow instance failed, for example
+// because the workflow code is not deterministic.
+type ReplayError struct {
+	Event *history.Event
+
+	Err error
+}
+
+func (e *ReplayError) Error() string {
+	// Non-determinism errors already name the event
+	var nderr *NonDeterminismError
+	if errors.As(e.Err, &nderr) {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("replaying event %d (%v): %v", e.Event.SequenceID, e.Event.Type, e.Err)
+}
+
+func (e *ReplayError) Unwrap() error {
+	return e.Err
+}
+
+// HistoryMismatchError is returned by ExecuteTask if the history of a task cannot be applied to the state of the
+// executor, for example because the executor is outdated or the history is not ordered by sequence id. The executor
+// must not be used afterwards.
+type HistoryMismatchError struct {
+	TaskSequenceID     int64
+	ExecutorSequenceID int64
+}
+
+func (e *HistoryMismatchError) Error() string {
+	return fmt.Sprintf("history at sequence id %d does not match executor state at sequence id %d",
+		e.TaskSequenceID, e.ExecutorSequenceID)
 }
 
 type WorkflowHistoryProvider interface {
@@ -37,48 +81,115 @@ type WorkflowHistoryProvider interface {
 type WorkflowExecutor interface {
 	ExecuteTask(ctx context.Context, t *task.Workflow) (*ExecutionResult, error)
 
+	// StackTrace returns the stack traces of the running coroutines of the workflow, showing where each of them is
+	// blocked
+	StackTrace() string
+
 	Close()
 }
 
+type ExecutorOption func(e *executor)
+
+// WithDeadlockTimeout sets the duration after which workflow code that does not yield back to the executor, for
+// example because it is blocked on a native channel, is considered deadlocked. Defaults to sync.DeadlockDetection,
+// which is also used when d is zero.
+func WithDeadlockTimeout(d time.Duration) ExecutorOption {
+	return func(e *executor) {
+		if d > 0 {
+			e.deadlockTimeout = d
+		}
+	}
+}
+
+// WithPayloadMatching makes the executor compare the inputs of activities and sub-workflows and the arguments of
+// signals issued when replaying with the recorded history
+func WithPayloadMatching(enabled bool) ExecutorOption {
+	return func(e *executor) {
+		e.payloadMatching = enabled
+	}
+}
+
+// WithReplayOnly makes the executor only replay the recorded history of tasks. New events and commands are not
+// executed, so the workflow code stops where the history ends, without running side effects or any other code live.
+// Used to inspect the state of workflow instances.
+func WithReplayOnly() ExecutorOption {
+	return func(e *executor) {
+		e.replayOnly = true
+	}
+}
+
 type executor struct {
 	registry          *Registry
+	interceptors      []interceptor.WorkflowInterceptor
 	historyProvider   WorkflowHistoryProvider
 	workflow          *workflow
 	workflowTracer    *workflowtracer.WorkflowTracer
 	workflowState     *workflowstate.WfState
 	workflowCtx       sync.Context
 	workflowCtxCancel sync.CancelFunc
+	taskConverter     *converter.TaskConverter
 	clock             clock.Clock
 	logger            log.Logger
 	tracer            trace.Tracer
 	lastSequenceID    int64
+	deadlockTimeout   time.Duration
+	payloadMatching   bool
+	replayOnly        bool
 }
 
-func NewExecutor(logger log.Logger, tracer trace.Tracer, registry *Registry, cv converter.Converter, historyProvider WorkflowHistoryProvider, instance *core.WorkflowInstance, clock clock.Clock) WorkflowExecutor {
+func NewExecutor(logger log.Logger, tracer trace.Tracer, registry *Registry, cv converter.Converter, propagators []contextpropagation.ContextPropagator, interceptors []interceptor.WorkflowInterceptor, historyProvider WorkflowHistoryProvider, instance *core.WorkflowInstance, clock clock.Clock, opts ...ExecutorOption) WorkflowExecutor {
 	s := workflowstate.NewWorkflowState(instance, logger, clock)
 
 	wfTracer := workflowtracer.New(tracer)
 
+	// Codecs of the converter use the context of the task being executed
+	taskConverter := converter.NewTaskConverter(cv, instance)
+
 	wfCtx := sync.Background()
-	wfCtx = converter.WithConverter(wfCtx, cv)
+	wfCtx = converter.WithConverter(wfCtx, taskConverter)
+	wfCtx = contextpropagation.WithPropagators(wfCtx, propagators)
 	wfCtx = workflowtracer.WithWorkflowTracer(wfCtx, wfTracer)
 	wfCtx = workflowstate.WithWorkflowState(wfCtx, s)
 	wfCtx, cancel := sync.WithCancel(wfCtx)
 
-	return &executor{
+	e := &executor{
 		registry:          registry,
+		interceptors:      interceptors,
 		historyProvider:   historyProvider,
 		workflowTracer:    wfTracer,
 		workflowState:     s,
 		workflowCtx:       wfCtx,
 		workflowCtxCancel: cancel,
+		taskConverter:     taskConverter,
 		clock:             clock,
 		logger:            logger,
 		tracer:            tracer,
+		deadlockTimeout:   sync.DeadlockDetection,
 	}
+
+	for _, o := range opts {
+		o(e)
+	}
+
+	return e
 }
 
-func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) (*ExecutionResult, error) {
+func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) (result *ExecutionResult, err error) {
+	e.taskConverter.SetContext(ctx)
+
+	// Workflow code that does not yield back to the executor cannot be stopped, but the task can still fail. The
+	// executor must not be used afterwards.
+	defer func() {
+		if r := recover(); r != nil {
+			derr, ok := r.(*sync.DeadlockError)
+			if !ok {
+				panic(r)
+			}
+
+			err = derr
+		}
+	}()
+
 	ctx = tracing.UnmarshalSpan(ctx, t.Metadata)
 	ctx, span := e.tracer.Start(ctx, "WorkflowTaskExecution", trace.WithAttributes(
 		attribute.String(tracing.WorkflowInstanceID, t.WorkflowInstance.InstanceID),
@@ -111,6 +222,7 @@ func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) (*Executio
 	}
 
 	skipNewEvents := false
+	var replayErr *ReplayError
 
 	if t.LastSequenceID > e.lastSequenceID {
 		logger.Debug("Task has newer history than current state, fetching and replaying history", "task_sequence_id", t.LastSequenceID, "local_sequence_id", e.lastSequenceID)
@@ -120,9 +232,19 @@ func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) (*Executio
 			return nil, fmt.Errorf("getting workflow history: %w", err)
 		}
 
+		if err := e.checkHistory(h); err != nil {
+			return nil, err
+		}
+
 		if err := e.replayHistory(h); err != nil {
 			logger.Error("Error while replaying history", "error", err)
 
+			if e.replayOnly {
+				return &ExecutionResult{ReplayError: err}, nil
+			}
+
+			replayErr = err
+
 			// Fail workflow with an error. Skip executing new events, but still go through the commands
 			e.workflowCompleted(nil, err)
 			skipNewEvents = true
@@ -132,10 +254,14 @@ func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) (*Executio
 		} else if t.LastSequenceID != e.lastSequenceID {
 			logger.Error("After replaying history, task still has newer history than current state", "task_sequence_id", t.LastSequenceID, "local_sequence_id", e.lastSequenceID)
 
-			return nil, errors.New("even after fetching history and replaying history executor state does not match task")
+			return nil, &HistoryMismatchError{TaskSequenceID: t.LastSequenceID, ExecutorSequenceID: e.lastSequenceID}
 		}
 	} else if t.LastSequenceID < e.lastSequenceID {
-		return nil, fmt.Errorf("task has older history than current state, cannot execute")
+		return nil, &HistoryMismatchError{TaskSequenceID: t.LastSequenceID, ExecutorSequenceID: e.lastSequenceID}
+	}
+
+	if e.replayOnly {
+		return &ExecutionResult{}, nil
 	}
 
 	// Always add a WorkflowTaskStarted event before executing new tasks
@@ -199,18 +325,31 @@ func (e *executor) ExecuteTask(ctx context.Context, t *task.Workflow) (*Executio
 		ActivityEvents: activityEvents,
 		TimerEvents:    timerEvents,
 		WorkflowEvents: workflowEvents,
+		ReplayError:    replayErr,
 	}, nil
 }
 
-func (e *executor) replayHistory(h []*history.Event) error {
-	e.workflowState.SetReplaying(true)
+// checkHistory verifies that the given history continues the state of the executor
+func (e *executor) checkHistory(h []*history.Event) error {
+	last := e.lastSequenceID
 	for _, event := range h {
-		if event.SequenceID < e.lastSequenceID {
-			e.logger.Panic("history has older events than current state")
+		if event.SequenceID < last {
+			return &HistoryMismatchError{TaskSequenceID: event.SequenceID, ExecutorSequenceID: last}
 		}
 
+		last = event.SequenceID
+	}
+
+	return nil
+}
+
+func (e *executor) replayHistory(h []*history.Event) *ReplayError {
+	e.workflowState.SetReplaying(true)
+	for _, event := range h {
+		e.workflowState.SetHistoryLength(event.SequenceID)
+
 		if err := e.executeEvent(event); err != nil {
-			return err
+			return &ReplayError{Event: event, Err: err}
 		}
 
 		e.lastSequenceID = event.SequenceID
@@ -223,15 +362,19 @@ func (e *executor) executeNewEvents(newEvents []*history.Event) ([]*history.Even
 	e.workflowState.SetReplaying(false)
 
 	for i, event := range newEvents {
+		// Sequence IDs are only assigned once the task is done, mirror what replaying will observe
+		e.workflowState.SetHistoryLength(e.lastSequenceID + int64(i) + 1)
+
 		if err := e.executeEvent(event); err != nil {
 			return newEvents[:i], err
 		}
 	}
 
 	if e.workflow.Completed() {
-		// TODO: Is this too early? We haven't committed some of the commands
+		// Workflows can finish without waiting for all futures, for example the losing futures of workflow.Any.
+		// Results reported for them after the workflow finished are discarded.
 		if e.workflowState.HasPendingFutures() {
-			e.logger.Panic("workflow completed, but there are still pending futures")
+			e.logger.Debug("Workflow completed with pending futures")
 		}
 
 		e.workflowCompleted(e.workflow.Result(), e.workflow.Error())
@@ -240,6 +383,14 @@ func (e *executor) executeNewEvents(newEvents []*history.Event) ([]*history.Even
 	return newEvents, nil
 }
 
+func (e *executor) StackTrace() string {
+	if e.workflow == nil {
+		return ""
+	}
+
+	return e.workflow.StackTrace()
+}
+
 func (e *executor) Close() {
 	if e.workflow != nil {
 		e.logger.Debug("Stopping workflow executor", "instance_id", e.workflowState.Instance().InstanceID)
@@ -263,7 +414,7 @@ func (e *executor) executeEvent(event *history.Event) error {
 
 	switch event.Type {
 	case history.EventType_WorkflowExecutionStarted:
-		err = e.handleWorkflowExecutionStarted(event.Attributes.(*history.ExecutionStartedAttributes))
+		err = e.handleWorkflowExecutionStarted(event, event.Attributes.(*history.ExecutionStartedAttributes))
 
 	case history.EventType_WorkflowExecutionFinished:
 	// Ignore
@@ -274,6 +425,9 @@ func (e *executor) executeEvent(event *history.Event) error {
 	case history.EventType_WorkflowTaskStarted:
 		err = e.handleWorkflowTaskStarted(event, event.Attributes.(*history.WorkflowTaskStartedAttributes))
 
+	case history.EventType_WorkflowTaskFailed:
+	// Ignore, the events of the failed task are not part of the history
+
 	case history.EventType_ActivityScheduled:
 		err = e.handleActivityScheduled(event, event.Attributes.(*history.ActivityScheduledAttributes))
 
@@ -300,6 +454,8 @@ func (e *executor) executeEvent(event *history.Event) error {
 
 	case history.EventType_SubWorkflowScheduled:
 		err = e.handleSubWorkflowScheduled(event, event.Attributes.(*history.SubWorkflowScheduledAttributes))
+	case history.EventType_SubWorkflowStarted:
+		err = e.handleSubWorkflowStarted(event, event.Attributes.(*history.SubWorkflowStartedAttributes))
 	case history.EventType_SubWorkflowCancellationRequested:
 		err = e.handleSubWorkflowCancellationRequest(event, event.Attributes.(*history.SubWorkflowCancellationRequestedAttributes))
 	case history.EventType_SubWorkflowFailed:
@@ -307,6 +463,25 @@ func (e *executor) executeEvent(event *history.Event) error {
 	case history.EventType_SubWorkflowCompleted:
 		err = e.handleSubWorkflowCompleted(event, event.Attributes.(*history.SubWorkflowCompletedAttributes))
 
+	case history.EventType_SignalExternalWorkflowScheduled:
+		err = e.handleSignalExternalWorkflowScheduled(event, event.Attributes.(*history.SignalExternalWorkflowScheduledAttributes))
+	case history.EventType_SignalExternalWorkflowCompleted:
+		err = e.handleExternalWorkflowResult(event, nil)
+	case history.EventType_SignalExternalWorkflowFailed:
+		a := event.Attributes.(*history.SignalExternalWorkflowFailedAttributes)
+		err = e.handleExternalWorkflowResult(event, externalWorkflowError(a.Error, a.InstanceNotFound, a.InstanceFinished))
+
+	case history.EventType_RequestCancelExternalWorkflowScheduled:
+		err = e.handleRequestCancelExternalWorkflowScheduled(event, event.Attributes.(*history.RequestCancelExternalWorkflowScheduledAttributes))
+	case history.EventType_RequestCancelExternalWorkflowCompleted:
+		err = e.handleExternalWorkflowResult(event, nil)
+	case history.EventType_RequestCancelExternalWorkflowFailed:
+		a := event.Attributes.(*history.RequestCancelExternalWorkflowFailedAttributes)
+		err = e.handleExternalWorkflowResult(event, externalWorkflowError(a.Error, a.InstanceNotFound, false))
+
+	case history.EventType_SearchAttributesUpserted:
+		err = e.handleSearchAttributesUpserted(event, event.Attributes.(*history.SearchAttributesUpsertedAttributes))
+
 	default:
 		return fmt.Errorf("unknown event type: %v", event.Type)
 	}
@@ -314,15 +489,32 @@ func (e *executor) executeEvent(event *history.Event) error {
 	return err
 }
 
-func (e *executor) handleWorkflowExecutionStarted(a *history.ExecutionStartedAttributes) error {
+func (e *executor) handleWorkflowExecutionStarted(event *history.Event, a *history.ExecutionStartedAttributes) error {
 	wfFn, err := e.registry.GetWorkflow(a.Name)
 	if err != nil {
 		return fmt.Errorf("workflow %s not found", a.Name)
 	}
 
-	e.workflow = NewWorkflow(reflect.ValueOf(wfFn))
+	e.workflowState.SetStarted(&workflowstate.Started{
+		Name:      a.Name,
+		StartedAt: event.Timestamp,
+		Attempt:   a.Attempt,
+		Metadata:  a.Metadata,
+	})
+
+	metadata := a.Metadata
+	if metadata == nil {
+		metadata = &core.WorkflowMetadata{}
+	}
+
+	ctx, err := contextpropagation.ExtractToWorkflow(e.workflowCtx, metadata)
+	if err != nil {
+		return err
+	}
+
+	e.workflow = NewWorkflow(reflect.ValueOf(wfFn), e.interceptors, e.deadlockTimeout)
 
-	return e.workflow.Execute(e.workflowCtx, a.Inputs)
+	return e.workflow.Execute(ctx, a.Name, a.Inputs)
 }
 
 func (e *executor) handleWorkflowCanceled() error {
@@ -338,22 +530,25 @@ func (e *executor) handleWorkflowTaskStarted(event *history.Event, a *history.Wo
 }
 
 func (e *executor) handleActivityScheduled(event *history.Event, a *history.ActivityScheduledAttributes) error {
-	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
-	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled an activity which could not be found")
+	if sewc, ok := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID).(*command.SignalExternalWorkflowCommand); ok && a.Name == deliverWorkflowSignalActivity {
+		return e.handleLegacySignalScheduled(event, a, sewc)
 	}
 
-	sac, ok := c.(*command.ScheduleActivityCommand)
-	if !ok {
-		return fmt.Errorf("previous workflow execution scheduled an activity, not: %v", c.Type())
+	sac, err := commandForEvent[*command.ScheduleActivityCommand](e, event, "ScheduleActivity")
+	if err != nil {
+		return err
 	}
 
 	// Ensure the same activity was scheduled again
-	if a.Name != sac.Name {
-		return fmt.Errorf("previous workflow execution scheduled different type of activity: %s, %s", a.Name, sac.Name)
+	if err := e.matchString(event, sac, "name", a.Name, sac.Name); err != nil {
+		return err
+	}
+
+	if err := e.matchPayloads(event, sac, "inputs", a.Inputs, sac.Inputs); err != nil {
+		return err
 	}
 
-	c.Commit()
+	sac.Commit()
 
 	return nil
 }
@@ -371,17 +566,12 @@ func (e *executor) handleActivityCompleted(event *history.Event, a *history.Acti
 
 	e.workflowState.RemoveFuture(event.ScheduleEventID)
 
-	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
-	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled an activity which could not be found")
-	}
-
-	sac, ok := c.(*command.ScheduleActivityCommand)
-	if !ok {
-		return fmt.Errorf("previous workflow execution scheduled an activity, not: %v", c.Type())
+	c, err := activityCommandForEvent(e, event)
+	if err != nil {
+		return err
 	}
 
-	sac.Done()
+	c.Done()
 
 	return e.workflow.Continue()
 }
@@ -398,32 +588,79 @@ func (e *executor) handleActivityFailed(event *history.Event, a *history.Activit
 
 	e.workflowState.RemoveFuture(event.ScheduleEventID)
 
-	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
-	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled an activity which could not be found")
+	c, err := activityCommandForEvent(e, event)
+	if err != nil {
+		return err
+	}
+
+	c.Done()
+
+	return e.workflow.Continue()
+}
+
+// deliverWorkflowSignalActivity is the activity workflows scheduled to send signals before the SignalExternalWorkflow
+// command existed
+var deliverWorkflowSignalActivity = fn.Name((*signals.Activities).DeliverWorkflowSignal)
+
+// handleLegacySignalScheduled matches a signal sent via the DeliverWorkflowSignal activity in existing histories
+// against the SignalExternalWorkflow command the workflow issues now. The signal has already been delivered or will
+// be by the still registered activity, its result resolves the future of the command.
+func (e *executor) handleLegacySignalScheduled(event *history.Event, a *history.ActivityScheduledAttributes, sewc *command.SignalExternalWorkflowCommand) error {
+	cv := converter.GetConverter(e.workflowCtx)
+
+	instanceID, err := cv.To(sewc.InstanceID)
+	if err != nil {
+		return err
 	}
 
-	sac, ok := c.(*command.ScheduleActivityCommand)
-	if !ok {
-		return fmt.Errorf("previous workflow execution scheduled an activity, not: %v", c.Type())
+	name, err := cv.To(sewc.Name)
+	if err != nil {
+		return err
 	}
 
-	sac.Done()
+	if len(a.Inputs) != 3 {
+		return e.commandMismatch(event, sewc, "inputs", fmt.Sprintf("%d values", len(a.Inputs)), "3 values")
+	}
 
-	return e.workflow.Continue()
+	if err := e.comparePayloads(event, sewc, "instance", a.Inputs[:1], []payload.Payload{instanceID}); err != nil {
+		return err
+	}
+
+	if err := e.comparePayloads(event, sewc, "signal name", a.Inputs[1:2], []payload.Payload{name}); err != nil {
+		return err
+	}
+
+	if err := e.matchPayloads(event, sewc, "signal argument", a.Inputs[2:], []payload.Payload{sewc.Arg}); err != nil {
+		return err
+	}
+
+	sewc.Commit()
+
+	return nil
+}
+
+// activityCommandForEvent returns the command an activity event has been recorded for. That is either a
+// ScheduleActivity command, or a SignalExternalWorkflow command for signals sent via the legacy activity.
+func activityCommandForEvent(e *executor, event *history.Event) (command.Command, error) {
+	if sewc, ok := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID).(*command.SignalExternalWorkflowCommand); ok {
+		return sewc, nil
+	}
+
+	return commandForEvent[*command.ScheduleActivityCommand](e, event, "ScheduleActivity")
 }
 
 func (e *executor) handleTimerScheduled(event *history.Event, a *history.TimerScheduledAttributes) error {
-	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
-	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled a timer")
+	stc, err := commandForEvent[*command.ScheduleTimerCommand](e, event, "ScheduleTimer")
+	if err != nil {
+		return err
 	}
 
-	if _, ok := c.(*command.ScheduleTimerCommand); !ok {
-		return fmt.Errorf("previous workflow execution scheduled a timer, not: %v", c.Type())
+	// Timers scheduled by older versions did not record their duration
+	if a.Duration != 0 && a.Duration != stc.Duration() {
+		return e.commandMismatch(event, stc, "duration", a.Duration, stc.Duration())
 	}
 
-	c.Commit()
+	stc.Commit()
 
 	return nil
 }
@@ -441,29 +678,20 @@ func (e *executor) handleTimerFired(event *history.Event, a *history.TimerFiredA
 
 	e.workflowState.RemoveFuture(event.ScheduleEventID)
 
-	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
-	if c == nil {
-		return fmt.Errorf("no command found for timer fired event")
-	}
-
-	if _, ok := c.(*command.ScheduleTimerCommand); !ok {
-		return fmt.Errorf("schedule timer command not found, instead: %v", c.Type())
+	stc, err := commandForEvent[*command.ScheduleTimerCommand](e, event, "ScheduleTimer")
+	if err != nil {
+		return err
 	}
 
-	c.Done()
+	stc.Done()
 
 	return e.workflow.Continue()
 }
 
 func (e *executor) handleTimerCanceled(event *history.Event, a *history.TimerCanceledAttributes) error {
-	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
-	if c == nil {
-		return fmt.Errorf("previous workflow execution canceled a timer")
-	}
-
-	stc, ok := c.(*command.ScheduleTimerCommand)
-	if !ok {
-		return fmt.Errorf("previous workflow execution canceled a timer, not: %v", c.Type())
+	stc, err := commandForEvent[*command.ScheduleTimerCommand](e, event, "ScheduleTimer")
+	if err != nil {
+		return err
 	}
 
 	stc.HandleCancel()
@@ -485,38 +713,52 @@ func (e *executor) handleTimerCanceled(event *history.Event, a *history.TimerCan
 }
 
 func (e *executor) handleSubWorkflowScheduled(event *history.Event, a *history.SubWorkflowScheduledAttributes) error {
-	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
-	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled a sub workflow")
+	sswc, err := commandForEvent[*command.ScheduleSubWorkflowCommand](e, event, "ScheduleSubWorkflow")
+	if err != nil {
+		return err
 	}
 
-	sswc, ok := c.(*command.ScheduleSubWorkflowCommand)
-	if !ok {
-		return fmt.Errorf("previous workflow execution scheduled a sub workflow, not: %v", c.Type())
+	if err := e.matchString(event, sswc, "name", a.Name, sswc.Name); err != nil {
+		return err
+	}
+
+	if err := e.matchPayloads(event, sswc, "inputs", a.Inputs, sswc.Inputs); err != nil {
+		return err
 	}
 
-	if a.Name != sswc.Name {
-		return fmt.Errorf("previous workflow execution scheduled different type of sub workflow: %s, %s", a.Name, sswc.Name)
+	if a.Detached != sswc.Detached {
+		return e.commandMismatch(event, sswc, "detached", a.Detached, sswc.Detached)
 	}
 
 	// If we are replaying this event, the command will have generated a new instance ID. Ensure we use the same one as
 	// when the command was originally committed.
 	sswc.Instance = a.SubWorkflowInstance
 
-	c.Commit()
+	sswc.Commit()
 
 	return nil
 }
 
-func (e *executor) handleSubWorkflowCancellationRequest(event *history.Event, a *history.SubWorkflowCancellationRequestedAttributes) error {
-	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
-	if c == nil {
-		return fmt.Errorf("previous workflow execution cancelled a sub-workflow execution")
+func (e *executor) handleSubWorkflowStarted(event *history.Event, a *history.SubWorkflowStartedAttributes) error {
+	sswc, err := commandForEvent[*command.ScheduleSubWorkflowCommand](e, event, "ScheduleSubWorkflow")
+	if err != nil {
+		return err
 	}
 
-	sswc, ok := c.(*command.ScheduleSubWorkflowCommand)
-	if !ok {
-		return fmt.Errorf("previous workflow execution cancelled a sub-workflow execution, not: %v", c.Type())
+	sswc.HandleStarted(a.SubWorkflowInstance)
+
+	// Detached sub-workflows do not report back their result, the command is done once the sub-workflow has started
+	if sswc.Detached && sswc.State() == command.CommandState_Committed {
+		sswc.Done()
+	}
+
+	return e.workflow.Continue()
+}
+
+func (e *executor) handleSubWorkflowCancellationRequest(event *history.Event, a *history.SubWorkflowCancellationRequestedAttributes) error {
+	sswc, err := commandForEvent[*command.ScheduleSubWorkflowCommand](e, event, "ScheduleSubWorkflow")
+	if err != nil {
+		return err
 	}
 
 	sswc.HandleCancel()
@@ -525,27 +767,32 @@ func (e *executor) handleSubWorkflowCancellationRequest(event *history.Event, a
 }
 
 func (e *executor) handleSubWorkflowFailed(event *history.Event, a *history.SubWorkflowFailedAttributes) error {
-	f, ok := e.workflowState.FutureByScheduleEventID(event.ScheduleEventID)
-	if !ok {
-		return errors.New("no pending future found for sub workflow failed event")
+	sswc, err := commandForEvent[*command.ScheduleSubWorkflowCommand](e, event, "ScheduleSubWorkflow")
+	if err != nil {
+		return err
 	}
 
-	if err := f(nil, errors.New(a.Error)); err != nil {
-		return fmt.Errorf("setting sub workflow failed result: %w", err)
+	subWorkflowErr := errors.New(a.Error)
+	if a.InstanceAlreadyExists {
+		subWorkflowErr = core.ErrInstanceAlreadyExists
 	}
 
-	e.workflowState.RemoveFuture(event.ScheduleEventID)
+	// Detached sub-workflows only fail if they could not be created, their future is not tracked
+	if !sswc.Detached {
+		f, ok := e.workflowState.FutureByScheduleEventID(event.ScheduleEventID)
+		if !ok {
+			return errors.New("no pending future found for sub workflow failed event")
+		}
 
-	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
-	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled a sub-workflow execution")
-	}
+		if err := f(nil, subWorkflowErr); err != nil {
+			return fmt.Errorf("setting sub workflow failed result: %w", err)
+		}
 
-	if _, ok := c.(*command.ScheduleSubWorkflowCommand); !ok {
-		return fmt.Errorf("previous workflow execution cancelled a sub-workflow execution, not: %v", c.Type())
+		e.workflowState.RemoveFuture(event.ScheduleEventID)
 	}
 
-	c.Done()
+	sswc.HandleFailed(subWorkflowErr)
+	sswc.Done()
 
 	return e.workflow.Continue()
 }
@@ -562,16 +809,12 @@ func (e *executor) handleSubWorkflowCompleted(event *history.Event, a *history.S
 
 	e.workflowState.RemoveFuture(event.ScheduleEventID)
 
-	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
-	if c == nil {
-		return fmt.Errorf("previous workflow execution cancelled a sub-workflow execution")
-	}
-
-	if _, ok := c.(*command.ScheduleSubWorkflowCommand); !ok {
-		return fmt.Errorf("previous workflow execution cancelled a sub-workflow execution, not: %v", c.Type())
+	sswc, err := commandForEvent[*command.ScheduleSubWorkflowCommand](e, event, "ScheduleSubWorkflow")
+	if err != nil {
+		return err
 	}
 
-	c.Done()
+	sswc.Done()
 
 	return e.workflow.Continue()
 }
@@ -583,15 +826,90 @@ func (e *executor) handleSignalReceived(event *history.Event, a *history.SignalR
 	return e.workflow.Continue()
 }
 
-func (e *executor) handleSideEffectResult(event *history.Event, a *history.SideEffectResultAttributes) error {
-	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
-	if c == nil {
-		return fmt.Errorf("previous workflow execution scheduled a side effect")
+func (e *executor) handleSignalExternalWorkflowScheduled(event *history.Event, a *history.SignalExternalWorkflowScheduledAttributes) error {
+	sewc, err := commandForEvent[*command.SignalExternalWorkflowCommand](e, event, "SignalExternalWorkflow")
+	if err != nil {
+		return err
+	}
+
+	if err := e.matchString(event, sewc, "instance", a.InstanceID, sewc.InstanceID); err != nil {
+		return err
 	}
 
-	sec, ok := c.(*command.SideEffectCommand)
+	if err := e.matchString(event, sewc, "signal name", a.Name, sewc.Name); err != nil {
+		return err
+	}
+
+	if err := e.matchPayloads(event, sewc, "signal argument", []payload.Payload{a.Arg}, []payload.Payload{sewc.Arg}); err != nil {
+		return err
+	}
+
+	sewc.Commit()
+
+	return nil
+}
+
+func (e *executor) handleRequestCancelExternalWorkflowScheduled(event *history.Event, a *history.RequestCancelExternalWorkflowScheduledAttributes) error {
+	rcewc, err := commandForEvent[*command.RequestCancelExternalWorkflowCommand](e, event, "RequestCancelExternalWorkflow")
+	if err != nil {
+		return err
+	}
+
+	if err := e.matchString(event, rcewc, "instance", a.InstanceID, rcewc.InstanceID); err != nil {
+		return err
+	}
+
+	rcewc.Commit()
+
+	return nil
+}
+
+// handleExternalWorkflowResult resolves the future of a signal or cancellation request sent to another workflow instance
+func (e *executor) handleExternalWorkflowResult(event *history.Event, resultErr error) error {
+	f, ok := e.workflowState.FutureByScheduleEventID(event.ScheduleEventID)
 	if !ok {
-		return fmt.Errorf("previous workflow execution scheduled a side effect, not: %v", c.Type())
+		return fmt.Errorf("no pending future found for %v event", event.Type)
+	}
+
+	if err := f(nil, resultErr); err != nil {
+		return fmt.Errorf("setting external workflow result: %w", err)
+	}
+
+	e.workflowState.RemoveFuture(event.ScheduleEventID)
+
+	var c command.Command
+	var err error
+	switch event.Type {
+	case history.EventType_SignalExternalWorkflowCompleted, history.EventType_SignalExternalWorkflowFailed:
+		c, err = commandForEvent[*command.SignalExternalWorkflowCommand](e, event, "SignalExternalWorkflow")
+	default:
+		c, err = commandForEvent[*command.RequestCancelExternalWorkflowCommand](e, event, "RequestCancelExternalWorkflow")
+	}
+	if err != nil {
+		return err
+	}
+
+	c.Done()
+
+	return e.workflow.Continue()
+}
+
+func externalWorkflowError(msg string, instanceNotFound, instanceFinished bool) error {
+	if instanceNotFound {
+		return core.ErrInstanceNotFound
+	}
+
+	if instanceFinished {
+		return core.ErrInstanceFinished
+	}
+
+	return errors.New(msg)
+}
+
+func (e *executor) handleSideEffectResult(event *history.Event, a *history.SideEffectResultAttributes) error {
+	sec, err := commandForEvent[*command.SideEffectCommand](e, event, "SideEffect")
+	if err != nil {
+		return err
 	}
 
 	sec.Done()
@@ -610,6 +928,21 @@ func (e *executor) handleSideEffectResult(event *history.Event, a *history.SideE
 	return e.workflow.Continue()
 }
 
+func (e *executor) handleSearchAttributesUpserted(event *history.Event, a *history.SearchAttributesUpsertedAttributes) error {
+	usac, err := commandForEvent[*command.UpsertSearchAttributesCommand](e, event, "UpsertSearchAttributes")
+	if err != nil {
+		return err
+	}
+
+	if err := e.matchJSON(event, usac, "search attributes", a.SearchAttributes, usac.SearchAttributes); err != nil {
+		return err
+	}
+
+	usac.Commit()
+
+	return nil
+}
+
 func (e *executor) workflowCompleted(result payload.Payload, err error) {
 	eventId := e.workflowState.GetNextScheduleEventID()
 
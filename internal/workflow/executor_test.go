@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/interceptor"
 	"github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/command"
 	"github.com/cschleiden/go-workflows/internal/converter"
@@ -31,11 +32,15 @@ func (t *testHistoryProvider) GetWorkflowInstanceHistory(ctx context.Context, in
 	return t.history, nil
 }
 
-func newExecutor(r *Registry, i *core.WorkflowInstance, historyProvider WorkflowHistoryProvider) *executor {
+func newExecutor(r *Registry, i *core.WorkflowInstance, historyProvider WorkflowHistoryProvider, opts ...ExecutorOption) *executor {
+	return newExecutorWithConverter(r, converter.DefaultConverter, i, historyProvider, opts...)
+}
+
+func newExecutorWithConverter(r *Registry, cv converter.Converter, i *core.WorkflowInstance, historyProvider WorkflowHistoryProvider, opts ...ExecutorOption) *executor {
 	logger := logger.NewDefaultLogger()
 	tracer := trace.NewNoopTracerProvider().Tracer("test")
 
-	e := NewExecutor(logger, tracer, r, converter.DefaultConverter, historyProvider, i, clock.New())
+	e := NewExecutor(logger, tracer, r, cv, nil, nil, historyProvider, i, clock.New(), opts...)
 
 	return e.(*executor)
 }
@@ -183,6 +188,137 @@ func Test_Executor(t *testing.T) {
 				require.Len(t, e.workflowState.Commands(), 2)
 			},
 		},
+		{
+			name: "Workflow with legacy signal activity replay",
+			f: func(t *testing.T, r *Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflowWithSignal := func(ctx sync.Context) error {
+					_, err := wf.SignalWorkflow(ctx, "other-instance", "signal", 42).Get(ctx)
+					return err
+				}
+
+				r.RegisterWorkflow(workflowWithSignal)
+
+				instanceID, _ := converter.DefaultConverter.To("other-instance")
+				name, _ := converter.DefaultConverter.To("signal")
+				arg, _ := converter.DefaultConverter.To(42)
+				result, _ := converter.DefaultConverter.To(nil)
+
+				task := &task.Workflow{
+					ID:               "taskID",
+					WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+					Metadata:         &core.WorkflowMetadata{},
+					LastSequenceID:   3,
+				}
+
+				// Signals used to be sent via the DeliverWorkflowSignal activity
+				hp.history = []*history.Event{
+					history.NewHistoryEvent(
+						1,
+						time.Now(),
+						history.EventType_WorkflowExecutionStarted,
+						&history.ExecutionStartedAttributes{
+							Name:   fn.Name(workflowWithSignal),
+							Inputs: []payload.Payload{},
+						},
+					),
+					history.NewHistoryEvent(
+						2,
+						time.Now(),
+						history.EventType_ActivityScheduled,
+						&history.ActivityScheduledAttributes{
+							Name:   "DeliverWorkflowSignal",
+							Inputs: []payload.Payload{instanceID, name, arg},
+						},
+						history.ScheduleEventID(1),
+					),
+					history.NewHistoryEvent(
+						3,
+						time.Now(),
+						history.EventType_ActivityCompleted,
+						&history.ActivityCompletedAttributes{
+							Result: result,
+						},
+						history.ScheduleEventID(1),
+					),
+				}
+
+				result2, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.Nil(t, result2.ReplayError)
+				require.NoError(t, e.workflow.err)
+				require.True(t, e.workflow.Completed())
+
+				// The signal is not sent again
+				require.Empty(t, result2.WorkflowEvents)
+			},
+		},
+		{
+			name: "Workflow with failed task replay",
+			f: func(t *testing.T, r *Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflowWithActivity := func(ctx sync.Context) (int, error) {
+					return wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx)
+				}
+
+				r.RegisterWorkflow(workflowWithActivity)
+				r.RegisterActivity(activity1)
+
+				inputs, _ := converter.DefaultConverter.To(42)
+				result, _ := converter.DefaultConverter.To(42)
+
+				task := &task.Workflow{
+					ID:               "taskID",
+					WorkflowInstance: core.NewWorkflowInstance("instanceID", "executionID"),
+					Metadata:         &core.WorkflowMetadata{},
+					LastSequenceID:   4,
+				}
+
+				hp.history = []*history.Event{
+					history.NewHistoryEvent(
+						1,
+						time.Now(),
+						history.EventType_WorkflowExecutionStarted,
+						&history.ExecutionStartedAttributes{
+							Name:   fn.Name(workflowWithActivity),
+							Inputs: []payload.Payload{},
+						},
+					),
+					history.NewHistoryEvent(
+						2,
+						time.Now(),
+						history.EventType_ActivityScheduled,
+						&history.ActivityScheduledAttributes{
+							Name:   "activity1",
+							Inputs: []payload.Payload{inputs},
+						},
+						history.ScheduleEventID(1),
+					),
+					history.NewHistoryEvent(
+						3,
+						time.Now(),
+						history.EventType_WorkflowTaskFailed,
+						&history.WorkflowTaskFailedAttributes{
+							Error: "task failed",
+						},
+					),
+					history.NewHistoryEvent(
+						4,
+						time.Now(),
+						history.EventType_ActivityCompleted,
+						&history.ActivityCompletedAttributes{
+							Result: result,
+						},
+						history.ScheduleEventID(1),
+					),
+				}
+
+				executionResult, err := e.ExecuteTask(context.Background(), task)
+				require.NoError(t, err)
+				require.Nil(t, executionResult.ReplayError)
+				require.NoError(t, e.workflow.err)
+				require.True(t, e.workflow.Completed())
+				require.Equal(t, int64(4), e.lastSequenceID-int64(len(executionResult.Executed)))
+			},
+		},
 		{
 			name: "Workflow with new events",
 			f: func(t *testing.T, r *Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
@@ -582,6 +718,49 @@ func Test_Executor(t *testing.T) {
 				require.True(t, e.workflow.Completed())
 			},
 		},
+		{
+			name: "Complete workflow with pending futures",
+			f: func(t *testing.T, r *Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflow := func(ctx wf.Context) error {
+					// Don't wait for the timer
+					wf.ScheduleTimer(ctx, time.Hour)
+
+					return nil
+				}
+
+				r.RegisterWorkflow(workflow)
+
+				result, err := e.ExecuteTask(context.Background(), startWorkflowTask("instanceID", workflow))
+				require.NoError(t, err)
+				require.NoError(t, e.workflow.err)
+				require.True(t, e.workflowState.HasPendingFutures())
+				require.True(t, result.Completed)
+				require.Len(t, result.TimerEvents, 1)
+				require.Equal(t, history.EventType_WorkflowExecutionFinished, result.Executed[len(result.Executed)-1].Type)
+			},
+		},
+		{
+			name: "Task with older history than executor",
+			f: func(t *testing.T, r *Registry, e *executor, i *core.WorkflowInstance, hp *testHistoryProvider) {
+				workflow := func(ctx wf.Context) error {
+					wf.Sleep(ctx, time.Millisecond)
+
+					return nil
+				}
+
+				r.RegisterWorkflow(workflow)
+
+				result, err := e.ExecuteTask(context.Background(), startWorkflowTask("instanceID", workflow))
+				require.NoError(t, err)
+
+				_, err = e.ExecuteTask(context.Background(), continueTask("instanceID", result.TimerEvents, 1))
+
+				var merr *HistoryMismatchError
+				require.ErrorAs(t, err, &merr)
+				require.Equal(t, int64(1), merr.TaskSequenceID)
+				require.Equal(t, result.Executed[len(result.Executed)-1].SequenceID, merr.ExecutorSequenceID)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -596,6 +775,158 @@ func Test_Executor(t *testing.T) {
 	}
 }
 
+type replayRecordingInterceptor struct {
+	replaying []bool
+}
+
+func (ri *replayRecordingInterceptor) ExecuteWorkflow(
+	ctx sync.Context, execution *interceptor.WorkflowExecution, next interceptor.ExecuteWorkflowFunc,
+) (payload.Payload, error) {
+	ri.replaying = append(ri.replaying, wf.Replaying(ctx))
+
+	return next(ctx, execution)
+}
+
+func Test_Executor_WorkflowInterceptors(t *testing.T) {
+	r := NewRegistry()
+
+	workflowHits := 0
+	workflowWithActivity := func(ctx sync.Context) (int, error) {
+		workflowHits++
+
+		return wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx)
+	}
+
+	r.RegisterWorkflow(workflowWithActivity)
+	r.RegisterActivity(activity1)
+
+	ic := &replayRecordingInterceptor{}
+	newInterceptedExecutor := func(hp WorkflowHistoryProvider) *executor {
+		e := NewExecutor(
+			logger.NewDefaultLogger(), trace.NewNoopTracerProvider().Tracer("test"), r, converter.DefaultConverter,
+			nil, []interceptor.WorkflowInterceptor{ic}, hp, core.NewWorkflowInstance("instanceID", "executionID"), clock.New())
+
+		return e.(*executor)
+	}
+
+	hp := &testHistoryProvider{}
+	e := newInterceptedExecutor(hp)
+
+	startTask := startWorkflowTask("instanceID", workflowWithActivity)
+	taskResult, err := e.ExecuteTask(context.Background(), startTask)
+	require.NoError(t, err)
+	require.False(t, e.workflow.Completed())
+	require.Equal(t, []bool{false}, ic.replaying)
+
+	// Execute the next task with a new executor, which has to replay the workflow from its history
+	hp.history = taskResult.Executed
+
+	result, _ := converter.DefaultConverter.To(42)
+	e = newInterceptedExecutor(hp)
+	_, err = e.ExecuteTask(context.Background(), continueTask("instanceID", []*history.Event{
+		history.NewPendingEvent(
+			time.Now(),
+			history.EventType_ActivityCompleted,
+			&history.ActivityCompletedAttributes{
+				Result: result,
+			},
+			history.ScheduleEventID(1),
+		),
+	}, int64(len(hp.history))))
+	require.NoError(t, err)
+	require.NoError(t, e.workflow.err)
+	require.True(t, e.workflow.Completed())
+	require.Equal(t, 2, workflowHits)
+
+	// The interceptor is called again for the replay
+	require.Equal(t, []bool{false, true}, ic.replaying)
+}
+
+func Test_Executor_Deadlock(t *testing.T) {
+	r := NewRegistry()
+
+	ch := make(chan struct{})
+	defer close(ch)
+
+	deadlockedWorkflow := func(ctx sync.Context) error {
+		<-ch
+
+		return nil
+	}
+
+	r.RegisterWorkflow(deadlockedWorkflow)
+
+	e := NewExecutor(
+		logger.NewDefaultLogger(), trace.NewNoopTracerProvider().Tracer("test"), r, converter.DefaultConverter,
+		nil, nil, &testHistoryProvider{}, core.NewWorkflowInstance("instanceID", "executionID"), clock.New(),
+		WithDeadlockTimeout(10*time.Millisecond))
+
+	_, err := e.ExecuteTask(context.Background(), startWorkflowTask("instanceID", deadlockedWorkflow))
+
+	var derr *sync.DeadlockError
+	require.ErrorAs(t, err, &derr)
+	require.Equal(t, 10*time.Millisecond, derr.Timeout)
+	require.Contains(t, derr.StackTrace(), "coroutine 0:")
+	require.Contains(t, derr.StackTrace(), "executor_test.go")
+
+	// Closing the executor must not wait for the deadlocked workflow
+	e.Close()
+}
+
+func Test_Executor_ReplayOnly(t *testing.T) {
+	sideEffects := 0
+	workflow := func(ctx sync.Context) error {
+		wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42).Get(ctx)
+
+		// Not recorded in the history, replaying must not execute it
+		wf.SideEffect(ctx, func(ctx sync.Context) int {
+			sideEffects++
+			return 42
+		}).Get(ctx)
+
+		wf.ScheduleTimer(ctx, time.Hour).Get(ctx)
+
+		return nil
+	}
+
+	r := NewRegistry()
+	require.NoError(t, r.RegisterWorkflow(workflow))
+
+	hp := &testHistoryProvider{}
+	e := newExecutor(r, core.NewWorkflowInstance("instanceID", "executionID"), hp)
+
+	result, err := e.ExecuteTask(context.Background(), startWorkflowTask("instanceID", workflow))
+	require.NoError(t, err)
+
+	activityResult, err := converter.DefaultConverter.To(42)
+	require.NoError(t, err)
+
+	hp.history = append(result.Executed, history.NewHistoryEvent(
+		int64(len(result.Executed)+1),
+		time.Now(),
+		history.EventType_ActivityCompleted,
+		&history.ActivityCompletedAttributes{
+			Result: activityResult,
+		},
+		history.ScheduleEventID(1),
+	))
+
+	e = newExecutor(r, core.NewWorkflowInstance("instanceID", "executionID"), hp, WithReplayOnly())
+	defer e.Close()
+
+	result, err = e.ExecuteTask(context.Background(), continueTask("instanceID", []*history.Event{}, int64(len(hp.history))))
+	require.NoError(t, err)
+	require.Nil(t, result.ReplayError)
+	require.Empty(t, result.Executed)
+	require.Equal(t, 0, sideEffects)
+
+	// The workflow stopped where the recorded history ends, waiting for the side effect
+	commands := e.workflowState.Commands()
+	require.Len(t, commands, 2)
+	require.IsType(t, &command.SideEffectCommand{}, commands[1])
+	require.Equal(t, command.CommandState_Pending, commands[1].State())
+}
+
 func startWorkflowTask(instanceID string, workflow interface{}, workflowArgs ...interface{}) *task.Workflow {
 	inputs, err := args.ArgsToInputs(converter.DefaultConverter, workflowArgs...)
 	if err != nil {
@@ -638,3 +969,228 @@ func pendingCommands(commands []command.Command) []command.Command {
 	}
 	return pending
 }
+
+func activity2(ctx context.Context, r int) (int, error) {
+	return r, nil
+}
+
+func subWorkflow1(ctx sync.Context) error {
+	return nil
+}
+
+func subWorkflow2(ctx sync.Context) error {
+	return nil
+}
+
+func Test_Executor_NonDeterminism(t *testing.T) {
+	tests := []struct {
+		name     string
+		wf       func(ctx sync.Context, version int) error
+		field    string
+		expected string
+		actual   string
+	}{
+		{
+			name: "ActivityName",
+			wf: func(ctx sync.Context, version int) error {
+				a := activity1
+				if version == 2 {
+					a = activity2
+				}
+
+				wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, a, 42)
+				return nil
+			},
+			field:    "name",
+			expected: `"activity1"`,
+			actual:   `"activity2"`,
+		},
+		{
+			name: "ActivityInputs",
+			wf: func(ctx sync.Context, version int) error {
+				wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 41+version)
+				return nil
+			},
+			field:    "inputs",
+			expected: `"42"`,
+			actual:   `"43"`,
+		},
+		{
+			name: "TimerDuration",
+			wf: func(ctx sync.Context, version int) error {
+				wf.ScheduleTimer(ctx, time.Duration(version)*time.Second)
+				return nil
+			},
+			field:    "duration",
+			expected: "1s",
+			actual:   "2s",
+		},
+		{
+			name: "SubWorkflowName",
+			wf: func(ctx sync.Context, version int) error {
+				swf := subWorkflow1
+				if version == 2 {
+					swf = subWorkflow2
+				}
+
+				wf.CreateSubWorkflowInstance[any](ctx, wf.DefaultSubWorkflowOptions, swf)
+				return nil
+			},
+			field:    "name",
+			expected: `"subWorkflow1"`,
+			actual:   `"subWorkflow2"`,
+		},
+		{
+			name: "SignalName",
+			wf: func(ctx sync.Context, version int) error {
+				name := "signal"
+				if version == 2 {
+					name = "other-signal"
+				}
+
+				wf.SignalWorkflow(ctx, "other-instance", name, 42)
+				return nil
+			},
+			field:    "signal name",
+			expected: `"signal"`,
+			actual:   `"other-signal"`,
+		},
+		{
+			name: "SideEffectOrder",
+			wf: func(ctx sync.Context, version int) error {
+				sideEffect := func(ctx sync.Context) int { return 42 }
+
+				if version == 1 {
+					wf.SideEffect(ctx, sideEffect)
+				}
+
+				wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, 42)
+
+				if version == 2 {
+					wf.SideEffect(ctx, sideEffect)
+				}
+
+				return nil
+			},
+			expected: "SideEffect command",
+			actual:   "ScheduleActivity command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := 1
+			workflow := func(ctx sync.Context) error {
+				if err := tt.wf(ctx, version); err != nil {
+					return err
+				}
+
+				// Block, so that only the scheduling of commands is recorded
+				wf.ScheduleTimer(ctx, time.Hour).Get(ctx)
+
+				return nil
+			}
+
+			r := NewRegistry()
+			require.NoError(t, r.RegisterWorkflow(workflow))
+
+			hp := &testHistoryProvider{}
+			e := newExecutor(r, core.NewWorkflowInstance("instanceID", "executionID"), hp)
+
+			result, err := e.ExecuteTask(context.Background(), startWorkflowTask("instanceID", workflow))
+			require.NoError(t, err)
+			require.Nil(t, result.ReplayError)
+
+			// Replay the recorded history with the changed workflow
+			hp.history = result.Executed
+			version = 2
+
+			e = newExecutor(r, core.NewWorkflowInstance("instanceID", "executionID"), hp, WithPayloadMatching(true))
+			result, err = e.ExecuteTask(context.Background(), continueTask("instanceID", []*history.Event{}, int64(len(hp.history))))
+			require.NoError(t, err)
+			require.NotNil(t, result.ReplayError)
+
+			var nderr *NonDeterminismError
+			require.ErrorAs(t, result.ReplayError, &nderr)
+			require.Equal(t, tt.field, nderr.Field)
+			require.Equal(t, tt.expected, nderr.Expected)
+			require.Equal(t, tt.actual, nderr.Actual)
+
+			// The workflow instance is failed with the error
+			require.Equal(t, history.EventType_WorkflowExecutionFinished, result.Executed[len(result.Executed)-1].Type)
+			a := result.Executed[len(result.Executed)-1].Attributes.(*history.ExecutionCompletedAttributes)
+			require.Equal(t, nderr.Error(), a.Error)
+		})
+	}
+}
+
+func Test_Executor_NonDeterminism_PayloadMatching(t *testing.T) {
+	tests := []struct {
+		name            string
+		converter       converter.Converter
+		payloadMatching bool
+		arg             func(version int) interface{}
+		mismatch        bool
+	}{
+		{
+			name:      "DisabledByDefault",
+			converter: converter.DefaultConverter,
+			arg:       func(version int) interface{} { return 41 + version },
+		},
+		{
+			name:            "Enabled",
+			converter:       converter.DefaultConverter,
+			payloadMatching: true,
+			arg:             func(version int) interface{} { return 41 + version },
+			mismatch:        true,
+		},
+		{
+			// Histories written before encodings were introduced contain JSON without an encoding header
+			name:            "RawJSONHistory",
+			converter:       converter.NewCompositeConverter(converter.NewJSONPayloadConverter()),
+			payloadMatching: true,
+			arg:             func(version int) interface{} { return map[string]int{"a": 1, "b": 2} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := 1
+			workflow := func(ctx sync.Context) error {
+				wf.ExecuteActivity[int](ctx, wf.DefaultActivityOptions, activity1, tt.arg(version))
+
+				// Block, so that only the scheduling of commands is recorded
+				wf.ScheduleTimer(ctx, time.Hour).Get(ctx)
+
+				return nil
+			}
+
+			r := NewRegistry()
+			require.NoError(t, r.RegisterWorkflow(workflow))
+
+			hp := &testHistoryProvider{}
+			e := newExecutor(r, core.NewWorkflowInstance("instanceID", "executionID"), hp)
+
+			result, err := e.ExecuteTask(context.Background(), startWorkflowTask("instanceID", workflow))
+			require.NoError(t, err)
+			require.Nil(t, result.ReplayError)
+
+			// Replay the recorded history with the changed workflow and the configured converter
+			hp.history = result.Executed
+			version = 2
+
+			e = newExecutorWithConverter(r, tt.converter, core.NewWorkflowInstance("instanceID", "executionID"), hp, WithPayloadMatching(tt.payloadMatching))
+			result, err = e.ExecuteTask(context.Background(), continueTask("instanceID", []*history.Event{}, int64(len(hp.history))))
+			require.NoError(t, err)
+
+			if !tt.mismatch {
+				require.Nil(t, result.ReplayError)
+				return
+			}
+
+			var nderr *NonDeterminismError
+			require.ErrorAs(t, result.ReplayError, &nderr)
+			require.Equal(t, "inputs", nderr.Field)
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/payload"
+)
+
+// NonDeterminismError is returned when the commands issued by the workflow code do not match the recorded history
+// of the workflow instance, usually because the workflow code has changed in an incompatible way.
+type NonDeterminismError struct {
+	// Event is the history event that does not match
+	Event *history.Event
+
+	// Field is the attribute of the event that does not match, empty if the command itself does not match
+	Field string
+
+	// Expected is the value recorded in the history, Actual the value issued by the workflow code
+	Expected string
+	Actual   string
+
+	// Location is the source location in the workflow code that issued the command, if known
+	Location string
+}
+
+func (e *NonDeterminismError) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "non-deterministic workflow: %v event %d", e.Event.Type, e.Event.SequenceID)
+
+	if e.Field != "" {
+		fmt.Fprintf(&b, " has different %s", e.Field)
+	}
+
+	fmt.Fprintf(&b, ", expected %s, got %s", e.Expected, e.Actual)
+
+	if e.Location != "" {
+		fmt.Fprintf(&b, " at %s", e.Location)
+	}
+
+	return b.String()
+}
+
+// commandForEvent returns the command the given event has been recorded for, or an error if the workflow has not
+// issued a command of the expected type for it
+func commandForEvent[T command.Command](e *executor, event *history.Event, commandType string) (T, error) {
+	var t T
+
+	c := e.workflowState.CommandByScheduleEventID(event.ScheduleEventID)
+	if c == nil {
+		return t, &NonDeterminismError{
+			Event:    event,
+			Expected: commandType + " command",
+			Actual:   "no command",
+		}
+	}
+
+	t, ok := c.(T)
+	if !ok {
+		return t, &NonDeterminismError{
+			Event:    event,
+			Expected: commandType + " command",
+			Actual:   c.Type() + " command",
+			Location: e.workflowState.CommandLocation(c.ID()),
+		}
+	}
+
+	return t, nil
+}
+
+// commandMismatch returns an error describing that the given attribute of a command does not match the recorded event
+func (e *executor) commandMismatch(event *history.Event, c command.Command, field string, expected, actual interface{}) error {
+	return &NonDeterminismError{
+		Event:    event,
+		Field:    field,
+		Expected: fmt.Sprintf("%v", expected),
+		Actual:   fmt.Sprintf("%v", actual),
+		Location: e.workflowState.CommandLocation(c.ID()),
+	}
+}
+
+// matchString ensures the given attribute of a command matches the recorded event
+func (e *executor) matchString(event *history.Event, c command.Command, field, expected, actual string) error {
+	if expected != actual {
+		return e.commandMismatch(event, c, field, fmt.Sprintf("%q", expected), fmt.Sprintf("%q", actual))
+	}
+
+	return nil
+}
+
+// matchPayloads ensures the given payloads of a command match the recorded event, if enabled with
+// WithPayloadMatching
+func (e *executor) matchPayloads(event *history.Event, c command.Command, field string, expected, actual []payload.Payload) error {
+	if !e.payloadMatching {
+		return nil
+	}
+
+	return e.comparePayloads(event, c, field, expected, actual)
+}
+
+// comparePayloads ensures the given payloads of a command match the recorded event. Payloads are compared after their
+// codecs have been undone, so that codecs that are not deterministic do not cause false positives, and JSON values
+// are compared regardless of their formatting and encoding header, so that histories written before encodings were
+// introduced still match.
+func (e *executor) comparePayloads(event *history.Event, c command.Command, field string, expected, actual []payload.Payload) error {
+	if len(expected) != len(actual) {
+		return e.commandMismatch(event, c, field, fmt.Sprintf("%d values", len(expected)), fmt.Sprintf("%d values", len(actual)))
+	}
+
+	cv := converter.GetConverter(e.workflowCtx)
+
+	for i := range expected {
+		if bytes.Equal(expected[i], actual[i]) {
+			continue
+		}
+
+		ep, err := converter.DecodePayload(cv, expected[i])
+		if err != nil {
+			return fmt.Errorf("decoding recorded %s: %w", field, err)
+		}
+
+		ap, err := converter.DecodePayload(cv, actual[i])
+		if err != nil {
+			return fmt.Errorf("decoding %s: %w", field, err)
+		}
+
+		ei, ed, err := converter.OpenEnvelope(ep)
+		if err != nil {
+			return fmt.Errorf("decoding recorded %s: %w", field, err)
+		}
+
+		ai, ad, err := converter.OpenEnvelope(ap)
+		if err != nil {
+			return fmt.Errorf("decoding %s: %w", field, err)
+		}
+
+		if ei != ai || !equalData(ei, ed, ad) {
+			f := field
+			if len(expected) > 1 {
+				f = fmt.Sprintf("%s[%d]", field, i)
+			}
+
+			return e.commandMismatch(event, c, f, describePayload(ed), describePayload(ad))
+		}
+	}
+
+	return nil
+}
+
+// equalData reports whether the given serialized values are equal. JSON values are compared semantically.
+func equalData(info converter.EncodingInfo, a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+
+	if info.Encoding != converter.EncodingJSON {
+		return false
+	}
+
+	av, err := decodeJSON(a)
+	if err != nil {
+		return false
+	}
+
+	bv, err := decodeJSON(b)
+	if err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(av, bv)
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// matchJSON ensures the given attribute of a command matches the recorded event, after both have been serialized
+func (e *executor) matchJSON(event *history.Event, c command.Command, field string, expected, actual interface{}) error {
+	eb, err := json.Marshal(expected)
+	if err != nil {
+		return err
+	}
+
+	ab, err := json.Marshal(actual)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(eb, ab) {
+		return e.commandMismatch(event, c, field, string(eb), string(ab))
+	}
+
+	return nil
+}
+
+// maxPayloadLength is the maximum length of payloads included in errors
+const maxPayloadLength = 64
+
+func describePayload(p payload.Payload) string {
+	if len(p) > maxPayloadLength {
+		return fmt.Sprintf("%q...", p[:maxPayloadLength])
+	}
+
+	return fmt.Sprintf("%q", p)
+}
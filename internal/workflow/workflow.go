@@ -4,80 +4,74 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
+	"github.com/cschleiden/go-workflows/interceptor"
 	"github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/payload"
 	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
 )
 
 type Workflow interface{}
 
 type workflow struct {
-	s      sync.Scheduler
-	fn     reflect.Value
-	result payload.Payload
-	err    error
+	s            sync.Scheduler
+	fn           reflect.Value
+	interceptors []interceptor.WorkflowInterceptor
+	result       payload.Payload
+	err          error
 }
 
-func NewWorkflow(workflowFn reflect.Value) *workflow {
-	s := sync.NewScheduler()
+func NewWorkflow(workflowFn reflect.Value, interceptors []interceptor.WorkflowInterceptor, deadlockTimeout time.Duration) *workflow {
+	s := sync.NewScheduler(sync.WithDeadlockDetection(deadlockTimeout))
 
 	return &workflow{
-		s:  s,
-		fn: workflowFn,
+		s:            s,
+		fn:           workflowFn,
+		interceptors: interceptors,
 	}
 }
 
-func (w *workflow) Execute(ctx sync.Context, inputs []payload.Payload) error {
+func (w *workflow) Execute(ctx sync.Context, name string, inputs []payload.Payload) error {
 	w.s.NewCoroutine(ctx, func(ctx sync.Context) error {
-		converter := converter.GetConverter(ctx)
-		args, addContext, err := args.InputsToArgs(converter, w.fn, inputs)
-		if err != nil {
-			return fmt.Errorf("converting workflow inputs: %w", err)
-		}
-
-		if !addContext {
-			return errors.New("workflow must accept context as first argument")
-		}
-
-		args[0] = reflect.ValueOf(ctx)
+		// Errors calling the workflow function, as opposed to errors returned by the workflow, abort the execution
+		var callErr error
 
-		// Call workflow function
-		r := w.fn.Call(args)
+		execute := func(ctx sync.Context, execution *interceptor.WorkflowExecution) (payload.Payload, error) {
+			result, workflowErr, err := w.call(ctx, execution.Inputs)
+			if err != nil {
+				callErr = err
+				return nil, err
+			}
 
-		// Process result
-		if len(r) < 1 || len(r) > 2 {
-			return errors.New("workflow has to return either (error) or (result, error)")
+			return result, workflowErr
 		}
 
-		var result payload.Payload
-
-		if len(r) > 1 {
-			var err error
-			result, err = converter.To(r[0].Interface())
-			if err != nil {
-				return fmt.Errorf("converting workflow result: %w", err)
-			}
-		} else {
-			result, err = converter.To(nil)
-			if err != nil {
-				return fmt.Errorf("converting workflow result: %w", err)
+		// Wrap the workflow execution with the configured interceptors, the first interceptor is the outermost one
+		for i := len(w.interceptors) - 1; i >= 0; i-- {
+			ic, next := w.interceptors[i], execute
+			execute = func(ctx sync.Context, execution *interceptor.WorkflowExecution) (payload.Payload, error) {
+				return ic.ExecuteWorkflow(ctx, execution, next)
 			}
 		}
 
-		errResult := r[len(r)-1]
-		if errResult.IsNil() {
-			w.result = result
-			return nil
+		result, err := execute(ctx, &interceptor.WorkflowExecution{
+			Name:   name,
+			Inputs: inputs,
+		})
+		if callErr != nil {
+			return callErr
 		}
 
-		errInterface, ok := errResult.Interface().(error)
-		if !ok {
-			return fmt.Errorf("activity error result does not satisfy error interface (%T): %v", errResult, errResult)
+		if err != nil {
+			w.err = err
+			return nil
 		}
 
-		w.err = errInterface
+		w.result = result
 
 		return nil
 	})
@@ -85,6 +79,62 @@ func (w *workflow) Execute(ctx sync.Context, inputs []payload.Payload) error {
 	return w.s.Execute()
 }
 
+// call calls the workflow function and returns its result and the error returned by the workflow. The last error is
+// set if the function could not be called or its result could not be converted.
+func (w *workflow) call(ctx sync.Context, inputs []payload.Payload) (payload.Payload, error, error) {
+	cv := converter.GetConverter(ctx)
+	args, addContext, err := args.InputsToArgs(cv, w.fn, inputs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("converting workflow inputs: %w", err)
+	}
+
+	if !addContext {
+		return nil, nil, errors.New("workflow must accept context as first argument")
+	}
+
+	args[0] = reflect.ValueOf(ctx)
+
+	// Call workflow function
+	r := w.fn.Call(args)
+
+	// Process result
+	if len(r) < 1 || len(r) > 2 {
+		return nil, nil, errors.New("workflow has to return either (error) or (result, error)")
+	}
+
+	var result payload.Payload
+
+	// The result of a sub-workflow is recorded in the history of its parent as well
+	resultConverter := cv
+	if wfState := workflowstate.WorkflowState(ctx); wfState.Instance().SubWorkflow() && !wfState.Replaying() {
+		resultConverter = converter.SharedWith(cv, core.NewWorkflowInstance(wfState.Instance().ParentInstanceID, ""))
+	}
+
+	if len(r) > 1 {
+		result, err = resultConverter.To(r[0].Interface())
+		if err != nil {
+			return nil, nil, fmt.Errorf("converting workflow result: %w", err)
+		}
+	} else {
+		result, err = resultConverter.To(nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("converting workflow result: %w", err)
+		}
+	}
+
+	errResult := r[len(r)-1]
+	if errResult.IsNil() {
+		return result, nil, nil
+	}
+
+	errInterface, ok := errResult.Interface().(error)
+	if !ok {
+		return nil, nil, fmt.Errorf("activity error result does not satisfy error interface (%T): %v", errResult, errResult)
+	}
+
+	return nil, errInterface, nil
+}
+
 func (w *workflow) Continue() error {
 	return w.s.Execute()
 }
@@ -103,6 +153,11 @@ func (w *workflow) Error() error {
 	return w.err
 }
 
+// StackTrace returns the stack traces of the running coroutines of the workflow
+func (w *workflow) StackTrace() string {
+	return w.s.StackTrace()
+}
+
 func (w *workflow) Close() {
 	// End coroutine execution to prevent goroutine leaks
 	w.s.Exit()
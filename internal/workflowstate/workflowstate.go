@@ -2,6 +2,8 @@ package workflowstate
 
 import (
 	"fmt"
+	"runtime"
+	"strings"
 	"time"
 
 	"github.com/benbjohnson/clock"
@@ -50,6 +52,7 @@ type WfState struct {
 	instance        *core.WorkflowInstance
 	scheduleEventID int64
 	commands        []command.Command
+	locations       map[int64]string
 	pendingFutures  map[int64]DecodingSettable
 	replaying       bool
 
@@ -60,12 +63,24 @@ type WfState struct {
 
 	clock clock.Clock
 	time  time.Time
+
+	started       *Started
+	historyLength int64
+}
+
+// Started holds information about the start of the workflow execution
+type Started struct {
+	Name      string
+	StartedAt time.Time
+	Attempt   int
+	Metadata  *core.WorkflowMetadata
 }
 
 func NewWorkflowState(instance *core.WorkflowInstance, logger log.Logger, clock clock.Clock) *WfState {
 	state := &WfState{
 		instance:        instance,
 		commands:        []command.Command{},
+		locations:       map[int64]string{},
 		scheduleEventID: 1,
 		pendingFutures:  map[int64]DecodingSettable{},
 
@@ -119,6 +134,50 @@ func (wf *WfState) Commands() []command.Command {
 
 func (wf *WfState) AddCommand(cmd command.Command) {
 	wf.commands = append(wf.commands, cmd)
+
+	if location := callerLocation(); location != "" {
+		wf.locations[cmd.ID()] = location
+	}
+}
+
+// CommandLocation returns the source location in the workflow code that added the command with the given id, if known
+func (wf *WfState) CommandLocation(id int64) string {
+	return wf.locations[id]
+}
+
+// libraryPrefixes are the packages that add commands on behalf of workflow code
+var libraryPrefixes = []string{
+	"github.com/cschleiden/go-workflows/internal/",
+	"github.com/cschleiden/go-workflows/workflow.",
+	"reflect.",
+	"runtime.",
+}
+
+// callerLocation returns the file and line of the first caller outside of this library
+func callerLocation() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+
+		library := false
+		for _, prefix := range libraryPrefixes {
+			if strings.HasPrefix(frame.Function, prefix) {
+				library = true
+				break
+			}
+		}
+
+		if !library && frame.Function != "" {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+
+		if !more {
+			return ""
+		}
+	}
 }
 
 func (wf *WfState) CommandByScheduleEventID(scheduleEventID int64) command.Command {
@@ -147,6 +206,25 @@ func (wf *WfState) Time() time.Time {
 	return wf.time
 }
 
+func (wf *WfState) SetStarted(started *Started) {
+	wf.started = started
+}
+
+// Started returns information about the start of the workflow execution, nil if the workflow has not been started
+func (wf *WfState) Started() *Started {
+	return wf.started
+}
+
+func (wf *WfState) SetHistoryLength(historyLength int64) {
+	wf.historyLength = historyLength
+}
+
+// HistoryLength returns the number of events in the workflow history up to and including the event currently
+// being executed.
+func (wf *WfState) HistoryLength() int64 {
+	return wf.historyLength
+}
+
 func (wf *WfState) Instance() *core.WorkflowInstance {
 	return wf.instance
 }
@@ -0,0 +1,136 @@
+package replayer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// contextEvents is the number of recorded events shown before the first non-deterministic event
+const contextEvents = 3
+
+// NonDeterminismError is returned when replaying a history with the current workflow code does not produce the
+// recorded history.
+type NonDeterminismError struct {
+	Instance *workflow.Instance
+
+	// Event is the first recorded event that does not match the replayed workflow. It is nil if the replayed
+	// workflow issued commands after the end of the recorded history.
+	Event *history.Event
+
+	// Reason describes how the replayed workflow differs from the recorded history
+	Reason string
+
+	// History is the replayed history
+	History []*history.Event
+
+	// Actual describes the command the replayed workflow issued for Event, if known
+	Actual string
+
+	// Replayed are the events the replayed workflow issued after the recorded history, if any
+	Replayed []*history.Event
+}
+
+func (e *NonDeterminismError) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "replaying workflow instance %s: %s\n", e.Instance.InstanceID, e.Reason)
+
+	b.WriteString(e.Diff())
+
+	return b.String()
+}
+
+// Diff returns a diff between the recorded history and the replayed workflow, starting a few events before the
+// first non-deterministic event.
+func (e *NonDeterminismError) Diff() string {
+	idx := len(e.History)
+	for i, event := range e.History {
+		if event == e.Event {
+			idx = i
+			break
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("--- history\n+++ replay\n")
+
+	start := idx - contextEvents
+	if start < 0 {
+		start = 0
+	}
+
+	for _, event := range e.History[start:idx] {
+		fmt.Fprintf(&b, "  %s\n", describeEvent(event))
+	}
+
+	if e.Event != nil {
+		fmt.Fprintf(&b, "- %s\n", describeEvent(e.Event))
+	}
+
+	if e.Actual != "" {
+		fmt.Fprintf(&b, "+ %s\n", e.Actual)
+	} else if e.Replayed == nil {
+		fmt.Fprintf(&b, "+ %s\n", e.Reason)
+	}
+
+	for _, event := range e.Replayed {
+		fmt.Fprintf(&b, "+ %s\n", describeEvent(event))
+	}
+
+	return b.String()
+}
+
+// describeEvent returns a single line description of the given event with the attributes that identify it
+func describeEvent(event *history.Event) string {
+	parts := []string{fmt.Sprintf("%4d %v", event.SequenceID, event.Type)}
+
+	if event.ScheduleEventID != 0 {
+		parts = append(parts, fmt.Sprintf("schedule_event_id=%d", event.ScheduleEventID))
+	}
+
+	switch a := event.Attributes.(type) {
+	case *history.ExecutionStartedAttributes:
+		parts = append(parts, "name="+a.Name)
+
+	case *history.ExecutionCompletedAttributes:
+		if a.Error != "" {
+			parts = append(parts, fmt.Sprintf("error=%q", a.Error))
+		}
+
+	case *history.WorkflowTaskFailedAttributes:
+		parts = append(parts, fmt.Sprintf("error=%q", a.Error))
+
+	case *history.ActivityScheduledAttributes:
+		parts = append(parts, "name="+a.Name)
+
+	case *history.ActivityFailedAttributes:
+		parts = append(parts, fmt.Sprintf("reason=%q", a.Reason))
+
+	case *history.TimerScheduledAttributes:
+		parts = append(parts, "at="+a.At.Format(time.RFC3339Nano))
+
+	case *history.SubWorkflowScheduledAttributes:
+		parts = append(parts, "name="+a.Name)
+		if a.SubWorkflowInstance != nil {
+			parts = append(parts, "instance="+a.SubWorkflowInstance.InstanceID)
+		}
+
+	case *history.SubWorkflowFailedAttributes:
+		parts = append(parts, fmt.Sprintf("error=%q", a.Error))
+
+	case *history.SignalReceivedAttributes:
+		parts = append(parts, "name="+a.Name)
+
+	case *history.SignalExternalWorkflowScheduledAttributes:
+		parts = append(parts, "instance="+a.InstanceID, "name="+a.Name)
+
+	case *history.RequestCancelExternalWorkflowScheduledAttributes:
+		parts = append(parts, "instance="+a.InstanceID)
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,179 @@
+package replayer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/diag"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/workflow"
+)
+
+// exportedInstance mirrors diag.WorkflowInstanceInfo, with the attributes of events left to be decoded by their type
+type exportedInstance struct {
+	*diag.WorkflowInstanceRef
+
+	History []*exportedEvent `json:"history,omitempty"`
+}
+
+type exportedEvent struct {
+	ID              string          `json:"id,omitempty"`
+	SequenceID      int64           `json:"sequence_id,omitempty"`
+	Type            string          `json:"type,omitempty"`
+	Timestamp       time.Time       `json:"timestamp,omitempty"`
+	ScheduleEventID int64           `json:"schedule_event_id,omitempty"`
+	Attributes      json.RawMessage `json:"attributes,omitempty"`
+	VisibleAt       *time.Time      `json:"visible_at,omitempty"`
+}
+
+// LoadHistoryFromJSON reads a workflow instance and its history as returned by the diag API for a single instance
+func LoadHistoryFromJSON(r io.Reader) (*workflow.Instance, []*history.Event, error) {
+	var ei exportedInstance
+	if err := json.NewDecoder(r).Decode(&ei); err != nil {
+		return nil, nil, fmt.Errorf("decoding exported workflow instance: %w", err)
+	}
+
+	if ei.WorkflowInstanceRef == nil || ei.Instance == nil {
+		return nil, nil, fmt.Errorf("exported workflow instance is missing the instance")
+	}
+
+	h := make([]*history.Event, 0, len(ei.History))
+	for _, e := range ei.History {
+		eventType, ok := history.ParseEventType(e.Type)
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown event type %q of event %d", e.Type, e.SequenceID)
+		}
+
+		attributes, err := history.DeserializeAttributes(eventType, e.Attributes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("decoding attributes of event %d: %w", e.SequenceID, err)
+		}
+
+		h = append(h, &history.Event{
+			ID:              e.ID,
+			SequenceID:      e.SequenceID,
+			Type:            eventType,
+			Timestamp:       e.Timestamp,
+			ScheduleEventID: e.ScheduleEventID,
+			Attributes:      attributes,
+			VisibleAt:       e.VisibleAt,
+		})
+	}
+
+	return ei.Instance, h, nil
+}
+
+// ReplayHistoryFromJSON replays a history exported from the diag API. The diag API decodes all payloads, so by
+// default they are read with the default converter.
+func (r *Replayer) ReplayHistoryFromJSON(ctx context.Context, rd io.Reader) error {
+	instance, h, err := LoadHistoryFromJSON(rd)
+	if err != nil {
+		return err
+	}
+
+	return r.ReplayHistory(ctx, instance, h)
+}
+
+// ReplayHistoryFromJSONFile replays a history exported from the diag API and stored in the given file
+func (r *Replayer) ReplayHistoryFromJSONFile(ctx context.Context, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return r.ReplayHistoryFromJSON(ctx, f)
+}
+
+// ReplayWorkflowInstance replays the history of the given workflow instance stored in the backend
+func (r *Replayer) ReplayWorkflowInstance(ctx context.Context, b backend.Backend, instance *workflow.Instance) error {
+	h, err := b.GetWorkflowInstanceHistory(ctx, instance, nil)
+	if err != nil {
+		return fmt.Errorf("getting history of workflow instance %s: %w", instance.InstanceID, err)
+	}
+
+	return r.replay(ctx, r.converterFor(b), instance, h)
+}
+
+// BatchResult is the result of replaying multiple workflow instances
+type BatchResult struct {
+	// Replayed is the number of workflow instances that have been replayed successfully
+	Replayed int
+
+	// Skipped are the workflow instances that have not been replayed because their workflow is not registered
+	Skipped []*workflow.Instance
+
+	// Failures are the workflow instances that could not be replayed
+	Failures []*Failure
+}
+
+type Failure struct {
+	Instance *workflow.Instance
+
+	// Err is a *NonDeterminismError if the workflow code is not deterministic
+	Err error
+}
+
+// ReplayWorkflowInstances replays the histories of all workflow instances matching the given options. When options
+// is nil, all active workflow instances are replayed. Instances of workflows that are not registered are skipped.
+//
+// Instances that fail to replay are reported in the result, an error is only returned if the backend cannot be read.
+func (r *Replayer) ReplayWorkflowInstances(ctx context.Context, b backend.Backend, options *backend.ListWorkflowInstancesOptions) (*BatchResult, error) {
+	if options == nil {
+		state := backend.WorkflowInstanceStateActive
+		options = &backend.ListWorkflowInstancesOptions{
+			State: &state,
+		}
+	}
+
+	// Don't modify the caller's options while paging
+	opts := *options
+
+	result := &BatchResult{}
+
+	for {
+		page, err := b.ListWorkflowInstances(ctx, &opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing workflow instances: %w", err)
+		}
+
+		for _, info := range page.Instances {
+			if _, err := r.registry.GetWorkflow(info.WorkflowName); err != nil {
+				result.Skipped = append(result.Skipped, info.Instance)
+				continue
+			}
+
+			h, err := b.GetWorkflowInstanceHistory(ctx, info.Instance, nil)
+			if err != nil {
+				return nil, fmt.Errorf("getting history of workflow instance %s: %w", info.Instance.InstanceID, err)
+			}
+
+			if err := r.replay(ctx, r.converterFor(b), info.Instance, h); err != nil {
+				result.Failures = append(result.Failures, &Failure{Instance: info.Instance, Err: err})
+				continue
+			}
+
+			result.Replayed++
+		}
+
+		if page.NextCursor == "" {
+			return result, nil
+		}
+
+		opts.Cursor = page.NextCursor
+	}
+}
+
+func (r *Replayer) converterFor(b backend.Backend) converter.Converter {
+	if r.options.Converter != nil {
+		return r.options.Converter
+	}
+
+	return b.Converter()
+}
@@ -0,0 +1,238 @@
+package replayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/benbjohnson/clock"
+	"github.com/cschleiden/go-workflows/interceptor"
+	"github.com/cschleiden/go-workflows/internal/contextpropagation"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/internal/logger"
+	"github.com/cschleiden/go-workflows/internal/task"
+	internal "github.com/cschleiden/go-workflows/internal/workflow"
+	"github.com/cschleiden/go-workflows/log"
+	"github.com/cschleiden/go-workflows/workflow"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type options struct {
+	Logger               log.Logger
+	Converter            converter.Converter
+	ContextPropagators   []contextpropagation.ContextPropagator
+	WorkflowInterceptors []interceptor.WorkflowInterceptor
+	MatchPayloads        bool
+}
+
+type Option func(o *options)
+
+// WithLogger sets the logger used by the replayed workflow executions
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) {
+		o.Logger = logger
+	}
+}
+
+// WithConverter sets the converter used to decode payloads in replayed histories. Histories loaded from a backend are
+// decoded with the converter of the backend by default, histories exported from the diag API with the default
+// converter.
+func WithConverter(converter converter.Converter) Option {
+	return func(o *options) {
+		o.Converter = converter
+	}
+}
+
+// WithContextPropagator adds a context propagator to the replayed workflow executions
+func WithContextPropagator(prop workflow.ContextPropagator) Option {
+	return func(o *options) {
+		o.ContextPropagators = append(o.ContextPropagators, prop)
+	}
+}
+
+// WithWorkflowInterceptors wraps the replayed workflow executions with the given interceptors
+func WithWorkflowInterceptors(interceptors ...interceptor.WorkflowInterceptor) Option {
+	return func(o *options) {
+		o.WorkflowInterceptors = append(o.WorkflowInterceptors, interceptors...)
+	}
+}
+
+// WithPayloadMatching makes the replayer also compare the inputs of activities and sub-workflows and the arguments of
+// signals issued by the workflow code with the recorded history, see worker.Options.MatchReplayedPayloads
+func WithPayloadMatching() Option {
+	return func(o *options) {
+		o.MatchPayloads = true
+	}
+}
+
+// Replayer replays recorded workflow histories against the registered workflows, to verify that changes to the
+// workflow code are deterministic before they are deployed.
+type Replayer struct {
+	options  *options
+	registry *internal.Registry
+	tracer   trace.Tracer
+}
+
+func New(opts ...Option) *Replayer {
+	options := &options{
+		Logger: logger.NewDefaultLogger(),
+	}
+
+	for _, o := range opts {
+		o(options)
+	}
+
+	return &Replayer{
+		options:  options,
+		registry: internal.NewRegistry(),
+		tracer:   trace.NewNoopTracerProvider().Tracer("replayer"),
+	}
+}
+
+// RegisterWorkflow registers a workflow to replay histories of
+func (r *Replayer) RegisterWorkflow(w workflow.Workflow) error {
+	return r.registry.RegisterWorkflow(w)
+}
+
+// ReplayHistory replays the given history of a workflow instance. It returns a *NonDeterminismError if the current
+// workflow code does not produce the recorded history.
+func (r *Replayer) ReplayHistory(ctx context.Context, instance *workflow.Instance, h []*history.Event) error {
+	cv := r.options.Converter
+	if cv == nil {
+		cv = converter.DefaultConverter
+	}
+
+	return r.replay(ctx, cv, instance, h)
+}
+
+func (r *Replayer) replay(ctx context.Context, cv converter.Converter, instance *workflow.Instance, h []*history.Event) (err error) {
+	if len(h) == 0 {
+		return errors.New("history is empty")
+	}
+
+	// Workflow code or commands panic on some unexpected histories, report them like any other replay failure
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("replaying workflow instance %s: panic: %v", instance.InstanceID, p)
+		}
+	}()
+
+	e := internal.NewExecutor(
+		r.options.Logger, r.tracer, r.registry, cv, r.options.ContextPropagators, r.options.WorkflowInterceptors,
+		&historyProvider{h}, instance, clock.New(), internal.WithPayloadMatching(r.options.MatchPayloads))
+	defer e.Close()
+
+	result, err := e.ExecuteTask(ctx, &task.Workflow{
+		ID:                    "replay",
+		WorkflowInstance:      instance,
+		WorkflowInstanceState: core.WorkflowInstanceStateActive,
+		Metadata:              &core.WorkflowMetadata{},
+		LastSequenceID:        h[len(h)-1].SequenceID,
+	})
+	if err != nil {
+		return fmt.Errorf("replaying workflow instance %s: %w", instance.InstanceID, err)
+	}
+
+	if result.ReplayError != nil {
+		nderr := &NonDeterminismError{
+			Instance: instance,
+			Event:    result.ReplayError.Event,
+			Reason:   result.ReplayError.Err.Error(),
+			History:  h,
+		}
+
+		var cerr *internal.NonDeterminismError
+		if errors.As(result.ReplayError.Err, &cerr) {
+			nderr.Actual = cerr.Actual
+			if cerr.Field != "" {
+				nderr.Actual = cerr.Field + " " + cerr.Actual
+			}
+
+			if cerr.Location != "" {
+				nderr.Actual += " at " + cerr.Location
+			}
+		}
+
+		return nderr
+	}
+
+	// The replayed workflow has processed all recorded events. It must not issue any commands that are not part of
+	// the history, except for completing the workflow if the recorded execution completed, too.
+	var expected, actual []*history.Event
+
+	if last := h[len(h)-1]; last.Type == history.EventType_WorkflowExecutionFinished {
+		expected = append(expected, last)
+	}
+
+	for _, event := range result.Executed {
+		if event.Type != history.EventType_WorkflowTaskStarted {
+			actual = append(actual, event)
+		}
+	}
+
+	if reason := compareTail(expected, actual); reason != "" {
+		var event *history.Event
+		if len(expected) > 0 {
+			event = expected[0]
+		}
+
+		return &NonDeterminismError{
+			Instance: instance,
+			Event:    event,
+			Reason:   reason,
+			History:  h,
+			Replayed: actual,
+		}
+	}
+
+	return nil
+}
+
+// compareTail compares the events produced after replaying the full history with the expected events, and returns
+// a description of the difference, if any
+func compareTail(expected, actual []*history.Event) string {
+	for _, event := range actual {
+		if event.Type != history.EventType_WorkflowExecutionFinished {
+			return fmt.Sprintf("workflow issued %v which is not part of the history", event.Type)
+		}
+	}
+
+	switch {
+	case len(expected) == 0 && len(actual) == 0:
+		return ""
+
+	case len(expected) == 0:
+		return "workflow completed, but the recorded execution did not complete"
+
+	case len(actual) == 0:
+		return "workflow did not complete, but the recorded execution completed"
+	}
+
+	ea := expected[0].Attributes.(*history.ExecutionCompletedAttributes)
+	aa := actual[0].Attributes.(*history.ExecutionCompletedAttributes)
+	if ea.Error != aa.Error {
+		return fmt.Sprintf("workflow completed with error %q, but the recorded execution completed with error %q", aa.Error, ea.Error)
+	}
+
+	return ""
+}
+
+type historyProvider struct {
+	history []*history.Event
+}
+
+func (hp *historyProvider) GetWorkflowInstanceHistory(ctx context.Context, instance *core.WorkflowInstance, lastSequenceID *int64) ([]*history.Event, error) {
+	if lastSequenceID == nil {
+		return hp.history, nil
+	}
+
+	for i, event := range hp.history {
+		if event.SequenceID > *lastSequenceID {
+			return hp.history[i:], nil
+		}
+	}
+
+	return nil, nil
+}
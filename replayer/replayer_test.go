@@ -0,0 +1,191 @@
+package replayer
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/sqlite"
+	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/diag"
+	"github.com/cschleiden/go-workflows/internal/history"
+	"github.com/cschleiden/go-workflows/worker"
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// workflowVersion selects the code path of Workflow1, to simulate changes to the workflow code between recording and
+// replaying a history
+var workflowVersion = 1
+
+func Workflow1(ctx workflow.Context, msg string) (string, error) {
+	switch workflowVersion {
+	case 2:
+		// Schedules a different activity
+		return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, Activity2, msg).Get(ctx)
+
+	case 3:
+		// Schedules an additional activity
+		r, err := workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, Activity1, msg).Get(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, Activity2, r).Get(ctx)
+	}
+
+	return workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, Activity1, msg).Get(ctx)
+}
+
+func Workflow2(ctx workflow.Context, msg string) error {
+	return nil
+}
+
+func Activity1(ctx context.Context, msg string) (string, error) {
+	return msg + " world", nil
+}
+
+func Activity2(ctx context.Context, msg string) (string, error) {
+	return msg + "!", nil
+}
+
+// record runs the given workflows to completion and returns the backend containing their histories
+func record(t *testing.T, workflows ...workflow.Workflow) (diag.Backend, []*workflow.Instance) {
+	workflowVersion = 1
+	t.Cleanup(func() { workflowVersion = 1 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Use a file based database, the in-memory database doesn't survive stopping the worker
+	b := sqlite.NewSqliteBackend(filepath.Join(t.TempDir(), "replayer.sqlite"))
+	c := client.New(b)
+
+	w := worker.New(b, nil)
+	for _, wf := range workflows {
+		require.NoError(t, w.RegisterWorkflow(wf))
+	}
+	require.NoError(t, w.RegisterActivity(Activity1))
+	require.NoError(t, w.Start(ctx))
+
+	instances := make([]*workflow.Instance, 0, len(workflows))
+	for _, wf := range workflows {
+		instance, err := c.CreateWorkflowInstance(ctx, client.WorkflowInstanceOptions{
+			InstanceID: uuid.NewString(),
+		}, wf, "hello")
+		require.NoError(t, err)
+		require.NoError(t, c.WaitForWorkflowInstance(ctx, instance, time.Second*10))
+
+		instances = append(instances, instance)
+	}
+
+	cancel()
+	require.NoError(t, w.WaitForCompletion())
+
+	return b, instances
+}
+
+func Test_Replayer_Deterministic(t *testing.T) {
+	b, instances := record(t, Workflow1)
+
+	r := New()
+	require.NoError(t, r.RegisterWorkflow(Workflow1))
+
+	require.NoError(t, r.ReplayWorkflowInstance(context.Background(), b, instances[0]))
+}
+
+func Test_Replayer_DifferentActivity(t *testing.T) {
+	b, instances := record(t, Workflow1)
+
+	r := New()
+	require.NoError(t, r.RegisterWorkflow(Workflow1))
+
+	workflowVersion = 2
+	err := r.ReplayWorkflowInstance(context.Background(), b, instances[0])
+
+	var nderr *NonDeterminismError
+	require.True(t, errors.As(err, &nderr))
+	require.Equal(t, history.EventType_ActivityScheduled, nderr.Event.Type)
+	require.Contains(t, nderr.Diff(), "- ")
+	require.Contains(t, nderr.Diff(), "ActivityScheduled schedule_event_id=1 name=Activity1")
+	require.Contains(t, err.Error(), `ActivityScheduled event 3 has different name, expected "Activity1", got "Activity2"`)
+	require.Contains(t, nderr.Diff(), `+ name "Activity2" at `)
+	require.Contains(t, nderr.Actual, "replayer_test.go:")
+}
+
+func Test_Replayer_AdditionalActivity(t *testing.T) {
+	b, instances := record(t, Workflow1)
+
+	r := New()
+	require.NoError(t, r.RegisterWorkflow(Workflow1))
+
+	workflowVersion = 3
+	err := r.ReplayWorkflowInstance(context.Background(), b, instances[0])
+
+	var nderr *NonDeterminismError
+	require.True(t, errors.As(err, &nderr))
+	require.Equal(t, history.EventType_WorkflowExecutionFinished, nderr.Event.Type)
+	require.Equal(t, "workflow issued ActivityScheduled which is not part of the history", nderr.Reason)
+	require.Len(t, nderr.Replayed, 1)
+	require.Contains(t, nderr.Diff(), "+ ")
+}
+
+func Test_Replayer_FromJSON(t *testing.T) {
+	b, instances := record(t, Workflow1)
+
+	srv := httptest.NewServer(diag.NewServeMux(b))
+	defer srv.Close()
+
+	rsp, err := srv.Client().Get(srv.URL + "/api/" + instances[0].InstanceID)
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+
+	instance, h, err := LoadHistoryFromJSON(rsp.Body)
+	require.NoError(t, err)
+	require.Equal(t, instances[0].InstanceID, instance.InstanceID)
+
+	recorded, err := b.GetWorkflowInstanceHistory(context.Background(), instances[0], nil)
+	require.NoError(t, err)
+	require.Len(t, h, len(recorded))
+	for i := range recorded {
+		require.Equal(t, recorded[i].Type, h[i].Type)
+		require.Equal(t, recorded[i].SequenceID, h[i].SequenceID)
+	}
+
+	r := New()
+	require.NoError(t, r.RegisterWorkflow(Workflow1))
+
+	require.NoError(t, r.ReplayHistory(context.Background(), instance, h))
+
+	workflowVersion = 2
+	require.Error(t, r.ReplayHistory(context.Background(), instance, h))
+}
+
+func Test_Replayer_ReplayWorkflowInstances(t *testing.T) {
+	b, _ := record(t, Workflow1, Workflow1, Workflow2)
+
+	r := New()
+	require.NoError(t, r.RegisterWorkflow(Workflow1))
+
+	// All recorded instances have finished
+	result, err := r.ReplayWorkflowInstances(context.Background(), b, nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, result.Replayed)
+
+	result, err = r.ReplayWorkflowInstances(context.Background(), b, &backend.ListWorkflowInstancesOptions{PageSize: 1})
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Replayed)
+	require.Len(t, result.Skipped, 1)
+	require.Empty(t, result.Failures)
+
+	workflowVersion = 2
+	result, err = r.ReplayWorkflowInstances(context.Background(), b, &backend.ListWorkflowInstancesOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 0, result.Replayed)
+	require.Len(t, result.Failures, 2)
+}
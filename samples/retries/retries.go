@@ -71,7 +71,7 @@ func Workflow1(ctx workflow.Context, msg string) error {
 			FirstRetryInterval: time.Second * 3,
 			BackoffCoefficient: 1,
 		},
-	}, WorkflowWithFailures, "Hello world"+uuid.NewString()).Get(ctx)
+	}, WorkflowWithFailures, msg).Get(ctx)
 	if err != nil {
 		return fmt.Errorf("starting subworkflow: %w", err)
 	}
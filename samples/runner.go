@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/backend/bolt"
 	"github.com/cschleiden/go-workflows/backend/mysql"
+	"github.com/cschleiden/go-workflows/backend/postgres"
 	"github.com/cschleiden/go-workflows/backend/redis"
 	"github.com/cschleiden/go-workflows/backend/sqlite"
 	"github.com/cschleiden/go-workflows/diag"
@@ -16,7 +18,7 @@ import (
 )
 
 func GetBackend(name string, opt ...backend.BackendOption) backend.Backend {
-	b := flag.String("backend", "redis", "backend to use: memory, sqlite, mysql, redis")
+	b := flag.String("backend", "redis", "backend to use: memory, sqlite, bolt, mysql, postgres, redis")
 	flag.Parse()
 
 	switch *b {
@@ -26,9 +28,20 @@ func GetBackend(name string, opt ...backend.BackendOption) backend.Backend {
 	case "sqlite":
 		return sqlite.NewSqliteBackend(name+".sqlite", opt...)
 
+	case "bolt":
+		b, err := bolt.NewBoltBackend(name+".db", bolt.WithBackendOptions(opt...))
+		if err != nil {
+			panic(err)
+		}
+
+		return b
+
 	case "mysql":
 		return mysql.NewMysqlBackend("localhost", 3306, "root", "root", name, opt...)
 
+	case "postgres":
+		return postgres.NewPostgresBackend("localhost", 5432, "postgres", "root", "postgres", postgres.WithBackendOptions(opt...))
+
 	case "redis":
 		rclient := redisv9.NewUniversalClient(&redisv9.UniversalOptions{
 			Addrs:        []string{"localhost:6379"},
@@ -0,0 +1,150 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Query selects workflow instances by their search attributes
+type Query interface {
+	// Validate returns an error if the query cannot be executed
+	Validate() error
+}
+
+type Op int
+
+const (
+	OpEq Op = iota
+	OpGt
+	OpGte
+	OpLt
+	OpLte
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpEq:
+		return "="
+	case OpGt:
+		return ">"
+	case OpGte:
+		return ">="
+	case OpLt:
+		return "<"
+	case OpLte:
+		return "<="
+	}
+
+	return "?"
+}
+
+// Condition matches instances with a search attribute Key of the same type as Value, for which
+// `attribute Op Value` holds.
+type Condition struct {
+	Key   string
+	Op    Op
+	Value Value
+
+	err error
+}
+
+func (c *Condition) Validate() error {
+	if c.err != nil {
+		return c.err
+	}
+
+	if c.Key == "" {
+		return errors.New("search attribute name cannot be empty")
+	}
+
+	if c.Op != OpEq && c.Value.Type == TypeBool {
+		return fmt.Errorf("search attribute %s: range queries are not supported for bool values", c.Key)
+	}
+
+	return nil
+}
+
+// AndQuery matches instances matched by all of its queries
+type AndQuery struct {
+	Queries []Query
+}
+
+func (q *AndQuery) Validate() error {
+	return validateAll(q.Queries)
+}
+
+// OrQuery matches instances matched by any of its queries
+type OrQuery struct {
+	Queries []Query
+}
+
+func (q *OrQuery) Validate() error {
+	return validateAll(q.Queries)
+}
+
+func validateAll(queries []Query) error {
+	if len(queries) == 0 {
+		return errors.New("compound query requires at least one query")
+	}
+
+	for _, q := range queries {
+		if q == nil {
+			return errors.New("query cannot be nil")
+		}
+
+		if err := q.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newCondition(key string, op Op, value any) *Condition {
+	v, err := NewValue(value)
+	if err != nil {
+		err = fmt.Errorf("search attribute %s: %w", key, err)
+	}
+
+	return &Condition{Key: key, Op: op, Value: v, err: err}
+}
+
+// Eq matches instances where the search attribute key equals value
+func Eq(key string, value any) Query {
+	return newCondition(key, OpEq, value)
+}
+
+// Gt matches instances where the search attribute key is greater than value
+func Gt(key string, value any) Query {
+	return newCondition(key, OpGt, value)
+}
+
+// Gte matches instances where the search attribute key is greater than or equal to value
+func Gte(key string, value any) Query {
+	return newCondition(key, OpGte, value)
+}
+
+// Lt matches instances where the search attribute key is less than value
+func Lt(key string, value any) Query {
+	return newCondition(key, OpLt, value)
+}
+
+// Lte matches instances where the search attribute key is less than or equal to value
+func Lte(key string, value any) Query {
+	return newCondition(key, OpLte, value)
+}
+
+// Between matches instances where the search attribute key is in the inclusive range [from, to]
+func Between(key string, from, to any) Query {
+	return And(Gte(key, from), Lte(key, to))
+}
+
+// And matches instances matched by all of the given queries
+func And(queries ...Query) Query {
+	return &AndQuery{Queries: queries}
+}
+
+// Or matches instances matched by any of the given queries
+func Or(queries ...Query) Query {
+	return &OrQuery{Queries: queries}
+}
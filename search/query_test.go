@@ -0,0 +1,58 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewValue(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		value any
+		want  Value
+	}{
+		{"string", "test", Value{Type: TypeString, String: "test"}},
+		{"int", 42, Value{Type: TypeInt, Int: 42}},
+		{"int64", int64(42), Value{Type: TypeInt, Int: 42}},
+		{"float", 4.2, Value{Type: TypeFloat, Float: 4.2}},
+		{"bool", true, Value{Type: TypeBool, Int: 1}},
+		{"time", now, Value{Type: TypeTime, Int: now.UnixMilli()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewValue(tt.value)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := NewValue(struct{}{})
+	require.Error(t, err)
+
+	_, err = NewValue(uint64(42))
+	require.Error(t, err)
+}
+
+func TestValue_Interface(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli()).UTC()
+
+	for _, v := range []any{"test", int64(42), 4.2, true, now} {
+		sv, err := NewValue(v)
+		require.NoError(t, err)
+		require.Equal(t, v, sv.Interface())
+	}
+}
+
+func TestQuery_Validate(t *testing.T) {
+	require.NoError(t, And(Eq("customer", "c1"), Or(Gt("total", 12), Between("created", time.Now(), time.Now()))).Validate())
+
+	require.Error(t, Eq("", "c1").Validate())
+	require.Error(t, Eq("customer", []string{}).Validate())
+	require.Error(t, Gt("flag", true).Validate())
+	require.Error(t, And().Validate())
+	require.Error(t, Or(Eq("customer", "c1"), nil).Validate())
+}
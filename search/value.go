@@ -0,0 +1,142 @@
+package search
+
+import (
+	"fmt"
+	"time"
+)
+
+// Type is the type of a search attribute value. Queries only match attributes of the same type.
+type Type int
+
+const (
+	TypeString Type = iota
+	TypeInt
+	TypeFloat
+	TypeBool
+	TypeTime
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeString:
+		return "String"
+	case TypeInt:
+		return "Int"
+	case TypeFloat:
+		return "Float"
+	case TypeBool:
+		return "Bool"
+	case TypeTime:
+		return "Time"
+	}
+
+	return "Unknown"
+}
+
+// Value is a typed search attribute value. Bool and Time values are stored in Int, as 0/1 and as milliseconds
+// since the Unix epoch.
+type Value struct {
+	Type   Type    `json:"type"`
+	String string  `json:"s,omitempty"`
+	Int    int64   `json:"i,omitempty"`
+	Float  float64 `json:"f,omitempty"`
+}
+
+// NewValue converts the given value to a typed search attribute value. Supported are strings, integers, floats,
+// bools, and time.Time.
+func NewValue(v any) (Value, error) {
+	switch t := v.(type) {
+	case string:
+		return Value{Type: TypeString, String: t}, nil
+	case int:
+		return Value{Type: TypeInt, Int: int64(t)}, nil
+	case int8:
+		return Value{Type: TypeInt, Int: int64(t)}, nil
+	case int16:
+		return Value{Type: TypeInt, Int: int64(t)}, nil
+	case int32:
+		return Value{Type: TypeInt, Int: int64(t)}, nil
+	case int64:
+		return Value{Type: TypeInt, Int: t}, nil
+	case uint8:
+		return Value{Type: TypeInt, Int: int64(t)}, nil
+	case uint16:
+		return Value{Type: TypeInt, Int: int64(t)}, nil
+	case uint32:
+		return Value{Type: TypeInt, Int: int64(t)}, nil
+	case float32:
+		return Value{Type: TypeFloat, Float: float64(t)}, nil
+	case float64:
+		return Value{Type: TypeFloat, Float: t}, nil
+	case bool:
+		var i int64
+		if t {
+			i = 1
+		}
+		return Value{Type: TypeBool, Int: i}, nil
+	case time.Time:
+		return Value{Type: TypeTime, Int: t.UnixMilli()}, nil
+	}
+
+	return Value{}, fmt.Errorf("unsupported search attribute type %T", v)
+}
+
+// Interface returns the Go value for the search attribute value
+func (v Value) Interface() any {
+	switch v.Type {
+	case TypeString:
+		return v.String
+	case TypeInt:
+		return v.Int
+	case TypeFloat:
+		return v.Float
+	case TypeBool:
+		return v.Int != 0
+	case TypeTime:
+		return time.UnixMilli(v.Int).UTC()
+	}
+
+	return nil
+}
+
+// Numeric returns true if the value is stored in Int or Float
+func (v Value) Numeric() bool {
+	return v.Type != TypeString
+}
+
+// Number returns the numeric representation of the value
+func (v Value) Number() float64 {
+	if v.Type == TypeFloat {
+		return v.Float
+	}
+
+	return float64(v.Int)
+}
+
+// Attributes are the search attributes of a workflow instance. A nil value removes the attribute.
+type Attributes map[string]*Value
+
+// NewAttributes converts the given map to typed search attributes
+func NewAttributes(attributes map[string]any) (Attributes, error) {
+	r := make(Attributes, len(attributes))
+
+	for k, v := range attributes {
+		if k == "" {
+			return nil, fmt.Errorf("search attribute name cannot be empty")
+		}
+
+		if v == nil {
+			r[k] = nil
+			continue
+		}
+
+		sv, err := NewValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("search attribute %s: %w", k, err)
+		}
+
+		r[k] = &sv
+	}
+
+	return r, nil
+}
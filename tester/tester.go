@@ -2,6 +2,7 @@ package tester
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend"
+	"github.com/cschleiden/go-workflows/interceptor"
 	"github.com/cschleiden/go-workflows/internal/activity"
 	margs "github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/command"
@@ -25,6 +27,7 @@ import (
 	"github.com/cschleiden/go-workflows/internal/task"
 	"github.com/cschleiden/go-workflows/internal/workflow"
 	"github.com/cschleiden/go-workflows/log"
+	wf "github.com/cschleiden/go-workflows/workflow"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 	"go.opentelemetry.io/otel/trace"
@@ -88,12 +91,16 @@ type testWorkflow struct {
 	instance      *core.WorkflowInstance
 	history       []*history.Event
 	pendingEvents []*history.Event
+	finished      bool
 }
 
 type options struct {
-	TestTimeout time.Duration
-	Logger      log.Logger
-	Converter   converter.Converter
+	TestTimeout          time.Duration
+	Logger               log.Logger
+	Converter            converter.Converter
+	ContextPropagators   []wf.ContextPropagator
+	WorkflowInterceptors []interceptor.WorkflowInterceptor
+	ActivityInterceptors []interceptor.ActivityInterceptor
 }
 
 type workflowTester[TResult any] struct {
@@ -161,6 +168,27 @@ func WithTestTimeout(timeout time.Duration) WorkflowTesterOption {
 	}
 }
 
+// WithContextPropagator adds a context propagator, like a backend configured with it
+func WithContextPropagator(prop wf.ContextPropagator) WorkflowTesterOption {
+	return func(o *options) {
+		o.ContextPropagators = append(o.ContextPropagators, prop)
+	}
+}
+
+// WithWorkflowInterceptors wraps workflow executions with the given interceptors, like a worker configured with them
+func WithWorkflowInterceptors(interceptors ...interceptor.WorkflowInterceptor) WorkflowTesterOption {
+	return func(o *options) {
+		o.WorkflowInterceptors = append(o.WorkflowInterceptors, interceptors...)
+	}
+}
+
+// WithActivityInterceptors wraps executions of activities that are not mocked with the given interceptors
+func WithActivityInterceptors(interceptors ...interceptor.ActivityInterceptor) WorkflowTesterOption {
+	return func(o *options) {
+		o.ActivityInterceptors = append(o.ActivityInterceptors, interceptors...)
+	}
+}
+
 func NewWorkflowTester[TResult any](wf interface{}, opts ...WorkflowTesterOption) WorkflowTester[TResult] {
 	if err := margs.ReturnTypeMatch[TResult](wf); err != nil {
 		panic(fmt.Sprintf("workflow return type does not match: %s", err))
@@ -285,7 +313,9 @@ func (wt *workflowTester[TResult]) Execute(args ...interface{}) {
 			tw.pendingEvents = tw.pendingEvents[:0]
 
 			// Execute task
-			e := workflow.NewExecutor(wt.logger, wt.tracer, wt.registry, converter.DefaultConverter, &testHistoryProvider{tw.history}, tw.instance, wt.clock)
+			e := workflow.NewExecutor(
+				wt.logger, wt.tracer, wt.registry, wt.converter, wt.options.ContextPropagators, wt.options.WorkflowInterceptors,
+				&testHistoryProvider{tw.history}, tw.instance, wt.clock)
 
 			result, err := e.ExecuteTask(context.Background(), t)
 			if err != nil {
@@ -304,7 +334,11 @@ func (wt *workflowTester[TResult]) Execute(args ...interface{}) {
 				case history.EventType_WorkflowExecutionFinished:
 					a := event.Attributes.(*history.ExecutionCompletedAttributes)
 
-					if !tw.instance.SubWorkflow() {
+					wt.mtw.Lock()
+					tw.finished = true
+					wt.mtw.Unlock()
+
+					if tw.instance.InstanceID == wt.wfi.InstanceID {
 						wt.workflowFinished = true
 						wt.workflowResult = a.Result
 						wt.workflowErr = a.Error
@@ -315,20 +349,53 @@ func (wt *workflowTester[TResult]) Execute(args ...interface{}) {
 				}
 			}
 
+			// Sub-workflows that could not be created because they already exist
+			existingInstances := make(map[string]bool)
+			for _, workflowEvent := range result.WorkflowEvents {
+				if workflowEvent.HistoryEvent.Type == history.EventType_WorkflowExecutionStarted &&
+					!errors.Is(wt.targetErr(workflowEvent.WorkflowInstance.InstanceID), backend.ErrInstanceNotFound) {
+					existingInstances[workflowEvent.WorkflowInstance.InstanceID] = true
+				}
+			}
+
+			targetErr := func(instanceID string) error {
+				if existingInstances[instanceID] {
+					return backend.ErrInstanceAlreadyExists
+				}
+
+				return wt.targetErr(instanceID)
+			}
+
 			// Schedule sub-workflows and handle x-workflow events
 			for _, workflowEvent := range result.WorkflowEvents {
 				gotNewEvents = true
 				wt.logger.Debug("Workflow event", "event_type", workflowEvent.HistoryEvent.Type)
 
+				if existingInstances[workflowEvent.WorkflowInstance.InstanceID] {
+					// Sub-workflow already exists, the sending instance is notified below
+					continue
+				}
+
 				switch workflowEvent.HistoryEvent.Type {
 				case history.EventType_WorkflowExecutionStarted:
 					wt.scheduleSubWorkflow(workflowEvent)
 
 				default:
+					if targetErr(workflowEvent.WorkflowInstance.InstanceID) != nil {
+						// Target instance does not exist or has finished, the sending instance is notified below
+						continue
+					}
+
 					wt.sendEvent(workflowEvent.WorkflowInstance, workflowEvent.HistoryEvent)
 				}
 			}
 
+			// Report the outcome of sub-workflows, signals, and cancellation requests sent to other instances
+			for _, resultEvent := range history.ExternalWorkflowResults(wt.clock.Now(), result.Executed, targetErr) {
+				gotNewEvents = true
+				wt.sendEvent(tw.instance, resultEvent)
+			}
+
 			// Schedule activities
 			for _, event := range result.ActivityEvents {
 				gotNewEvents = true
@@ -431,7 +498,7 @@ func (wt *workflowTester[TResult]) SignalWorkflowInstance(wfi *core.WorkflowInst
 		return backend.ErrInstanceNotFound
 	}
 
-	arg, err := converter.DefaultConverter.To(value)
+	arg, err := converter.ForInstance(context.Background(), wt.converter, wfi).To(value)
 	if err != nil {
 		panic("Could not convert signal value to string" + err.Error())
 	}
@@ -462,7 +529,7 @@ func (wt *workflowTester[TResult]) WorkflowFinished() bool {
 func (wt *workflowTester[TResult]) WorkflowResult() (TResult, string) {
 	var r TResult
 	if wt.workflowResult != nil {
-		if err := converter.DefaultConverter.From(wt.workflowResult, &r); err != nil {
+		if err := converter.ForInstance(context.Background(), wt.converter, wt.wfi).From(wt.workflowResult, &r); err != nil {
 			panic("could not convert workflow result to expected type" + err.Error())
 		}
 	}
@@ -492,7 +559,7 @@ func (wt *workflowTester[TResult]) scheduleActivity(wfi *core.WorkflowInstance,
 				panic("Could not find activity " + e.Name + " in registry")
 			}
 
-			argValues, addContext, err := margs.InputsToArgs(converter.DefaultConverter, reflect.ValueOf(afn), e.Inputs)
+			argValues, addContext, err := margs.InputsToArgs(wt.converter, reflect.ValueOf(afn), e.Inputs)
 			if err != nil {
 				panic("Could not convert activity inputs to args: " + err.Error())
 			}
@@ -516,7 +583,7 @@ func (wt *workflowTester[TResult]) scheduleActivity(wfi *core.WorkflowInstance,
 				activityResult = nil
 			case 2:
 				result := results.Get(0)
-				activityResult, err = converter.DefaultConverter.To(result)
+				activityResult, err = converter.ForInstance(context.Background(), wt.converter, wfi).To(result)
 				if err != nil {
 					panic("Could not convert result for activity " + e.Name + ": " + err.Error())
 				}
@@ -533,7 +600,8 @@ func (wt *workflowTester[TResult]) scheduleActivity(wfi *core.WorkflowInstance,
 			}
 
 		} else {
-			executor := activity.NewExecutor(wt.logger, wt.tracer, wt.converter, wt.registry)
+			executor := activity.NewExecutor(
+				wt.logger, wt.tracer, wt.converter, wt.options.ContextPropagators, wt.options.ActivityInterceptors, wt.registry)
 			activityResult, activityErr = executor.ExecuteActivity(context.Background(), &task.Activity{
 				ID:               uuid.NewString(),
 				Metadata:         &core.WorkflowMetadata{},
@@ -616,6 +684,24 @@ func (wt *workflowTester[TResult]) getWorkflow(instance *core.WorkflowInstance)
 	return wt.testWorkflowsByInstanceID[instance.InstanceID]
 }
 
+// targetErr returns ErrInstanceNotFound if the given instance does not exist, and ErrInstanceFinished if it has
+// already finished
+func (wt *workflowTester[TResult]) targetErr(instanceID string) error {
+	wt.mtw.RLock()
+	defer wt.mtw.RUnlock()
+
+	tw := wt.testWorkflowsByInstanceID[instanceID]
+	if tw == nil {
+		return backend.ErrInstanceNotFound
+	}
+
+	if tw.finished {
+		return backend.ErrInstanceFinished
+	}
+
+	return nil
+}
+
 func (wt *workflowTester[TResult]) addWorkflow(instance *core.WorkflowInstance, initialEvent *history.Event) *testWorkflow {
 	wt.mtw.Lock()
 	defer wt.mtw.Unlock()
@@ -644,7 +730,7 @@ func (wt *workflowTester[TResult]) scheduleSubWorkflow(event history.WorkflowEve
 		panic("Could not find workflow " + a.Name + " in registry")
 	}
 
-	argValues, addContext, err := margs.InputsToArgs(converter.DefaultConverter, reflect.ValueOf(wfn), a.Inputs)
+	argValues, addContext, err := margs.InputsToArgs(converter.ForInstance(context.Background(), wt.converter, event.WorkflowInstance), reflect.ValueOf(wfn), a.Inputs)
 	if err != nil {
 		panic("Could not convert workflow inputs to args: " + err.Error())
 	}
@@ -677,7 +763,13 @@ func (wt *workflowTester[TResult]) scheduleSubWorkflow(event history.WorkflowEve
 		workflowResult = nil
 	case 2:
 		result := results.Get(0)
-		workflowResult, err = converter.DefaultConverter.To(result)
+		// The result is recorded in the history of the parent as well
+		instances := []*core.WorkflowInstance{event.WorkflowInstance}
+		if event.WorkflowInstance.SubWorkflow() {
+			instances = append(instances, core.NewWorkflowInstance(event.WorkflowInstance.ParentInstanceID, ""))
+		}
+
+		workflowResult, err = converter.ForInstances(context.Background(), wt.converter, instances...).To(result)
 		if err != nil {
 			panic("Could not convert result for mocked workflow " + a.Name + ": " + err.Error())
 		}
@@ -703,7 +795,7 @@ func (wt *workflowTester[TResult]) scheduleSubWorkflow(event history.WorkflowEve
 func (wt *workflowTester[TResult]) getInitialEvent(wf interface{}, args []interface{}) *history.Event {
 	name := fn.Name(wf)
 
-	inputs, err := margs.ArgsToInputs(converter.DefaultConverter, args...)
+	inputs, err := margs.ArgsToInputs(converter.ForInstance(context.Background(), wt.converter, wt.wfi), args...)
 	if err != nil {
 		panic(err)
 	}
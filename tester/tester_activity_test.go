@@ -1,9 +1,12 @@
 package tester
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/cschleiden/go-workflows/activity"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -103,3 +106,37 @@ func Test_ActivityRaceWithSignal(t *testing.T) {
 	require.Equal(t, "signal", wr)
 	tester.AssertExpectations(t)
 }
+
+func Test_ActivityGetInfo(t *testing.T) {
+	var attempts []int
+
+	activity1 := func(ctx context.Context) (string, error) {
+		info := activity.GetInfo(ctx)
+		attempts = append(attempts, info.Attempt)
+
+		if info.Attempt < 3 {
+			return "", errors.New("retry")
+		}
+
+		return info.WorkflowInstance.InstanceID, nil
+	}
+
+	wf := func(ctx workflow.Context) (string, error) {
+		return workflow.ExecuteActivity[string](ctx, workflow.ActivityOptions{
+			RetryOptions: workflow.RetryOptions{
+				MaxAttempts: 3,
+			},
+		}, activity1).Get(ctx)
+	}
+
+	tester := NewWorkflowTester[string](wf)
+	tester.Registry().RegisterActivity(activity1)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	wr, werr := tester.WorkflowResult()
+	require.Empty(t, werr)
+	require.NotEmpty(t, wr)
+	require.Equal(t, []int{1, 2, 3}, attempts)
+}
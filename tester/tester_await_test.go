@@ -0,0 +1,105 @@
+package tester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AwaitCondition(t *testing.T) {
+	tester := NewWorkflowTester[int](workflowAwait)
+
+	tester.ScheduleCallback(time.Second, func() {
+		tester.SignalWorkflow("approval", "a")
+	})
+
+	tester.ScheduleCallback(2*time.Second, func() {
+		tester.SignalWorkflow("approval", "b")
+	})
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	r, err := tester.WorkflowResult()
+	require.Empty(t, err)
+	require.Equal(t, 2, r)
+}
+
+func workflowAwait(ctx workflow.Context) (int, error) {
+	approvals := 0
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		c := workflow.NewSignalChannel[string](ctx, "approval")
+		for i := 0; i < 2; i++ {
+			c.Receive(ctx)
+			approvals++
+		}
+	})
+
+	if err := workflow.AwaitCondition(ctx, func() bool {
+		return approvals >= 2
+	}); err != nil {
+		return 0, err
+	}
+
+	return approvals, nil
+}
+
+func Test_AwaitWithTimeout_ConditionMet(t *testing.T) {
+	tester := NewWorkflowTester[bool](workflowAwaitWithTimeout)
+	start := tester.Now()
+
+	tester.ScheduleCallback(time.Second, func() {
+		tester.SignalWorkflow("approval", "a")
+	})
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	r, err := tester.WorkflowResult()
+	require.Empty(t, err)
+	require.True(t, r)
+
+	// The timeout timer was canceled, the workflow must not have waited for it
+	require.True(t, tester.Now().Before(start.Add(time.Minute)))
+}
+
+func Test_AwaitWithTimeout_Timeout(t *testing.T) {
+	tester := NewWorkflowTester[bool](workflowAwaitWithTimeout)
+	start := tester.Now()
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	r, err := tester.WorkflowResult()
+	require.Empty(t, err)
+	require.False(t, r)
+	require.Equal(t, start.Add(time.Minute), tester.Now())
+}
+
+func workflowAwaitWithTimeout(ctx workflow.Context) (bool, error) {
+	approved := false
+
+	// Stop waiting for the signal once the condition timed out, so that the workflow can finish
+	rctx, cancel := workflow.WithCancel(ctx)
+	defer cancel()
+
+	workflow.Go(rctx, func(ctx workflow.Context) {
+		c := workflow.NewSignalChannel[string](ctx, "approval")
+		workflow.Select(ctx,
+			workflow.Receive(c, func(ctx workflow.Context, v string, ok bool) {
+				approved = true
+			}),
+			workflow.Receive[struct{}](ctx.Done(), func(ctx workflow.Context, v struct{}, ok bool) {}),
+		)
+	})
+
+	return workflow.AwaitWithTimeout(ctx, time.Minute, func() bool {
+		return approved
+	})
+}
@@ -0,0 +1,208 @@
+package tester
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+func sleepSubWorkflow(ctx workflow.Context, seconds int) (int, error) {
+	if err := workflow.Sleep(ctx, time.Duration(seconds)*time.Second); err != nil {
+		return 0, err
+	}
+
+	return seconds, nil
+}
+
+func Test_All(t *testing.T) {
+	wf := func(ctx workflow.Context) ([]int, error) {
+		return workflow.All(ctx,
+			workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, sleepSubWorkflow, 3),
+			workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, sleepSubWorkflow, 1),
+			workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, sleepSubWorkflow, 2),
+		)
+	}
+
+	tester := NewWorkflowTester[[]int](wf)
+	tester.Registry().RegisterWorkflow(sleepSubWorkflow)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	wr, werr := tester.WorkflowResult()
+	require.Empty(t, werr)
+	require.Equal(t, []int{3, 1, 2}, wr)
+}
+
+func Test_All_Error(t *testing.T) {
+	failingActivity := func() (int, error) {
+		return 0, errors.New("activity error")
+	}
+
+	wf := func(ctx workflow.Context) ([]int, error) {
+		return workflow.All(ctx,
+			workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, sleepSubWorkflow, 1),
+			workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{}, failingActivity),
+		)
+	}
+
+	tester := NewWorkflowTester[[]int](wf)
+	tester.Registry().RegisterWorkflow(sleepSubWorkflow)
+	tester.Registry().RegisterActivity(failingActivity)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	_, werr := tester.WorkflowResult()
+	require.Equal(t, "activity error", werr)
+}
+
+func Test_Any(t *testing.T) {
+	type anyResult struct {
+		R  int
+		At time.Time
+	}
+
+	wf := func(ctx workflow.Context) (anyResult, error) {
+		fs := []workflow.Future[int]{
+			workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, sleepSubWorkflow, 3),
+			workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, sleepSubWorkflow, 1),
+			workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, sleepSubWorkflow, 2),
+		}
+
+		r, err := workflow.Any(ctx, fs...)
+		if err != nil {
+			return anyResult{}, err
+		}
+
+		at := workflow.Now(ctx)
+
+		// Wait for the remaining sub-workflows before completing
+		if _, err := workflow.All(ctx, fs...); err != nil {
+			return anyResult{}, err
+		}
+
+		return anyResult{R: r, At: at}, nil
+	}
+
+	tester := NewWorkflowTester[anyResult](wf)
+	tester.Registry().RegisterWorkflow(sleepSubWorkflow)
+	start := tester.Now()
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	wr, werr := tester.WorkflowResult()
+	require.Empty(t, werr)
+	require.Equal(t, 1, wr.R)
+
+	e := start.Add(time.Second)
+	require.True(t, e.Equal(wr.At), "expected %v, got %v", e, wr.At)
+}
+
+func Test_Any_RemainingFuturesPending(t *testing.T) {
+	wf := func(ctx workflow.Context) error {
+		_, err := workflow.Any(ctx,
+			workflow.ScheduleTimer(ctx, time.Second),
+			workflow.ScheduleTimer(ctx, time.Hour),
+		)
+
+		return err
+	}
+
+	tester := NewWorkflowTester[any](wf)
+	start := tester.Now()
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	_, werr := tester.WorkflowResult()
+	require.Empty(t, werr)
+	require.True(t, tester.Now().Before(start.Add(time.Hour)), "workflow should not wait for the remaining timer")
+}
+
+func Test_Any_NoFutures(t *testing.T) {
+	wf := func(ctx workflow.Context) (int, error) {
+		return workflow.Any[int](ctx)
+	}
+
+	tester := NewWorkflowTester[int](wf)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	_, werr := tester.WorkflowResult()
+	require.Equal(t, workflow.ErrNoFutures.Error(), werr)
+}
+
+func Test_Any_AllFail(t *testing.T) {
+	failingActivity := func(msg string) (int, error) {
+		return 0, errors.New(msg)
+	}
+
+	wf := func(ctx workflow.Context) (int, error) {
+		return workflow.Any(ctx,
+			workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{}, failingActivity, "first"),
+			workflow.ExecuteActivity[int](ctx, workflow.ActivityOptions{}, failingActivity, "second"),
+		)
+	}
+
+	tester := NewWorkflowTester[int](wf)
+	tester.Registry().RegisterActivity(failingActivity)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	_, werr := tester.WorkflowResult()
+	require.NotEmpty(t, werr)
+}
+
+func Test_AnyWithCancel(t *testing.T) {
+	wf := func(ctx workflow.Context) (int, error) {
+		timerFn := func(seconds int) func(ctx workflow.Context) workflow.Future[int] {
+			return func(ctx workflow.Context) workflow.Future[int] {
+				return workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, sleepSubWorkflow, seconds)
+			}
+		}
+
+		return workflow.AnyWithCancel(ctx, timerFn(30), timerFn(10), timerFn(20))
+	}
+
+	tester := NewWorkflowTester[int](wf)
+	tester.Registry().RegisterWorkflow(sleepSubWorkflow)
+	start := tester.Now()
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	wr, werr := tester.WorkflowResult()
+	require.Empty(t, werr)
+	require.Equal(t, 10, wr)
+	require.True(t, tester.Now().Before(start.Add(20*time.Second)), "losers should have been canceled")
+}
+
+func Test_MapConcurrent(t *testing.T) {
+	wf := func(ctx workflow.Context) ([]int, error) {
+		return workflow.MapConcurrent(ctx, []int{10, 10, 10, 10}, 2, func(ctx workflow.Context, seconds int) workflow.Future[int] {
+			return workflow.CreateSubWorkflowInstance[int](ctx, workflow.DefaultSubWorkflowOptions, sleepSubWorkflow, seconds)
+		})
+	}
+
+	tester := NewWorkflowTester[[]int](wf)
+	tester.Registry().RegisterWorkflow(sleepSubWorkflow)
+	start := tester.Now()
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	wr, werr := tester.WorkflowResult()
+	require.Empty(t, werr)
+	require.Equal(t, []int{10, 10, 10, 10}, wr)
+
+	// Two batches of two sub-workflows each
+	e := start.Add(20 * time.Second)
+	require.True(t, e.Equal(tester.Now()), "expected %v, got %v", e, tester.Now())
+}
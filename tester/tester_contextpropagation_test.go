@@ -0,0 +1,70 @@
+package tester
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cschleiden/go-workflows/workflow"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantKey struct{}
+
+type tenantPropagator struct{}
+
+func (*tenantPropagator) Inject(ctx context.Context, metadata *workflow.Metadata) error {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		metadata.Set("tenant", tenant)
+	}
+
+	return nil
+}
+
+func (*tenantPropagator) Extract(ctx context.Context, metadata *workflow.Metadata) (context.Context, error) {
+	return context.WithValue(ctx, tenantKey{}, metadata.Get("tenant")), nil
+}
+
+func (*tenantPropagator) InjectFromWorkflow(ctx workflow.Context, metadata *workflow.Metadata) error {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		metadata.Set("tenant", tenant)
+	}
+
+	return nil
+}
+
+func (*tenantPropagator) ExtractToWorkflow(ctx workflow.Context, metadata *workflow.Metadata) (workflow.Context, error) {
+	return workflow.WithValue(ctx, tenantKey{}, metadata.Get("tenant")), nil
+}
+
+func Test_ContextPropagation(t *testing.T) {
+	activity := func(ctx context.Context) (string, error) {
+		return "activity:" + ctx.Value(tenantKey{}).(string), nil
+	}
+
+	subWorkflow := func(ctx workflow.Context) (string, error) {
+		r, err := workflow.ExecuteActivity[string](ctx, workflow.DefaultActivityOptions, activity).Get(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		return "sub:" + ctx.Value(tenantKey{}).(string) + "," + r, nil
+	}
+
+	wf := func(ctx workflow.Context) (string, error) {
+		ctx = workflow.WithValue(ctx, tenantKey{}, "tenant1")
+
+		return workflow.CreateSubWorkflowInstance[string](ctx, workflow.DefaultSubWorkflowOptions, subWorkflow).Get(ctx)
+	}
+
+	tester := NewWorkflowTester[string](wf, WithContextPropagator(&tenantPropagator{}))
+	tester.Registry().RegisterWorkflow(subWorkflow)
+	tester.Registry().RegisterActivity(activity)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	wr, werr := tester.WorkflowResult()
+	require.Empty(t, werr)
+	require.Equal(t, "sub:tenant1,activity:tenant1", wr)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -151,3 +152,132 @@ func Test_SubWorkflow_Signals(t *testing.T) {
 	require.Equal(t, "hello42", wfR)
 	tester.AssertExpectations(t)
 }
+
+func Test_SubWorkflow_Started(t *testing.T) {
+	subWorkflow := func(ctx workflow.Context) (string, error) {
+		c := workflow.NewSignalChannel[string](ctx, "subworkflow-signal")
+		r, _ := c.Receive(ctx)
+
+		return r, nil
+	}
+
+	workflowWithSub := func(ctx workflow.Context) (string, error) {
+		h := workflow.StartSubWorkflowInstance[string](ctx, workflow.DefaultSubWorkflowOptions, subWorkflow)
+
+		instance, err := h.Started().Get(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		// Use the generated instance ID to signal the sub-workflow
+		if _, err := workflow.SignalWorkflow(ctx, instance.InstanceID, "subworkflow-signal", instance.InstanceID).Get(ctx); err != nil {
+			return "", err
+		}
+
+		return h.Result().Get(ctx)
+	}
+
+	tester := NewWorkflowTester[string](workflowWithSub)
+	tester.Registry().RegisterWorkflow(subWorkflow)
+
+	var subWorkflowInstance *core.WorkflowInstance
+	tester.ListenSubWorkflow(func(instance *core.WorkflowInstance, name string) {
+		subWorkflowInstance = instance
+	})
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	wfR, wfE := tester.WorkflowResult()
+	require.Empty(t, wfE)
+	require.Equal(t, subWorkflowInstance.InstanceID, wfR)
+}
+
+func Test_SubWorkflow_Detached(t *testing.T) {
+	subWorkflowCompleted := false
+
+	subWorkflow := func(ctx workflow.Context) error {
+		if _, err := workflow.ScheduleTimer(ctx, time.Hour).Get(ctx); err != nil {
+			return err
+		}
+
+		subWorkflowCompleted = true
+
+		return nil
+	}
+
+	workflowWithSub := func(ctx workflow.Context) (bool, error) {
+		h := workflow.StartSubWorkflowInstance[any](ctx, workflow.SubWorkflowOptions{
+			InstanceID: "detached",
+			Detached:   true,
+		}, subWorkflow)
+
+		instance, err := h.Started().Get(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		return instance.SubWorkflow(), nil
+	}
+
+	tester := NewWorkflowTester[bool](workflowWithSub)
+	tester.Registry().RegisterWorkflow(subWorkflow)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	wfR, wfE := tester.WorkflowResult()
+	require.Empty(t, wfE)
+	require.False(t, wfR, "detached sub-workflow should not be linked to parent")
+	require.False(t, subWorkflowCompleted, "parent should complete before detached sub-workflow")
+}
+
+func Test_SubWorkflow_GetInfo(t *testing.T) {
+	var attempts []int
+	var parentInstanceID, subParentInstanceID string
+
+	subWorkflow := func(ctx workflow.Context) (string, error) {
+		info := workflow.GetInfo(ctx)
+		if !workflow.Replaying(ctx) {
+			attempts = append(attempts, info.Attempt)
+		}
+
+		if info.Attempt < 2 {
+			return "", errors.New("retry")
+		}
+
+		subParentInstanceID = info.ParentInstanceID
+
+		return info.Name, nil
+	}
+
+	workflowWithSub := func(ctx workflow.Context) (string, error) {
+		info := workflow.GetInfo(ctx)
+		if info.Attempt != 1 || info.ParentInstanceID != "" || info.HistoryLength < 1 {
+			return "", errors.New("unexpected workflow info")
+		}
+
+		parentInstanceID = info.Instance.InstanceID
+
+		return workflow.CreateSubWorkflowInstance[string](ctx, workflow.SubWorkflowOptions{
+			RetryOptions: workflow.RetryOptions{
+				MaxAttempts: 2,
+			},
+		}, subWorkflow).Get(ctx)
+	}
+
+	tester := NewWorkflowTester[string](workflowWithSub)
+	tester.Registry().RegisterWorkflow(subWorkflow)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+
+	wfR, wfE := tester.WorkflowResult()
+	require.Empty(t, wfE)
+	require.Equal(t, fn.Name(subWorkflow), wfR)
+	require.Equal(t, []int{1, 2}, attempts)
+	require.Equal(t, parentInstanceID, subParentInstanceID)
+}
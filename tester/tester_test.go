@@ -6,7 +6,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/workflow"
 	"github.com/stretchr/testify/mock"
@@ -187,13 +186,13 @@ func Test_SignalSubWorkflowBeforeScheduling(t *testing.T) {
 
 	require.True(t, tester.WorkflowFinished())
 	wfR, wfErr := tester.WorkflowResult()
-	require.Equal(t, backend.ErrInstanceNotFound.Error(), wfErr)
-	require.IsType(t, "", wfR)
+	require.Empty(t, wfErr)
+	require.Equal(t, "finished without errors!", wfR)
 }
 
 func workflowSubWorkFlowsAndSignals(ctx workflow.Context) (string, error) {
 	_, err := workflow.SignalWorkflow(ctx, "subworkflow", "test", "").Get(ctx)
-	if err != backend.ErrInstanceNotFound {
+	if err != workflow.ErrInstanceNotFound {
 		return "", err
 	}
 
@@ -240,3 +239,54 @@ func waitForSignal(ctx workflow.Context) (int, error) {
 
 	return 42, nil
 }
+
+func Test_CancelSubWorkflow(t *testing.T) {
+	tester := NewWorkflowTester[string](workflowCancelWorkflow)
+	require.NoError(t, tester.Registry().RegisterWorkflow(waitForTimer))
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	wfR, wfErr := tester.WorkflowResult()
+	require.Empty(t, wfErr)
+	require.Equal(t, "canceled", wfR)
+}
+
+func workflowCancelWorkflow(ctx workflow.Context) (string, error) {
+	sw := workflow.CreateSubWorkflowInstance[string](ctx, workflow.SubWorkflowOptions{
+		InstanceID: "subworkflow",
+	}, waitForTimer)
+
+	if _, err := workflow.CancelWorkflow(ctx, "subworkflow").Get(ctx); err != nil {
+		return "", err
+	}
+
+	return sw.Get(ctx)
+}
+
+func waitForTimer(ctx workflow.Context) (string, error) {
+	if _, err := workflow.ScheduleTimer(ctx, time.Hour).Get(ctx); err != nil {
+		if err == workflow.Canceled {
+			return "canceled", nil
+		}
+
+		return "", err
+	}
+
+	return "fired", nil
+}
+
+func Test_CancelWorkflow_NotFound(t *testing.T) {
+	wf := func(ctx workflow.Context) error {
+		_, err := workflow.CancelWorkflow(ctx, "does-not-exist").Get(ctx)
+		return err
+	}
+
+	tester := NewWorkflowTester[any](wf)
+
+	tester.Execute()
+
+	require.True(t, tester.WorkflowFinished())
+	_, wfErr := tester.WorkflowResult()
+	require.Equal(t, workflow.ErrInstanceNotFound.Error(), wfErr)
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/benbjohnson/clock"
 	"github.com/cschleiden/go-workflows/backend"
 	"github.com/cschleiden/go-workflows/client"
+	"github.com/cschleiden/go-workflows/internal/retention"
 	"github.com/cschleiden/go-workflows/internal/signals"
+	internalsync "github.com/cschleiden/go-workflows/internal/sync"
 	internal "github.com/cschleiden/go-workflows/internal/worker"
 	workflowinternal "github.com/cschleiden/go-workflows/internal/workflow"
 	"github.com/cschleiden/go-workflows/workflow"
@@ -38,6 +40,13 @@ type Worker interface {
 
 	// WaitForCompletion
 	WaitForCompletion() error
+
+	// StackTrace returns the stack traces of all coroutines of the given active workflow instance, showing where
+	// each of them is blocked. The worker replays the history of the instance to reconstruct its state, so the
+	// workflow has to be registered with it. The workflow code stops where the recorded history ends, side effects
+	// and other code that would run live are not executed. If the workflow is deadlocked, a *DeadlockError with the
+	// stack traces is returned.
+	StackTrace(ctx context.Context, instance *workflow.Instance) (string, error)
 }
 
 type worker struct {
@@ -50,6 +59,7 @@ type worker struct {
 
 	workflowWorker *internal.WorkflowWorker
 	activityWorker *internal.ActivityWorker
+	cleaner        *retention.Cleaner
 
 	workflows  map[string]interface{}
 	activities map[string]interface{}
@@ -59,6 +69,20 @@ type Options = internal.Options
 
 var DefaultWorkerOptions = internal.DefaultOptions
 
+type NonDeterminismPolicy = internal.NonDeterminismPolicy
+
+// DeadlockError is returned when workflow code does not yield back to the worker within the configured
+// WorkflowDeadlockTimeout. It carries the stack traces of all coroutines of the workflow.
+type DeadlockError = internalsync.DeadlockError
+
+const (
+	// NonDeterminismFailInstance fails the workflow instance with the non-determinism error
+	NonDeterminismFailInstance = internal.NonDeterminismFailInstance
+
+	// NonDeterminismFailTask fails the workflow task, it is retried with a backoff
+	NonDeterminismFailTask = internal.NonDeterminismFailTask
+)
+
 func New(backend backend.Backend, options *Options) Worker {
 	if options == nil {
 		options = &internal.DefaultOptions
@@ -72,6 +96,26 @@ func New(backend backend.Backend, options *Options) Worker {
 		options.WorkflowExecutorCacheTTL = internal.DefaultOptions.WorkflowExecutorCacheTTL
 	}
 
+	if options.WorkflowTaskRetryInterval == 0 {
+		options.WorkflowTaskRetryInterval = internal.DefaultOptions.WorkflowTaskRetryInterval
+	}
+
+	if options.WorkflowTaskMaxRetryInterval == 0 {
+		options.WorkflowTaskMaxRetryInterval = internal.DefaultOptions.WorkflowTaskMaxRetryInterval
+	}
+
+	if options.WorkflowDeadlockTimeout == 0 {
+		options.WorkflowDeadlockTimeout = internal.DefaultOptions.WorkflowDeadlockTimeout
+	}
+
+	if options.RetentionCleanupInterval == 0 {
+		options.RetentionCleanupInterval = internal.DefaultOptions.RetentionCleanupInterval
+	}
+
+	if options.RetentionBatchSize == 0 {
+		options.RetentionBatchSize = internal.DefaultOptions.RetentionBatchSize
+	}
+
 	registry := workflowinternal.NewRegistry()
 
 	// Register internal activities
@@ -83,8 +127,9 @@ func New(backend backend.Backend, options *Options) Worker {
 		done: make(chan struct{}),
 		wg:   &sync.WaitGroup{},
 
-		workflowWorker: internal.NewWorkflowWorker(backend, registry, options),
+		workflowWorker: internal.NewWorkflowWorker(backend, registry, clock.New(), options),
 		activityWorker: internal.NewActivityWorker(backend, registry, clock.New(), options),
+		cleaner:        retention.NewCleaner(backend, clock.New(), options.RetentionCleanupInterval, options.RetentionBatchSize),
 
 		registry: registry,
 	}
@@ -99,6 +144,9 @@ func (w *worker) Start(ctx context.Context) error {
 		return fmt.Errorf("starting activity worker: %w", err)
 	}
 
+	// Remove instances that finished before the retention period of the backend
+	w.cleaner.Start(ctx)
+
 	return nil
 }
 
@@ -111,9 +159,15 @@ func (w *worker) WaitForCompletion() error {
 		return err
 	}
 
+	w.cleaner.WaitForCompletion()
+
 	return nil
 }
 
+func (w *worker) StackTrace(ctx context.Context, instance *workflow.Instance) (string, error) {
+	return w.workflowWorker.StackTrace(ctx, instance)
+}
+
 func (w *worker) RegisterWorkflow(wf workflow.Workflow) error {
 	return w.registry.RegisterWorkflow(wf)
 }
@@ -5,7 +5,9 @@ import (
 
 	a "github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/contextpropagation"
 	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/fn"
 	"github.com/cschleiden/go-workflows/internal/sync"
 	"github.com/cschleiden/go-workflows/internal/tracing"
@@ -25,12 +27,15 @@ var DefaultActivityOptions = ActivityOptions{
 
 // ExecuteActivity schedules the given activity to be executed
 func ExecuteActivity[TResult any](ctx Context, options ActivityOptions, activity interface{}, args ...interface{}) Future[TResult] {
+	// Retries are linked to the first attempt of the activity
+	var firstScheduleEventID int64
+
 	return withRetries(ctx, options.RetryOptions, func(ctx sync.Context, attempt int) Future[TResult] {
-		return executeActivity[TResult](ctx, options, attempt, activity, args...)
+		return executeActivity[TResult](ctx, options, attempt, &firstScheduleEventID, activity, args...)
 	})
 }
 
-func executeActivity[TResult any](ctx Context, options ActivityOptions, attempt int, activity interface{}, args ...interface{}) Future[TResult] {
+func executeActivity[TResult any](ctx Context, options ActivityOptions, attempt int, firstScheduleEventID *int64, activity interface{}, args ...interface{}) Future[TResult] {
 	f := sync.NewFuture[TResult]()
 
 	if ctx.Err() != nil {
@@ -57,11 +62,24 @@ func executeActivity[TResult any](ctx Context, options ActivityOptions, attempt
 		return f
 	}
 
+	metadata := &core.WorkflowMetadata{}
+	if err := contextpropagation.InjectFromWorkflow(ctx, metadata); err != nil {
+		f.Set(*new(TResult), err)
+		return f
+	}
+
 	wfState := workflowstate.WorkflowState(ctx)
 	scheduleEventID := wfState.GetNextScheduleEventID()
 
+	var retryOf int64
+	if attempt == 0 {
+		*firstScheduleEventID = scheduleEventID
+	} else {
+		retryOf = *firstScheduleEventID
+	}
+
 	name := fn.Name(activity)
-	cmd := command.NewScheduleActivityCommand(scheduleEventID, name, inputs)
+	cmd := command.NewScheduleActivityCommand(scheduleEventID, name, inputs, metadata, attempt, retryOf)
 	wfState.AddCommand(cmd)
 	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(cv, f))
 
@@ -0,0 +1,58 @@
+package workflow
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/workflowtracer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AwaitCondition blocks the workflow until the given condition returns true. The condition is evaluated again whenever
+// any other workflow goroutine made progress, for example after receiving a signal or an activity result.
+//
+// The condition must only depend on workflow state, otherwise the workflow is not deterministic. If the context
+// is canceled before the condition is met, Canceled is returned.
+func AwaitCondition(ctx Context, condition func() bool) error {
+	return sync.AwaitCondition(ctx, condition)
+}
+
+// AwaitWithTimeout blocks the workflow until the given condition returns true or the timeout expires. It returns
+// true if the condition was met, and false if the timeout expired first.
+//
+// The timeout is backed by a durable timer, which is canceled when the condition is met before it fires.
+func AwaitWithTimeout(ctx Context, timeout time.Duration, condition func() bool) (bool, error) {
+	ctx, span := workflowtracer.Tracer(ctx).Start(ctx, "AwaitWithTimeout",
+		trace.WithAttributes(attribute.Int64("timeout_ms", int64(timeout/time.Millisecond))))
+	defer span.End()
+
+	// Avoid scheduling a timer if the condition is already met
+	if condition() {
+		return true, nil
+	}
+
+	tctx, cancel := WithCancel(ctx)
+	defer cancel()
+
+	t := ScheduleTimer(tctx, timeout).(sync.FutureInternal[struct{}])
+
+	met := false
+	if err := sync.AwaitCondition(ctx, func() bool {
+		met = condition()
+		return met || t.Ready()
+	}); err != nil {
+		return false, err
+	}
+
+	// The condition takes precedence if it was met at the same time the timer fired
+	if met {
+		return true, nil
+	}
+
+	if _, err := t.Get(ctx); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
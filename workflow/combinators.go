@@ -0,0 +1,176 @@
+package workflow
+
+import "errors"
+
+// ErrNoFutures is returned by Any and AnyWithCancel when they are called without any futures
+var ErrNoFutures = errors.New("no futures given")
+
+// All waits for all of the given futures to be ready and returns their results in the order of the given futures.
+// If any of the futures returns an error, the first error observed is returned once all futures are ready.
+func All[T any](ctx Context, fs ...Future[T]) ([]T, error) {
+	results := make([]T, len(fs))
+	pending := indices(len(fs))
+
+	var firstErr error
+
+	for len(pending) > 0 {
+		Select(ctx, awaitPending(fs, pending, func(ctx Context, idx int, f Future[T]) {
+			pending = without(pending, idx)
+
+			r, err := f.Get(ctx)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+
+			results[idx] = r
+		})...)
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
+
+// Any waits for the first of the given futures to complete successfully and returns its result. If all futures
+// return an error, the error of the last future to complete is returned. Without any futures, ErrNoFutures is
+// returned.
+//
+// The remaining futures are not canceled and their results are discarded. Activities and sub-workflows with retries
+// keep the workflow running until they are done, even if the workflow function has returned. Use AnyWithCancel to
+// cancel them.
+func Any[T any](ctx Context, fs ...Future[T]) (T, error) {
+	if len(fs) == 0 {
+		return *new(T), ErrNoFutures
+	}
+
+	pending := indices(len(fs))
+
+	var lastErr error
+
+	for len(pending) > 0 {
+		var r T
+		var err error
+		done := false
+
+		Select(ctx, awaitPending(fs, pending, func(ctx Context, idx int, f Future[T]) {
+			pending = without(pending, idx)
+			r, err = f.Get(ctx)
+			done = err == nil
+		})...)
+
+		if done {
+			return r, nil
+		}
+
+		lastErr = err
+	}
+
+	return *new(T), lastErr
+}
+
+// AnyWithCancel starts all of the given functions with a shared, cancelable context and waits for the first of the
+// returned futures to complete successfully. Once a result is available, the context is canceled and AnyWithCancel
+// waits for the remaining futures to settle before returning.
+//
+// Activities cannot be canceled at this time, so AnyWithCancel still waits for any remaining activities to finish.
+func AnyWithCancel[T any](ctx Context, fns ...func(ctx Context) Future[T]) (T, error) {
+	if len(fns) == 0 {
+		return *new(T), ErrNoFutures
+	}
+
+	cctx, cancel := WithCancel(ctx)
+	defer cancel()
+
+	fs := make([]Future[T], len(fns))
+	for i, fn := range fns {
+		fs[i] = fn(cctx)
+	}
+
+	r, err := Any(ctx, fs...)
+
+	// Cancel the remaining futures and wait for them, their results are discarded
+	cancel()
+	All(ctx, fs...)
+
+	return r, err
+}
+
+// MapConcurrent calls fn for every item and waits for all returned futures. At most limit futures are pending at
+// the same time, a limit <= 0 starts all of them at once. Results are returned in the order of the given items.
+//
+// If any future returns an error, no further items are started and the first error is returned once all pending
+// futures are ready.
+func MapConcurrent[T, R any](ctx Context, items []T, limit int, fn func(ctx Context, item T) Future[R]) ([]R, error) {
+	if limit <= 0 {
+		limit = len(items)
+	}
+
+	results := make([]R, len(items))
+	fs := make([]Future[R], len(items))
+	pending := make([]int, 0, limit)
+	next := 0
+
+	var firstErr error
+
+	for len(pending) > 0 || (next < len(items) && firstErr == nil) {
+		for firstErr == nil && next < len(items) && len(pending) < limit {
+			fs[next] = fn(ctx, items[next])
+			pending = append(pending, next)
+			next++
+		}
+
+		Select(ctx, awaitPending(fs, pending, func(ctx Context, idx int, f Future[R]) {
+			pending = without(pending, idx)
+
+			r, err := f.Get(ctx)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+
+			results[idx] = r
+		})...)
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
+
+// awaitPending returns a select case for each of the futures at the pending indices. Pending indices are kept in
+// ascending order to keep the selection deterministic.
+func awaitPending[T any](fs []Future[T], pending []int, handler func(ctx Context, idx int, f Future[T])) []SelectCase {
+	cases := make([]SelectCase, 0, len(pending))
+
+	for _, idx := range pending {
+		idx := idx
+		cases = append(cases, Await(fs[idx], func(ctx Context, f Future[T]) {
+			handler(ctx, idx, f)
+		}))
+	}
+
+	return cases
+}
+
+func indices(n int) []int {
+	r := make([]int, n)
+	for i := range r {
+		r[i] = i
+	}
+
+	return r
+}
+
+// without removes the given index from the pending indices, keeping their order
+func without(pending []int, idx int) []int {
+	for i, p := range pending {
+		if p == idx {
+			return append(pending[:i], pending[i+1:]...)
+		}
+	}
+
+	return pending
+}
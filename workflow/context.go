@@ -17,3 +17,9 @@ func WithCancel(parent Context) (ctx Context, cancel CancelFunc) {
 func NewDisconnectedContext(ctx Context) Context {
 	return sync.NewDisconnectedContext(ctx)
 }
+
+// WithValue returns a copy of parent in which the value associated with key is val. Values are not persisted, use a
+// ContextPropagator to pass values to activities and sub-workflows.
+func WithValue(parent Context, key, val interface{}) Context {
+	return sync.WithValue(parent, key, val)
+}
@@ -0,0 +1,52 @@
+package workflow
+
+import (
+	"time"
+
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+)
+
+type Info struct {
+	// Name is the name the workflow was registered with
+	Name string
+
+	// StartedAt is the time the workflow instance was started
+	StartedAt time.Time
+
+	Instance *Instance
+
+	// ParentInstanceID is the instance ID of the parent workflow, empty if this is not a sub-workflow
+	ParentInstanceID string
+
+	// Attempt is the current attempt of a sub-workflow started with retry options, starting at 1
+	Attempt int
+
+	// HistoryLength is the number of events in the workflow history up to and including the event currently
+	// being processed
+	HistoryLength int64
+
+	Metadata *Metadata
+}
+
+// GetInfo returns information about the current workflow execution. All values are recorded in the workflow
+// history, so they are safe to use in workflow code.
+func GetInfo(ctx Context) *Info {
+	wfState := workflowstate.WorkflowState(ctx)
+	instance := wfState.Instance()
+
+	info := &Info{
+		Instance:         instance,
+		ParentInstanceID: instance.ParentInstanceID,
+		Attempt:          1,
+		HistoryLength:    wfState.HistoryLength(),
+	}
+
+	if started := wfState.Started(); started != nil {
+		info.Name = started.Name
+		info.StartedAt = started.StartedAt
+		info.Attempt = started.Attempt + 1
+		info.Metadata = started.Metadata
+	}
+
+	return info
+}
@@ -83,7 +83,7 @@ func withRetries[T any](ctx sync.Context, retryOptions RetryOptions, fn func(ctx
 				break
 			}
 
-			f = fn(ctx, attempt+1)
+			f = fn(ctx, attempt)
 		}
 
 		r.Set(result, err)
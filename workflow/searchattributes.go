@@ -0,0 +1,35 @@
+package workflow
+
+import (
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/workflowstate"
+	"github.com/cschleiden/go-workflows/internal/workflowtracer"
+	"github.com/cschleiden/go-workflows/search"
+)
+
+// UpsertSearchAttributes adds or updates the given search attributes of the current workflow instance. Setting
+// an attribute to nil removes it. Supported values are strings, integers, floats, bools, and time.Time.
+//
+// The update is recorded in the workflow history and applied to the backend's index once the current workflow
+// task has been completed. Instances can then be found using client.ListWorkflowInstances.
+func UpsertSearchAttributes(ctx Context, attributes map[string]any) error {
+	ctx, span := workflowtracer.Tracer(ctx).Start(ctx, "UpsertSearchAttributes")
+	defer span.End()
+
+	sa, err := search.NewAttributes(attributes)
+	if err != nil {
+		return err
+	}
+
+	if len(sa) == 0 {
+		return nil
+	}
+
+	wfState := workflowstate.WorkflowState(ctx)
+	scheduleEventID := wfState.GetNextScheduleEventID()
+
+	cmd := command.NewUpsertSearchAttributesCommand(scheduleEventID, sa)
+	wfState.AddCommand(cmd)
+
+	return nil
+}
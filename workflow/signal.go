@@ -1,24 +1,99 @@
 package workflow
 
 import (
-	"github.com/cschleiden/go-workflows/internal/signals"
+	"fmt"
+
+	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/converter"
+	"github.com/cschleiden/go-workflows/internal/core"
+	"github.com/cschleiden/go-workflows/internal/sync"
+	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
 	"github.com/cschleiden/go-workflows/internal/workflowtracer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrInstanceNotFound is returned by the futures of SignalWorkflow and CancelWorkflow if the target
+// workflow instance does not exist.
+var ErrInstanceNotFound = core.ErrInstanceNotFound
+
+// ErrInstanceFinished is returned by the future of SignalWorkflow if the target workflow instance has already
+// finished.
+var ErrInstanceFinished = core.ErrInstanceFinished
+
 func NewSignalChannel[T any](ctx Context, name string) Channel[T] {
 	wfState := workflowstate.WorkflowState(ctx)
 	return workflowstate.GetSignalChannel[T](ctx, wfState, name)
 }
 
+// SignalWorkflow sends a signal to the workflow instance with the given ID. The returned future resolves once the
+// signal has been delivered, with ErrInstanceNotFound if the instance does not exist, or with ErrInstanceFinished if
+// the instance has already finished.
 func SignalWorkflow[T any](ctx Context, instanceID string, name string, arg T) Future[any] {
-	ctx, span := workflowtracer.Tracer(ctx).Start(ctx, "SignalWorkflow")
+	f := sync.NewFuture[any]()
+
+	if ctx.Err() != nil {
+		f.Set(nil, ctx.Err())
+		return f
+	}
+
+	wfState := workflowstate.WorkflowState(ctx)
+
+	// The argument is recorded in the history of the receiving instance as well. When replaying it has been stored
+	// for it already.
+	cv := converter.GetConverter(ctx)
+	argConverter := cv
+	if !wfState.Replaying() {
+		argConverter = converter.SharedWith(cv, core.NewWorkflowInstance(instanceID, ""))
+	}
+
+	input, err := argConverter.To(arg)
+	if err != nil {
+		f.Set(nil, fmt.Errorf("converting signal argument: %w", err))
+		return f
+	}
+
+	scheduleEventID := wfState.GetNextScheduleEventID()
+
+	cmd := command.NewSignalExternalWorkflowCommand(scheduleEventID, instanceID, name, input)
+	wfState.AddCommand(cmd)
+	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(cv, f))
+
+	_, span := workflowtracer.Tracer(ctx).Start(ctx, "SignalWorkflow",
+		trace.WithAttributes(
+			attribute.String(tracing.WorkflowInstanceID, instanceID),
+			attribute.String("signal.name", name),
+			attribute.Int64(tracing.ScheduleEventID, scheduleEventID),
+		))
+	defer span.End()
+
+	return f
+}
+
+// CancelWorkflow requests cancellation of the workflow instance with the given ID. The returned future resolves once
+// the request has been delivered, or with ErrInstanceNotFound if the instance does not exist.
+func CancelWorkflow(ctx Context, instanceID string) Future[any] {
+	f := sync.NewFuture[any]()
+
+	if ctx.Err() != nil {
+		f.Set(nil, ctx.Err())
+		return f
+	}
+
+	wfState := workflowstate.WorkflowState(ctx)
+	scheduleEventID := wfState.GetNextScheduleEventID()
+
+	cmd := command.NewRequestCancelExternalWorkflowCommand(scheduleEventID, instanceID)
+	wfState.AddCommand(cmd)
+	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(converter.GetConverter(ctx), f))
+
+	_, span := workflowtracer.Tracer(ctx).Start(ctx, "CancelWorkflow",
+		trace.WithAttributes(
+			attribute.String(tracing.WorkflowInstanceID, instanceID),
+			attribute.Int64(tracing.ScheduleEventID, scheduleEventID),
+		))
 	defer span.End()
 
-	var a *signals.Activities
-	return ExecuteActivity[any](ctx, ActivityOptions{
-		RetryOptions: RetryOptions{
-			MaxAttempts: 1,
-		},
-	}, a.DeliverWorkflowSignal, instanceID, name, arg)
+	return f
 }
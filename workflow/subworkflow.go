@@ -5,6 +5,7 @@ import (
 
 	a "github.com/cschleiden/go-workflows/internal/args"
 	"github.com/cschleiden/go-workflows/internal/command"
+	"github.com/cschleiden/go-workflows/internal/contextpropagation"
 	"github.com/cschleiden/go-workflows/internal/converter"
 	"github.com/cschleiden/go-workflows/internal/core"
 	"github.com/cschleiden/go-workflows/internal/fn"
@@ -12,6 +13,7 @@ import (
 	"github.com/cschleiden/go-workflows/internal/tracing"
 	"github.com/cschleiden/go-workflows/internal/workflowstate"
 	"github.com/cschleiden/go-workflows/internal/workflowtracer"
+	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -20,8 +22,17 @@ type SubWorkflowOptions struct {
 	InstanceID string
 
 	RetryOptions RetryOptions
+
+	// Detached starts the sub-workflow without linking it to the parent workflow. The parent does not wait for a
+	// detached sub-workflow and can complete while it is still running. Canceling the parent does not cancel a
+	// detached sub-workflow, and retries are not supported.
+	Detached bool
 }
 
+// ErrInstanceAlreadyExists is returned by the futures of a sub-workflow if it could not be created because an
+// instance with the same ID already exists.
+var ErrInstanceAlreadyExists = core.ErrInstanceAlreadyExists
+
 var (
 	DefaultSubWorkflowRetryOptions = RetryOptions{
 		// Disable retries by default for sub-workflows
@@ -33,43 +44,117 @@ var (
 	}
 )
 
+// SubWorkflowHandle is returned when starting a sub-workflow instance.
+type SubWorkflowHandle[TResult any] interface {
+	// Started returns a future that resolves with the sub-workflow instance once the sub-workflow has been started.
+	Started() Future[*Instance]
+
+	// Result returns a future that resolves with the result of the sub-workflow. For detached sub-workflows it
+	// resolves with the zero value as soon as the sub-workflow has been started.
+	Result() Future[TResult]
+}
+
+type subWorkflowHandle[TResult any] struct {
+	started Future[*Instance]
+	result  Future[TResult]
+}
+
+func (h *subWorkflowHandle[TResult]) Started() Future[*Instance] {
+	return h.started
+}
+
+func (h *subWorkflowHandle[TResult]) Result() Future[TResult] {
+	return h.result
+}
+
+// CreateSubWorkflowInstance creates a new sub-workflow instance and returns a future for its result.
 func CreateSubWorkflowInstance[TResult any](ctx sync.Context, options SubWorkflowOptions, workflow interface{}, args ...interface{}) Future[TResult] {
-	return withRetries(ctx, options.RetryOptions, func(ctx sync.Context, attempt int) Future[TResult] {
-		return createSubWorkflowInstance[TResult](ctx, options, attempt, workflow, args...)
-	})
+	// Nobody can wait for the sub-workflow to start, don't ask to be notified
+	return startSubWorkflowInstance[TResult](ctx, options, false, workflow, args...).Result()
 }
 
-func createSubWorkflowInstance[TResult any](ctx sync.Context, options SubWorkflowOptions, attempt int, wf interface{}, args ...interface{}) Future[TResult] {
+// StartSubWorkflowInstance creates a new sub-workflow instance and returns a handle, which allows to wait for the
+// sub-workflow to be started as well as for its result.
+func StartSubWorkflowInstance[TResult any](ctx sync.Context, options SubWorkflowOptions, workflow interface{}, args ...interface{}) SubWorkflowHandle[TResult] {
+	return startSubWorkflowInstance[TResult](ctx, options, true, workflow, args...)
+}
+
+func startSubWorkflowInstance[TResult any](ctx sync.Context, options SubWorkflowOptions, notifyStarted bool, workflow interface{}, args ...interface{}) SubWorkflowHandle[TResult] {
+	started := sync.NewFuture[*Instance]()
+
+	var f Future[TResult]
+	if options.Detached {
+		f = createSubWorkflowInstance[TResult](ctx, options, 0, notifyStarted, started, workflow, args...)
+	} else {
+		f = withRetries(ctx, options.RetryOptions, func(ctx sync.Context, attempt int) Future[TResult] {
+			return createSubWorkflowInstance[TResult](ctx, options, attempt, notifyStarted, started, workflow, args...)
+		})
+	}
+
+	return &subWorkflowHandle[TResult]{
+		started: started,
+		result:  f,
+	}
+}
+
+func createSubWorkflowInstance[TResult any](
+	ctx sync.Context, options SubWorkflowOptions, attempt int, notifyStarted bool, started sync.SettableFuture[*Instance], wf interface{}, args ...interface{},
+) Future[TResult] {
 	f := sync.NewFuture[TResult]()
 
+	// Only the first attempt determines the started sub-workflow instance
+	setStarted := func(instance *Instance, err error) {
+		if !started.HasValue() {
+			started.Set(instance, err)
+		}
+	}
+
 	// If the context is already canceled, return immediately.
 	if ctx.Err() != nil {
 		f.Set(*new(TResult), ctx.Err())
+		setStarted(nil, ctx.Err())
 		return f
 	}
 
 	// Check return type
 	if err := a.ReturnTypeMatch[TResult](wf); err != nil {
 		f.Set(*new(TResult), err)
+		setStarted(nil, err)
 		return f
 	}
 
 	// Check arguments
 	if err := a.ParamsMatch(wf, args...); err != nil {
 		f.Set(*new(TResult), err)
+		setStarted(nil, err)
 		return f
 	}
 
 	name := fn.Name(wf)
 
+	wfState := workflowstate.WorkflowState(ctx)
+
+	instanceID := options.InstanceID
+	if instanceID == "" {
+		instanceID = uuid.NewString()
+	}
+
+	// Inputs are recorded in the history of the sub-workflow as well. When replaying they have been stored for it
+	// already, and a generated instance id is replaced with the recorded one.
 	cv := converter.GetConverter(ctx)
-	inputs, err := a.ArgsToInputs(cv, args...)
+	inputsConverter := cv
+	if !wfState.Replaying() {
+		inputsConverter = converter.SharedWith(cv, core.NewWorkflowInstance(instanceID, ""))
+	}
+
+	inputs, err := a.ArgsToInputs(inputsConverter, args...)
 	if err != nil {
-		f.Set(*new(TResult), fmt.Errorf("converting subworkflow input: %w", err))
+		err = fmt.Errorf("converting subworkflow input: %w", err)
+		f.Set(*new(TResult), err)
+		setStarted(nil, err)
 		return f
 	}
 
-	wfState := workflowstate.WorkflowState(ctx)
 	scheduleEventID := wfState.GetNextScheduleEventID()
 
 	ctx, span := workflowtracer.Tracer(ctx).Start(ctx,
@@ -84,13 +169,39 @@ func createSubWorkflowInstance[TResult any](ctx sync.Context, options SubWorkflo
 	metadata := &core.WorkflowMetadata{}
 	span.Marshal(metadata)
 
-	cmd := command.NewScheduleSubWorkflowCommand(scheduleEventID, wfState.Instance(), options.InstanceID, name, inputs, metadata)
+	if err := contextpropagation.InjectFromWorkflow(ctx, metadata); err != nil {
+		f.Set(*new(TResult), err)
+		setStarted(nil, err)
+		return f
+	}
+
+	cmd := command.NewScheduleSubWorkflowCommand(scheduleEventID, wfState.Instance(), instanceID, name, inputs, metadata, attempt, options.Detached)
+	cmd.NotifyStarted = notifyStarted
 	wfState.AddCommand(cmd)
+
+	if options.Detached {
+		// Detached sub-workflows do not report back their result, resolve the future once the sub-workflow has
+		// been started. The future is not tracked, so the parent can complete without waiting for the sub-workflow.
+		cmd.WhenStarted(func(instance *core.WorkflowInstance, err error) {
+			setStarted(instance, err)
+			f.Set(*new(TResult), err)
+		})
+
+		return f
+	}
+
+	cmd.WhenStarted(setStarted)
+
 	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(cv, f))
 
 	// Check if the channel is cancelable
 	if c, cancelable := ctx.Done().(sync.CancelChannel); cancelable {
 		c.AddReceiveCallback(func(v struct{}, ok bool) {
+			if fi, ok := f.(sync.FutureInternal[TResult]); ok && fi.Ready() {
+				// Sub-workflow has already completed, nothing to cancel
+				return
+			}
+
 			cmd.Cancel()
 			if cmd.State() == command.CommandState_Canceled {
 				// Remove the sub-workflow future from the workflow state and mark it as canceled if it hasn't already fired
@@ -100,6 +211,9 @@ func createSubWorkflowInstance[TResult any](ctx sync.Context, options SubWorkflo
 						f.Set(*new(TResult), sync.Canceled)
 					}
 				}
+
+				// The sub-workflow was canceled before it was started
+				setStarted(nil, sync.Canceled)
 			}
 		})
 	}
@@ -25,7 +25,7 @@ func ScheduleTimer(ctx Context, delay time.Duration) Future[struct{}] {
 
 	scheduleEventID := wfState.GetNextScheduleEventID()
 	at := Now(ctx).Add(delay)
-	timerCmd := command.NewScheduleTimerCommand(scheduleEventID, at)
+	timerCmd := command.NewScheduleTimerCommand(scheduleEventID, at, delay)
 	wfState.AddCommand(timerCmd)
 
 	wfState.TrackFuture(scheduleEventID, workflowstate.AsDecodingSettable(converter.GetConverter(ctx), f))
@@ -51,16 +51,19 @@ func ScheduleTimer(ctx Context, delay time.Duration) Future[struct{}] {
 			}
 			canceled = true
 
-			timerCmd.Cancel()
-
 			// Remove the timer future from the workflow state and mark it as canceled if it hasn't already fired. This is different
 			// from subworkflow behavior, where we want to wait for the subworkflow to complete before proceeding. Here we can
 			// continue right away.
 			if fi, ok := f.(sync.FutureInternal[struct{}]); ok {
-				if !fi.Ready() {
-					wfState.RemoveFuture(scheduleEventID)
-					f.Set(v, sync.Canceled)
+				if fi.Ready() {
+					// Timer has already fired, nothing to cancel
+					return
 				}
+
+				timerCmd.Cancel()
+
+				wfState.RemoveFuture(scheduleEventID)
+				f.Set(v, sync.Canceled)
 			}
 		})
 	}
@@ -1,6 +1,7 @@
 package workflow
 
 import (
+	"github.com/cschleiden/go-workflows/internal/contextpropagation"
 	"github.com/cschleiden/go-workflows/internal/core"
 )
 
@@ -8,4 +9,9 @@ type (
 	Instance = core.WorkflowInstance
 	Metadata = core.WorkflowMetadata
 	Workflow = interface{}
+
+	// ContextPropagator propagates values, like a tenant or request id, from the context.Context passed to the
+	// client to workflows, and from workflows to their activities and sub-workflows. Configure propagators using the
+	// backend options.
+	ContextPropagator = contextpropagation.ContextPropagator
 )